@@ -0,0 +1,453 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// sessionBindExtension is sent by OpenSSH clients to tell the agent which
+// host a connection is authenticating to
+const sessionBindExtension = "session-bind@openssh.com"
+
+var errLocked = errors.New("agent: locked")
+
+// entry is a key held in memory by the agent
+type entry struct {
+	signer  ssh.Signer
+	comment string
+	confirm bool
+	timer   *time.Timer
+}
+
+// Agent is an in-memory ssh-agent implementation. Keys are only ever held
+// decrypted in process memory and are dropped once their lifetime expires.
+type Agent struct {
+	mu         sync.Mutex
+	keys       []*entry
+	locked     bool
+	passphrase []byte
+
+	// vaultKey is the keystore vault master key of an unlocked session
+	vaultKey   []byte
+	vaultTimer *time.Timer
+
+	rules     RuleSource
+	confirmer Confirmer
+	hostKeys  HostKeyMatcher
+}
+
+// New creates an empty agent
+func New() *Agent {
+	return &Agent{}
+}
+
+// SetRules installs the source of per-key usage rules
+func (a *Agent) SetRules(rules RuleSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rules = rules
+}
+
+// SetConfirmer sets how sign requests needing approval are confirmed
+func (a *Agent) SetConfirmer(confirmer Confirmer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.confirmer = confirmer
+}
+
+// SetHostKeyMatcher sets how destination host keys are mapped to hosts
+func (a *Agent) SetHostKeyMatcher(matcher HostKeyMatcher) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hostKeys = matcher
+}
+
+// List returns the identities known to the agent
+func (a *Agent) List() ([]*sshagent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		// Like OpenSSH, a locked agent reports no identities
+		return nil, nil
+	}
+
+	ids := make([]*sshagent.Key, 0, len(a.keys))
+	for _, e := range a.keys {
+		pub := e.signer.PublicKey()
+		ids = append(ids, &sshagent.Key{
+			Format:  pub.Type(),
+			Blob:    pub.Marshal(),
+			Comment: e.comment,
+		})
+	}
+	return ids, nil
+}
+
+// Add adds a private key to the agent
+func (a *Agent) Add(key sshagent.AddedKey) error {
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+	if key.Certificate != nil {
+		signer, err = ssh.NewCertSigner(key.Certificate, signer)
+		if err != nil {
+			return fmt.Errorf("agent: %w", err)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		return errLocked
+	}
+
+	e := &entry{signer: signer, comment: key.Comment, confirm: key.ConfirmBeforeUse}
+
+	// Replace an existing identity instead of holding it twice
+	blob := signer.PublicKey().Marshal()
+	for i, existing := range a.keys {
+		if bytes.Equal(existing.signer.PublicKey().Marshal(), blob) {
+			a.stopTimer(existing)
+			a.keys = append(a.keys[:i], a.keys[i+1:]...)
+			break
+		}
+	}
+
+	if key.LifetimeSecs > 0 {
+		lifetime := time.Duration(key.LifetimeSecs) * time.Second
+		e.timer = time.AfterFunc(lifetime, func() { a.expire(e) })
+	}
+
+	a.keys = append(a.keys, e)
+	return nil
+}
+
+// Remove removes the identity matching the given public key
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		return errLocked
+	}
+
+	blob := key.Marshal()
+	for i, e := range a.keys {
+		if bytes.Equal(e.signer.PublicKey().Marshal(), blob) {
+			a.stopTimer(e)
+			a.keys = append(a.keys[:i], a.keys[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("agent: key not found")
+}
+
+// RemoveAll removes every identity from the agent
+func (a *Agent) RemoveAll() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		return errLocked
+	}
+
+	for _, e := range a.keys {
+		a.stopTimer(e)
+	}
+	a.keys = nil
+	return nil
+}
+
+// Lock locks the agent with a passphrase until Unlock is called
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		return errLocked
+	}
+	a.locked = true
+	a.passphrase = append([]byte(nil), passphrase...)
+	a.dropVaultKey()
+	return nil
+}
+
+// Unlock undoes the effect of Lock
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.locked {
+		return errors.New("agent: not locked")
+	}
+	if subtle.ConstantTimeCompare(passphrase, a.passphrase) != 1 {
+		return errors.New("agent: incorrect passphrase")
+	}
+	a.locked = false
+	a.passphrase = nil
+	return nil
+}
+
+// Sign signs data with the identity matching the given public key
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data honouring the RSA SHA-2 signature flags. Requests
+// made without a session cannot prove their destination, so keys restricted
+// to bound hosts are refused.
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags sshagent.SignatureFlags) (*ssh.Signature, error) {
+	return a.sign(key, data, flags, nil)
+}
+
+// sign authorizes and performs a sign request for a connection with the given session binds
+func (a *Agent) sign(key ssh.PublicKey, data []byte, flags sshagent.SignatureFlags, binds []*sessionBind) (*ssh.Signature, error) {
+	a.mu.Lock()
+	if a.locked {
+		a.mu.Unlock()
+		return nil, errLocked
+	}
+	e := a.findEntry(key)
+	a.mu.Unlock()
+
+	if e == nil {
+		return nil, errors.New("agent: key not found")
+	}
+
+	if err := a.authorize(e, binds); err != nil {
+		return nil, err
+	}
+
+	signer := e.signer
+	if flags == 0 {
+		return signer.Sign(rand.Reader, data)
+	}
+
+	algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("agent: signature does not support non-default signature algorithm: %T", signer)
+	}
+
+	var algorithm string
+	switch flags {
+	case sshagent.SignatureFlagRsaSha256:
+		algorithm = ssh.KeyAlgoRSASHA256
+	case sshagent.SignatureFlagRsaSha512:
+		algorithm = ssh.KeyAlgoRSASHA512
+	default:
+		return nil, fmt.Errorf("agent: unsupported signature flags: %d", flags)
+	}
+	return algorithmSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+}
+
+// authorize applies the key's rule to a sign request. Any failure to
+// evaluate the rule denies the request.
+func (a *Agent) authorize(e *entry, binds []*sessionBind) error {
+	a.mu.Lock()
+	rules, confirmer := a.rules, a.confirmer
+	a.mu.Unlock()
+
+	var rule *Rule
+	if rules != nil {
+		var err error
+		// Certificates share the rule of the key they certify
+		pub := e.signer.PublicKey()
+		if cert, ok := pub.(*ssh.Certificate); ok {
+			pub = cert.Key
+		}
+		rule, err = rules(pub)
+		if err != nil {
+			return fmt.Errorf("agent: failed to evaluate key policy: %w", err)
+		}
+	}
+
+	name := e.comment
+	if rule != nil && rule.KeyName != "" {
+		name = rule.KeyName
+	}
+
+	destination := ""
+	if rule != nil {
+		if rule.Policy == models.KeyPolicyNever {
+			return fmt.Errorf("agent: key %s has policy 'never'", name)
+		}
+		if rule.Restricted {
+			dest, err := a.checkDestination(name, rule, binds)
+			if err != nil {
+				return err
+			}
+			destination = dest.String()
+		}
+	}
+
+	if !e.confirm && (rule == nil || rule.Policy != models.KeyPolicyAsk) {
+		return nil
+	}
+
+	if confirmer == nil {
+		return fmt.Errorf("agent: key %s requires confirmation but no confirmer is configured", name)
+	}
+
+	prompt := fmt.Sprintf("Allow use of key %s (%s)?", name, ssh.FingerprintSHA256(e.signer.PublicKey()))
+	if destination != "" {
+		prompt = fmt.Sprintf("Allow use of key %s (%s) to authenticate to %s?", name, ssh.FingerprintSHA256(e.signer.PublicKey()), destination)
+	}
+
+	ok, err := confirmer.Confirm(prompt)
+	if err != nil {
+		return fmt.Errorf("agent: confirmation failed: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("agent: use of key %s was refused", name)
+	}
+	return nil
+}
+
+// checkDestination returns the allowed destination the connection is bound to
+func (a *Agent) checkDestination(name string, rule *Rule, binds []*sessionBind) (Destination, error) {
+	if len(binds) == 0 {
+		return Destination{}, fmt.Errorf("agent: key %s is restricted to its hosts but the client did not identify the destination", name)
+	}
+	for _, b := range binds {
+		if b.isForwarding {
+			return Destination{}, fmt.Errorf("agent: key %s is restricted to its hosts and cannot be used through a forwarded agent", name)
+		}
+	}
+
+	a.mu.Lock()
+	matcher := a.hostKeys
+	a.mu.Unlock()
+
+	if matcher != nil {
+		hostKey := binds[len(binds)-1].hostKey
+		for _, dest := range rule.Destinations {
+			if matcher.Matches(dest, hostKey) {
+				return dest, nil
+			}
+		}
+	}
+
+	return Destination{}, fmt.Errorf("agent: key %s is not allowed for this destination", name)
+}
+
+// Signers returns signers for all identities held by the agent
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked {
+		return nil, errLocked
+	}
+
+	signers := make([]ssh.Signer, 0, len(a.keys))
+	for _, e := range a.keys {
+		signers = append(signers, e.signer)
+	}
+	return signers, nil
+}
+
+// NewSession returns a view of the agent for a single client connection,
+// which tracks the destination the connection is bound to
+func (a *Agent) NewSession() sshagent.ExtendedAgent {
+	return &session{Agent: a}
+}
+
+// findEntry returns the entry for a public key. Callers must hold a.mu.
+func (a *Agent) findEntry(key ssh.PublicKey) *entry {
+	blob := key.Marshal()
+	for _, e := range a.keys {
+		if bytes.Equal(e.signer.PublicKey().Marshal(), blob) {
+			return e
+		}
+	}
+	return nil
+}
+
+// session is the per-connection view of an Agent
+type session struct {
+	*Agent
+
+	mu    sync.Mutex
+	binds []*sessionBind
+}
+
+// Sign signs data for the destination this connection is bound to
+func (s *session) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return s.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data for the destination this connection is bound to
+func (s *session) SignWithFlags(key ssh.PublicKey, data []byte, flags sshagent.SignatureFlags) (*ssh.Signature, error) {
+	s.mu.Lock()
+	binds := append([]*sessionBind(nil), s.binds...)
+	s.mu.Unlock()
+
+	return s.Agent.sign(key, data, flags, binds)
+}
+
+// Extension records session binds and passes other extensions to the agent.
+// The vault master key is never handed to or taken from a forwarded agent.
+func (s *session) Extension(extensionType string, contents []byte) ([]byte, error) {
+	if extensionType != sessionBindExtension {
+		if isVaultKeyExtension(extensionType) && s.forwarded() {
+			return nil, errVaultForwarded
+		}
+		return s.Agent.Extension(extensionType, contents)
+	}
+
+	bind, err := parseSessionBind(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.binds = append(s.binds, bind)
+	s.mu.Unlock()
+	return nil, nil
+}
+
+// forwarded reports whether the connection came through a forwarded agent
+func (s *session) forwarded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.binds {
+		if b.isForwarding {
+			return true
+		}
+	}
+	return false
+}
+
+// expire drops an entry once its lifetime has elapsed
+func (a *Agent) expire(target *entry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, e := range a.keys {
+		if e == target {
+			a.keys = append(a.keys[:i], a.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// stopTimer cancels the expiry timer of an entry. Callers must hold a.mu.
+func (a *Agent) stopTimer(e *entry) {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+}
@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func startTestServer(t *testing.T) *Client {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	srv := NewServer(New(), socket)
+	if err := srv.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestAgentSignsOverSocket(t *testing.T) {
+	client := startTestServer(t)
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	if err := client.Add(sshagent.AddedKey{PrivateKey: priv, Comment: "work"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	keys, err := client.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Comment != "work" {
+		t.Fatalf("unexpected identities: %v", keys)
+	}
+
+	data := []byte("session data")
+	sig, err := client.Sign(keys[0], data)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := keys[0].Verify(data, sig); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+
+	if err := client.Remove(keys[0]); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if keys, _ := client.List(); len(keys) != 0 {
+		t.Fatalf("expected no identities after Remove, got %d", len(keys))
+	}
+}
+
+func TestAgentKeyLifetime(t *testing.T) {
+	client := startTestServer(t)
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	if err := client.Add(sshagent.AddedKey{PrivateKey: priv, LifetimeSecs: 1}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if keys, _ := client.List(); len(keys) != 1 {
+		t.Fatalf("expected key to be loaded")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if keys, _ := client.List(); len(keys) == 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("key was not dropped after its lifetime expired")
+}
+
+func TestAgentLock(t *testing.T) {
+	a := New()
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	if err := a.Add(sshagent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	signer, _ := ssh.NewSignerFromKey(priv)
+
+	if err := a.Lock([]byte("secret")); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected locked agent to refuse to sign")
+	}
+	if err := a.Unlock([]byte("wrong")); err == nil {
+		t.Fatal("expected wrong passphrase to fail")
+	}
+	if err := a.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Fatalf("Sign after unlock failed: %v", err)
+	}
+}
+
+func TestAgentVaultSession(t *testing.T) {
+	client := startTestServer(t)
+
+	if _, err := client.VaultKey(); err == nil {
+		t.Fatal("expected a locked vault session")
+	}
+
+	master := bytes.Repeat([]byte{7}, 32)
+	if err := client.UnlockVault(master, 0); err != nil {
+		t.Fatalf("UnlockVault failed: %v", err)
+	}
+	got, err := client.VaultKey()
+	if err != nil {
+		t.Fatalf("VaultKey failed: %v", err)
+	}
+	if !bytes.Equal(got, master) {
+		t.Fatal("agent returned a different master key")
+	}
+
+	if err := client.LockVault(); err != nil {
+		t.Fatalf("LockVault failed: %v", err)
+	}
+	if _, err := client.VaultKey(); err == nil {
+		t.Fatal("expected the vault session to be locked")
+	}
+
+	if err := client.UnlockVault(master, time.Second); err != nil {
+		t.Fatalf("UnlockVault failed: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := client.VaultKey(); err == nil {
+		t.Fatal("expected the vault session to expire")
+	}
+}
+
+func TestAgentVaultRefusesForwardedSessions(t *testing.T) {
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, _ := ssh.NewSignerFromKey(hostPriv)
+
+	a := New()
+	master := bytes.Repeat([]byte{7}, 32)
+	unlock := ssh.Marshal(vaultUnlockRequest{MasterKey: master})
+
+	forwarded := a.NewSession()
+	if _, err := forwarded.Extension(sessionBindExtension, sessionBindRequest(t, hostSigner, true)); err != nil {
+		t.Fatalf("session-bind failed: %v", err)
+	}
+	if _, err := forwarded.Extension(vaultUnlockExtension, unlock); err == nil {
+		t.Fatal("expected a forwarded agent to be refused the vault unlock")
+	}
+
+	local := a.NewSession()
+	if _, err := local.Extension(vaultUnlockExtension, unlock); err != nil {
+		t.Fatalf("vault unlock failed: %v", err)
+	}
+	if _, err := forwarded.Extension(vaultKeyExtension, nil); err == nil {
+		t.Fatal("expected a forwarded agent to be refused the master key")
+	}
+	if _, err := local.Extension(vaultKeyExtension, nil); err != nil {
+		t.Fatalf("expected a local session to get the master key: %v", err)
+	}
+}
+
+func TestServerRefusesLiveSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+
+	first := NewServer(New(), socket)
+	if err := first.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go first.Serve()
+	defer first.Close()
+
+	if err := NewServer(New(), socket).Listen(); err == nil {
+		t.Fatal("expected second agent on the same socket to fail")
+	}
+}
+
+type fakeConfirmer struct {
+	answer  bool
+	prompts []string
+}
+
+func (f *fakeConfirmer) Confirm(prompt string) (bool, error) {
+	f.prompts = append(f.prompts, prompt)
+	return f.answer, nil
+}
+
+func TestAgentKeyPolicies(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+
+	rule := &Rule{KeyName: "work"}
+	confirmer := &fakeConfirmer{}
+
+	a := New()
+	a.SetRules(func(ssh.PublicKey) (*Rule, error) { return rule, nil })
+	a.SetConfirmer(confirmer)
+	if err := a.Add(sshagent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	rule.Policy = models.KeyPolicyNever
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected 'never' key to be refused")
+	}
+
+	rule.Policy = models.KeyPolicyAsk
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected declined confirmation to refuse signing")
+	}
+	confirmer.answer = true
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Fatalf("expected confirmed request to sign: %v", err)
+	}
+	if len(confirmer.prompts) != 2 {
+		t.Errorf("expected 2 confirmation prompts, got %d", len(confirmer.prompts))
+	}
+
+	rule.Policy = models.KeyPolicyAuto
+	confirmer.answer = false
+	if _, err := a.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Fatalf("expected 'auto' key to sign without confirmation: %v", err)
+	}
+}
+
+// sessionBindRequest builds a signed session-bind@openssh.com payload for hostSigner
+func sessionBindRequest(t *testing.T, hostSigner ssh.Signer, forwarding bool) []byte {
+	t.Helper()
+
+	sessionID := make([]byte, 32)
+	rand.Read(sessionID)
+	sig, err := hostSigner.Sign(rand.Reader, sessionID)
+	if err != nil {
+		t.Fatalf("failed to sign session id: %v", err)
+	}
+
+	return ssh.Marshal(struct {
+		HostKey      []byte
+		SessionID    []byte
+		Signature    []byte
+		IsForwarding bool
+	}{hostSigner.PublicKey().Marshal(), sessionID, ssh.Marshal(sig), forwarding})
+}
+
+func TestAgentHostRestriction(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, _ := ssh.NewSignerFromKey(hostPriv)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherPriv)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"work.example.com"}, hostSigner.PublicKey()) + "\n"
+	if err := os.WriteFile(knownHosts, []byte(line), 0600); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+
+	key := &models.Key{Name: "work", Policy: models.KeyPolicyAuto, RestrictToHosts: true}
+	hosts := []models.Host{
+		{Host: "work", Hostname: "work.example.com", KeyName: "work"},
+		{Host: "personal.example.com", KeyName: "personal"},
+	}
+	rule := RuleForKey(key, hosts, models.KeyPolicyAsk)
+	if len(rule.Destinations) != 1 || rule.Destinations[0].Host != "work.example.com" {
+		t.Fatalf("unexpected destinations: %+v", rule.Destinations)
+	}
+
+	a := New()
+	a.SetRules(func(ssh.PublicKey) (*Rule, error) { return rule, nil })
+	a.SetHostKeyMatcher(&KnownHostsMatcher{Files: []string{knownHosts}})
+	if err := a.Add(sshagent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Without a session bind the destination is unknown
+	if _, err := a.NewSession().Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected restricted key to be refused without a session bind")
+	}
+
+	bound := a.NewSession()
+	if _, err := bound.Extension(sessionBindExtension, sessionBindRequest(t, hostSigner, false)); err != nil {
+		t.Fatalf("session-bind failed: %v", err)
+	}
+	if _, err := bound.Sign(signer.PublicKey(), []byte("data")); err != nil {
+		t.Fatalf("expected signing for bound host to succeed: %v", err)
+	}
+
+	other := a.NewSession()
+	if _, err := other.Extension(sessionBindExtension, sessionBindRequest(t, otherSigner, false)); err != nil {
+		t.Fatalf("session-bind failed: %v", err)
+	}
+	if _, err := other.Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected signing for an unknown host to be refused")
+	}
+
+	forwarded := a.NewSession()
+	forwarded.Extension(sessionBindExtension, sessionBindRequest(t, hostSigner, true))
+	if _, err := forwarded.Sign(signer.PublicKey(), []byte("data")); err == nil {
+		t.Fatal("expected forwarded agent use to be refused")
+	}
+
+	// A bind whose signature doesn't match the host key is rejected
+	forged := sessionBindRequest(t, otherSigner, false)
+	var msg struct {
+		HostKey      []byte
+		SessionID    []byte
+		Signature    []byte
+		IsForwarding bool
+	}
+	ssh.Unmarshal(forged, &msg)
+	msg.HostKey = hostSigner.PublicKey().Marshal()
+	if _, err := a.NewSession().Extension(sessionBindExtension, ssh.Marshal(msg)); err == nil {
+		t.Fatal("expected forged session-bind to be rejected")
+	}
+}
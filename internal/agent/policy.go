@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// Destination is a host a key is allowed to authenticate to
+type Destination struct {
+	Host string
+	Port int
+}
+
+// String returns the destination in host[:port] form
+func (d Destination) String() string {
+	if d.Port > 0 && d.Port != 22 {
+		return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+	}
+	return d.Host
+}
+
+// Rule constrains how an identity may be used for signing
+type Rule struct {
+	KeyName string
+	Policy  models.KeyPolicy
+
+	// Restricted limits signing to Destinations; an empty list then denies every host
+	Restricted   bool
+	Destinations []Destination
+}
+
+// RuleSource looks up the rule for an identity. It returns nil for identities
+// without a rule, which are used without restriction.
+type RuleSource func(key ssh.PublicKey) (*Rule, error)
+
+// Confirmer asks the user to approve a sign request
+type Confirmer interface {
+	Confirm(prompt string) (bool, error)
+}
+
+// AskpassConfirmer confirms sign requests through an ssh-askpass compatible program
+type AskpassConfirmer struct {
+	Program string
+}
+
+// NewAskpassConfirmer returns a confirmer for program, falling back to
+// $SKM_ASKPASS and then $SSH_ASKPASS when program is empty
+func NewAskpassConfirmer(program string) *AskpassConfirmer {
+	if program == "" {
+		program = os.Getenv("SKM_ASKPASS")
+	}
+	if program == "" {
+		program = os.Getenv("SSH_ASKPASS")
+	}
+	return &AskpassConfirmer{Program: program}
+}
+
+// Confirm runs the askpass program in confirm mode; a zero exit status approves
+func (c *AskpassConfirmer) Confirm(prompt string) (bool, error) {
+	if c.Program == "" {
+		return false, errors.New("no askpass program configured (set agent.askpass, SKM_ASKPASS or SSH_ASKPASS)")
+	}
+
+	cmd := exec.Command(c.Program, prompt)
+	cmd.Env = append(os.Environ(), "SSH_ASKPASS_PROMPT=confirm")
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run askpass program: %w", err)
+	}
+	return true, nil
+}
+
+// HostKeyMatcher reports whether hostKey is a known key for a destination
+type HostKeyMatcher interface {
+	Matches(dest Destination, hostKey ssh.PublicKey) bool
+}
+
+// KnownHostsMatcher matches host keys against OpenSSH known_hosts files
+type KnownHostsMatcher struct {
+	Files []string
+}
+
+// Matches re-reads the known_hosts files so newly trusted hosts apply immediately
+func (m *KnownHostsMatcher) Matches(dest Destination, hostKey ssh.PublicKey) bool {
+	var files []string
+	for _, f := range m.Files {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return false
+	}
+
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return false
+	}
+
+	port := dest.Port
+	if port == 0 {
+		port = 22
+	}
+	address := net.JoinHostPort(dest.Host, strconv.Itoa(port))
+	remote := &net.TCPAddr{IP: net.IPv4zero, Port: port}
+
+	return callback(address, remote, hostKey) == nil
+}
+
+// sessionBind is a session-bind@openssh.com request sent by ssh on connect
+type sessionBind struct {
+	hostKey      ssh.PublicKey
+	isForwarding bool
+}
+
+// parseSessionBind decodes and verifies a session-bind@openssh.com request.
+// The host's signature over the session ID proves the bind was not forged.
+func parseSessionBind(contents []byte) (*sessionBind, error) {
+	var msg struct {
+		HostKey      []byte
+		SessionID    []byte
+		Signature    []byte
+		IsForwarding bool
+	}
+	if err := ssh.Unmarshal(contents, &msg); err != nil {
+		return nil, fmt.Errorf("agent: malformed session-bind request: %w", err)
+	}
+
+	hostKey, err := ssh.ParsePublicKey(msg.HostKey)
+	if err != nil {
+		return nil, fmt.Errorf("agent: invalid host key in session-bind: %w", err)
+	}
+
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(msg.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("agent: invalid signature in session-bind: %w", err)
+	}
+	if err := hostKey.Verify(msg.SessionID, &sig); err != nil {
+		return nil, fmt.Errorf("agent: session-bind signature does not verify: %w", err)
+	}
+
+	return &sessionBind{hostKey: hostKey, isForwarding: msg.IsForwarding}, nil
+}
+
+// RuleForKey builds the rule for a managed key from its policy and the hosts bound to it
+func RuleForKey(key *models.Key, hosts []models.Host, defaultPolicy models.KeyPolicy) *Rule {
+	rule := &Rule{
+		KeyName:    key.Name,
+		Policy:     key.EffectivePolicy(defaultPolicy),
+		Restricted: key.RestrictToHosts,
+	}
+	// Archived and revoked keys must not sign even if still loaded
+	if !key.IsUsable() {
+		rule.Policy = models.KeyPolicyNever
+	}
+	if !rule.Restricted {
+		return rule
+	}
+
+	for _, h := range hosts {
+		if h.KeyName != key.Name {
+			continue
+		}
+		name := h.RemoteName()
+		// Wildcard patterns cannot be matched against a concrete host key
+		if strings.ContainsAny(name, "*?!") {
+			continue
+		}
+		rule.Destinations = append(rule.Destinations, Destination{Host: name, Port: h.Port})
+	}
+	return rule
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+// DefaultSocketName is the socket file name used inside the SKM config directory
+const DefaultSocketName = "agent.sock"
+
+// Server serves an Agent over a Unix socket
+type Server struct {
+	agent      *Agent
+	socketPath string
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewServer creates a server for the given agent and socket path
+func NewServer(a *Agent, socketPath string) *Server {
+	return &Server{
+		agent:      a,
+		socketPath: socketPath,
+	}
+}
+
+// SocketPath returns the path the server listens on
+func (s *Server) SocketPath() string {
+	return s.socketPath
+}
+
+// Listen creates the Unix socket, replacing a stale one left behind by a
+// previous agent that did not shut down cleanly
+func (s *Server) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(s.socketPath); err == nil {
+		if conn, err := net.Dial("unix", s.socketPath); err == nil {
+			conn.Close()
+			return fmt.Errorf("an agent is already listening on %s", s.socketPath)
+		}
+		if err := os.Remove(s.socketPath); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	return nil
+}
+
+// Serve accepts connections until Close is called
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+
+	if listener == nil {
+		return errors.New("agent server is not listening")
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		go func(c net.Conn) {
+			defer c.Close()
+			_ = sshagent.ServeAgent(s.agent.NewSession(), c)
+		}(conn)
+	}
+}
+
+// ListenAndServe listens on the socket and serves until Close is called
+func (s *Server) ListenAndServe() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Close stops accepting connections and removes the socket file
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.listener == nil {
+		return nil
+	}
+	s.closed = true
+
+	err := s.listener.Close()
+	_ = os.Remove(s.socketPath)
+	return err
+}
+
+// Client is a connection to a running agent
+type Client struct {
+	sshagent.ExtendedAgent
+	conn net.Conn
+}
+
+// Dial connects to the agent listening on socketPath
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("agent is not running at %s: %w", socketPath, err)
+	}
+	return &Client{
+		ExtendedAgent: sshagent.NewClient(conn),
+		conn:          conn,
+	}, nil
+}
+
+// Close closes the connection to the agent
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
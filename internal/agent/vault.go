@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+// Extensions holding the vault master key for an unlocked session
+const (
+	vaultUnlockExtension = "vault-unlock@skm"
+	vaultKeyExtension    = "vault-key@skm"
+	vaultLockExtension   = "vault-lock@skm"
+)
+
+// msgSuccess prefixes extension replies that carry data
+const msgSuccess = 6
+
+var (
+	errVaultLocked    = errors.New("agent: vault is locked")
+	errVaultForwarded = errors.New("agent: the vault cannot be used through a forwarded agent")
+)
+
+// isVaultKeyExtension reports whether an extension carries the master key
+func isVaultKeyExtension(extensionType string) bool {
+	return extensionType == vaultUnlockExtension || extensionType == vaultKeyExtension
+}
+
+// vaultUnlockRequest is the payload of vaultUnlockExtension
+type vaultUnlockRequest struct {
+	MasterKey    []byte
+	LifetimeSecs uint32
+}
+
+// vaultKeyReply is the payload of a vaultKeyExtension reply
+type vaultKeyReply struct {
+	MasterKey []byte
+}
+
+// Extension handles the vault session extensions
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	switch extensionType {
+	case vaultUnlockExtension:
+		var req vaultUnlockRequest
+		if err := ssh.Unmarshal(contents, &req); err != nil {
+			return nil, err
+		}
+		a.unlockVault(req.MasterKey, time.Duration(req.LifetimeSecs)*time.Second)
+		return nil, nil
+
+	case vaultKeyExtension:
+		master, err := a.vaultMasterKey()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{msgSuccess}, ssh.Marshal(vaultKeyReply{MasterKey: master})...), nil
+
+	case vaultLockExtension:
+		a.lockVault()
+		return nil, nil
+
+	default:
+		return nil, sshagent.ErrExtensionUnsupported
+	}
+}
+
+// unlockVault holds the vault master key, for lifetime if it is not zero
+func (a *Agent) unlockVault(master []byte, lifetime time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.dropVaultKey()
+	a.vaultKey = append([]byte(nil), master...)
+	if lifetime > 0 {
+		a.vaultTimer = time.AfterFunc(lifetime, a.lockVault)
+	}
+}
+
+// vaultMasterKey returns the held master key
+func (a *Agent) vaultMasterKey() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.locked || a.vaultKey == nil {
+		return nil, errVaultLocked
+	}
+	return append([]byte(nil), a.vaultKey...), nil
+}
+
+// lockVault forgets the master key
+func (a *Agent) lockVault() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.dropVaultKey()
+}
+
+// dropVaultKey wipes the master key. Callers must hold a.mu.
+func (a *Agent) dropVaultKey() {
+	if a.vaultTimer != nil {
+		a.vaultTimer.Stop()
+		a.vaultTimer = nil
+	}
+	for i := range a.vaultKey {
+		a.vaultKey[i] = 0
+	}
+	a.vaultKey = nil
+}
+
+// UnlockVault hands the vault master key to the agent for lifetime (zero
+// keeps it until the vault is locked or the agent stops)
+func (c *Client) UnlockVault(master []byte, lifetime time.Duration) error {
+	_, err := c.Extension(vaultUnlockExtension, ssh.Marshal(vaultUnlockRequest{
+		MasterKey:    master,
+		LifetimeSecs: uint32(lifetime / time.Second),
+	}))
+	return err
+}
+
+// VaultKey returns the vault master key held by the agent
+func (c *Client) VaultKey() ([]byte, error) {
+	reply, err := c.Extension(vaultKeyExtension, nil)
+	if err != nil {
+		return nil, errVaultLocked
+	}
+	if len(reply) == 0 || reply[0] != msgSuccess {
+		return nil, errors.New("agent: invalid vault key reply")
+	}
+
+	var key vaultKeyReply
+	if err := ssh.Unmarshal(reply[1:], &key); err != nil {
+		return nil, err
+	}
+	return key.MasterKey, nil
+}
+
+// LockVault makes the agent forget the vault master key
+func (c *Client) LockVault() error {
+	_, err := c.Extension(vaultLockExtension, nil)
+	return err
+}
@@ -67,6 +67,34 @@ func (c *Client) FetchPrivateKeys() ([]PrivateKeyData, error) {
 	return keys, nil
 }
 
+// SyncHostKeys uploads pinned host keys to the server
+func (c *Client) SyncHostKeys(hosts []HostKeyData) error {
+	return c.doRequest("POST", "/api/v1/hostkeys", hosts, nil)
+}
+
+// FetchHostKeys retrieves pinned host keys from the server
+func (c *Client) FetchHostKeys() ([]HostKeyData, error) {
+	var hosts []HostKeyData
+	if err := c.doRequest("GET", "/api/v1/hostkeys", nil, &hosts); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// SyncTombstones uploads tombstones of deleted keys to the server
+func (c *Client) SyncTombstones(tombstones []models.KeyTombstone) error {
+	return c.doRequest("POST", "/api/v1/keys/tombstones", tombstones, nil)
+}
+
+// FetchTombstones retrieves tombstones of keys deleted on any device
+func (c *Client) FetchTombstones() ([]models.KeyTombstone, error) {
+	var tombstones []models.KeyTombstone
+	if err := c.doRequest("GET", "/api/v1/keys/tombstones", nil, &tombstones); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
 // GetDevices retrieves all devices for the current user
 func (c *Client) GetDevices() ([]models.Device, error) {
 	var devices []models.Device
@@ -76,9 +104,78 @@ func (c *Client) GetDevices() ([]models.Device, error) {
 	return devices, nil
 }
 
-// RevokeDevice revokes a device
-func (c *Client) RevokeDevice(deviceID string) error {
-	return c.doRequest("POST", fmt.Sprintf("/api/v1/devices/%s/revoke", deviceID), nil, nil)
+// RevokeDevice revokes a device. With revokeKeys every key created on the
+// device is revoked too.
+func (c *Client) RevokeDevice(deviceID string, revokeKeys bool) error {
+	path := fmt.Sprintf("/api/v1/devices/%s/revoke", deviceID)
+	if revokeKeys {
+		path += "?revoke_keys=true"
+	}
+	return c.doRequest("POST", path, nil, nil)
+}
+
+// FetchKRL downloads the server's key revocation list in the binary OpenSSH
+// format, for RevokedKeys in sshd_config
+func (c *Client) FetchKRL() ([]byte, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/api/v1/krl", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+// SignUserCertificate asks the server CA to issue a user certificate
+func (c *Client) SignUserCertificate(req *CertificateRequest) (*CertificateResponse, error) {
+	var resp CertificateResponse
+	if err := c.doRequest("POST", "/api/v1/ca/user/sign", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetUserCA retrieves the public key of the server's user CA
+func (c *Client) GetUserCA() (*CAPublicKey, error) {
+	var ca CAPublicKey
+	if err := c.doRequest("GET", "/api/v1/ca/user", nil, &ca); err != nil {
+		return nil, err
+	}
+	return &ca, nil
+}
+
+// SignHostCertificate asks the server CA to issue a host certificate. The
+// request's principals are the hostnames the certificate is valid for.
+func (c *Client) SignHostCertificate(req *CertificateRequest) (*CertificateResponse, error) {
+	var resp CertificateResponse
+	if err := c.doRequest("POST", "/api/v1/ca/host/sign", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetHostCA retrieves the public key of the server's host CA
+func (c *Client) GetHostCA() (*CAPublicKey, error) {
+	var ca CAPublicKey
+	if err := c.doRequest("GET", "/api/v1/ca/host", nil, &ca); err != nil {
+		return nil, err
+	}
+	return &ca, nil
 }
 
 // Login authenticates and retrieves a token
@@ -152,8 +249,28 @@ type PublicKeyData struct {
 	Tags        []string  `json:"tags,omitempty"`
 	Comment     string    `json:"comment,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
+	// SecurityKey is set for FIDO2 keys; only the public half is synced
+	SecurityKey *models.SecurityKeyInfo `json:"security_key,omitempty"`
+	// State is the lifecycle state of the key (empty = active)
+	State models.KeyState `json:"state,omitempty"`
+	// DeviceID is the device the key was created or imported on
+	DeviceID string `json:"device_id,omitempty"`
 }
 
+// RevokedKeyData records a key revoked by a user or with its device
+type RevokedKeyData struct {
+	Fingerprint string    `json:"fingerprint"`
+	PublicKey   string    `json:"public_key,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	DeviceID    string    `json:"device_id,omitempty"`
+	RevokedAt   time.Time `json:"revoked_at"`
+}
+
+// EncryptionMethodEnvelope marks private keys wrapped in a pkg/crypto envelope,
+// base64-encoded, under the sync passphrase. Other methods hold the key file
+// as it was stored.
+const EncryptionMethodEnvelope = "skm-envelope-v1"
+
 // PrivateKeyData represents encrypted private key data for sync
 type PrivateKeyData struct {
 	Name              string    `json:"name"`
@@ -165,3 +282,35 @@ type PrivateKeyData struct {
 	RecipientDeviceID string    `json:"recipient_device_id,omitempty"` // For device-specific encryption
 	CreatedAt         time.Time `json:"created_at"`
 }
+
+// HostKeyData represents the pinned host keys of one host for sync
+type HostKeyData struct {
+	Address string           `json:"address"` // known_hosts form: host or [host]:port
+	Keys    []models.HostKey `json:"keys"`
+}
+
+// CertificateRequest asks the server CA to certify a public key
+type CertificateRequest struct {
+	Key             PublicKeyData     `json:"key"`
+	Principals      []string          `json:"principals"`
+	ValidAfter      *time.Time        `json:"valid_after,omitempty"`
+	ValidBefore     *time.Time        `json:"valid_before,omitempty"`
+	CriticalOptions map[string]string `json:"critical_options,omitempty"`
+	Extensions      map[string]string `json:"extensions,omitempty"` // empty requests the default extensions
+}
+
+// CertificateResponse carries a certificate issued by the server CA
+type CertificateResponse struct {
+	Certificate string    `json:"certificate"` // authorized_keys format (-cert.pub contents)
+	Serial      uint64    `json:"serial"`
+	KeyID       string    `json:"key_id"`
+	Principals  []string  `json:"principals"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// CAPublicKey is the public half of a server CA
+type CAPublicKey struct {
+	Kind      string `json:"kind"`       // "user" or "host"
+	PublicKey string `json:"public_key"` // authorized_keys format
+}
@@ -0,0 +1,288 @@
+package ca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CA kinds kept by the server
+const (
+	KindUser = "user"
+	KindHost = "host"
+)
+
+const (
+	// DefaultUserValidity is used when a request does not ask for a validity window
+	DefaultUserValidity = 16 * time.Hour
+	// MaxUserValidity caps how long a user certificate may be valid
+	MaxUserValidity = 7 * 24 * time.Hour
+
+	// DefaultHostValidity is used when a host certificate request does not ask for a window
+	DefaultHostValidity = 365 * 24 * time.Hour
+	// MaxHostValidity caps how long a host certificate may be valid
+	MaxHostValidity = 2 * 365 * 24 * time.Hour
+
+	// clockSkew backdates certificates so slightly slow server clocks accept them
+	clockSkew = 5 * time.Minute
+)
+
+// userCriticalOptions are the critical options OpenSSH understands for user certificates
+var userCriticalOptions = map[string]bool{
+	"force-command":   true,
+	"source-address":  true,
+	"verify-required": true,
+}
+
+// userExtensions are the extensions OpenSSH understands for user certificates
+var userExtensions = map[string]bool{
+	"no-touch-required":       true,
+	"permit-X11-forwarding":   true,
+	"permit-agent-forwarding": true,
+	"permit-port-forwarding":  true,
+	"permit-pty":              true,
+	"permit-user-rc":          true,
+}
+
+// DefaultUserExtensions returns the extensions ssh-keygen grants by default
+func DefaultUserExtensions() map[string]string {
+	return map[string]string{
+		"permit-X11-forwarding":   "",
+		"permit-agent-forwarding": "",
+		"permit-port-forwarding":  "",
+		"permit-pty":              "",
+		"permit-user-rc":          "",
+	}
+}
+
+// Authority signs certificates with a CA key
+type Authority struct {
+	signer ssh.Signer
+}
+
+// New creates an authority from a CA signer
+func New(signer ssh.Signer) *Authority {
+	return &Authority{signer: signer}
+}
+
+// GenerateKey creates a new ed25519 CA key and returns it as an OpenSSH PEM
+func GenerateKey(comment string) ([]byte, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CA key: %w", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// Load creates an authority from an OpenSSH PEM encoded CA key
+func Load(pemData []byte) (*Authority, error) {
+	signer, err := ssh.ParsePrivateKey(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	return New(signer), nil
+}
+
+// PublicKey returns the CA public key
+func (a *Authority) PublicKey() ssh.PublicKey {
+	return a.signer.PublicKey()
+}
+
+// AuthorizedKey returns the CA public key in authorized_keys format, as used
+// by TrustedUserCAKeys and @cert-authority lines
+func (a *Authority) AuthorizedKey() string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(a.signer.PublicKey())))
+}
+
+// UserRequest describes a user certificate to be issued
+type UserRequest struct {
+	PublicKey       ssh.PublicKey
+	KeyID           string
+	Serial          uint64
+	Principals      []string
+	ValidAfter      time.Time
+	ValidBefore     time.Time
+	CriticalOptions map[string]string
+	Extensions      map[string]string
+}
+
+// SignUser validates a request and issues a user certificate
+func (a *Authority) SignUser(req UserRequest) (*ssh.Certificate, error) {
+	if req.PublicKey == nil {
+		return nil, errors.New("public key is required")
+	}
+	if _, ok := req.PublicKey.(*ssh.Certificate); ok {
+		return nil, errors.New("cannot sign a certificate")
+	}
+	if len(req.Principals) == 0 {
+		// A certificate without principals is valid for every user
+		return nil, errors.New("at least one principal is required")
+	}
+	for _, p := range req.Principals {
+		if strings.TrimSpace(p) == "" || strings.ContainsAny(p, ", \t\n") {
+			return nil, fmt.Errorf("invalid principal: %q", p)
+		}
+	}
+
+	validAfter, validBefore, err := validityWindow(req.ValidAfter, req.ValidBefore, DefaultUserValidity, MaxUserValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkOptions(req.CriticalOptions, userCriticalOptions, "critical option"); err != nil {
+		return nil, err
+	}
+	if address, ok := req.CriticalOptions["source-address"]; ok {
+		if err := checkSourceAddress(address); err != nil {
+			return nil, err
+		}
+	}
+
+	extensions := req.Extensions
+	if extensions == nil {
+		extensions = DefaultUserExtensions()
+	}
+	if err := checkOptions(extensions, userExtensions, "extension"); err != nil {
+		return nil, err
+	}
+
+	cert := &ssh.Certificate{
+		Key:             req.PublicKey,
+		Serial:          req.Serial,
+		CertType:        ssh.UserCert,
+		KeyId:           req.KeyID,
+		ValidPrincipals: append([]string(nil), req.Principals...),
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: copyOptions(req.CriticalOptions),
+			Extensions:      copyOptions(extensions),
+		},
+	}
+
+	if err := cert.SignCert(rand.Reader, a.signer); err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// HostRequest describes a host certificate to be issued
+type HostRequest struct {
+	PublicKey   ssh.PublicKey
+	KeyID       string
+	Serial      uint64
+	Hostnames   []string
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
+// SignHost validates a request and issues a host certificate
+func (a *Authority) SignHost(req HostRequest) (*ssh.Certificate, error) {
+	if req.PublicKey == nil {
+		return nil, errors.New("public key is required")
+	}
+	if _, ok := req.PublicKey.(*ssh.Certificate); ok {
+		return nil, errors.New("cannot sign a certificate")
+	}
+	if len(req.Hostnames) == 0 {
+		// A host certificate without principals is valid for every host
+		return nil, errors.New("at least one hostname is required")
+	}
+	for _, h := range req.Hostnames {
+		if strings.TrimSpace(h) == "" || strings.ContainsAny(h, ", \t\n*?!") {
+			return nil, fmt.Errorf("invalid hostname: %q", h)
+		}
+	}
+
+	validAfter, validBefore, err := validityWindow(req.ValidAfter, req.ValidBefore, DefaultHostValidity, MaxHostValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &ssh.Certificate{
+		Key:             req.PublicKey,
+		Serial:          req.Serial,
+		CertType:        ssh.HostCert,
+		KeyId:           req.KeyID,
+		ValidPrincipals: append([]string(nil), req.Hostnames...),
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+
+	if err := cert.SignCert(rand.Reader, a.signer); err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// validityWindow fills in defaults for a requested validity window and checks its bounds
+func validityWindow(validAfter, validBefore time.Time, def, max time.Duration) (time.Time, time.Time, error) {
+	now := time.Now()
+	if validAfter.IsZero() {
+		validAfter = now.Add(-clockSkew)
+	}
+	if validBefore.IsZero() {
+		validBefore = now.Add(def)
+	}
+	if !validBefore.After(validAfter) {
+		return time.Time{}, time.Time{}, errors.New("certificate must expire after it becomes valid")
+	}
+	if validBefore.Sub(now) > max {
+		return time.Time{}, time.Time{}, fmt.Errorf("certificate validity exceeds the maximum of %s", max)
+	}
+	return validAfter, validBefore, nil
+}
+
+// checkOptions rejects option names that are not in the allowed set
+func checkOptions(options map[string]string, allowed map[string]bool, label string) error {
+	var unknown []string
+	for name := range options {
+		if !allowed[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unsupported %s: %s", label, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// checkSourceAddress validates a comma separated list of CIDR blocks
+func checkSourceAddress(value string) error {
+	for _, addr := range strings.Split(value, ",") {
+		addr = strings.TrimSpace(addr)
+		if _, _, err := net.ParseCIDR(addr); err == nil {
+			continue
+		}
+		if net.ParseIP(addr) != nil {
+			continue
+		}
+		return fmt.Errorf("invalid source-address: %q", addr)
+	}
+	return nil
+}
+
+func copyOptions(options map[string]string) map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(options))
+	for k, v := range options {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package ca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestAuthority(t *testing.T) *Authority {
+	t.Helper()
+	pemData, err := GenerateKey("test-ca")
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	authority, err := Load(pemData)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return authority
+}
+
+func newTestPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return sshPub
+}
+
+func TestSignUser(t *testing.T) {
+	authority := newTestAuthority(t)
+	pub := newTestPublicKey(t)
+
+	cert, err := authority.SignUser(UserRequest{
+		PublicKey:       pub,
+		KeyID:           "skm:alice:laptop",
+		Serial:          7,
+		Principals:      []string{"alice"},
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatalf("SignUser failed: %v", err)
+	}
+
+	if cert.CertType != ssh.UserCert || cert.Serial != 7 || cert.KeyId != "skm:alice:laptop" {
+		t.Errorf("unexpected certificate fields: %+v", cert)
+	}
+	if _, ok := cert.Extensions["permit-pty"]; !ok {
+		t.Errorf("default extensions not applied: %v", cert.Extensions)
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(authority.PublicKey().Marshal())
+		},
+	}
+	if err := checker.CheckCert("alice", cert); err != nil {
+		t.Errorf("certificate does not verify for alice: %v", err)
+	}
+	if err := checker.CheckCert("bob", cert); err == nil {
+		t.Error("certificate should not be valid for bob")
+	}
+
+	if !strings.HasPrefix(authority.AuthorizedKey(), "ssh-ed25519 ") {
+		t.Errorf("unexpected authorized key: %s", authority.AuthorizedKey())
+	}
+}
+
+func TestSignUserRejectsInvalidRequests(t *testing.T) {
+	authority := newTestAuthority(t)
+	pub := newTestPublicKey(t)
+
+	tests := []struct {
+		name string
+		req  UserRequest
+	}{
+		{"no principals", UserRequest{PublicKey: pub}},
+		{"too long", UserRequest{PublicKey: pub, Principals: []string{"alice"}, ValidBefore: time.Now().Add(MaxUserValidity + time.Hour)}},
+		{"unknown critical option", UserRequest{PublicKey: pub, Principals: []string{"alice"}, CriticalOptions: map[string]string{"bogus": ""}}},
+		{"unknown extension", UserRequest{PublicKey: pub, Principals: []string{"alice"}, Extensions: map[string]string{"permit-everything": ""}}},
+		{"bad source address", UserRequest{PublicKey: pub, Principals: []string{"alice"}, CriticalOptions: map[string]string{"source-address": "not-an-ip"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := authority.SignUser(tt.req); err == nil {
+				t.Error("expected SignUser to fail")
+			}
+		})
+	}
+}
+
+func TestSignHost(t *testing.T) {
+	authority := newTestAuthority(t)
+	pub := newTestPublicKey(t)
+
+	cert, err := authority.SignHost(HostRequest{
+		PublicKey: pub,
+		KeyID:     "skm-host:alice:web1",
+		Serial:    3,
+		Hostnames: []string{"web1.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("SignHost failed: %v", err)
+	}
+	if cert.CertType != ssh.HostCert {
+		t.Errorf("expected host certificate, got type %d", cert.CertType)
+	}
+
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return string(auth.Marshal()) == string(authority.PublicKey().Marshal())
+		},
+	}
+	if err := checker.CheckCert("web1.example.com", cert); err != nil {
+		t.Errorf("certificate does not verify for web1.example.com: %v", err)
+	}
+
+	if _, err := authority.SignHost(HostRequest{PublicKey: pub, Hostnames: []string{"*.example.com"}}); err == nil {
+		t.Error("expected wildcard hostname to be rejected")
+	}
+}
@@ -0,0 +1,381 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	sshagent "golang.org/x/crypto/ssh/agent"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/agent"
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the built-in SSH agent",
+	Long: `Serve keys from the SKM keystore over the ssh-agent protocol.
+
+Keys are decrypted in memory only and are dropped again after a timeout,
+so passphrase-protected keys never have to be written to disk in plaintext.
+
+Typical usage:
+  skm agent start &                 # start the agent on the default socket
+  skm config set agent.enabled true # point managed hosts at the agent
+  skm agent unlock work             # load a key for the default timeout
+  skm agent unlock --tag github     # load every key tagged "github"
+  skm agent lock                    # drop all keys`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent in the foreground",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socket, _ := cmd.Flags().GetString("socket")
+		if socket == "" {
+			socket = agentSocketPath()
+		}
+
+		cfg := configManager.Get()
+
+		a := agent.New()
+		a.SetRules(agentRules())
+		a.SetConfirmer(agent.NewAskpassConfirmer(cfg.Agent.Askpass))
+		a.SetHostKeyMatcher(&agent.KnownHostsMatcher{Files: knownHostsFiles(cfg)})
+
+		srv := agent.NewServer(a, socket)
+		if err := srv.Listen(); err != nil {
+			return err
+		}
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			LogVerbose("shutting down agent")
+			_ = srv.Close()
+		}()
+
+		fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", socket)
+		fmt.Fprintf(os.Stderr, "✓ SKM agent listening on %s\n", socket)
+		if cfg.Agent.EnforcePolicies {
+			fmt.Fprintln(os.Stderr, "  Key policies and host restrictions are enforced")
+		}
+
+		return srv.Serve()
+	},
+}
+
+var agentUnlockCmd = &cobra.Command{
+	Use:   "unlock [name...]",
+	Short: "Load keys into the agent",
+	Long: `Decrypt keys from the keystore and load them into the running agent.
+Keys are selected by name or by tag and stay loaded until the timeout expires
+or they are locked again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		if len(args) == 0 && len(tags) == 0 {
+			return fmt.Errorf("specify key names or use --tag")
+		}
+
+		keys, err := selectKeys(args, tags)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys match the given tags")
+		}
+
+		cfg := configManager.Get()
+		if !cmd.Flags().Changed("timeout") {
+			timeout = time.Duration(cfg.Agent.DefaultTimeoutMinutes) * time.Minute
+		}
+
+		client, err := agent.Dial(agentSocketPath())
+		if err != nil {
+			return fmt.Errorf("%w (start it with: skm agent start)", err)
+		}
+		defer client.Close()
+
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		unlocked := 0
+		for i := range keys {
+			key := &keys[i]
+
+			if !key.IsUsable() {
+				fmt.Printf("- %s: key is %s\n", key.Name, key.EffectiveState())
+				continue
+			}
+
+			if cfg.Agent.EnforcePolicies && key.EffectivePolicy(cfg.DefaultKeyPolicy) == models.KeyPolicyNever {
+				fmt.Printf("✗ %s: key policy is 'never'; it cannot be used through the agent\n", key.Name)
+				continue
+			}
+
+			if key.Type.IsSecurityKey() {
+				fmt.Printf("- %s: security keys sign on the authenticator; add it with: ssh-add %s\n", key.Name, key.Path)
+				continue
+			}
+
+			var privateKey interface{}
+			err := withPassphrase(key, func(passphrase string) error {
+				var err error
+				privateKey, err = ks.ParsePrivateKey(key, passphrase)
+				return err
+			})
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				continue
+			}
+
+			err = client.Add(sshagent.AddedKey{
+				PrivateKey:   privateKey,
+				Comment:      key.Name,
+				LifetimeSecs: uint32(timeout / time.Second),
+			})
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				continue
+			}
+
+			if key.CertPath != "" {
+				if err := addCertificate(client, key, privateKey, timeout); err != nil {
+					fmt.Printf("⚠️  %s: certificate not loaded: %v\n", key.Name, err)
+				}
+			}
+
+			if timeout > 0 {
+				fmt.Printf("✓ Unlocked %s for %s\n", key.Name, timeout)
+			} else {
+				fmt.Printf("✓ Unlocked %s until locked\n", key.Name)
+			}
+			unlocked++
+		}
+
+		if unlocked == 0 {
+			return fmt.Errorf("no keys were unlocked")
+		}
+		return nil
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock [name...]",
+	Short: "Remove keys from the agent",
+	Long:  `Remove the given keys from the running agent, or every key when none are given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+
+		client, err := agent.Dial(agentSocketPath())
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if len(args) == 0 && len(tags) == 0 {
+			if err := client.RemoveAll(); err != nil {
+				return fmt.Errorf("failed to lock agent: %w", err)
+			}
+			fmt.Println("✓ Removed all keys from the agent")
+			return nil
+		}
+
+		keys, err := selectKeys(args, tags)
+		if err != nil {
+			return err
+		}
+
+		loaded, err := client.List()
+		if err != nil {
+			return fmt.Errorf("failed to list agent keys: %w", err)
+		}
+
+		for _, key := range keys {
+			removed := false
+			for _, loadedKey := range loaded {
+				if !agentKeyMatches(loadedKey, &key) {
+					continue
+				}
+				if err := client.Remove(loadedKey); err != nil {
+					fmt.Printf("✗ %s: %v\n", key.Name, err)
+				} else {
+					removed = true
+				}
+			}
+			if removed {
+				fmt.Printf("✓ Locked %s\n", key.Name)
+			} else {
+				LogVerbose("%s is not loaded in the agent", key.Name)
+			}
+		}
+
+		return nil
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show agent status and loaded keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		socket := agentSocketPath()
+
+		fmt.Printf("Socket:        %s\n", socket)
+		fmt.Printf("SSH config:    %s\n", enabledLabel(cfg.Agent.Enabled))
+		fmt.Printf("Policies:      %s\n", enabledLabel(cfg.Agent.EnforcePolicies))
+
+		client, err := agent.Dial(socket)
+		if err != nil {
+			fmt.Println("Status:        not running")
+			return nil
+		}
+		defer client.Close()
+
+		loaded, err := client.List()
+		if err != nil {
+			return fmt.Errorf("failed to list agent keys: %w", err)
+		}
+
+		fmt.Println("Status:        running")
+		fmt.Printf("Loaded keys:   %d\n", len(loaded))
+		if len(loaded) == 0 {
+			return nil
+		}
+
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tFINGERPRINT")
+		for _, k := range loaded {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k.Comment, k.Type(), ssh.FingerprintSHA256(k))
+		}
+		return w.Flush()
+	},
+}
+
+// agentRules returns the agent's rule source. The configuration is reloaded
+// for every lookup so policy changes apply without restarting the agent.
+func agentRules() agent.RuleSource {
+	var mu sync.Mutex
+
+	return func(pub ssh.PublicKey) (*agent.Rule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := configManager.Load(); err != nil {
+			return nil, err
+		}
+		cfg := configManager.Get()
+		if !cfg.Agent.EnforcePolicies {
+			return nil, nil
+		}
+
+		keys, err := configManager.ListKeys()
+		if err != nil {
+			return nil, err
+		}
+		hosts, err := configManager.ListEffectiveHosts()
+		if err != nil {
+			return nil, err
+		}
+
+		fingerprint := ssh.FingerprintSHA256(pub)
+		for i := range keys {
+			if keys[i].Fingerprint == fingerprint {
+				return agent.RuleForKey(&keys[i], hosts, cfg.DefaultKeyPolicy), nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// addCertificate loads a key's certificate into the agent alongside the key,
+// skipping certificates that have already expired
+func addCertificate(client *agent.Client, key *models.Key, privateKey interface{}, timeout time.Duration) error {
+	data, err := os.ReadFile(key.CertPath)
+	if err != nil {
+		return err
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return err
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return fmt.Errorf("%s is not a certificate", key.CertPath)
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && time.Now().Unix() >= int64(cert.ValidBefore) {
+		return fmt.Errorf("certificate expired; run: skm key cert request %s", key.Name)
+	}
+
+	return client.Add(sshagent.AddedKey{
+		PrivateKey:   privateKey,
+		Certificate:  cert,
+		Comment:      key.Name,
+		LifetimeSecs: uint32(timeout / time.Second),
+	})
+}
+
+// knownHostsFiles returns the known_hosts files used to identify destinations
+func knownHostsFiles(cfg *models.Config) []string {
+	return []string{
+		filepath.Join(expandPath(cfg.SSHDir, ""), "known_hosts"),
+		pinnedKnownHostsPath(),
+		"/etc/ssh/ssh_known_hosts",
+	}
+}
+
+// agentKeyMatches reports whether an identity held by the agent belongs to key
+func agentKeyMatches(loaded *sshagent.Key, key *models.Key) bool {
+	if key.Fingerprint != "" {
+		var pub ssh.PublicKey = loaded
+		if parsed, err := ssh.ParsePublicKey(loaded.Blob); err == nil {
+			if cert, ok := parsed.(*ssh.Certificate); ok {
+				pub = cert.Key
+			}
+		}
+		return ssh.FingerprintSHA256(pub) == key.Fingerprint
+	}
+	return loaded.Comment == key.Name
+}
+
+// enabledLabel renders a boolean setting for status output
+func enabledLabel(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.AddCommand(agentStartCmd)
+	agentStartCmd.Flags().String("socket", "", "Socket path (default: <config dir>/agent.sock)")
+
+	agentCmd.AddCommand(agentUnlockCmd)
+	agentUnlockCmd.Flags().StringSlice("tag", []string{}, "Unlock every key with this tag")
+	agentUnlockCmd.Flags().Duration("timeout", 0, "How long keys stay loaded (default: agent.default_timeout_minutes)")
+	agentUnlockCmd.ValidArgsFunction = ValidKeyNamesFunc
+	agentUnlockCmd.RegisterFlagCompletionFunc("tag", ValidTagsFunc)
+
+	agentCmd.AddCommand(agentLockCmd)
+	agentLockCmd.Flags().StringSlice("tag", []string{}, "Lock every key with this tag")
+	agentLockCmd.ValidArgsFunction = ValidKeyNamesFunc
+	agentLockCmd.RegisterFlagCompletionFunc("tag", ValidTagsFunc)
+
+	agentCmd.AddCommand(agentStatusCmd)
+}
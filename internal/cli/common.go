@@ -4,23 +4,323 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/agent"
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/knownhosts"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+	"github.com/all-dot-files/ssh-key-manager/internal/secrets"
 	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
 )
 
+var (
+	secretsOnce    sync.Once
+	secretPrompter *prompt.Prompter
+)
+
 // updateSSHConfig syncs the current SKM configuration to the ~/.ssh/config file
 func updateSSHConfig() error {
+	return applySSHConfig(sshConfigManager())
+}
+
+// applySSHConfig writes the current SKM configuration with sshMgr
+func applySSHConfig(sshMgr *sshconfig.Manager) error {
+	cfg := configManager.Get()
+
+	// Pinned host keys live in an skm-owned file checked next to the user's own
+	if err := knownhosts.WriteFile(pinnedKnownHostsPath(), cfg.Hosts); err != nil {
+		return err
+	}
+
+	plan, err := sshMgr.Plan(cfg.Hosts, managedKeys())
+	if err != nil {
+		return err
+	}
+	if err := sshMgr.Apply(plan); err != nil {
+		return err
+	}
+	printPlanWarnings(plan)
+
+	khMgr := sshconfig.NewKnownHostsManager(cfg.SSHDir)
+	return khMgr.UpdateCertAuthorities(cfg.HostCAs)
+}
+
+// sshConfigManager returns an SSH config manager set up from the configuration
+func sshConfigManager() *sshconfig.Manager {
 	cfg := configManager.Get()
 	sshMgr := sshconfig.NewManager(cfg.SSHDir)
+	sshMgr.SetIncludeMode(sshConfigMode() == models.SSHConfigModeInclude)
+	if cfg.Agent.Enabled {
+		sshMgr.SetIdentityAgent(agentSocketPath())
+	}
+	sshMgr.SetKnownHostsFiles(knownHostsPath(), pinnedKnownHostsPath())
+	sshMgr.SetHostGroups(cfg.HostGroups)
+	return sshMgr
+}
 
+// managedKeys returns the configured keys by name
+func managedKeys() map[string]*models.Key {
+	cfg := configManager.Get()
 	keyMap := make(map[string]*models.Key)
 	for i := range cfg.Keys {
 		keyMap[cfg.Keys[i].Name] = &cfg.Keys[i]
 	}
+	return keyMap
+}
+
+// printPlanWarnings reports skipped hosts and conflicting user settings
+func printPlanWarnings(plan *sshconfig.Plan) {
+	for _, skipped := range plan.Skipped {
+		kind := "Host"
+		if skipped.Group {
+			kind = "Host group"
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  %s %s left out of the SSH config: %s\n", kind, skipped.Host, skipped.Reason)
+	}
+	for _, conflict := range plan.Conflicts {
+		fmt.Fprintf(os.Stderr, "⚠️  Conflict: %s\n", conflict)
+	}
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+}
+
+// sshConfigMode returns where managed hosts are written: include or inline
+func sshConfigMode() string {
+	if configManager.Get().SSHConfigMode == models.SSHConfigModeInline {
+		return models.SSHConfigModeInline
+	}
+	return models.SSHConfigModeInclude
+}
+
+// migrateSSHConfig moves the hosts of an install that still keeps them in a
+// block of the user config into the include file. Every command writing the
+// SSH config does this as part of the write; skm init calls it so upgrading
+// does not wait for the next change of a host.
+func migrateSSHConfig() {
+	if configManager == nil {
+		return
+	}
+	if _, err := os.Stat(configManager.GetConfigPath()); err != nil {
+		return
+	}
+	if sshConfigMode() != models.SSHConfigModeInclude {
+		return
+	}
+
+	sshMgr := sshconfig.NewManager(configManager.Get().SSHDir)
+	if found, err := sshMgr.HasManagedSection(); err != nil || !found {
+		return
+	}
+	if err := updateSSHConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to move SKM hosts to %s: %v\n", sshMgr.IncludePath(), err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "✓ Moved SKM hosts to %s (previous config saved as %s)\n", sshMgr.IncludePath(), sshMgr.BackupPath())
+}
+
+// knownHostsPath returns the known_hosts file holding the SKM managed section
+func knownHostsPath() string {
+	return sshconfig.NewKnownHostsManager(configManager.Get().SSHDir).Path()
+}
+
+// pinnedKnownHostsPath returns the skm-owned known_hosts file holding pinned host keys
+func pinnedKnownHostsPath() string {
+	return filepath.Join(configManager.GetConfigDir(), knownhosts.DefaultFileName)
+}
+
+// agentSocketPath returns the socket the built-in SSH agent listens on
+func agentSocketPath() string {
+	cfg := configManager.Get()
+	if cfg.Agent.SocketPath != "" {
+		return expandPath(cfg.Agent.SocketPath, "")
+	}
+	return filepath.Join(configManager.GetConfigDir(), agent.DefaultSocketName)
+}
+
+// selectKeys returns the keys matching the given names or carrying any of the given tags
+func selectKeys(names []string, tags []string) ([]models.Key, error) {
+	var selected []models.Key
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		key, err := configManager.GetKey(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key.Name] {
+			seen[key.Name] = true
+			selected = append(selected, *key)
+		}
+	}
+
+	if len(tags) > 0 {
+		keys, err := configManager.ListKeys()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %w", err)
+		}
+		for _, key := range keys {
+			if seen[key.Name] || !hasAnyTag(key.Tags, tags) {
+				continue
+			}
+			seen[key.Name] = true
+			selected = append(selected, key)
+		}
+	}
+
+	return selected, nil
+}
+
+// hasAnyTag reports whether keyTags contains at least one of tags
+func hasAnyTag(keyTags []string, tags []string) bool {
+	for _, tag := range tags {
+		for _, keyTag := range keyTags {
+			if keyTag == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkKeyPolicy enforces the configured key algorithm policy unless overridden
+func checkKeyPolicy(keyType models.KeyType, bits int, allowWeak bool) error {
+	if err := configManager.Get().KeyAlgorithmPolicy.Check(keyType, bits); err != nil {
+		if allowWeak {
+			fmt.Fprintf(os.Stderr, "⚠️  %v (allowed by --allow-weak)\n", err)
+			return nil
+		}
+		return fmt.Errorf("%w (use --allow-weak to override)", err)
+	}
+	return nil
+}
+
+// checkPublicKeyPolicy enforces the key algorithm policy on an authorized_keys formatted key
+func checkPublicKeyPolicy(publicKey []byte, allowWeak bool) error {
+	keyType, bits, err := keystore.PublicKeyInfo(publicKey)
+	if err != nil {
+		return err
+	}
+	return checkKeyPolicy(keyType, bits, allowWeak)
+}
+
+// prompter returns the shared prompter for passphrases and passwords,
+// honouring --passphrase-file and --passphrase-fd
+func prompter() *prompt.Prompter {
+	secretsOnce.Do(func() {
+		secretPrompter = prompt.New()
+		if passphraseFile != "" {
+			secretPrompter.PassphraseFile = passphraseFile
+		}
+		if passphraseFD >= 0 {
+			secretPrompter.PassphraseFD = passphraseFD
+		}
+	})
+	return secretPrompter
+}
+
+// readPassphrase reads the passphrase of an existing key
+func readPassphrase(keyName string) (string, error) {
+	return prompter().Secret(prompt.Request{
+		Prompt: fmt.Sprintf("Passphrase for key '%s'", keyName),
+		Env:    prompt.EnvPassphrase,
+	})
+}
+
+// configureSecretStore opens the configured secret backend for the config manager
+func configureSecretStore() error {
+	cfg := configManager.Get()
+	if cfg == nil {
+		return nil
+	}
+
+	filePath := cfg.Secrets.FilePath
+	if filePath == "" {
+		filePath = filepath.Join(configManager.GetConfigDir(), "secrets.enc")
+	}
+
+	store, err := secrets.Open(cfg.Secrets.Backend, secrets.Options{
+		FilePath: filePath,
+		MasterPassphrase: func() (string, error) {
+			req := prompt.Request{Prompt: "Master passphrase for the SKM secret store", Env: prompt.EnvMasterPassphrase}
+			if _, err := os.Stat(filePath); os.IsNotExist(err) {
+				return prompter().NewSecret(req)
+			}
+			return prompter().Secret(req)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	backend := cfg.Secrets.Backend
+	configManager.SetSecretStore(store, backend != "" && backend != secrets.BackendAuto)
+	return nil
+}
+
+// withPassphrase calls use with the passphrase of key. Keys in the vault use
+// their data key. Otherwise a passphrase cached in the secret store is tried
+// first; a typed one that works is cached when secrets.cache_passphrases is
+// enabled.
+func withPassphrase(key *models.Key, use func(passphrase string) error) error {
+	if !key.HasPassphrase {
+		return use("")
+	}
+
+	if dataKey, inVault, err := vaultDataKey(key); inVault || err != nil {
+		if err != nil {
+			return err
+		}
+		return use(dataKey)
+	}
+
+	store := configManager.SecretStore()
+	caching := store != nil && configManager.Get().Secrets.CachePassphrases && key.Fingerprint != ""
+	if caching {
+		if cached, err := store.Get(secrets.KeyPassphrase(key.Fingerprint)); err == nil {
+			if err := use(cached); err == nil {
+				return nil
+			}
+			LogVerbose("cached passphrase of %s no longer works", key.Name)
+		}
+	}
+
+	passphrase, err := readPassphrase(key.Name)
+	if err != nil {
+		return err
+	}
+	if err := use(passphrase); err != nil {
+		return err
+	}
+
+	if caching {
+		if err := store.Set(secrets.KeyPassphrase(key.Fingerprint), passphrase); err != nil {
+			LogVerbose("failed to cache passphrase of %s: %v", key.Name, err)
+		}
+	}
+	return nil
+}
+
+// forgetPassphrase drops the cached passphrase of key, if any
+func forgetPassphrase(key *models.Key) {
+	if store := configManager.SecretStore(); store != nil && key.Fingerprint != "" {
+		if err := store.Delete(secrets.KeyPassphrase(key.Fingerprint)); err != nil {
+			LogVerbose("failed to forget passphrase of %s: %v", key.Name, err)
+		}
+	}
+}
 
-	return sshMgr.UpdateConfig(cfg.Hosts, keyMap)
+// confirm asks for an interactive confirmation. It fails without a terminal
+// so scripts must pass an explicit flag instead.
+func confirm(cmd *cobra.Command, question, expected string) (bool, error) {
+	p := &prompt.Prompter{In: cmd.InOrStdin(), Out: cmd.OutOrStdout()}
+	return p.Confirm(question, expected)
 }
 
 // promptUser asks the user for input with an optional default value
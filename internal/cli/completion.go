@@ -149,3 +149,29 @@ func ValidProjectNamesFunc(cmd *cobra.Command, args []string, toComplete string)
 	cfg := configManager.Get()
 	return cfg.Projects, cobra.ShellCompDirectiveNoFileComp
 }
+
+// ValidHostNamesFunc returns managed host names for completion
+func ValidHostNamesFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if configManager == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var hosts []string
+	for _, host := range configManager.Get().Hosts {
+		hosts = append(hosts, host.Host)
+	}
+	return hosts, cobra.ShellCompDirectiveNoFileComp
+}
+
+// ValidGroupNamesFunc returns host group names for completion
+func ValidGroupNamesFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if configManager == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var groups []string
+	for _, group := range configManager.Get().HostGroups {
+		groups = append(groups, group.Name)
+	}
+	return groups, cobra.ShellCompDirectiveNoFileComp
+}
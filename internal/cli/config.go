@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/secrets"
 )
 
 var configCmd = &cobra.Command{
@@ -30,6 +31,7 @@ var configShowCmd = &cobra.Command{
 		}
 		fmt.Printf("Keystore Path:   %s\n", cfg.KeystorePath)
 		fmt.Printf("SSH Directory:   %s\n", cfg.SSHDir)
+		fmt.Printf("SSH Config Mode: %s\n", sshConfigMode())
 		fmt.Printf("Debug Mode:      %v\n", cfg.Debug)
 
 		if cfg.User != "" {
@@ -55,6 +57,20 @@ var configShowCmd = &cobra.Command{
 		fmt.Printf("  Auto Rotate:            %v\n", cfg.KeyRotationPolicy.AutoRotate)
 		fmt.Printf("  Notify on Rotation:     %v\n", cfg.KeyRotationPolicy.NotifyOnRotation)
 
+		fmt.Printf("\nSSH Agent:\n")
+		fmt.Printf("  Enabled:                %v\n", cfg.Agent.Enabled)
+		fmt.Printf("  Socket:                 %s\n", agentSocketPath())
+		fmt.Printf("  Default Timeout:        %d minutes\n", cfg.Agent.DefaultTimeoutMinutes)
+		fmt.Printf("  Enforce Policies:       %v\n", cfg.Agent.EnforcePolicies)
+
+		fmt.Printf("\nSecrets:\n")
+		if store := configManager.SecretStore(); store != nil {
+			fmt.Printf("  Backend:                %s\n", store.Name())
+		} else {
+			fmt.Printf("  Backend:                none (config file)\n")
+		}
+		fmt.Printf("  Cache Passphrases:      %v\n", cfg.Secrets.CachePassphrases)
+
 		fmt.Printf("\nData:\n")
 		fmt.Printf("  Keys:                   %d\n", len(cfg.Keys))
 		fmt.Printf("  Hosts:                  %d\n", len(cfg.Hosts))
@@ -75,7 +91,13 @@ Examples:
   skm config set user "John Doe"
   skm config set email john@example.com
   skm config set key_rotation_policy.enabled true
-  skm config set key_rotation_policy.max_key_age_months 24`,
+  skm config set key_rotation_policy.max_key_age_months 24
+  skm config set agent.enabled true
+  skm config set ssh_config_mode inline
+  skm config set secrets.backend file
+  skm config set secrets.cache_passphrases true
+  skm config set key_algorithm_policy.min_rsa_bits 3072
+  skm config set key_algorithm_policy.allowed_algorithms ed25519,ecdsa,ed25519-sk`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		key := args[0]
@@ -106,6 +128,12 @@ Examples:
 		case "server":
 			cfg.Server = value
 
+		case "ssh_config_mode":
+			if value != models.SSHConfigModeInclude && value != models.SSHConfigModeInline {
+				return fmt.Errorf("invalid SSH config mode: %s (must be include or inline)", value)
+			}
+			cfg.SSHConfigMode = value
+
 		case "default_key_policy":
 			policy := models.KeyPolicy(value)
 			if policy != models.KeyPolicyAuto && policy != models.KeyPolicyAsk && policy != models.KeyPolicyNever {
@@ -158,6 +186,138 @@ Examples:
 				return fmt.Errorf("unknown key rotation policy field: %s", parts[1])
 			}
 
+		case "key_encryption":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a key encryption field")
+			}
+
+			val, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid integer value: %s", value)
+			}
+
+			switch parts[1] {
+			case "argon_time":
+				cfg.KeyEncryption.ArgonTime = uint32(val)
+			case "argon_memory_kib":
+				cfg.KeyEncryption.ArgonMemoryKiB = uint32(val)
+			case "argon_threads":
+				if val > 255 {
+					return fmt.Errorf("invalid thread count: %s", value)
+				}
+				cfg.KeyEncryption.ArgonThreads = uint8(val)
+			default:
+				return fmt.Errorf("unknown key encryption field: %s", parts[1])
+			}
+
+			if err := kdfParams().Validate(); err != nil {
+				return fmt.Errorf("invalid key encryption settings: %w", err)
+			}
+
+		case "key_algorithm_policy":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a key algorithm policy field")
+			}
+
+			switch parts[1] {
+			case "allowed_algorithms":
+				var allowed []models.KeyType
+				for _, name := range strings.Split(value, ",") {
+					name = strings.TrimSpace(name)
+					if name == "" {
+						continue
+					}
+					keyType, err := models.ParseKeyType(name)
+					if err != nil {
+						return err
+					}
+					allowed = append(allowed, keyType)
+				}
+				cfg.KeyAlgorithmPolicy.AllowedAlgorithms = allowed
+
+			case "min_rsa_bits":
+				val, err := strconv.Atoi(value)
+				if err != nil || val < 0 {
+					return fmt.Errorf("invalid key size: %s", value)
+				}
+				cfg.KeyAlgorithmPolicy.MinRSABits = val
+
+			case "min_ecdsa_bits":
+				val, err := strconv.Atoi(value)
+				if err != nil || (val != 0 && val != 256 && val != 384 && val != 521) {
+					return fmt.Errorf("invalid ECDSA key size: %s (must be 256, 384 or 521)", value)
+				}
+				cfg.KeyAlgorithmPolicy.MinECDSABits = val
+
+			default:
+				return fmt.Errorf("unknown key algorithm policy field: %s", parts[1])
+			}
+
+		case "agent":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify an agent field")
+			}
+
+			switch parts[1] {
+			case "enabled":
+				val, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid boolean value: %s", value)
+				}
+				cfg.Agent.Enabled = val
+
+			case "socket_path":
+				cfg.Agent.SocketPath = value
+
+			case "default_timeout_minutes":
+				val, err := strconv.Atoi(value)
+				if err != nil || val < 0 {
+					return fmt.Errorf("invalid timeout: %s", value)
+				}
+				cfg.Agent.DefaultTimeoutMinutes = val
+
+			case "enforce_policies":
+				val, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid boolean value: %s", value)
+				}
+				cfg.Agent.EnforcePolicies = val
+
+			case "askpass":
+				cfg.Agent.Askpass = value
+
+			default:
+				return fmt.Errorf("unknown agent field: %s", parts[1])
+			}
+
+		case "secrets":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a secrets field")
+			}
+
+			switch parts[1] {
+			case "backend":
+				switch value {
+				case secrets.BackendAuto, secrets.BackendSecretService, secrets.BackendFile, secrets.BackendNone:
+					cfg.Secrets.Backend = value
+				default:
+					return fmt.Errorf("invalid secret backend: %s (use auto, secret-service, file or none)", value)
+				}
+
+			case "file_path":
+				cfg.Secrets.FilePath = value
+
+			case "cache_passphrases":
+				val, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid boolean value: %s", value)
+				}
+				cfg.Secrets.CachePassphrases = val
+
+			default:
+				return fmt.Errorf("unknown secrets field: %s", parts[1])
+			}
+
 		case "sync_policy":
 			if len(parts) < 2 {
 				return fmt.Errorf("must specify a sync policy field")
@@ -197,6 +357,25 @@ Examples:
 			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 
+		// Agent settings change the IdentityAgent lines of managed hosts, and
+		// the mode moves them between the include file and the user config
+		if parts[0] == "agent" || parts[0] == "ssh_config_mode" {
+			if err := updateSSHConfig(); err != nil {
+				fmt.Printf("⚠️  Failed to update SSH config: %v\n", err)
+			}
+		}
+
+		// Move a plaintext server token into the newly selected backend
+		if parts[0] == "secrets" && cfg.ServerToken != "" {
+			if err := configureSecretStore(); err != nil {
+				fmt.Printf("⚠️  Server token left in the config file: %v\n", err)
+			} else if configManager.SecretStore() != nil {
+				if err := configManager.SetServerToken(cfg.ServerToken); err != nil {
+					fmt.Printf("⚠️  Server token left in the config file: %v\n", err)
+				}
+			}
+		}
+
 		fmt.Printf("✓ Set %s = %s\n", key, value)
 		return nil
 	},
@@ -229,6 +408,8 @@ var configGetCmd = &cobra.Command{
 			fmt.Println(cfg.KeystorePath)
 		case "ssh_dir":
 			fmt.Println(cfg.SSHDir)
+		case "ssh_config_mode":
+			fmt.Println(sshConfigMode())
 		case "default_key_policy":
 			fmt.Println(cfg.DefaultKeyPolicy)
 
@@ -251,6 +432,86 @@ var configGetCmd = &cobra.Command{
 				return fmt.Errorf("unknown key rotation policy field: %s", parts[1])
 			}
 
+		case "key_encryption":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a key encryption field")
+			}
+			params := kdfParams()
+			switch parts[1] {
+			case "argon_time":
+				fmt.Println(params.Time)
+			case "argon_memory_kib":
+				fmt.Println(params.Memory)
+			case "argon_threads":
+				fmt.Println(params.Threads)
+			default:
+				return fmt.Errorf("unknown key encryption field: %s", parts[1])
+			}
+
+		case "key_algorithm_policy":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a key algorithm policy field")
+			}
+			policy := cfg.KeyAlgorithmPolicy
+			switch parts[1] {
+			case "allowed_algorithms":
+				names := make([]string, 0, len(policy.AllowedAlgorithms))
+				for _, t := range policy.AllowedAlgorithms {
+					names = append(names, t.ShortName())
+				}
+				fmt.Println(strings.Join(names, ","))
+			case "min_rsa_bits":
+				if policy.MinRSABits == 0 {
+					policy.MinRSABits = models.DefaultMinRSABits
+				}
+				fmt.Println(policy.MinRSABits)
+			case "min_ecdsa_bits":
+				if policy.MinECDSABits == 0 {
+					policy.MinECDSABits = models.DefaultMinECDSABits
+				}
+				fmt.Println(policy.MinECDSABits)
+			default:
+				return fmt.Errorf("unknown key algorithm policy field: %s", parts[1])
+			}
+
+		case "secrets":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify a secrets field")
+			}
+			switch parts[1] {
+			case "backend":
+				if cfg.Secrets.Backend == "" {
+					fmt.Println(secrets.BackendAuto)
+				} else {
+					fmt.Println(cfg.Secrets.Backend)
+				}
+			case "file_path":
+				fmt.Println(cfg.Secrets.FilePath)
+			case "cache_passphrases":
+				fmt.Println(cfg.Secrets.CachePassphrases)
+			default:
+				return fmt.Errorf("unknown secrets field: %s", parts[1])
+			}
+
+		case "agent":
+			if len(parts) < 2 {
+				return fmt.Errorf("must specify an agent field")
+			}
+			switch parts[1] {
+			case "enabled":
+				fmt.Println(cfg.Agent.Enabled)
+			case "socket_path":
+				fmt.Println(agentSocketPath())
+			case "default_timeout_minutes":
+				fmt.Println(cfg.Agent.DefaultTimeoutMinutes)
+			case "enforce_policies":
+				fmt.Println(cfg.Agent.EnforcePolicies)
+			case "askpass":
+				fmt.Println(cfg.Agent.Askpass)
+			default:
+				return fmt.Errorf("unknown agent field: %s", parts[1])
+			}
+
 		default:
 			return fmt.Errorf("unknown configuration key: %s", key)
 		}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -52,7 +53,7 @@ var gitBindCmd = &cobra.Command{
 		}
 
 		// Check if host exists, auto-create if enabled
-		hostConfig, err := configManager.GetHost(host)
+		hostConfig, err := configManager.GetEffectiveHost(host)
 		if err != nil {
 			if !autoCreate {
 				return fmt.Errorf("host %s not found. Add it with: skm host add %s --user <user> --key <key>\nOr use --auto-create flag", host, host)
@@ -91,10 +92,17 @@ var gitBindCmd = &cobra.Command{
 					return err
 				}
 
+				if err := checkKeyPolicy(models.KeyTypeED25519, keystore.DefaultBits(models.KeyTypeED25519), false); err != nil {
+					return err
+				}
+
 				key, err := ks.GenerateKey(keyName, models.KeyTypeED25519, "", 0)
 				if err != nil {
 					return fmt.Errorf("failed to generate key: %w", err)
 				}
+				if err := protectNewKey(ks, key); err != nil {
+					return fmt.Errorf("failed to add key to the vault: %w", err)
+				}
 
 				if err := configManager.AddKey(*key); err != nil {
 					return fmt.Errorf("failed to add key to config: %w", err)
@@ -123,7 +131,7 @@ var gitBindCmd = &cobra.Command{
 
 			fmt.Printf("✓ Created host: %s (user: %s, key: %s)\n", host, user, keyName)
 
-			hostConfig, _ = configManager.GetHost(host)
+			hostConfig, _ = configManager.GetEffectiveHost(host)
 		}
 
 		// If key is specified, verify it exists
@@ -165,6 +173,16 @@ var gitBindCmd = &cobra.Command{
 	},
 }
 
+// gitManager returns a Git manager checking host keys against the user's
+// known_hosts and the pinned host keys, and choosing keys with the
+// configured rules
+func gitManager() *git.Manager {
+	gitMgr := git.NewManager(configManager)
+	gitMgr.SetKnownHostsFiles(knownHostsPath(), pinnedKnownHostsPath())
+	gitMgr.SetKeyRules(configManager.Get().GitKeyRules)
+	return gitMgr
+}
+
 var gitListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all bound Git repositories",
@@ -222,7 +240,15 @@ var gitExecCmd = &cobra.Command{
 			return fmt.Errorf("failed to resolve path: %w", err)
 		}
 
-		gitMgr := git.NewManager(configManager)
+		gitMgr := gitManager()
+		// Run git with SKM as its ssh so every remote gets its own key
+		if skmPath, err := os.Executable(); err == nil {
+			command := strconv.Quote(filepath.ToSlash(filepath.Clean(skmPath)))
+			if cfgFile != "" {
+				command += " --config " + strconv.Quote(cfgFile)
+			}
+			gitMgr.SetSSHCommand(command + " git helper ssh-command")
+		}
 		if err := gitMgr.WrapCommand(absPath, gitArgs); err != nil {
 			return fmt.Errorf("git command failed: %w", err)
 		}
@@ -366,13 +392,14 @@ var gitHelperSSHCommandCmd = &cobra.Command{
 	Use:    "ssh-command",
 	Short:  "SSH command wrapper for Git helper (internal)",
 	Hidden: true,
+	// The arguments are ssh's, such as -p 22 or -o SendEnv=GIT_PROTOCOL
+	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// This is called as core.sshCommand by Git
 		// Args will be the SSH arguments passed by Git
 		// We need to determine the host and inject the correct key
 
-		gitMgr := git.NewManager(configManager)
-		return gitMgr.HandleSSHCommand(args)
+		return gitManager().HandleSSHCommand(args)
 	},
 }
 
@@ -465,8 +492,7 @@ var gitAutoConfigCmd = &cobra.Command{
 			return fmt.Errorf("failed to resolve path: %w", err)
 		}
 
-		gitMgr := git.NewManager(configManager)
-		if err := gitMgr.AutoConfigureRepo(absPath); err != nil {
+		if err := gitManager().AutoConfigureRepo(absPath); err != nil {
 			// Not a fatal error, just inform the user
 			fmt.Fprintf(os.Stderr, "Note: %v\n", err)
 			return nil
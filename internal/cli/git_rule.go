@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/pkg/errors"
+)
+
+var gitRuleCmd = &cobra.Command{
+	Use:   "rule",
+	Short: "Choose keys for Git remotes by URL pattern",
+	Long: `Manage rules choosing the key of Git remotes by URL pattern, so
+repositories of different organizations on the same host use different keys.
+
+A pattern is host:path, where * matches any characters including /. The most
+specific rule wins: the longest pattern matching the remote URL. A remote
+bound with 'skm git bind --key' keeps its key.`,
+}
+
+var gitRuleAddCmd = &cobra.Command{
+	Use:   "add <pattern>",
+	Short: "Add a rule choosing the key of matching remotes",
+	Example: `  skm git rule add 'github.com:myorg/*' --key work
+  skm git rule add 'github.com:*' --key personal`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		keyName, _ := cmd.Flags().GetString("key")
+
+		rule := models.GitKeyRule{Pattern: args[0], KeyName: keyName}
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		for _, existing := range cfg.GitKeyRules {
+			if existing.Pattern == rule.Pattern {
+				return fmt.Errorf("git rule %s already exists", rule.Pattern)
+			}
+		}
+		if _, err := configManager.GetKey(keyName); err != nil {
+			return errors.New(errors.ErrNotFound, "GIT", fmt.Sprintf("key %s not found", keyName)).
+				WithSuggestion(fmt.Sprintf("Create it with: skm key gen --name %s", keyName))
+		}
+
+		cfg.GitKeyRules = append(cfg.GitKeyRules, rule)
+		if err := configManager.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		fmt.Printf("✓ Added git rule: %s → %s\n", rule.Pattern, rule.KeyName)
+		return nil
+	},
+}
+
+var gitRuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the Git key rules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		if len(cfg.GitKeyRules) == 0 {
+			fmt.Println("No git rules configured. Add one with: skm git rule add")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PATTERN\tKEY")
+		fmt.Fprintln(w, "-------\t---")
+		for _, rule := range cfg.GitKeyRules {
+			fmt.Fprintf(w, "%s\t%s\n", rule.Pattern, rule.KeyName)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var gitRuleRemoveCmd = &cobra.Command{
+	Use:   "remove <pattern>",
+	Short: "Remove a Git key rule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		remaining := cfg.GitKeyRules[:0]
+		for _, rule := range cfg.GitKeyRules {
+			if rule.Pattern != args[0] {
+				remaining = append(remaining, rule)
+			}
+		}
+		if len(remaining) == len(cfg.GitKeyRules) {
+			return errors.New(errors.ErrNotFound, "GIT", fmt.Sprintf("git rule %s not found", args[0])).
+				WithSuggestion("Run 'skm git rule list' to see available rules")
+		}
+		cfg.GitKeyRules = remaining
+
+		if err := configManager.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		fmt.Printf("✓ Removed git rule: %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	gitCmd.AddCommand(gitRuleCmd)
+
+	gitRuleCmd.AddCommand(gitRuleAddCmd)
+	gitRuleAddCmd.Flags().StringP("key", "k", "", "Key used by matching remotes")
+	gitRuleAddCmd.MarkFlagRequired("key")
+	gitRuleAddCmd.RegisterFlagCompletionFunc("key", ValidKeyNamesFunc)
+
+	gitRuleCmd.AddCommand(gitRuleListCmd)
+	gitRuleCmd.AddCommand(gitRuleRemoveCmd)
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -29,13 +31,24 @@ var hostAddCmd = &cobra.Command{
 		port, _ := cmd.Flags().GetInt("port")
 		actualHost, _ := cmd.Flags().GetString("hostname")
 		autoCreate, _ := cmd.Flags().GetBool("auto-create-key")
+		tags, _ := cmd.Flags().GetStringArray("tag")
+		jumpHost, _ := cmd.Flags().GetString("jump-host")
+
+		// A host matching a group may leave the user and key to the group
+		inherited := models.Host{Host: hostname, User: user, KeyName: keyName, Tags: tags}.
+			WithGroups(configManager.Get().HostGroups)
+		if inherited.User == "" {
+			return fmt.Errorf("user is required: use --user or add the host to a group that sets one")
+		}
 
-		if user == "" {
-			return fmt.Errorf("user is required")
+		// Check the options before any key is created
+		var options models.HostOptions
+		if err := applyHostOptionFlags(cmd, &options); err != nil {
+			return err
 		}
 
 		// Auto-create key if requested and key doesn't exist
-		if keyName == "" || autoCreate {
+		if (keyName == "" && inherited.KeyName == "") || autoCreate {
 			if keyName == "" {
 				keyName = fmt.Sprintf("%s-key", hostname)
 			}
@@ -66,10 +79,18 @@ var hostAddCmd = &cobra.Command{
 					return err
 				}
 
-				key, err := ks.GenerateKey(keyName, kt, "", 4096)
+				bits := keystore.DefaultBits(kt)
+				if err := checkKeyPolicy(kt, bits, false); err != nil {
+					return err
+				}
+
+				key, err := ks.GenerateKeyWithSize(keyName, kt, "", bits)
 				if err != nil {
 					return fmt.Errorf("failed to generate key: %w", err)
 				}
+				if err := protectNewKey(ks, key); err != nil {
+					return fmt.Errorf("failed to add key to the vault: %w", err)
+				}
 
 				if err := configManager.AddKey(*key); err != nil {
 					return fmt.Errorf("failed to add key to config: %w", err)
@@ -79,22 +100,31 @@ var hostAddCmd = &cobra.Command{
 			}
 		}
 
-		if keyName == "" {
-			return fmt.Errorf("key is required")
-		}
-
 		// Verify key exists
-		if _, err := configManager.GetKey(keyName); err != nil {
-			return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("key %s not found", keyName)).
-				WithSuggestion(fmt.Sprintf("Create it with: skm key gen --name %s", keyName))
+		effectiveKey := keyName
+		if effectiveKey == "" {
+			effectiveKey = inherited.KeyName
+		}
+		if _, err := configManager.GetKey(effectiveKey); err != nil {
+			return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("key %s not found", effectiveKey)).
+				WithSuggestion(fmt.Sprintf("Create it with: skm key gen --name %s", effectiveKey))
 		}
 
 		host := models.Host{
-			Host:     hostname,
-			User:     user,
-			KeyName:  keyName,
-			Port:     port,
-			Hostname: actualHost,
+			Host:        hostname,
+			User:        user,
+			KeyName:     keyName,
+			Port:        port,
+			Hostname:    actualHost,
+			Tags:        tags,
+			JumpHost:    jumpHost,
+			HostOptions: options,
+		}
+		if err := host.Validate(); err != nil {
+			return err
+		}
+		if err := checkJumpChain(&host); err != nil {
+			return err
 		}
 
 		if err := configManager.AddHost(host); err != nil {
@@ -107,11 +137,77 @@ var hostAddCmd = &cobra.Command{
 		}
 
 		fmt.Printf("✓ Added host: %s\n", hostname)
-		fmt.Printf("  User: %s\n", user)
-		fmt.Printf("  Key: %s\n", keyName)
-		if port > 0 {
-			fmt.Printf("  Port: %d\n", port)
+		printHostSettings(&host)
+		fmt.Println("\n✓ Updated ~/.ssh/config")
+
+		return nil
+	},
+}
+
+var hostEditCmd = &cobra.Command{
+	Use:   "edit <hostname>",
+	Short: "Change the settings and ssh_config options of a host",
+	Long: `Change the settings and ssh_config options of a host.
+
+Only the flags given are changed. A repeatable flag such as --local-forward
+replaces the existing list; --unset removes an option. Options without their
+own flag can be set with --option Keyword=value.`,
+	Example: `  skm host edit db --proxy-jump bastion --forward-agent
+  skm host edit db --local-forward "5432 localhost:5432"
+  skm host edit db -o LogLevel=ERROR --unset ControlPersist`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: ValidHostNamesFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hostname := args[0]
+
+		host, err := configManager.GetHost(hostname)
+		if err != nil {
+			return errors.WrapWithSuggestion(err, errors.ErrNotFound, "HOST",
+				fmt.Sprintf("host %s not found", hostname),
+				"Run 'skm host list' to see available hosts")
+		}
+
+		if cmd.Flags().Changed("user") {
+			host.User, _ = cmd.Flags().GetString("user")
+		}
+		if cmd.Flags().Changed("key") {
+			host.KeyName, _ = cmd.Flags().GetString("key")
+			if _, err := configManager.GetKey(host.KeyName); err != nil {
+				return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("key %s not found", host.KeyName)).
+					WithSuggestion(fmt.Sprintf("Create it with: skm key gen --name %s", host.KeyName))
+			}
 		}
+		if cmd.Flags().Changed("port") {
+			host.Port, _ = cmd.Flags().GetInt("port")
+		}
+		if cmd.Flags().Changed("hostname") {
+			host.Hostname, _ = cmd.Flags().GetString("hostname")
+		}
+		if cmd.Flags().Changed("tag") {
+			host.Tags, _ = cmd.Flags().GetStringArray("tag")
+		}
+		if cmd.Flags().Changed("jump-host") {
+			host.JumpHost, _ = cmd.Flags().GetString("jump-host")
+		}
+		if err := applyHostOptionFlags(cmd, &host.HostOptions); err != nil {
+			return err
+		}
+		if err := host.Validate(); err != nil {
+			return err
+		}
+		if err := checkJumpChain(host); err != nil {
+			return err
+		}
+
+		if err := configManager.UpdateHost(hostname, *host); err != nil {
+			return fmt.Errorf("failed to update host: %w", err)
+		}
+		if err := updateSSHConfig(); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+
+		fmt.Printf("✓ Updated host: %s\n", hostname)
+		printHostSettings(host)
 		fmt.Println("\n✓ Updated ~/.ssh/config")
 
 		return nil
@@ -121,7 +217,17 @@ var hostAddCmd = &cobra.Command{
 var hostListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all SSH host configurations",
+	Long: `List the managed hosts with their effective settings. Values a host
+inherits from a host group are marked with *.`,
+	Example: `  skm host list
+  skm host list --group prod`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		groupName, _ := cmd.Flags().GetString("group")
+		groups := configManager.Get().HostGroups
+		if groupName != "" && findHostGroup(groupName) == nil {
+			return hostGroupNotFound(groupName)
+		}
+
 		hosts, err := configManager.ListHosts()
 		if err != nil {
 			return fmt.Errorf("failed to list hosts: %w", err)
@@ -133,32 +239,135 @@ var hostListCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "HOST\tUSER\tKEY\tPORT\tHOSTNAME")
-		fmt.Fprintln(w, "----\t----\t---\t----\t--------")
+		fmt.Fprintln(w, "HOST\tUSER\tKEY\tPORT\tHOSTNAME\tGROUPS")
+		fmt.Fprintln(w, "----\t----\t---\t----\t--------\t------")
+
+		listed, anyInherited := 0, false
+		for i := range hosts {
+			host := &hosts[i]
+			var names []string
+			for _, g := range models.GroupsOf(host, groups) {
+				names = append(names, g.Name)
+			}
+			if groupName != "" && !slices.Contains(names, groupName) {
+				continue
+			}
+			listed++
 
-		for _, host := range hosts {
+			effective := host.WithGroups(groups)
 			port := "-"
-			if host.Port > 0 {
-				port = fmt.Sprintf("%d", host.Port)
+			if effective.Port > 0 {
+				port = fmt.Sprintf("%d", effective.Port)
 			}
 			hostname := "-"
 			if host.Hostname != "" {
 				hostname = host.Hostname
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			memberOf := "-"
+			if len(names) > 0 {
+				memberOf = strings.Join(names, ",")
+			}
+
+			user := inheritedValue(host.User, effective.User)
+			keyName := inheritedValue(host.KeyName, effective.KeyName)
+			if host.Port == 0 && effective.Port > 0 {
+				port += "*"
+			}
+			anyInherited = anyInherited || strings.HasSuffix(user+keyName+port, "*")
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 				host.Host,
-				host.User,
-				host.KeyName,
+				user,
+				keyName,
 				port,
 				hostname,
+				memberOf,
 			)
 		}
 
 		w.Flush()
+		if listed == 0 {
+			fmt.Printf("No hosts in group %s.\n", groupName)
+		}
+		if anyInherited {
+			fmt.Println("\n* inherited from a host group")
+		}
 		return nil
 	},
 }
 
+// checkJumpChain verifies the jump hosts of host are managed hosts and do not
+// lead back to it
+func checkJumpChain(host *models.Host) error {
+	groups := configManager.Get().HostGroups
+	effective := host.WithGroups(groups)
+	_, err := models.JumpChain(&effective, func(alias string) (*models.Host, error) {
+		// The host being changed is not saved yet
+		if strings.EqualFold(alias, host.Host) {
+			return &effective, nil
+		}
+		hop, err := configManager.GetHost(alias)
+		if err != nil {
+			return nil, err
+		}
+		resolved := hop.WithGroups(groups)
+		return &resolved, nil
+	})
+	if err != nil {
+		return errors.New(errors.ErrInvalidInput, "HOST", err.Error()).
+			WithSuggestion("Add the jump host first with: skm host add <host>")
+	}
+	return nil
+}
+
+// inheritedValue returns the effective value, marked with * if it comes from
+// a host group
+func inheritedValue(own, effective string) string {
+	if own == "" && effective != "" {
+		return effective + "*"
+	}
+	if effective == "" {
+		return "-"
+	}
+	return effective
+}
+
+// printHostSettings prints the effective settings of a host, noting those
+// inherited from a host group
+func printHostSettings(host *models.Host) {
+	groups := configManager.Get().HostGroups
+	effective := host.WithGroups(groups)
+	note := func(inherited bool) string {
+		if inherited {
+			return " (from host group)"
+		}
+		return ""
+	}
+
+	fmt.Printf("  User: %s%s\n", effective.User, note(host.User == "" && effective.User != ""))
+	fmt.Printf("  Key: %s%s\n", effective.KeyName, note(host.KeyName == "" && effective.KeyName != ""))
+	if effective.Port > 0 {
+		fmt.Printf("  Port: %d%s\n", effective.Port, note(host.Port == 0))
+	}
+	if host.Hostname != "" {
+		fmt.Printf("  HostName: %s\n", host.Hostname)
+	}
+	if len(host.Tags) > 0 {
+		fmt.Printf("  Tags: %s\n", strings.Join(host.Tags, ", "))
+	}
+	if effective.JumpHost != "" {
+		fmt.Printf("  Jump host: %s%s\n", effective.JumpHost, note(host.JumpHost == ""))
+	}
+	var names []string
+	for _, g := range models.GroupsOf(host, groups) {
+		names = append(names, g.Name)
+	}
+	if len(names) > 0 {
+		fmt.Printf("  Groups: %s\n", strings.Join(names, ", "))
+	}
+	printHostOptions(&effective.HostOptions)
+}
+
 var hostRemoveCmd = &cobra.Command{
 	Use:   "remove <hostname>",
 	Short: "Remove an SSH host configuration",
@@ -166,6 +375,25 @@ var hostRemoveCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hostname := args[0]
 
+		// Hosts reaching others through it would be left out of the SSH config
+		hosts, err := configManager.ListEffectiveHosts()
+		if err != nil {
+			return fmt.Errorf("failed to list hosts: %w", err)
+		}
+		for _, host := range hosts {
+			if host.JumpHost == hostname {
+				return errors.New(errors.ErrInvalidInput, "HOST", fmt.Sprintf("host %s is the jump host of %s", hostname, host.Host)).
+					WithSuggestion(fmt.Sprintf("Change it first with: skm host edit %s --jump-host <host>", host.Host))
+			}
+		}
+
+		for _, group := range configManager.Get().HostGroups {
+			if group.JumpHost == hostname {
+				return errors.New(errors.ErrInvalidInput, "HOST", fmt.Sprintf("host %s is the jump host of host group %s", hostname, group.Name)).
+					WithSuggestion(fmt.Sprintf("Change it first with: skm host group edit %s --jump-host <host>", group.Name))
+			}
+		}
+
 		if err := configManager.RemoveHost(hostname); err != nil {
 			return errors.WrapWithSuggestion(err, errors.ErrNotFound, "HOST",
 				fmt.Sprintf("host %s not found", hostname),
@@ -189,15 +417,31 @@ func init() {
 
 	// Add command
 	hostCmd.AddCommand(hostAddCmd)
-	hostAddCmd.Flags().StringP("user", "u", "", "SSH user (required)")
+	hostAddCmd.Flags().StringP("user", "u", "", "SSH user (required unless a host group sets one)")
 	hostAddCmd.Flags().StringP("key", "k", "", "Key name to use (optional, will auto-create if not specified)")
 	hostAddCmd.Flags().IntP("port", "p", 0, "SSH port")
 	hostAddCmd.Flags().String("hostname", "", "Actual hostname (if different from host alias)")
 	hostAddCmd.Flags().Bool("auto-create-key", false, "Automatically create key if it doesn't exist")
-	hostAddCmd.MarkFlagRequired("user")
+	hostAddCmd.Flags().StringArray("tag", []string{}, "Tag the host, adding it to the host group of that name (repeatable)")
+	hostAddCmd.Flags().StringP("jump-host", "J", "", "Managed host to connect through, using its own key")
+	hostAddCmd.RegisterFlagCompletionFunc("jump-host", ValidHostNamesFunc)
+	addHostOptionFlags(hostAddCmd)
+
+	// Edit command
+	hostCmd.AddCommand(hostEditCmd)
+	hostEditCmd.Flags().StringP("user", "u", "", "SSH user")
+	hostEditCmd.Flags().StringP("key", "k", "", "Key name to use")
+	hostEditCmd.Flags().IntP("port", "p", 0, "SSH port (0 for the default)")
+	hostEditCmd.Flags().String("hostname", "", "Actual hostname (empty to use the alias)")
+	hostEditCmd.Flags().StringArray("tag", []string{}, "Tag the host, replacing the current tags (repeatable)")
+	hostEditCmd.Flags().StringP("jump-host", "J", "", "Managed host to connect through (empty for none)")
+	hostEditCmd.RegisterFlagCompletionFunc("jump-host", ValidHostNamesFunc)
+	addHostOptionFlags(hostEditCmd)
 
 	// List command
 	hostCmd.AddCommand(hostListCmd)
+	hostListCmd.Flags().String("group", "", "Only list the members of this host group")
+	hostListCmd.RegisterFlagCompletionFunc("group", ValidGroupNamesFunc)
 
 	// Remove command
 	hostCmd.AddCommand(hostRemoveCmd)
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/knownhosts"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
+)
+
+var hostApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Write the managed hosts to the SSH config",
+	Long: `Regenerate the SSH config from the managed hosts.
+
+With --dry-run nothing is written; the files that would change are listed.
+--diff prints the changes as a unified diff.
+
+Hosts without a usable key are reported, as are settings of user-defined
+Host or Match blocks that take precedence over the values SKM writes and
+user Host blocks that repeat a managed alias.`,
+	Example: `  skm host apply --dry-run --diff
+  skm host apply`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		showDiff, _ := cmd.Flags().GetBool("diff")
+
+		cfg := configManager.Get()
+		sshMgr := sshConfigManager()
+		plan, err := sshMgr.Plan(cfg.Hosts, managedKeys())
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		changes := plan.Changes()
+		if showDiff {
+			fmt.Fprint(out, plan.Diff())
+		}
+
+		if dryRun {
+			if len(changes) == 0 {
+				fmt.Fprintln(out, "✓ SSH config is up to date")
+			}
+			for _, c := range changes {
+				switch {
+				case c.Remove:
+					fmt.Fprintf(out, "Would remove %s\n", c.Path)
+				case !c.Exists:
+					fmt.Fprintf(out, "Would create %s\n", c.Path)
+				default:
+					fmt.Fprintf(out, "Would update %s\n", c.Path)
+				}
+			}
+			printPlanWarnings(plan)
+			return nil
+		}
+
+		if err := knownhosts.WriteFile(pinnedKnownHostsPath(), cfg.Hosts); err != nil {
+			return err
+		}
+		if err := sshMgr.Apply(plan); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+		if err := sshconfig.NewKnownHostsManager(cfg.SSHDir).UpdateCertAuthorities(cfg.HostCAs); err != nil {
+			return err
+		}
+
+		if len(changes) == 0 {
+			fmt.Fprintln(out, "✓ SSH config is up to date")
+		}
+		for _, c := range changes {
+			if c.Remove {
+				fmt.Fprintf(out, "✓ Removed %s\n", c.Path)
+			} else {
+				fmt.Fprintf(out, "✓ Updated %s\n", c.Path)
+			}
+		}
+		printPlanWarnings(plan)
+		return nil
+	},
+}
+
+func init() {
+	hostCmd.AddCommand(hostApplyCmd)
+	hostApplyCmd.Flags().Bool("dry-run", false, "Show what would change without writing anything")
+	hostApplyCmd.Flags().Bool("diff", false, "Print the changes as a unified diff")
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/api"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
+)
+
+var hostCACmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Manage host certificate authorities trusted in known_hosts",
+}
+
+var hostCATrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Trust a host CA for a set of hosts",
+	Long: `Trust a host certificate authority by writing an @cert-authority line into
+the SKM managed section of known_hosts.
+
+By default the host CA of the configured SKM server is fetched. Use
+--public-key to trust a CA from a local public key file instead.`,
+	Example: `  skm host ca trust --pattern '*.example.com'
+  skm host ca trust --name corp --public-key ./host_ca.pub --pattern '*.corp.internal'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		name, _ := cmd.Flags().GetString("name")
+		patterns, _ := cmd.Flags().GetStringSlice("pattern")
+		publicKeyFile, _ := cmd.Flags().GetString("public-key")
+
+		if len(patterns) == 0 {
+			return fmt.Errorf("at least one --pattern is required")
+		}
+
+		var publicKey string
+		if publicKeyFile != "" {
+			data, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read public key: %w", err)
+			}
+			publicKey = string(data)
+		} else {
+			if cfg.Server == "" {
+				return fmt.Errorf("no server configured. Run: skm server-login (or use --public-key)")
+			}
+			client := api.NewClient(cfg.Server, configManager.GetServerToken())
+			hostCA, err := client.GetHostCA()
+			if err != nil {
+				return fmt.Errorf("failed to fetch host CA: %w", err)
+			}
+			publicKey = hostCA.PublicKey
+			if name == "" {
+				name = serverCAName(cfg.Server)
+			}
+		}
+
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+		if err != nil {
+			return fmt.Errorf("invalid CA public key: %w", err)
+		}
+		if name == "" {
+			return fmt.Errorf("--name is required with --public-key")
+		}
+
+		hostCA := models.HostCA{
+			Name:      name,
+			PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(parsed))),
+			Patterns:  patterns,
+			AddedAt:   time.Now(),
+		}
+
+		replaced := false
+		for i := range cfg.HostCAs {
+			if cfg.HostCAs[i].Name == name {
+				cfg.HostCAs[i] = hostCA
+				replaced = true
+			}
+		}
+		if !replaced {
+			cfg.HostCAs = append(cfg.HostCAs, hostCA)
+		}
+
+		if err := saveHostCAs(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Trusting host CA %s (%s) for %s\n", name, ssh.FingerprintSHA256(parsed), strings.Join(patterns, ", "))
+		return nil
+	},
+}
+
+var hostCAListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List trusted host CAs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		if len(cfg.HostCAs) == 0 {
+			fmt.Println("No host CAs trusted.")
+			return nil
+		}
+
+		for _, hostCA := range cfg.HostCAs {
+			fingerprint := "invalid key"
+			if parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostCA.PublicKey)); err == nil {
+				fingerprint = ssh.FingerprintSHA256(parsed)
+			}
+			fmt.Printf("%s\n", hostCA.Name)
+			fmt.Printf("  Fingerprint: %s\n", fingerprint)
+			fmt.Printf("  Hosts: %s\n", strings.Join(hostCA.Patterns, ", "))
+		}
+		return nil
+	},
+}
+
+var hostCARemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Stop trusting a host CA",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		remaining := cfg.HostCAs[:0]
+		for _, hostCA := range cfg.HostCAs {
+			if hostCA.Name != args[0] {
+				remaining = append(remaining, hostCA)
+			}
+		}
+		if len(remaining) == len(cfg.HostCAs) {
+			return fmt.Errorf("host CA not found: %s", args[0])
+		}
+		cfg.HostCAs = remaining
+
+		if err := saveHostCAs(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Removed host CA %s\n", args[0])
+		return nil
+	},
+}
+
+var hostCertCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage host certificates",
+}
+
+var hostCertRequestCmd = &cobra.Command{
+	Use:   "request <host-public-key>",
+	Short: "Request a host certificate from the server CA",
+	Long: `Request a certificate for a host key (e.g. /etc/ssh/ssh_host_ed25519_key.pub)
+from the SKM server's host CA. The certificate is written next to the key as
+<key>-cert.pub; enable it in sshd_config with:
+
+  HostCertificate /etc/ssh/ssh_host_ed25519_key-cert.pub
+
+The server only certifies hostnames covered by your account's host patterns.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		if cfg.Server == "" {
+			return fmt.Errorf("no server configured. Run: skm server-login")
+		}
+
+		if configManager.GetServerToken() == "" {
+			return fmt.Errorf("not logged in. Run: skm server-login")
+		}
+
+		hostnames, _ := cmd.Flags().GetStringSlice("hostname")
+		validity, _ := cmd.Flags().GetDuration("validity")
+		if len(hostnames) == 0 {
+			return fmt.Errorf("at least one --hostname is required")
+		}
+
+		pubPath := args[0]
+		data, err := os.ReadFile(pubPath)
+		if err != nil {
+			return fmt.Errorf("failed to read host public key: %w", err)
+		}
+		publicKey, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse host public key: %w", err)
+		}
+
+		req := &api.CertificateRequest{
+			Key: api.PublicKeyData{
+				Name:        hostnames[0],
+				Type:        publicKey.Type(),
+				PublicKey:   strings.TrimSpace(string(data)),
+				Fingerprint: ssh.FingerprintSHA256(publicKey),
+				Comment:     comment,
+				CreatedAt:   time.Now(),
+			},
+			Principals: hostnames,
+		}
+		if validity > 0 {
+			validBefore := time.Now().Add(validity)
+			req.ValidBefore = &validBefore
+		}
+
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
+		resp, err := client.SignHostCertificate(req)
+		if err != nil {
+			return fmt.Errorf("failed to request host certificate: %w", err)
+		}
+
+		certPath := strings.TrimSuffix(pubPath, ".pub") + "-cert.pub"
+		if err := os.WriteFile(certPath, []byte(resp.Certificate+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write certificate: %w", err)
+		}
+
+		fmt.Printf("✓ Issued host certificate %s\n", certPath)
+		fmt.Printf("  Serial: %d\n", resp.Serial)
+		fmt.Printf("  Hostnames: %s\n", strings.Join(resp.Principals, ", "))
+		fmt.Printf("  Valid: %s to %s\n", resp.ValidAfter.Format(time.RFC3339), resp.ValidBefore.Format(time.RFC3339))
+		return nil
+	},
+}
+
+// saveHostCAs persists the trusted host CAs and rewrites the managed known_hosts section
+func saveHostCAs() error {
+	if err := configManager.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	cfg := configManager.Get()
+	khMgr := sshconfig.NewKnownHostsManager(cfg.SSHDir)
+	if err := khMgr.UpdateCertAuthorities(cfg.HostCAs); err != nil {
+		return fmt.Errorf("failed to update known_hosts: %w", err)
+	}
+	return nil
+}
+
+// serverCAName derives the default name of the host CA of an SKM server
+func serverCAName(server string) string {
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		return "skm@" + u.Host
+	}
+	return "skm@" + server
+}
+
+func init() {
+	hostCmd.AddCommand(hostCACmd)
+
+	hostCACmd.AddCommand(hostCATrustCmd)
+	hostCATrustCmd.Flags().String("name", "", "Name of the CA (default: derived from the server URL)")
+	hostCATrustCmd.Flags().StringSlice("pattern", []string{}, "known_hosts host pattern the CA is trusted for (repeatable)")
+	hostCATrustCmd.Flags().String("public-key", "", "Trust the CA in this public key file instead of the server CA")
+
+	hostCACmd.AddCommand(hostCAListCmd)
+	hostCACmd.AddCommand(hostCARemoveCmd)
+
+	hostCmd.AddCommand(hostCertCmd)
+	hostCertCmd.AddCommand(hostCertRequestCmd)
+	hostCertRequestCmd.Flags().StringSlice("hostname", []string{}, "Hostname the certificate is valid for (repeatable)")
+	hostCertRequestCmd.Flags().Duration("validity", 0, "How long the certificate is valid (default: server default)")
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/export"
+)
+
+var hostExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export managed hosts for other tools",
+	Long: `Render the managed hosts, with the settings they inherit from their groups
+and the keys bound to them, in a format read by other tools.
+
+With --tag, only the hosts with that tag or in the host group of that name
+are exported, along with the jump hosts they go through.
+
+Termius and most other clients import the ssh-config format.`,
+	Example: `  skm host export --format ansible > inventory.ini
+  skm host export --format json | jq '.hosts[].hostname'
+  skm host export --format ssh-config --tag prod -o prod.conf
+  skm host export --format putty -o sessions.reg`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		tag, _ := cmd.Flags().GetString("tag")
+		output, _ := cmd.Flags().GetString("output")
+
+		renderer, err := export.Lookup(format)
+		if err != nil {
+			return err
+		}
+
+		hosts, err := configManager.ListHosts()
+		if err != nil {
+			return fmt.Errorf("failed to list hosts: %w", err)
+		}
+		inv := export.NewInventory(hosts, managedKeys(), configManager.Get().HostGroups)
+		if tag != "" {
+			inv = inv.Select(tag)
+			if len(inv.Hosts) == 0 {
+				return fmt.Errorf("no hosts with tag or in host group %s", tag)
+			}
+		}
+		for _, w := range inv.Warnings {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  %s\n", w)
+		}
+
+		var buf bytes.Buffer
+		if err := renderer.Render(&buf, inv); err != nil {
+			return fmt.Errorf("failed to export hosts: %w", err)
+		}
+		if output == "" {
+			_, err := cmd.OutOrStdout().Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Exported %d host(s) to %s\n", len(inv.Hosts), output)
+		return nil
+	},
+}
+
+// exportFormatsHelp lists the registered export formats for the help text
+func exportFormatsHelp() string {
+	var b strings.Builder
+	b.WriteString("\n\nFormats:\n")
+	for _, name := range export.Formats() {
+		r, _ := export.Lookup(name)
+		fmt.Fprintf(&b, "  %-14s %s\n", name, r.Description())
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func init() {
+	hostCmd.AddCommand(hostExportCmd)
+	hostExportCmd.Long += exportFormatsHelp()
+	hostExportCmd.Flags().StringP("format", "f", "", "Output format ("+strings.Join(export.Formats(), ", ")+")")
+	hostExportCmd.Flags().String("tag", "", "Only export hosts with this tag or in this host group")
+	hostExportCmd.Flags().StringP("output", "o", "", "Write to a file instead of standard output")
+	hostExportCmd.MarkFlagRequired("format")
+	hostExportCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return export.Formats(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/pkg/errors"
+)
+
+var hostGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage host groups sharing defaults",
+	Long: `Manage host groups: defaults shared by every host whose alias matches the
+group patterns (such as prod-* or *.internal.corp) or which is tagged with the
+group name.
+
+A member inherits each setting it leaves unset; the first matching group
+setting a value wins. The group is also written to the SSH config as its own
+Host block, after the individual hosts, so hosts you never added get the
+defaults too.`,
+}
+
+var hostGroupAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a host group",
+	Example: `  skm host group add prod --pattern 'prod-*' --user deploy --key prod-key --port 2222
+  skm host group add corp --pattern '*.internal.corp' --pattern '!bastion.internal.corp' --proxy-jump bastion`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		name := args[0]
+		if findHostGroup(name) != nil {
+			return fmt.Errorf("host group %s already exists", name)
+		}
+
+		group := models.HostGroup{Name: name}
+		group.Patterns, _ = cmd.Flags().GetStringSlice("pattern")
+		group.User, _ = cmd.Flags().GetString("user")
+		group.KeyName, _ = cmd.Flags().GetString("key")
+		group.Port, _ = cmd.Flags().GetInt("port")
+		group.JumpHost, _ = cmd.Flags().GetString("jump-host")
+		if err := applyHostOptionFlags(cmd, &group.HostOptions); err != nil {
+			return err
+		}
+		if err := checkHostGroup(&group); err != nil {
+			return err
+		}
+
+		cfg.HostGroups = append(cfg.HostGroups, group)
+		if err := saveHostGroups(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Added host group: %s\n", name)
+		printHostGroup(&group)
+		return nil
+	},
+}
+
+var hostGroupEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Change the patterns and defaults of a host group",
+	Long: `Change the patterns and defaults of a host group.
+
+Only the flags given are changed; --pattern replaces all patterns.`,
+	Example:           `  skm host group edit prod --user admin --server-alive-interval 30`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: ValidGroupNamesFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		group := findHostGroup(args[0])
+		if group == nil {
+			return hostGroupNotFound(args[0])
+		}
+
+		// Edit a copy so a rejected change leaves the config untouched
+		edited := *group
+		if cmd.Flags().Changed("pattern") {
+			edited.Patterns, _ = cmd.Flags().GetStringSlice("pattern")
+		}
+		if cmd.Flags().Changed("user") {
+			edited.User, _ = cmd.Flags().GetString("user")
+		}
+		if cmd.Flags().Changed("key") {
+			edited.KeyName, _ = cmd.Flags().GetString("key")
+		}
+		if cmd.Flags().Changed("port") {
+			edited.Port, _ = cmd.Flags().GetInt("port")
+		}
+		if cmd.Flags().Changed("jump-host") {
+			edited.JumpHost, _ = cmd.Flags().GetString("jump-host")
+		}
+		if err := applyHostOptionFlags(cmd, &edited.HostOptions); err != nil {
+			return err
+		}
+		if err := checkHostGroup(&edited); err != nil {
+			return err
+		}
+
+		*group = edited
+		if err := saveHostGroups(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Updated host group: %s\n", group.Name)
+		printHostGroup(group)
+		return nil
+	},
+}
+
+var hostGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List host groups and their members",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		if len(cfg.HostGroups) == 0 {
+			fmt.Println("No host groups configured. Add one with: skm host group add")
+			return nil
+		}
+
+		hosts, err := configManager.ListHosts()
+		if err != nil {
+			return fmt.Errorf("failed to list hosts: %w", err)
+		}
+
+		for i := range cfg.HostGroups {
+			group := &cfg.HostGroups[i]
+			fmt.Printf("%s\n", group.Name)
+			printHostGroup(group)
+
+			var members []string
+			for j := range hosts {
+				if group.Matches(&hosts[j]) {
+					members = append(members, hosts[j].Host)
+				}
+			}
+			if len(members) > 0 {
+				fmt.Printf("  Members: %s\n", strings.Join(members, ", "))
+			}
+		}
+		return nil
+	},
+}
+
+var hostGroupRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Short:             "Remove a host group",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: ValidGroupNamesFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		remaining := cfg.HostGroups[:0]
+		for _, group := range cfg.HostGroups {
+			if group.Name != args[0] {
+				remaining = append(remaining, group)
+			}
+		}
+		if len(remaining) == len(cfg.HostGroups) {
+			return hostGroupNotFound(args[0])
+		}
+		cfg.HostGroups = remaining
+
+		if err := saveHostGroups(); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Removed host group: %s\n", args[0])
+		return nil
+	},
+}
+
+// findHostGroup returns the configured group with name, or nil
+func findHostGroup(name string) *models.HostGroup {
+	cfg := configManager.Get()
+	for i := range cfg.HostGroups {
+		if cfg.HostGroups[i].Name == name {
+			return &cfg.HostGroups[i]
+		}
+	}
+	return nil
+}
+
+// hostGroupNotFound returns the error for an unknown group
+func hostGroupNotFound(name string) error {
+	return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("host group %s not found", name)).
+		WithSuggestion("Run 'skm host group list' to see available groups")
+}
+
+// checkHostGroup validates a group and the key it refers to
+func checkHostGroup(group *models.HostGroup) error {
+	if len(group.Patterns) == 0 {
+		return fmt.Errorf("at least one --pattern is required")
+	}
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if group.KeyName != "" {
+		if _, err := configManager.GetKey(group.KeyName); err != nil {
+			return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("key %s not found", group.KeyName)).
+				WithSuggestion(fmt.Sprintf("Create it with: skm key gen --name %s", group.KeyName))
+		}
+	}
+	if group.JumpHost != "" {
+		if _, err := configManager.GetHost(group.JumpHost); err != nil {
+			return errors.New(errors.ErrNotFound, "HOST", fmt.Sprintf("jump host %s not found", group.JumpHost)).
+				WithSuggestion(fmt.Sprintf("Add it first with: skm host add %s", group.JumpHost))
+		}
+	}
+	return nil
+}
+
+// saveHostGroups persists the host groups and rewrites the SSH config
+func saveHostGroups() error {
+	if err := configManager.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := updateSSHConfig(); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+	return nil
+}
+
+// printHostGroup prints the patterns and defaults of a group
+func printHostGroup(group *models.HostGroup) {
+	fmt.Printf("  Patterns: %s\n", strings.Join(group.Patterns, ", "))
+	if group.User != "" {
+		fmt.Printf("  User: %s\n", group.User)
+	}
+	if group.KeyName != "" {
+		fmt.Printf("  Key: %s\n", group.KeyName)
+	}
+	if group.Port > 0 {
+		fmt.Printf("  Port: %d\n", group.Port)
+	}
+	if group.JumpHost != "" {
+		fmt.Printf("  Jump host: %s\n", group.JumpHost)
+	}
+	printHostOptions(&group.HostOptions)
+}
+
+func init() {
+	hostCmd.AddCommand(hostGroupCmd)
+
+	hostGroupCmd.AddCommand(hostGroupAddCmd)
+	hostGroupAddCmd.Flags().StringSlice("pattern", []string{}, "Host pattern of the members, ! to exclude (repeatable)")
+	hostGroupAddCmd.Flags().StringP("user", "u", "", "Default SSH user")
+	hostGroupAddCmd.Flags().StringP("key", "k", "", "Default key name")
+	hostGroupAddCmd.Flags().IntP("port", "p", 0, "Default SSH port")
+	hostGroupAddCmd.Flags().StringP("jump-host", "J", "", "Managed host the members connect through")
+	addHostOptionFlags(hostGroupAddCmd)
+
+	hostGroupCmd.AddCommand(hostGroupEditCmd)
+	hostGroupEditCmd.Flags().StringSlice("pattern", []string{}, "Host pattern of the members, replacing the current ones (repeatable)")
+	hostGroupEditCmd.Flags().StringP("user", "u", "", "Default SSH user")
+	hostGroupEditCmd.Flags().StringP("key", "k", "", "Default key name")
+	hostGroupEditCmd.Flags().IntP("port", "p", 0, "Default SSH port (0 for none)")
+	hostGroupEditCmd.Flags().StringP("jump-host", "J", "", "Managed host the members connect through (empty for none)")
+	addHostOptionFlags(hostGroupEditCmd)
+
+	hostGroupCmd.AddCommand(hostGroupListCmd)
+	hostGroupCmd.AddCommand(hostGroupRemoveCmd)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+)
+
+var hostImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import the Host blocks of the SSH config as managed hosts",
+	Long: `Turn every Host block of the SSH config naming concrete hosts, including
+those in included files, into an SKM host with its HostName, User, Port,
+ProxyJump and other options.
+
+The IdentityFile of a host is matched against the managed keys; a key file
+SKM does not know yet is registered as a key. A ProxyJump naming another
+host becomes its jump host. Hosts already managed with different settings
+are reported as conflicts and kept unless --overwrite is given.
+
+Once imported, the blocks are no longer needed in the SSH config. With
+--remove-blocks, or after confirming, the blocks of the config file itself
+whose hosts are all written by SKM are removed; the previous config is kept
+as a backup.`,
+	Example: `  skm host import --dry-run
+  skm host import --remove-blocks`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		removeBlocks, _ := cmd.Flags().GetBool("remove-blocks")
+		out := cmd.OutOrStdout()
+
+		configPath := filepath.Join(cfg.SSHDir, "config")
+		planner := NewHostImportPlanner(configManager, cfg.SSHDir, overwrite)
+		hosts, err := planner.Plan()
+		if err != nil {
+			return err
+		}
+		if len(hosts) == 0 {
+			for _, w := range planner.Warnings() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  %s\n", w)
+			}
+			return fmt.Errorf("no Host blocks to import in %s", configPath)
+		}
+
+		fmt.Fprintf(out, "Found %d host(s) in %s\n", len(hosts), configPath)
+		for _, h := range hosts {
+			fmt.Fprintf(out, "- %s (%s) from %s\n", h.Host.Host, h.Status, h.Source)
+			switch {
+			case h.NewKey != nil:
+				fmt.Fprintf(out, "    key: %s (new, %s)\n", h.NewKey.Name, h.NewKey.Path)
+			case h.Host.KeyName != "":
+				fmt.Fprintf(out, "    key: %s\n", h.Host.KeyName)
+			}
+			if h.Host.JumpHost != "" {
+				fmt.Fprintf(out, "    jump host: %s\n", h.Host.JumpHost)
+			}
+		}
+		for _, w := range planner.Warnings() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  %s\n", w)
+		}
+
+		removable := planner.RemovableBlocks()
+		if dryRun {
+			if removeBlocks {
+				for _, b := range removable {
+					fmt.Fprintf(out, "Would remove Host %s at %s\n", strings.Join(b.Aliases, " "), b.Source())
+				}
+			}
+			fmt.Fprintln(out, "Dry run complete. No files written.")
+			return nil
+		}
+
+		if err := planner.Apply(); err != nil {
+			return err
+		}
+
+		if len(removable) > 0 && !removeBlocks {
+			p := &prompt.Prompter{In: cmd.InOrStdin(), Out: out}
+			if p.IsInteractive() {
+				question := fmt.Sprintf("Remove %d imported Host block(s) from the SSH config? Type 'y' to confirm", len(removable))
+				if removeBlocks, err = confirm(cmd, question, "y"); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintln(out, "Run again with --remove-blocks to remove the imported Host blocks from the SSH config.")
+			}
+		}
+
+		sshMgr := sshConfigManager()
+		if removeBlocks {
+			sshMgr.DropHostBlocks(removable)
+		}
+		if err := applySSHConfig(sshMgr); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+
+		if removeBlocks && len(removable) > 0 {
+			fmt.Fprintf(out, "✓ Removed %d Host block(s); the previous config is in %s\n", len(removable), sshMgr.BackupPath())
+		}
+		fmt.Fprintln(out, "Import complete.")
+		return nil
+	},
+}
+
+func init() {
+	hostCmd.AddCommand(hostImportCmd)
+	hostImportCmd.Flags().Bool("dry-run", false, "Preview the imported hosts without writing")
+	hostImportCmd.Flags().Bool("overwrite", false, "Replace managed hosts defined differently in the SSH config")
+	hostImportCmd.Flags().Bool("remove-blocks", false, "Remove the imported Host blocks from the SSH config without asking")
+}
@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/config"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
+)
+
+// HostImport is a host of the SSH config and how it is imported.
+type HostImport struct {
+	Host   models.Host
+	Source string
+	Status string // "new", "unchanged", "conflict" or "invalid"
+	// NewKey is the IdentityFile of the host, registered as a key on import
+	NewKey *models.Key
+
+	blocks     []int
+	seen       map[string]bool
+	identities []string
+	proxyJump  string
+}
+
+// HostImportPlanner turns the Host blocks of an SSH config into SKM hosts.
+type HostImportPlanner struct {
+	manager   *config.Manager
+	sshMgr    *sshconfig.Manager
+	sshDir    string
+	overwrite bool
+	blocks    []sshconfig.UserHostBlock
+	warnings  []string
+	// newKeys are the key files registered on import, by name
+	newKeys map[string]*models.Key
+	Hosts   []HostImport
+}
+
+// NewHostImportPlanner creates a planner for the SSH config in sshDir. With
+// overwrite, managed hosts defined differently in the file are replaced.
+func NewHostImportPlanner(manager *config.Manager, sshDir string, overwrite bool) *HostImportPlanner {
+	return &HostImportPlanner{
+		manager:   manager,
+		sshMgr:    sshconfig.NewManager(sshDir),
+		sshDir:    sshDir,
+		overwrite: overwrite,
+	}
+}
+
+// importedKeywords are written by SKM itself and not imported as options
+var importedKeywords = map[string]string{
+	"identitiesonly":     "",
+	"certificatefile":    "SKM writes the certificate of the key",
+	"identityagent":      "SKM writes its own agent",
+	"userknownhostsfile": "SKM writes its own known_hosts files",
+	"include":            "Include inside a Host block is not supported",
+}
+
+// Plan reads the concrete Host blocks, including those of included files, and
+// works out the hosts to import. A host defined by several blocks takes the
+// first value of each setting, as ssh does.
+func (p *HostImportPlanner) Plan() ([]HostImport, error) {
+	blocks, warnings, err := p.sshMgr.UserHostBlocks()
+	if err != nil {
+		return nil, err
+	}
+	p.blocks = blocks
+	p.warnings = append(p.warnings, warnings...)
+
+	index := make(map[string]int)
+	for bi := range blocks {
+		for _, alias := range blocks[bi].Aliases {
+			i, ok := index[strings.ToLower(alias)]
+			if !ok {
+				i = len(p.Hosts)
+				index[strings.ToLower(alias)] = i
+				p.Hosts = append(p.Hosts, HostImport{
+					Host:   models.Host{Host: alias},
+					Source: blocks[bi].Source(),
+					seen:   make(map[string]bool),
+				})
+			}
+			p.Hosts[i].blocks = append(p.Hosts[i].blocks, bi)
+			p.readBlock(&p.Hosts[i], &blocks[bi])
+		}
+	}
+
+	keys := p.existingKeys()
+	p.newKeys = make(map[string]*models.Key)
+	for i := range p.Hosts {
+		entry := &p.Hosts[i]
+		p.resolveJump(entry, index)
+		p.resolveKey(entry, keys)
+		p.resolveStatus(entry)
+	}
+	return p.Hosts, nil
+}
+
+// readBlock applies the directives of a block not set by an earlier one
+func (p *HostImportPlanner) readBlock(entry *HostImport, block *sshconfig.UserHostBlock) {
+	alias := entry.Host.Host
+	for _, d := range block.Directives {
+		name, value := d.Name(), d.Value()
+		repeated := name == "localforward" || name == "remoteforward" || name == "dynamicforward" || name == "identityfile"
+		if entry.seen[name] && !repeated {
+			continue
+		}
+		entry.seen[name] = true
+
+		if reason, skipped := importedKeywords[name]; skipped {
+			if reason != "" {
+				p.addWarnings(fmt.Sprintf("host %s: %s is not imported: %s", alias, d.Keyword, reason))
+			}
+			continue
+		}
+
+		switch name {
+		case "hostname":
+			entry.Host.Hostname = value
+		case "user":
+			entry.Host.User = value
+		case "port":
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				p.addWarnings(fmt.Sprintf("host %s: invalid Port %q is not imported", alias, value))
+				continue
+			}
+			entry.Host.Port = port
+		case "identityfile":
+			entry.identities = append(entry.identities, value)
+		case "proxyjump":
+			entry.proxyJump = value
+		default:
+			if err := entry.Host.Set(d.Keyword, value); err != nil {
+				p.addWarnings(fmt.Sprintf("host %s: %v", alias, err))
+			}
+		}
+	}
+}
+
+// resolveJump turns a ProxyJump naming a single managed or imported host into
+// a jump host, so the hop uses its own key
+func (p *HostImportPlanner) resolveJump(entry *HostImport, index map[string]int) {
+	value := entry.proxyJump
+	if value == "" {
+		return
+	}
+	if !strings.EqualFold(value, "none") && !strings.ContainsAny(value, ",@:") {
+		if i, ok := index[strings.ToLower(value)]; ok {
+			entry.Host.JumpHost = p.Hosts[i].Host.Host
+			return
+		}
+		if p.manager != nil {
+			if hop, err := p.manager.GetHost(value); err == nil {
+				entry.Host.JumpHost = hop.Host
+				return
+			}
+		}
+	}
+	entry.Host.ProxyJump = value
+}
+
+// existingKeys returns the managed keys by normalized path and by fingerprint
+func (p *HostImportPlanner) existingKeys() map[string]*models.Key {
+	keys := make(map[string]*models.Key)
+	if p.manager == nil {
+		return keys
+	}
+	listed, err := p.manager.ListKeys()
+	if err != nil {
+		return keys
+	}
+	for i := range listed {
+		keys[normalizePath(listed[i].Path)] = &listed[i]
+		if listed[i].Fingerprint != "" {
+			keys[listed[i].Fingerprint] = &listed[i]
+		}
+	}
+	return keys
+}
+
+// resolveKey binds the host to the managed key of the first of its
+// IdentityFiles found, or registers that file as a new key
+func (p *HostImportPlanner) resolveKey(entry *HostImport, keys map[string]*models.Key) {
+	alias := entry.Host.Host
+	for _, identity := range entry.identities {
+		if strings.Contains(identity, "%") {
+			p.addWarnings(fmt.Sprintf("host %s: IdentityFile %s uses tokens and is not imported", alias, identity))
+			continue
+		}
+
+		path := expandPath(identity, p.sshDir)
+		pubPath := path + ".pub"
+		key, ok := keys[normalizePath(path)]
+		if !ok {
+			if fp := fingerprint(pubPath); fp != "" {
+				key, ok = keys[fp]
+			}
+		}
+		if !ok {
+			var err error
+			if key, err = p.newKey(path, keys); err != nil {
+				p.addWarnings(fmt.Sprintf("host %s: IdentityFile %s is not imported: %v", alias, path, err))
+				continue
+			}
+		}
+
+		entry.Host.KeyName = key.Name
+		entry.NewKey = p.newKeys[key.Name]
+		return
+	}
+	if len(entry.identities) > 0 {
+		p.addWarnings(fmt.Sprintf("host %s: no usable IdentityFile; assign a key with: skm host edit %s --key <key>", alias, alias))
+	}
+}
+
+// newKey describes a key file SKM does not manage yet, to be registered on
+// import
+func (p *HostImportPlanner) newKey(path string, keys map[string]*models.Key) (*models.Key, error) {
+	pubPath := path + ".pub"
+	fp := fingerprint(pubPath)
+	if fp == "" || readable(path) != nil {
+		return nil, fmt.Errorf("key or public key not found")
+	}
+	if err := checkImportPolicy(p.manager, pubPath); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if _, exists := p.keyNamed(name, keys); exists {
+		return nil, fmt.Errorf("a different key named %s already exists", name)
+	}
+	key := &models.Key{
+		Name:        name,
+		Path:        path,
+		PubPath:     pubPath,
+		Fingerprint: fp,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := describeImportedKey(key); err != nil {
+		p.addWarnings(fmt.Sprintf("%s: %v", name, err))
+	}
+	keys[normalizePath(path)] = key
+	keys[fp] = key
+	p.newKeys[name] = key
+	return key, nil
+}
+
+// keyNamed returns the managed or newly found key called name
+func (p *HostImportPlanner) keyNamed(name string, keys map[string]*models.Key) (*models.Key, bool) {
+	for _, key := range keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// resolveStatus compares the host with the managed host of the same alias
+func (p *HostImportPlanner) resolveStatus(entry *HostImport) {
+	if err := entry.Host.Validate(); err != nil {
+		entry.Status = "invalid"
+		p.addWarnings(err.Error())
+		return
+	}
+	entry.Status = "new"
+	if p.manager == nil {
+		return
+	}
+	existing, err := p.manager.GetHost(entry.Host.Host)
+	if err != nil {
+		return
+	}
+	if sameHost(existing, &entry.Host) {
+		entry.Status = "unchanged"
+	} else {
+		entry.Status = "conflict"
+	}
+}
+
+// sameHost reports whether two hosts write the same SSH config
+func sameHost(a, b *models.Host) bool {
+	return a.User == b.User && a.Hostname == b.Hostname && a.Port == b.Port &&
+		a.KeyName == b.KeyName && a.JumpHost == b.JumpHost &&
+		reflect.DeepEqual(a.Directives(), b.Directives())
+}
+
+// imported reports whether the host is managed by SKM after Apply
+func (p *HostImportPlanner) imported(entry *HostImport) bool {
+	return entry.Status == "new" || entry.Status == "unchanged" || (entry.Status == "conflict" && p.overwrite)
+}
+
+// Apply adds the new hosts and their keys; conflicting hosts are replaced
+// only with overwrite, keeping their pinned host keys and tags.
+func (p *HostImportPlanner) Apply() error {
+	if p.manager == nil {
+		return fmt.Errorf("config manager not initialized")
+	}
+
+	added := make(map[string]bool)
+	for i := range p.Hosts {
+		entry := &p.Hosts[i]
+		if entry.Status == "unchanged" || !p.imported(entry) {
+			continue
+		}
+
+		if entry.NewKey != nil && !added[entry.NewKey.Name] {
+			if err := p.manager.AddKey(*entry.NewKey); err != nil {
+				return fmt.Errorf("add key %s: %w", entry.NewKey.Name, err)
+			}
+			added[entry.NewKey.Name] = true
+		}
+
+		if entry.Status == "new" {
+			if err := p.manager.AddHost(entry.Host); err != nil {
+				return fmt.Errorf("add host %s: %w", entry.Host.Host, err)
+			}
+			continue
+		}
+		existing, err := p.manager.GetHost(entry.Host.Host)
+		if err != nil {
+			return fmt.Errorf("get host %s: %w", entry.Host.Host, err)
+		}
+		host := entry.Host
+		host.HostKeys = existing.HostKeys
+		host.Tags = existing.Tags
+		if err := p.manager.UpdateHost(host.Host, host); err != nil {
+			return fmt.Errorf("update host %s: %w", host.Host, err)
+		}
+	}
+	return nil
+}
+
+// RemovableBlocks returns the Host blocks of the SSH config itself whose
+// hosts are all imported and written by SKM, so removing them keeps every
+// host reachable. Blocks of included files are never removed.
+func (p *HostImportPlanner) RemovableBlocks() []sshconfig.UserHostBlock {
+	var groups []models.HostGroup
+	if p.manager != nil {
+		groups = p.manager.Get().HostGroups
+	}
+
+	removable := make(map[int]bool)
+	for bi := range p.blocks {
+		removable[bi] = p.sshMgr.InConfig(&p.blocks[bi])
+	}
+	for i := range p.Hosts {
+		entry := &p.Hosts[i]
+		written := p.imported(entry) && entry.Host.WithGroups(groups).KeyName != ""
+		for _, bi := range entry.blocks {
+			removable[bi] = removable[bi] && written
+		}
+	}
+
+	var blocks []sshconfig.UserHostBlock
+	for bi := range p.blocks {
+		if removable[bi] {
+			blocks = append(blocks, p.blocks[bi])
+		}
+	}
+	return blocks
+}
+
+// addWarnings records warnings that were not recorded yet
+func (p *HostImportPlanner) addWarnings(warnings ...string) {
+	for _, warning := range warnings {
+		known := false
+		for _, w := range p.warnings {
+			if w == warning {
+				known = true
+				break
+			}
+		}
+		if !known {
+			p.warnings = append(p.warnings, warning)
+		}
+	}
+}
+
+// Warnings returns the problems found while planning
+func (p *HostImportPlanner) Warnings() []string {
+	return p.warnings
+}
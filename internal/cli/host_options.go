@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// hostOptionFlag maps a host add/edit flag to its ssh_config keyword
+type hostOptionFlag struct {
+	name    string
+	keyword string
+	usage   string
+	kind    string // "string", "bool", "int" or "list"
+}
+
+var hostOptionFlags = []hostOptionFlag{
+	{"proxy-jump", "ProxyJump", "Jump hosts, [user@]host[:port] separated by commas", "string"},
+	{"forward-agent", "ForwardAgent", "Forward the SSH agent to the host", "bool"},
+	{"local-forward", "LocalForward", `Local port forward, e.g. "8080 localhost:80" (repeatable)`, "list"},
+	{"remote-forward", "RemoteForward", `Remote port forward, e.g. "9000 localhost:9000" (repeatable)`, "list"},
+	{"dynamic-forward", "DynamicForward", "SOCKS port forward, e.g. 1080 (repeatable)", "list"},
+	{"server-alive-interval", "ServerAliveInterval", "Seconds between keepalive messages", "int"},
+	{"server-alive-count-max", "ServerAliveCountMax", "Unanswered keepalives before disconnecting", "int"},
+	{"control-master", "ControlMaster", "Connection sharing: yes, no, ask, auto or autoask", "string"},
+	{"control-path", "ControlPath", "Control socket path, e.g. ~/.ssh/cm-%r@%h:%p", "string"},
+	{"control-persist", "ControlPersist", "Keep the master connection open: yes, no or a time such as 10m", "string"},
+	{"add-keys-to-agent", "AddKeysToAgent", "Add used keys to the agent: yes, no, ask, confirm or a time", "string"},
+	{"strict-host-key-checking", "StrictHostKeyChecking", "yes, no, ask, accept-new or off", "string"},
+	{"compression", "Compression", "Compress the connection", "bool"},
+	{"request-tty", "RequestTTY", "yes, no, force or auto", "string"},
+}
+
+// addHostOptionFlags registers the ssh_config option flags on cmd
+func addHostOptionFlags(cmd *cobra.Command) {
+	for _, f := range hostOptionFlags {
+		switch f.kind {
+		case "bool":
+			cmd.Flags().Bool(f.name, false, f.usage)
+		case "int":
+			cmd.Flags().Int(f.name, 0, f.usage)
+		case "list":
+			cmd.Flags().StringArray(f.name, nil, f.usage)
+		default:
+			cmd.Flags().String(f.name, "", f.usage)
+		}
+	}
+	cmd.Flags().StringArrayP("option", "o", nil, "Any other ssh_config option as Keyword=value (repeatable)")
+	cmd.Flags().StringArray("unset", nil, "Remove an option by its ssh_config keyword (repeatable)")
+}
+
+// applyHostOptionFlags applies the option flags given on the command line to
+// opts. --unset is applied first; a repeatable flag replaces the existing list.
+func applyHostOptionFlags(cmd *cobra.Command, opts *models.HostOptions) error {
+	unset, _ := cmd.Flags().GetStringArray("unset")
+	for _, keyword := range unset {
+		if err := opts.Unset(keyword); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range hostOptionFlags {
+		if !cmd.Flags().Changed(f.name) {
+			continue
+		}
+
+		var values []string
+		switch f.kind {
+		case "bool":
+			b, _ := cmd.Flags().GetBool(f.name)
+			values = []string{"no"}
+			if b {
+				values = []string{"yes"}
+			}
+		case "int":
+			n, _ := cmd.Flags().GetInt(f.name)
+			values = []string{strconv.Itoa(n)}
+		case "list":
+			values, _ = cmd.Flags().GetStringArray(f.name)
+			_ = opts.Unset(f.keyword)
+		default:
+			s, _ := cmd.Flags().GetString(f.name)
+			values = []string{s}
+		}
+
+		for _, value := range values {
+			if err := opts.Set(f.keyword, value); err != nil {
+				return fmt.Errorf("invalid --%s: %w", f.name, err)
+			}
+		}
+	}
+
+	options, _ := cmd.Flags().GetStringArray("option")
+	for _, option := range options {
+		keyword, value, ok := strings.Cut(option, "=")
+		if !ok || strings.TrimSpace(keyword) == "" {
+			return fmt.Errorf("invalid --option %q: expected Keyword=value", option)
+		}
+		if err := opts.Set(strings.TrimSpace(keyword), value); err != nil {
+			return err
+		}
+	}
+
+	return opts.Validate()
+}
+
+// printHostOptions prints the ssh_config options of a host
+func printHostOptions(opts *models.HostOptions) {
+	for _, d := range opts.Directives() {
+		fmt.Printf("  %s: %s\n", d.Keyword, d.Value)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig/parser"
+)
+
+// systemSSHConfig is read after the user's config, like ssh does
+const systemSSHConfig = "/etc/ssh/ssh_config"
+
+var hostResolveCmd = &cobra.Command{
+	Use:   "resolve <host>",
+	Short: "Show the effective SSH settings for a host, like ssh -G",
+	Long: `Resolve the settings ssh would use to connect to a host.
+
+The SSH config (including Include files, Host patterns and Match blocks) is
+evaluated the way ssh -G does: the first value of a setting wins, and
+IdentityFile, CertificateFile and forwardings accumulate. Without -F the
+user's config and ` + systemSSHConfig + ` are read.
+
+Match exec commands are run with /bin/sh unless --no-exec is given.`,
+	Example: `  skm host resolve github.com
+  skm host resolve prod-db --origin
+  skm host resolve web -l deploy -F ./ssh_config`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: ValidHostNamesFunc,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, _ := cmd.Flags().GetString("config-file")
+		remoteUser, _ := cmd.Flags().GetString("login")
+		showOrigin, _ := cmd.Flags().GetBool("origin")
+		noExec, _ := cmd.Flags().GetBool("no-exec")
+
+		paths := []string{configFile}
+		if configFile == "" {
+			paths = []string{filepath.Join(configManager.Get().SSHDir, "config"), systemSSHConfig}
+		}
+
+		var files []*parser.File
+		for _, path := range paths {
+			file, err := parser.Parse(path)
+			if os.IsNotExist(err) && configFile == "" {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to parse SSH config: %w", err)
+			}
+			files = append(files, file)
+		}
+
+		opts := parser.Options{User: remoteUser}
+		if !noExec {
+			opts.Exec = func(command string) bool {
+				return exec.Command("/bin/sh", "-c", command).Run() == nil
+			}
+		}
+		settings := parser.Resolve(args[0], opts, files...)
+
+		out := cmd.OutOrStdout()
+		keywords := []string{"hostname", "user", "port"}
+		for _, keyword := range settings.Keywords() {
+			if keyword != "hostname" && keyword != "user" && keyword != "port" {
+				keywords = append(keywords, keyword)
+			}
+		}
+		for _, keyword := range keywords {
+			for _, v := range settings.Origins(keyword) {
+				if !showOrigin {
+					fmt.Fprintf(out, "%s %s\n", keyword, v.Value)
+				} else if v.File == "" {
+					fmt.Fprintf(out, "%s %s  # default\n", keyword, v.Value)
+				} else {
+					fmt.Fprintf(out, "%s %s  # %s:%d\n", keyword, v.Value, v.File, v.Line)
+				}
+			}
+		}
+
+		for _, warning := range settings.Warnings {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠️  %s\n", warning)
+		}
+		return nil
+	},
+}
+
+func init() {
+	hostCmd.AddCommand(hostResolveCmd)
+	hostResolveCmd.Flags().StringP("config-file", "F", "", "Read this SSH config file instead of the user and system configs")
+	hostResolveCmd.Flags().StringP("login", "l", "", "Remote user, as with ssh -l")
+	hostResolveCmd.Flags().Bool("origin", false, "Show the file and line each value comes from")
+	hostResolveCmd.Flags().Bool("no-exec", false, "Do not run Match exec commands; such blocks never match")
+}
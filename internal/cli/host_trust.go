@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/knownhosts"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+var hostTrustCmd = &cobra.Command{
+	Use:   "trust <host>",
+	Short: "Pin or re-pin the host keys of a host",
+	Long: `Connect to a host and pin the host keys it presents.
+
+The first time a host is trusted its keys are pinned without asking. When the
+host later presents different keys the change is shown as a diff and must be
+confirmed before the new keys replace the pinned ones. Pinned keys are written
+to an skm-owned known_hosts file that the managed SSH config references.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		host, err := configManager.GetHost(args[0])
+		if err != nil {
+			return err
+		}
+
+		address := knownhosts.Address(host)
+		keys, err := knownhosts.Scan(address, timeout)
+		if err != nil {
+			return err
+		}
+
+		if len(host.HostKeys) > 0 {
+			diff := knownhosts.Compare(host.HostKeys, keys)
+			if !diff.Changed() {
+				fmt.Printf("✓ Host keys of %s (%s) are unchanged\n", host.Host, address)
+				return nil
+			}
+
+			fmt.Printf("⚠️  The host keys of %s (%s) have changed:\n", host.Host, address)
+			fmt.Println(indent(diff.String(), "  "))
+			fmt.Println("This can mean the host was reinstalled or that someone is intercepting the connection.")
+			if !yes {
+				ok, err := confirm(cmd, "Type 'yes' to accept the new host keys", "yes")
+				if err != nil {
+					return fmt.Errorf("%w; rerun with --yes to accept the new host keys", err)
+				}
+				if !ok {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+		}
+
+		pinned := knownhosts.Pin(keys, time.Now())
+		if err := pinHostKeys(host, pinned); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Pinned %d host key(s) for %s (%s)\n", len(pinned), host.Host, address)
+		for _, k := range pinned {
+			fmt.Printf("  %s\n", k.Fingerprint)
+		}
+		return nil
+	},
+}
+
+var hostCheckCmd = &cobra.Command{
+	Use:   "check [host...]",
+	Short: "Check hosts against their pinned host keys",
+	Long:  `Connect to the given hosts, or every host with pinned keys, and report host keys that changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		var hosts []models.Host
+		if len(args) > 0 {
+			for _, name := range args {
+				host, err := configManager.GetHost(name)
+				if err != nil {
+					return err
+				}
+				hosts = append(hosts, *host)
+			}
+		} else {
+			all, err := configManager.ListHosts()
+			if err != nil {
+				return fmt.Errorf("failed to list hosts: %w", err)
+			}
+			for _, h := range all {
+				if len(h.HostKeys) > 0 {
+					hosts = append(hosts, h)
+				}
+			}
+		}
+
+		if len(hosts) == 0 {
+			fmt.Println("No hosts with pinned keys.")
+			return nil
+		}
+
+		changed := 0
+		for i := range hosts {
+			host := &hosts[i]
+			address := knownhosts.Address(host)
+
+			if len(host.HostKeys) == 0 {
+				fmt.Printf("- %s: no pinned keys (run: skm host trust %s)\n", host.Host, host.Host)
+				continue
+			}
+
+			keys, err := knownhosts.Scan(address, timeout)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", host.Host, err)
+				continue
+			}
+
+			diff := knownhosts.Compare(host.HostKeys, keys)
+			if !diff.Changed() {
+				fmt.Printf("✓ %s: host keys unchanged\n", host.Host)
+				continue
+			}
+
+			changed++
+			fmt.Printf("⚠️  %s: host keys changed\n", host.Host)
+			fmt.Println(indent(diff.String(), "    "))
+		}
+
+		if changed > 0 {
+			return fmt.Errorf("%d host(s) presented changed keys; review and run: skm host trust <host>", changed)
+		}
+		return nil
+	},
+}
+
+// pinHostKeys stores the pinned keys of a host and regenerates the known_hosts files
+func pinHostKeys(host *models.Host, keys []models.HostKey) error {
+	host.HostKeys = keys
+	if err := configManager.UpdateHost(host.Host, *host); err != nil {
+		return fmt.Errorf("failed to update host: %w", err)
+	}
+	if err := updateSSHConfig(); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+	return nil
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func init() {
+	hostCmd.AddCommand(hostTrustCmd)
+	hostTrustCmd.Flags().BoolP("yes", "y", false, "Accept changed host keys without asking")
+	hostTrustCmd.Flags().Duration("timeout", knownhosts.DefaultTimeout, "Connection timeout")
+
+	hostCmd.AddCommand(hostCheckCmd)
+	hostCheckCmd.Flags().Duration("timeout", knownhosts.DefaultTimeout, "Connection timeout")
+}
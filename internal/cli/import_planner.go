@@ -1,19 +1,22 @@
 package cli
 
 import (
-	"bufio"
 	"crypto/ed25519"
 	"crypto/rand"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 
 	"github.com/all-dot-files/ssh-key-manager/internal/config"
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/pattern"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig/parser"
 )
 
 // ImportPlan holds discovered keys, host bindings, and warnings.
@@ -31,7 +34,7 @@ type ImportedKey struct {
 	PrivatePath   string
 	PublicPath    string
 	Fingerprint   string
-	Status        string // "new" or "conflict"
+	Status        string // "new", "conflict" or "rejected"
 	DerivedPublic bool
 }
 
@@ -54,6 +57,7 @@ type ImportPlanner struct {
 	keyMap     map[string]ImportedKey // normalized path -> key
 	hostConfig string
 	dryRun     bool
+	allowWeak  bool
 	warnings   []string
 	Keys       []ImportedKey
 	Bindings   []HostBinding
@@ -116,12 +120,19 @@ func (p *ImportPlanner) DiscoverKeys() ([]ImportedKey, error) {
 			})
 			continue
 		}
+		status := "new"
+		if err := p.checkPolicy(pubPath); err != nil {
+			if !p.allowWeak {
+				status = "rejected"
+			}
+			p.warnings = append(p.warnings, fmt.Sprintf("%s: %v", name, err))
+		}
 		keys = append(keys, ImportedKey{
 			Alias:       alias,
 			PrivatePath: privPath,
 			PublicPath:  pubPath,
 			Fingerprint: fingerprint(pubPath),
-			Status:      "new",
+			Status:      status,
 		})
 	}
 	p.Keys = keys
@@ -132,69 +143,95 @@ func (p *ImportPlanner) DiscoverKeys() ([]ImportedKey, error) {
 	return keys, nil
 }
 
-// DetectBindings parses ssh config and proposes bindings to imported keys.
+// DetectBindings parses ssh config, including the files it includes, and
+// proposes bindings to imported keys. Concrete hosts are bound to the first
+// IdentityFile ssh would use for them, wherever it is set; wildcard patterns
+// only when their own block sets an IdentityFile.
 func (p *ImportPlanner) DetectBindings() ([]HostBinding, error) {
-	file, err := os.Open(p.hostConfig)
+	file, err := parser.Parse(p.hostConfig)
 	if err != nil {
-		return nil, fmt.Errorf("open ssh config: %w", err)
+		return nil, fmt.Errorf("parse ssh config: %w", err)
 	}
-	defer file.Close()
 
 	var bindings []HostBinding
-	currentHosts := []string{}
-	var currentUser string
-	var currentPort int
-	var currentIdentity string
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
-			continue
-		}
-		key := strings.ToLower(fields[0])
-		val := strings.Join(fields[1:], " ")
-		switch key {
-		case "host":
-			// flush previous host entries
-			currentHosts = strings.Fields(val)
-			currentUser = ""
-			currentPort = 0
-			currentIdentity = ""
-		case "user":
-			currentUser = val
-		case "port":
-			fmt.Sscanf(val, "%d", &currentPort)
-		case "identityfile":
-			currentIdentity = expandPath(val, p.sourceDir)
-			for _, h := range currentHosts {
-				k, ok := p.keyMap[normalizePath(currentIdentity)]
-				if !ok {
-					p.warnings = append(p.warnings, fmt.Sprintf("no imported key matches IdentityFile %s for host %s", currentIdentity, h))
-					continue
+	seen := make(map[string]bool)
+	file.Walk(func(_ *parser.File, block *parser.Block) {
+		for _, hostPattern := range block.Patterns() {
+			if seen[hostPattern] {
+				continue
+			}
+			if pattern.HasWildcard(hostPattern) && !setsIdentity(block) {
+				continue
+			}
+			seen[hostPattern] = true
+
+			settings := file.Resolve(hostPattern, parser.Options{})
+			p.addWarnings(settings.Warnings...)
+			identities := settings.Values("identityfile")
+			if len(identities) == 0 {
+				continue
+			}
+
+			var identity string
+			var key ImportedKey
+			found := false
+			for _, id := range identities {
+				identity = expandPath(settings.ExpandTokens(id), p.sourceDir)
+				if key, found = p.keyMap[normalizePath(identity)]; found {
+					break
 				}
-				bindings = append(bindings, HostBinding{
-					HostPattern: h,
-					User:        currentUser,
-					Port:        currentPort,
-					Identity:    currentIdentity,
-					TargetAlias: k.Alias,
-					Action:      "rebind",
-				})
 			}
+			if !found {
+				identity = expandPath(settings.ExpandTokens(identities[0]), p.sourceDir)
+				p.addWarnings(fmt.Sprintf("no imported key matches IdentityFile %s for host %s", identity, hostPattern))
+				continue
+			}
+
+			binding := HostBinding{
+				HostPattern: hostPattern,
+				Identity:    identity,
+				TargetAlias: key.Alias,
+				Action:      "rebind",
+			}
+			if settings.IsSet("user") {
+				binding.User = settings.Get("user")
+			}
+			if settings.IsSet("port") {
+				binding.Port, _ = strconv.Atoi(settings.Get("port"))
+			}
+			bindings = append(bindings, binding)
 		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan ssh config: %w", err)
-	}
+	})
 	p.Bindings = bindings
 	return bindings, nil
 }
 
+// setsIdentity reports whether a block sets an IdentityFile itself
+func setsIdentity(block *parser.Block) bool {
+	for _, d := range block.Directives {
+		if d.Name() == "identityfile" {
+			return true
+		}
+	}
+	return false
+}
+
+// addWarnings records warnings that were not recorded yet
+func (p *ImportPlanner) addWarnings(warnings ...string) {
+	for _, warning := range warnings {
+		known := false
+		for _, w := range p.warnings {
+			if w == warning {
+				known = true
+				break
+			}
+		}
+		if !known {
+			p.warnings = append(p.warnings, warning)
+		}
+	}
+}
+
 // Apply executes the import plan against the config manager.
 func (p *ImportPlanner) Apply() error {
 	if p.manager == nil {
@@ -202,7 +239,7 @@ func (p *ImportPlanner) Apply() error {
 	}
 	// import keys
 	for _, k := range p.Keys {
-		if k.Status == "conflict" {
+		if k.Status != "new" {
 			continue
 		}
 		model := models.Key{
@@ -213,6 +250,9 @@ func (p *ImportPlanner) Apply() error {
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
+		if err := describeImportedKey(&model); err != nil {
+			p.warnings = append(p.warnings, fmt.Sprintf("%s: %v", k.Alias, err))
+		}
 		if err := p.manager.AddKey(model); err != nil {
 			return fmt.Errorf("add key %s: %w", k.Alias, err)
 		}
@@ -252,6 +292,32 @@ func (p *ImportPlanner) Apply() error {
 	return nil
 }
 
+// SetAllowWeak imports keys that violate the key algorithm policy instead of rejecting them.
+func (p *ImportPlanner) SetAllowWeak(allow bool) {
+	p.allowWeak = allow
+}
+
+// checkPolicy checks a discovered public key against the key algorithm policy.
+func (p *ImportPlanner) checkPolicy(pubPath string) error {
+	return checkImportPolicy(p.manager, pubPath)
+}
+
+// checkImportPolicy checks a public key file against the key algorithm policy.
+func checkImportPolicy(manager *config.Manager, pubPath string) error {
+	if manager == nil {
+		return nil
+	}
+	data, err := os.ReadFile(pubPath)
+	if err != nil {
+		return err
+	}
+	keyType, bits, err := keystore.PublicKeyInfo(data)
+	if err != nil {
+		return err
+	}
+	return manager.Get().KeyAlgorithmPolicy.Check(keyType, bits)
+}
+
 func (p *ImportPlanner) Warnings() []string {
 	return p.warnings
 }
@@ -289,6 +355,45 @@ func expandPath(path string, base string) string {
 	return abs
 }
 
+// describeImportedKey fills in the key type and, for FIDO2 keys, the security key metadata
+func describeImportedKey(key *models.Key) error {
+	publicData, err := os.ReadFile(key.PubPath)
+	if err != nil {
+		return err
+	}
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(publicData)
+	if err != nil {
+		return err
+	}
+	keyType, err := keystore.KeyTypeOf(pub)
+	if err != nil {
+		return err
+	}
+	key.Type = keyType
+	key.Comment = comment
+	switch keyType {
+	case models.KeyTypeRSA:
+		key.RSABits = keystore.KeySize(pub)
+	case models.KeyTypeECDSA:
+		key.ECDSABits = keystore.KeySize(pub)
+	}
+	if !keyType.IsSecurityKey() {
+		return nil
+	}
+
+	privateData, err := os.ReadFile(key.Path)
+	if err != nil {
+		return err
+	}
+	info, err := keystore.ParseSecurityKey(privateData, publicData)
+	if err != nil {
+		return err
+	}
+	key.SecurityKey = info
+	key.HasPassphrase = !info.FlagsKnown
+	return nil
+}
+
 func fingerprint(pubPath string) string {
 	data, err := os.ReadFile(pubPath)
 	if err != nil {
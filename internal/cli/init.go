@@ -26,6 +26,7 @@ var initCmd = &cobra.Command{
 		}
 
 		cfg := configManager.Get()
+		migrateSSHConfig()
 
 		fmt.Println("✓ SKM initialized successfully!")
 		fmt.Printf("  Device ID: %s\n", cfg.DeviceID)
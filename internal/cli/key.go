@@ -10,6 +10,7 @@ import (
 
 	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
 	"github.com/all-dot-files/ssh-key-manager/internal/rotation"
 )
 
@@ -28,6 +29,8 @@ var keyGenCmd = &cobra.Command{
 		keyType, _ := cmd.Flags().GetString("type")
 		passphrase, _ := cmd.Flags().GetString("passphrase")
 		rsaBits, _ := cmd.Flags().GetInt("rsa-bits")
+		ecdsaBits, _ := cmd.Flags().GetInt("ecdsa-bits")
+		allowWeak, _ := cmd.Flags().GetBool("allow-weak")
 		tags, _ := cmd.Flags().GetStringSlice("tags")
 		comment, _ := cmd.Flags().GetString("comment")
 
@@ -50,15 +53,49 @@ var keyGenCmd = &cobra.Command{
 			kt = models.KeyTypeRSA
 		case "ecdsa":
 			kt = models.KeyTypeECDSA
+		case "ed25519-sk", "ecdsa-sk":
+			return fmt.Errorf("security keys are created on the authenticator: run 'ssh-keygen -t %s' and then 'skm key import-sk'", keyType)
 		default:
 			return fmt.Errorf("unsupported key type: %s", keyType)
 		}
 
+		bits := 256
+		switch kt {
+		case models.KeyTypeRSA:
+			bits = rsaBits
+		case models.KeyTypeECDSA:
+			bits = ecdsaBits
+		}
+		if err := checkKeyPolicy(kt, bits, allowWeak); err != nil {
+			return err
+		}
+
+		// In vault mode new keys are protected by the vault instead
+		noPassphrase, _ := cmd.Flags().GetBool("no-passphrase")
+		vault, err := currentVault()
+		if err != nil {
+			return err
+		}
+		if passphrase == "" && !noPassphrase && vault == nil {
+			req := prompt.Request{Prompt: "Passphrase (empty for none)", Env: prompt.EnvNewPassphrase}
+			if prompter().HasSource(req) {
+				passphrase, err = prompter().NewSecret(req)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		// Generate key
-		key, err := ks.GenerateKey(name, kt, passphrase, rsaBits)
+		key, err := ks.GenerateKeyWithSize(name, kt, passphrase, bits)
 		if err != nil {
 			return fmt.Errorf("failed to generate key: %w", err)
 		}
+		if !noPassphrase {
+			if err := protectNewKey(ks, key); err != nil {
+				return fmt.Errorf("failed to add key to the vault: %w", err)
+			}
+		}
 
 		key.Tags = tags
 		key.Comment = comment
@@ -80,36 +117,51 @@ var keyGenCmd = &cobra.Command{
 var keyListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all SSH keys",
+	Long: `List SSH keys. Active and deprecated keys are shown by default; use
+--state to pick lifecycle states or --all to include archived and revoked keys.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		keys, err := configManager.ListKeys()
+		all, err := configManager.ListKeys()
 		if err != nil {
 			return fmt.Errorf("failed to list keys: %w", err)
 		}
 
-		if len(keys) == 0 {
+		if len(all) == 0 {
 			fmt.Println("No keys found. Generate one with: skm key gen")
 			return nil
 		}
 
+		keys, err := filterKeysByState(cmd, all, models.KeyStateActive, models.KeyStateDeprecated)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			fmt.Println("No keys in the selected states. Use --all to list every key.")
+			return nil
+		}
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "NAME\tTYPE\tFINGERPRINT\tINSTALLED\tCREATED")
-		fmt.Fprintln(w, "----\t----\t---\t----\t-------")
+		fmt.Fprintln(w, "NAME\tTYPE\tFINGERPRINT\tSTATE\tINSTALLED\tCREATED")
+		fmt.Fprintln(w, "----\t----\t---\t-----\t----\t-------")
 
 		for _, key := range keys {
 			installed := "no"
 			if key.Installed {
 				installed = "yes"
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 				key.Name,
-				key.Type,
+				key.Type.ShortName(),
 				key.Fingerprint[:16]+"...",
+				key.EffectiveState(),
 				installed,
 				key.CreatedAt.Format("2006-01-02"),
 			)
 		}
 
 		w.Flush()
+		if hidden := len(all) - len(keys); hidden > 0 {
+			fmt.Printf("\n%d key(s) in other states not shown (use --all)\n", hidden)
+		}
 		return nil
 	},
 }
@@ -127,15 +179,38 @@ var keyShowCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Key: %s\n", key.Name)
-		fmt.Printf("Type: %s\n", key.Type)
+		fmt.Printf("Type: %s\n", key.Type.ShortName())
+		if sk := key.SecurityKey; sk != nil {
+			fmt.Printf("Security key application: %s\n", sk.Application)
+			if sk.FlagsKnown {
+				fmt.Printf("Resident: %v\n", sk.Resident)
+				fmt.Printf("Verify required: %v\n", sk.VerifyRequired)
+				fmt.Printf("Touch required: %v\n", !sk.NoTouchRequired)
+			} else {
+				fmt.Println("Security key flags: unknown (key handle is encrypted)")
+			}
+		}
 		fmt.Printf("Fingerprint: %s\n", key.Fingerprint)
+		fmt.Printf("State: %s\n", key.EffectiveState())
 		fmt.Printf("Private key: %s\n", key.Path)
 		fmt.Printf("Public key: %s\n", key.PubPath)
 		fmt.Printf("Installed: %v\n", key.Installed)
 		fmt.Printf("Has passphrase: %v\n", key.HasPassphrase)
+		policy := string(key.Policy)
+		if policy == "" {
+			policy = fmt.Sprintf("default (%s)", configManager.Get().DefaultKeyPolicy)
+		}
+		fmt.Printf("Agent policy: %s\n", policy)
+		fmt.Printf("Restricted to hosts: %v\n", key.RestrictToHosts)
+		if key.CertPath != "" {
+			fmt.Printf("Certificate: %s\n", key.CertPath)
+		}
 		if key.Type == models.KeyTypeRSA {
 			fmt.Printf("RSA bits: %d\n", key.RSABits)
 		}
+		if key.Type == models.KeyTypeECDSA && key.ECDSABits != 0 {
+			fmt.Printf("ECDSA curve: P-%d\n", key.ECDSABits)
+		}
 		if len(key.Tags) > 0 {
 			fmt.Printf("Tags: %v\n", key.Tags)
 		}
@@ -172,6 +247,9 @@ var keyInstallCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if !key.IsUsable() {
+			return fmt.Errorf("key %s is %s; restore it first with: skm key state %s active", name, key.EffectiveState(), name)
+		}
 
 		cfg := configManager.Get()
 		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
@@ -184,6 +262,9 @@ var keyInstallCmd = &cobra.Command{
 		}
 
 		fmt.Printf("✓ Installed key %s to %s\n", name, cfg.SSHDir)
+		if v, err := currentVault(); err == nil && v != nil && v.Has(key.Name) {
+			warnAgentOnly(ks, key)
+		}
 		return nil
 	},
 }
@@ -230,10 +311,11 @@ var keyDeleteCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 		if !force {
 			fmt.Fprintf(cmd.OutOrStdout(), "This will delete key files and remove the config entry for %s.\n", name)
-			fmt.Fprint(cmd.OutOrStdout(), "Type the key name to confirm or 'no' to cancel: ")
-			var response string
-			_, _ = fmt.Fscanln(cmd.InOrStdin(), &response)
-			if response != name {
+			ok, err := confirm(cmd, "Type the key name to confirm or 'no' to cancel", name)
+			if err != nil {
+				return fmt.Errorf("%w; rerun with --force to skip confirmation", err)
+			}
+			if !ok {
 				return fmt.Errorf("delete cancelled; rerun with --force to skip confirmation")
 			}
 		}
@@ -249,14 +331,20 @@ var keyDeleteCmd = &cobra.Command{
 			return err
 		}
 
-		// Delete from keystore
-		if err := ks.DeleteKey(key); err != nil {
-			return fmt.Errorf("failed to delete key files: %w", err)
+		if err := removeKey(ks, key); err != nil {
+			return err
 		}
 
-		// Remove from config
-		if err := configManager.RemoveKey(name); err != nil {
-			return fmt.Errorf("failed to remove key from config: %w", err)
+		// The tombstone makes other devices drop the key on their next sync pull
+		if key.Fingerprint != "" {
+			if err := configManager.AddTombstones(models.KeyTombstone{
+				Name:        key.Name,
+				Fingerprint: key.Fingerprint,
+				DeviceID:    cfg.DeviceID,
+				DeletedAt:   time.Now(),
+			}); err != nil {
+				return fmt.Errorf("failed to record tombstone: %w", err)
+			}
 		}
 
 		fmt.Printf("✓ Deleted key: %s\n", name)
@@ -264,6 +352,157 @@ var keyDeleteCmd = &cobra.Command{
 	},
 }
 
+// removeKey securely deletes the key files, the copy installed in ~/.ssh,
+// cached passphrase, vault data key and config entry of a key
+func removeKey(ks *keystore.KeyStore, key *models.Key) error {
+	if err := ks.UninstallFromSSH(key, configManager.Get().SSHDir); err != nil {
+		return fmt.Errorf("failed to remove installed key: %w", err)
+	}
+	if err := ks.DeleteKey(key); err != nil {
+		return fmt.Errorf("failed to delete key files: %w", err)
+	}
+	forgetPassphrase(key)
+	removeFromVault(key)
+
+	if err := configManager.RemoveKey(key.Name); err != nil {
+		return fmt.Errorf("failed to remove key from config: %w", err)
+	}
+	return nil
+}
+
+var keyMigrateFormatCmd = &cobra.Command{
+	Use:   "migrate-format [name...]",
+	Short: "Convert legacy key files to the OpenSSH format",
+	Long: `Rewrite private keys created by older SKM releases as openssh-key-v1 files
+so that ssh can load them directly. Passphrase-protected keys are re-encrypted
+natively with the same passphrase.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		passphrase, _ := cmd.Flags().GetString("passphrase")
+
+		if len(args) == 0 && !all {
+			return fmt.Errorf("specify key names or use --all")
+		}
+
+		var keys []models.Key
+		if all {
+			listed, err := configManager.ListKeys()
+			if err != nil {
+				return fmt.Errorf("failed to list keys: %w", err)
+			}
+			keys = listed
+		} else {
+			for _, name := range args {
+				key, err := configManager.GetKey(name)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, *key)
+			}
+		}
+
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		migrated := 0
+		for i := range keys {
+			key := &keys[i]
+
+			legacy, err := ks.IsLegacyFormat(key)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				continue
+			}
+			if !legacy {
+				LogVerbose("%s already uses the OpenSSH format", key.Name)
+				continue
+			}
+
+			migrate := func(keyPassphrase string) error {
+				return ks.MigrateFormat(key, keyPassphrase)
+			}
+			if passphrase != "" && key.HasPassphrase {
+				err = migrate(passphrase)
+			} else {
+				err = withPassphrase(key, migrate)
+			}
+			if err != nil {
+				fmt.Printf("✗ Failed to migrate %s: %v\n", key.Name, err)
+				continue
+			}
+
+			// Refresh the installed copy so ~/.ssh never keeps the old format
+			if _, err := os.Stat(ks.InstalledPath(key, cfg.SSHDir)); err == nil {
+				if err := ks.InstallToSSH(key, cfg.SSHDir); err != nil {
+					fmt.Printf("⚠️  Migrated %s but failed to refresh installed copy: %v\n", key.Name, err)
+				}
+			}
+
+			key.UpdatedAt = time.Now()
+			if err := configManager.UpdateKey(key.Name, *key); err != nil {
+				fmt.Printf("⚠️  Migrated %s but failed to update config: %v\n", key.Name, err)
+			}
+
+			fmt.Printf("✓ Migrated %s to OpenSSH format\n", key.Name)
+			migrated++
+		}
+
+		fmt.Printf("\n✓ Migrated %d key(s)\n", migrated)
+		return nil
+	},
+}
+
+var keyPolicyCmd = &cobra.Command{
+	Use:   "policy <name>",
+	Short: "Set how the SKM agent may use a key",
+	Long: `Set the agent usage policy of a key.
+
+  auto   sign without asking
+  ask    confirm every signature through the askpass program
+  never  refuse to sign with the key
+
+With --restrict-hosts the agent only signs for the hosts bound to the key
+(see 'skm host add --key'), identified by their host keys in known_hosts.
+Policies are enforced once 'skm config set agent.enforce_policies true' is set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := configManager.GetKey(args[0])
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("policy") {
+			value, _ := cmd.Flags().GetString("policy")
+			policy := models.KeyPolicy(value)
+			if value != "" && !policy.IsValid() {
+				return fmt.Errorf("invalid key policy: %s (must be auto, ask, or never)", value)
+			}
+			key.Policy = policy
+		}
+		if cmd.Flags().Changed("restrict-hosts") {
+			key.RestrictToHosts, _ = cmd.Flags().GetBool("restrict-hosts")
+		}
+
+		key.UpdatedAt = time.Now()
+		if err := configManager.UpdateKey(key.Name, *key); err != nil {
+			return fmt.Errorf("failed to update key: %w", err)
+		}
+
+		policy := string(key.Policy)
+		if policy == "" {
+			policy = "default"
+		}
+		fmt.Printf("✓ Key %s: policy=%s, restrict-hosts=%v\n", key.Name, policy, key.RestrictToHosts)
+		if !configManager.Get().Agent.EnforcePolicies {
+			fmt.Println("  Note: enable enforcement with 'skm config set agent.enforce_policies true'")
+		}
+		return nil
+	},
+}
+
 var keyRotationStatusCmd = &cobra.Command{
 	Use:   "rotation-status",
 	Short: "Check rotation status of all keys",
@@ -273,6 +512,12 @@ var keyRotationStatusCmd = &cobra.Command{
 			return fmt.Errorf("failed to list keys: %w", err)
 		}
 
+		// Deprecated, archived and revoked keys have already been replaced
+		keys, err = filterKeysByState(cmd, keys, models.KeyStateActive)
+		if err != nil {
+			return err
+		}
+
 		if len(keys) == 0 {
 			fmt.Println("No keys found.")
 			return nil
@@ -317,8 +562,10 @@ var keyRotationStatusCmd = &cobra.Command{
 var keyRotateCmd = &cobra.Command{
 	Use:   "rotate <name>",
 	Short: "Rotate an SSH key",
-	Long:  `Generate a new key to replace an existing one, updating all references.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Generate a new key to replace an existing one. The old key is marked
+deprecated unless --keep-old is given; archive it with skm key state once the
+new key is deployed.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 		keepOld, _ := cmd.Flags().GetBool("keep-old")
@@ -336,10 +583,13 @@ var keyRotateCmd = &cobra.Command{
 
 		// Generate new key with same parameters
 		newName := name + "-rotated-" + time.Now().Format("20060102")
-		newKey, err := ks.GenerateKey(newName, oldKey.Type, "", oldKey.RSABits)
+		newKey, err := ks.GenerateKeyWithSize(newName, oldKey.Type, "", oldKey.Bits())
 		if err != nil {
 			return fmt.Errorf("failed to generate new key: %w", err)
 		}
+		if err := protectNewKey(ks, newKey); err != nil {
+			return fmt.Errorf("failed to add new key to the vault: %w", err)
+		}
 
 		// Copy metadata
 		newKey.Tags = oldKey.Tags
@@ -355,12 +605,20 @@ var keyRotateCmd = &cobra.Command{
 
 		fmt.Printf("✓ Generated new key: %s\n", newName)
 		fmt.Printf("  Fingerprint: %s\n", newKey.Fingerprint)
+		if oldKey.HasPassphrase && !newKey.HasPassphrase {
+			fmt.Printf("  The new key has no passphrase; set one with: skm key passwd %s\n", newName)
+		}
 
 		if !keepOld {
+			if err := deprecateKey(oldKey); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Marked %s as deprecated\n", name)
+
 			fmt.Printf("\n⚠️  Remember to:\n")
 			fmt.Printf("  1. Update all services using the old key\n")
 			fmt.Printf("  2. Test the new key\n")
-			fmt.Printf("  3. Delete the old key with: skm key delete %s\n", name)
+			fmt.Printf("  3. Archive the old key with: skm key state %s archived\n", name)
 		}
 
 		return nil
@@ -390,13 +648,16 @@ var keyRotateBatchCmd = &cobra.Command{
 			fmt.Printf("  • %s (age: %d months)\n", info.Key.Name, info.AgeMonths)
 		}
 
-		fmt.Print("\nProceed with batch rotation? (y/N): ")
-		var response string
-		fmt.Scanln(&response)
-
-		if response != "y" && response != "Y" {
-			fmt.Println("Cancelled.")
-			return nil
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			fmt.Println()
+			ok, err := confirm(cmd, "Proceed with batch rotation? Type 'y' to confirm", "y")
+			if err != nil {
+				return fmt.Errorf("%w; rerun with --yes to skip confirmation", err)
+			}
+			if !ok {
+				fmt.Println("Cancelled.")
+				return nil
+			}
 		}
 
 		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
@@ -409,11 +670,15 @@ var keyRotateBatchCmd = &cobra.Command{
 			oldKey := info.Key
 			newName := oldKey.Name + "-rotated-" + time.Now().Format("20060102")
 
-			newKey, err := ks.GenerateKey(newName, oldKey.Type, "", oldKey.RSABits)
+			newKey, err := ks.GenerateKeyWithSize(newName, oldKey.Type, "", oldKey.Bits())
 			if err != nil {
 				fmt.Printf("✗ Failed to rotate %s: %v\n", oldKey.Name, err)
 				continue
 			}
+			if err := protectNewKey(ks, newKey); err != nil {
+				fmt.Printf("✗ Failed to add %s to the vault: %v\n", newName, err)
+				continue
+			}
 
 			newKey.Tags = oldKey.Tags
 			newKey.Comment = oldKey.Comment
@@ -426,6 +691,10 @@ var keyRotateBatchCmd = &cobra.Command{
 				continue
 			}
 
+			if err := deprecateKey(oldKey); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+
 			fmt.Printf("✓ Rotated %s → %s\n", oldKey.Name, newName)
 			successCount++
 		}
@@ -435,21 +704,39 @@ var keyRotateBatchCmd = &cobra.Command{
 	},
 }
 
+// deprecateKey marks a key replaced by rotation as deprecated
+func deprecateKey(key *models.Key) error {
+	if key.EffectiveState() != models.KeyStateActive {
+		return nil
+	}
+	key.State = models.KeyStateDeprecated
+	if err := configManager.UpdateKey(key.Name, *key); err != nil {
+		return fmt.Errorf("failed to mark %s as deprecated: %w", key.Name, err)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(keyCmd)
 
 	// Gen command
 	keyCmd.AddCommand(keyGenCmd)
 	keyGenCmd.Flags().StringP("name", "n", "", "Key name (required)")
-	keyGenCmd.Flags().StringP("type", "t", "ed25519", "Key type (ed25519, rsa, ecdsa)")
+	keyGenCmd.Flags().StringP("type", "t", "ed25519", "Key type (ed25519, rsa, ecdsa; security keys: use import-sk)")
 	keyGenCmd.Flags().StringP("passphrase", "p", "", "Passphrase to encrypt private key")
+	keyGenCmd.Flags().MarkDeprecated("passphrase", "it leaks into shell history; enter it at the prompt or use --passphrase-file, --passphrase-fd or SKM_NEW_PASSPHRASE")
+	keyGenCmd.Flags().Bool("no-passphrase", false, "Store the key unencrypted: no passphrase prompt and no vault")
 	keyGenCmd.Flags().IntP("rsa-bits", "b", 4096, "RSA key size (only for RSA keys)")
+	keyGenCmd.Flags().Int("ecdsa-bits", 256, "ECDSA curve size: 256, 384 or 521 (only for ECDSA keys)")
+	keyGenCmd.Flags().Bool("allow-weak", false, "Allow a key type or size rejected by the key algorithm policy")
 	keyGenCmd.Flags().StringSlice("tags", []string{}, "Tags for the key")
 	keyGenCmd.Flags().StringP("comment", "c", "", "Comment for the key")
 	keyGenCmd.MarkFlagRequired("name")
 
 	// List command
 	keyCmd.AddCommand(keyListCmd)
+	keyListCmd.Flags().StringSlice("state", []string{}, "Only list keys in these states (active, deprecated, archived, revoked)")
+	keyListCmd.Flags().Bool("all", false, "List keys in every state")
 
 	// Show command
 	keyCmd.AddCommand(keyShowCmd)
@@ -470,12 +757,28 @@ func init() {
 	keyDeleteCmd.ValidArgsFunction = ValidKeyNamesFunc
 	keyDeleteCmd.Flags().BoolP("force", "f", false, "Delete without interactive confirmation")
 
+	// Policy command
+	keyCmd.AddCommand(keyPolicyCmd)
+	keyPolicyCmd.Flags().String("policy", "", "Agent policy (auto, ask, never; empty for the default policy)")
+	keyPolicyCmd.Flags().Bool("restrict-hosts", false, "Only sign for the hosts bound to this key")
+	keyPolicyCmd.ValidArgsFunction = ValidKeyNamesFunc
+
+	// Migrate format command
+	keyCmd.AddCommand(keyMigrateFormatCmd)
+	keyMigrateFormatCmd.Flags().Bool("all", false, "Migrate every key in the keystore")
+	keyMigrateFormatCmd.Flags().StringP("passphrase", "p", "", "Passphrase of the encrypted keys being migrated")
+	keyMigrateFormatCmd.Flags().MarkDeprecated("passphrase", "it leaks into shell history; enter it at the prompt or use --passphrase-file, --passphrase-fd or SKM_PASSPHRASE")
+	keyMigrateFormatCmd.ValidArgsFunction = ValidKeyNamesFunc
+
 	// Rotation commands
 	keyCmd.AddCommand(keyRotationStatusCmd)
+	keyRotationStatusCmd.Flags().StringSlice("state", []string{}, "Check keys in these states instead of active keys")
+	keyRotationStatusCmd.Flags().Bool("all", false, "Check keys in every state")
 	keyCmd.AddCommand(keyRotateCmd)
-	keyRotateCmd.Flags().Bool("keep-old", false, "Keep the old key after rotation")
+	keyRotateCmd.Flags().Bool("keep-old", false, "Keep the old key active instead of marking it deprecated")
 	keyRotateCmd.ValidArgsFunction = ValidKeyNamesFunc
 	keyCmd.AddCommand(keyRotateBatchCmd)
+	keyRotateBatchCmd.Flags().BoolP("yes", "y", false, "Rotate without interactive confirmation")
 
 	// Add completion support for flags
 	keyGenCmd.RegisterFlagCompletionFunc("type", ValidKeyTypesFunc)
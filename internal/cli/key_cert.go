@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/api"
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+)
+
+var keyCertCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage SSH certificates for keys",
+}
+
+var keyCertRequestCmd = &cobra.Command{
+	Use:   "request <name>",
+	Short: "Request a user certificate from the server CA",
+	Long: `Request a user certificate for a key from the SKM server's certificate authority.
+
+The certificate is stored as <key>-cert.pub next to the private key and is
+referenced with CertificateFile in the managed SSH config. Principals default
+to your server username. Servers trust the CA with:
+
+  TrustedUserCAKeys /etc/ssh/skm_user_ca.pub
+
+where the file holds the output of 'skm key cert ca'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		if cfg.Server == "" {
+			return fmt.Errorf("no server configured. Run: skm server-login")
+		}
+
+		if configManager.GetServerToken() == "" {
+			return fmt.Errorf("not logged in. Run: skm server-login")
+		}
+
+		key, err := configManager.GetKey(args[0])
+		if err != nil {
+			return err
+		}
+
+		principals, _ := cmd.Flags().GetStringSlice("principals")
+		validity, _ := cmd.Flags().GetDuration("validity")
+		criticalOptions, _ := cmd.Flags().GetStringArray("critical-option")
+		extensions, _ := cmd.Flags().GetStringArray("extension")
+
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		pubKeyContent, err := ks.GetPublicKeyContent(key)
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %w", err)
+		}
+
+		req := &api.CertificateRequest{
+			Key: api.PublicKeyData{
+				Name:        key.Name,
+				Type:        string(key.Type),
+				PublicKey:   string(pubKeyContent),
+				Fingerprint: key.Fingerprint,
+				Comment:     key.Comment,
+				CreatedAt:   key.CreatedAt,
+			},
+			Principals: principals,
+		}
+		if validity > 0 {
+			validBefore := time.Now().Add(validity)
+			req.ValidBefore = &validBefore
+		}
+		if req.CriticalOptions, err = parseCertOptions(criticalOptions); err != nil {
+			return err
+		}
+		if req.Extensions, err = parseCertOptions(extensions); err != nil {
+			return err
+		}
+
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
+		resp, err := client.SignUserCertificate(req)
+		if err != nil {
+			return fmt.Errorf("failed to request certificate: %w", err)
+		}
+
+		certPath, err := ks.SaveCertificate(key, []byte(resp.Certificate))
+		if err != nil {
+			return err
+		}
+
+		key.CertPath = certPath
+		key.UpdatedAt = time.Now()
+		if err := configManager.UpdateKey(key.Name, *key); err != nil {
+			return fmt.Errorf("failed to update key: %w", err)
+		}
+
+		// Refresh the installed copy so ssh sees the new certificate
+		if _, err := os.Stat(ks.InstalledPath(key, cfg.SSHDir)); err == nil {
+			if err := ks.InstallToSSH(key, cfg.SSHDir); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update installed key: %v\n", err)
+			}
+		}
+
+		if err := updateSSHConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update SSH config: %v\n", err)
+		}
+
+		fmt.Printf("✓ Issued certificate for key %s\n", key.Name)
+		fmt.Printf("  Path: %s\n", certPath)
+		fmt.Printf("  Serial: %d\n", resp.Serial)
+		fmt.Printf("  Key ID: %s\n", resp.KeyID)
+		fmt.Printf("  Principals: %s\n", strings.Join(resp.Principals, ", "))
+		fmt.Printf("  Valid: %s to %s\n", resp.ValidAfter.Format(time.RFC3339), resp.ValidBefore.Format(time.RFC3339))
+		return nil
+	},
+}
+
+var keyCertCACmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Print the server's user CA public key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		if cfg.Server == "" {
+			return fmt.Errorf("no server configured. Run: skm server-login")
+		}
+
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
+		ca, err := client.GetUserCA()
+		if err != nil {
+			return fmt.Errorf("failed to fetch user CA: %w", err)
+		}
+
+		fmt.Println(ca.PublicKey)
+		return nil
+	},
+}
+
+// parseCertOptions parses name or name=value pairs given on the command line
+func parseCertOptions(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	options := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, _ := strings.Cut(v, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid certificate option: %q", v)
+		}
+		options[name] = value
+	}
+	return options, nil
+}
+
+func init() {
+	keyCmd.AddCommand(keyCertCmd)
+
+	keyCertCmd.AddCommand(keyCertRequestCmd)
+	keyCertRequestCmd.Flags().StringSlice("principals", []string{}, "Principals to certify (default: your server username)")
+	keyCertRequestCmd.Flags().Duration("validity", 0, "How long the certificate is valid (default: server default, e.g. 16h)")
+	keyCertRequestCmd.Flags().StringArray("critical-option", []string{}, "Critical option as name=value (e.g. source-address=10.0.0.0/8)")
+	keyCertRequestCmd.Flags().StringArray("extension", []string{}, "Extension to grant (replaces the defaults, e.g. permit-pty)")
+	keyCertRequestCmd.ValidArgsFunction = ValidKeyNamesFunc
+
+	keyCertCmd.AddCommand(keyCertCACmd)
+}
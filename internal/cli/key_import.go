@@ -2,9 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
 )
 
 var keyImportCmd = &cobra.Command{
@@ -18,8 +22,10 @@ var keyImportCmd = &cobra.Command{
 		detectHosts, _ := cmd.Flags().GetBool("detect-hosts")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		aliasPrefix, _ := cmd.Flags().GetString("alias-prefix")
+		allowWeak, _ := cmd.Flags().GetBool("allow-weak")
 
 		planner := NewImportPlanner(configManager, dir, aliasPrefix, dryRun)
+		planner.SetAllowWeak(allowWeak)
 		keys, err := planner.DiscoverKeys()
 		if err != nil {
 			return err
@@ -66,10 +72,68 @@ var keyImportCmd = &cobra.Command{
 	},
 }
 
+var keyImportSKCmd = &cobra.Command{
+	Use:   "import-sk <key-handle>",
+	Short: "Import a FIDO2 security key (ed25519-sk / ecdsa-sk)",
+	Long: `Import a FIDO2 security key created with ssh-keygen, e.g.:
+
+  ssh-keygen -t ed25519-sk -O resident -f ~/.ssh/id_ed25519_sk
+
+The key handle file and its .pub are copied into the keystore. The private
+key never leaves the authenticator, so signing requires the device.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		name, _ := cmd.Flags().GetString("name")
+		tags, _ := cmd.Flags().GetStringSlice("tags")
+		allowWeak, _ := cmd.Flags().GetBool("allow-weak")
+
+		handlePath := expandPath(args[0], "")
+		if name == "" {
+			name = strings.TrimPrefix(filepath.Base(handlePath), "id_")
+		}
+
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return fmt.Errorf("failed to open keystore: %w", err)
+		}
+
+		publicData, err := os.ReadFile(handlePath + ".pub")
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %w", err)
+		}
+		if err := checkPublicKeyPolicy(publicData, allowWeak); err != nil {
+			return err
+		}
+
+		key, err := ks.ImportSecurityKey(name, handlePath)
+		if err != nil {
+			return fmt.Errorf("failed to import security key: %w", err)
+		}
+		key.Tags = tags
+
+		if err := configManager.AddKey(*key); err != nil {
+			return fmt.Errorf("failed to add key to config: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Imported %s key: %s\n", key.Type.ShortName(), name)
+		fmt.Fprintf(cmd.OutOrStdout(), "  Application: %s\n", key.SecurityKey.Application)
+		fmt.Fprintf(cmd.OutOrStdout(), "  Fingerprint: %s\n", key.Fingerprint)
+		return nil
+	},
+}
+
 func init() {
 	keyCmd.AddCommand(keyImportCmd)
 	keyImportCmd.Flags().String("dir", filepath.Join("~", ".ssh"), "Directory containing SSH keys and config")
 	keyImportCmd.Flags().Bool("detect-hosts", true, "Parse ssh config to auto-bind hosts to imported keys")
 	keyImportCmd.Flags().Bool("dry-run", false, "Preview imports and bindings without writing")
 	keyImportCmd.Flags().String("alias-prefix", "", "Prefix to apply to imported key aliases")
+	keyImportCmd.Flags().Bool("allow-weak", false, "Import keys rejected by the key algorithm policy")
+
+	keyCmd.AddCommand(keyImportSKCmd)
+	keyImportSKCmd.Flags().StringP("name", "n", "", "Key name (default: derived from the file name)")
+	keyImportSKCmd.Flags().StringSlice("tags", []string{}, "Tags for the key")
+	keyImportSKCmd.Flags().Bool("allow-weak", false, "Import the key even if the key algorithm policy rejects it")
 }
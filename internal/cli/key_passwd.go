@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+)
+
+var keyPasswdCmd = &cobra.Command{
+	Use:   "passwd [name...]",
+	Short: "Add, change or remove the passphrase of keys",
+	Long: `Re-encrypt private keys with a new passphrase.
+
+Keys are selected by name and/or --tag. The current passphrase is asked for
+each encrypted key; the new passphrase is asked once and applied to every
+selected key. Use --remove to store the keys unencrypted. Installed copies in
+the SSH directory are refreshed.
+
+Without a terminal, set SKM_NEW_PASSPHRASE and SKM_PASSPHRASE, or pass
+--passphrase-file with the new passphrase on the first line followed by the
+current passphrase of each encrypted key.`,
+	Example: `  skm key passwd work
+  skm key passwd --tag laptop
+  skm key passwd old-key --remove`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		remove, _ := cmd.Flags().GetBool("remove")
+
+		if len(args) == 0 && len(tags) == 0 {
+			return fmt.Errorf("specify key names or --tag")
+		}
+
+		keys, err := selectKeys(args, tags)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no keys match the given tags")
+		}
+
+		newPassphrase := ""
+		if !remove {
+			newPassphrase, err = prompter().NewSecret(prompt.Request{Prompt: "New passphrase", Env: prompt.EnvNewPassphrase})
+			if err != nil {
+				return err
+			}
+			if newPassphrase == "" {
+				return fmt.Errorf("empty passphrase; use --remove to store keys unencrypted")
+			}
+		}
+
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for i := range keys {
+			key := &keys[i]
+
+			err := withPassphrase(key, func(oldPassphrase string) error {
+				return ks.ChangePassphrase(key, oldPassphrase, newPassphrase)
+			})
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+			forgetPassphrase(key)
+			removeFromVault(key)
+
+			if err := configManager.UpdateKey(key.Name, *key); err != nil {
+				fmt.Printf("✗ %s: failed to update key: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+
+			if key.Installed {
+				if err := ks.InstallToSSH(key, cfg.SSHDir); err != nil {
+					fmt.Printf("⚠️  %s: failed to refresh installed copy: %v\n", key.Name, err)
+				}
+			}
+
+			if remove {
+				fmt.Printf("✓ Removed passphrase from %s\n", key.Name)
+			} else {
+				fmt.Printf("✓ Changed passphrase of %s\n", key.Name)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("failed to update %d of %d key(s)", failed, len(keys))
+		}
+		return nil
+	},
+}
+
+func init() {
+	keyCmd.AddCommand(keyPasswdCmd)
+	keyPasswdCmd.Flags().StringSlice("tag", []string{}, "Change the passphrase of every key with this tag")
+	keyPasswdCmd.Flags().Bool("remove", false, "Remove the passphrase instead of setting one")
+}
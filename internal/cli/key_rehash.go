@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+var keyRehashCmd = &cobra.Command{
+	Use:   "rehash [name...]",
+	Short: "Re-encrypt keys encrypted by older releases in the OpenSSH format",
+	Long: `Re-encrypt keys that older releases of SKM encrypted themselves, as
+headerless salt|nonce|ciphertext blobs or versioned envelopes, as OpenSSH
+private keys protected by the same passphrase, so ssh can load them.
+
+Keys already in the native OpenSSH format are left alone; other legacy
+formats are converted with skm key migrate-format.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+
+		var keys []models.Key
+		var err error
+		if all {
+			keys, err = configManager.ListKeys()
+		} else {
+			if len(args) == 0 && len(tags) == 0 {
+				return fmt.Errorf("specify key names, --tag or --all")
+			}
+			keys, err = selectKeys(args, tags)
+		}
+		if err != nil {
+			return err
+		}
+
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		rehashed, failed := 0, 0
+		for i := range keys {
+			key := &keys[i]
+
+			needed, err := ks.NeedsRehash(key)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+			if !needed {
+				if !all {
+					fmt.Printf("- %s: up to date\n", key.Name)
+				}
+				continue
+			}
+
+			err = withPassphrase(key, func(passphrase string) error {
+				return ks.Rehash(key, passphrase)
+			})
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+
+			if err := configManager.UpdateKey(key.Name, *key); err != nil {
+				fmt.Printf("✗ %s: failed to update key: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+
+			fmt.Printf("✓ Rehashed %s\n", key.Name)
+			rehashed++
+		}
+
+		fmt.Printf("\nRehashed %d key(s)\n", rehashed)
+		if failed > 0 {
+			return fmt.Errorf("failed to rehash %d key(s)", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	keyCmd.AddCommand(keyRehashCmd)
+	keyRehashCmd.Flags().Bool("all", false, "Rehash every key in the keystore that needs it")
+	keyRehashCmd.Flags().StringSlice("tag", []string{}, "Rehash every key with this tag")
+}
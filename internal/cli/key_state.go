@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/krl"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+)
+
+// revokedKeysFile is the KRL listing revoked keys, next to the config
+const revokedKeysFile = "revoked_keys.krl"
+
+var keyStateCmd = &cobra.Command{
+	Use:   "state <name> <active|deprecated|archived|revoked>",
+	Short: "Change the lifecycle state of a key",
+	Long: `Move a key through its lifecycle.
+
+  active      used normally
+  deprecated  still works but has been superseded (set by key rotate)
+  archived    removed from the SSH directory and SSH config, kept encrypted
+              for recovery; unencrypted keys are encrypted first (with the
+              vault when vault mode is enabled)
+  revoked     removed like an archived key and added to the key revocation
+              list (KRL) for RevokedKeys in sshd_config; this cannot be undone`,
+	Example: `  skm key state work-2023 archived
+  skm key state leaked-key revoked --yes`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return ValidKeyNamesFunc(cmd, args, toComplete)
+		}
+		var states []string
+		for _, s := range models.KeyStates {
+			states = append(states, string(s))
+		}
+		return states, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		state, err := models.ParseKeyState(args[1])
+		if err != nil {
+			return err
+		}
+
+		key, err := configManager.GetKey(name)
+		if err != nil {
+			return err
+		}
+		if key.EffectiveState() == state {
+			fmt.Printf("Key %s is already %s\n", name, state)
+			return nil
+		}
+		if key.EffectiveState() == models.KeyStateRevoked {
+			return fmt.Errorf("key %s is revoked and cannot be restored", name)
+		}
+
+		if state == models.KeyStateRevoked {
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				fmt.Fprintf(cmd.OutOrStdout(), "Revoking %s adds it to the key revocation list. This cannot be undone.\n", name)
+				ok, err := confirm(cmd, "Type the key name to confirm or 'no' to cancel", name)
+				if err != nil {
+					return fmt.Errorf("%w; rerun with --yes to skip confirmation", err)
+				}
+				if !ok {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+		}
+
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		if state == models.KeyStateArchived {
+			if err := encryptForArchive(ks, key); err != nil {
+				return err
+			}
+		}
+		if err := applyKeyState(ks, key, state); err != nil {
+			return err
+		}
+		if err := updateSSHConfig(); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+
+		fmt.Printf("✓ Key %s is now %s\n", name, state)
+
+		if !key.IsUsable() {
+			hosts, err := configManager.ListEffectiveHosts()
+			if err != nil {
+				return fmt.Errorf("failed to list hosts: %w", err)
+			}
+			for _, host := range hosts {
+				if host.KeyName == key.Name {
+					fmt.Printf("⚠️  Host %s uses this key and was removed from the SSH config\n", host.Host)
+				}
+			}
+		}
+
+		switch state {
+		case models.KeyStateRevoked:
+			path, count, err := writeRevokedKeys()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✓ Wrote %d revocation entries to %s\n", count, path)
+			fmt.Printf("  Distribute it to servers and set in sshd_config: RevokedKeys %s\n", path)
+		case models.KeyStateActive, models.KeyStateDeprecated:
+			if !key.Installed {
+				fmt.Printf("  Install it again with: skm key install %s\n", name)
+			}
+		}
+		return nil
+	},
+}
+
+// applyKeyState moves key to state and saves it. Archived and revoked keys are
+// removed from the SSH directory first.
+func applyKeyState(ks *keystore.KeyStore, key *models.Key, state models.KeyState) error {
+	if state == models.KeyStateArchived || state == models.KeyStateRevoked {
+		if err := ks.UninstallFromSSH(key, configManager.Get().SSHDir); err != nil {
+			return fmt.Errorf("failed to uninstall key: %w", err)
+		}
+	}
+
+	key.State = state
+	key.UpdatedAt = time.Now()
+	if err := configManager.UpdateKey(key.Name, *key); err != nil {
+		return fmt.Errorf("failed to update key: %w", err)
+	}
+	return nil
+}
+
+// encryptForArchive makes sure an archived key is only kept encrypted
+func encryptForArchive(ks *keystore.KeyStore, key *models.Key) error {
+	if key.HasPassphrase || key.Type.IsSecurityKey() {
+		return nil
+	}
+
+	v, err := currentVault()
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		if err := protectNewKey(ks, key); err != nil {
+			return fmt.Errorf("failed to add %s to the vault: %w", key.Name, err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Key %s is not encrypted; archived keys are kept encrypted.\n", key.Name)
+	passphrase, err := prompter().NewSecret(prompt.Request{Prompt: "New passphrase", Env: prompt.EnvNewPassphrase})
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("an archived key needs a passphrase")
+	}
+	return ks.ChangePassphrase(key, "", passphrase)
+}
+
+// revokedKeysPath returns the KRL generated from revoked keys
+func revokedKeysPath() string {
+	return filepath.Join(configManager.GetConfigDir(), revokedKeysFile)
+}
+
+// writeRevokedKeys regenerates the KRL from every revoked key and returns its
+// path and the number of revocation entries
+func writeRevokedKeys() (string, int, error) {
+	list, err := localRevocationList()
+	if err != nil {
+		return "", 0, err
+	}
+
+	path := revokedKeysPath()
+	if err := os.WriteFile(path, list.Marshal(), 0644); err != nil {
+		return "", 0, fmt.Errorf("failed to write revocation list: %w", err)
+	}
+	return path, list.Len(), nil
+}
+
+// localRevocationList builds a KRL from the revoked keys in the config and the
+// serials of their certificates
+func localRevocationList() (*krl.KRL, error) {
+	keys, err := configManager.ListKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	ks, err := keystore.NewKeyStore(configManager.Get().KeystorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	list := krl.New(uint64(time.Now().Unix()), "skm revoked keys")
+	for i := range keys {
+		key := &keys[i]
+		if key.EffectiveState() != models.KeyStateRevoked {
+			continue
+		}
+
+		if key.CertPath != "" {
+			if data, err := os.ReadFile(key.CertPath); err == nil {
+				if pub, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+					if cert, ok := pub.(*ssh.Certificate); ok {
+						list.RevokeCertSerial(cert.SignatureKey, cert.Serial)
+					}
+				}
+			}
+		}
+
+		// Prefer the full key; fall back to the fingerprint if the public key is gone
+		if data, err := ks.GetPublicKeyContent(key); err == nil {
+			if pub, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+				list.RevokeKey(pub)
+				continue
+			}
+		}
+		if err := list.RevokeFingerprint(key.Fingerprint); err != nil {
+			fmt.Printf("⚠️  %s: cannot add to the revocation list: %v\n", key.Name, err)
+		}
+	}
+	return list, nil
+}
+
+// filterKeysByState keeps the keys in the --state states, or in defaults
+// when the flag is not set. --all keeps every key.
+func filterKeysByState(cmd *cobra.Command, keys []models.Key, defaults ...models.KeyState) ([]models.Key, error) {
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		return keys, nil
+	}
+
+	states := defaults
+	if names, _ := cmd.Flags().GetStringSlice("state"); len(names) > 0 {
+		states = nil
+		for _, name := range names {
+			state, err := models.ParseKeyState(name)
+			if err != nil {
+				return nil, err
+			}
+			states = append(states, state)
+		}
+	}
+
+	var filtered []models.Key
+	for _, key := range keys {
+		for _, state := range states {
+			if key.EffectiveState() == state {
+				filtered = append(filtered, key)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+func init() {
+	keyCmd.AddCommand(keyStateCmd)
+	keyStateCmd.Flags().BoolP("yes", "y", false, "Revoke without interactive confirmation")
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/api"
+)
+
+var krlCmd = &cobra.Command{
+	Use:   "krl",
+	Short: "Manage the key revocation list (KRL)",
+}
+
+var krlExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the key revocation list for RevokedKeys in sshd_config",
+	Long: `Write an OpenSSH key revocation list (KRL) covering revoked keys and the
+serials of certificates issued for them.
+
+When logged in to an SKM server the server's list is fetched; it includes
+keys revoked on every device and with revoked devices. Use --local to build
+the list from the revoked keys on this device instead.
+
+Copy the file to your servers and set in sshd_config:
+
+  RevokedKeys /etc/ssh/revoked_keys.krl`,
+	Example: `  skm krl export
+  skm krl export --local --output /etc/ssh/revoked_keys.krl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = revokedKeysPath()
+		}
+		local, _ := cmd.Flags().GetBool("local")
+		if cfg.Server == "" || configManager.GetServerToken() == "" {
+			local = true
+		}
+
+		var data []byte
+		if local {
+			list, err := localRevocationList()
+			if err != nil {
+				return err
+			}
+			data = list.Marshal()
+			fmt.Printf("✓ Built revocation list with %d entries from local keys\n", list.Len())
+		} else {
+			client := api.NewClient(cfg.Server, configManager.GetServerToken())
+			var err error
+			data, err = client.FetchKRL()
+			if err != nil {
+				return fmt.Errorf("failed to fetch revocation list: %w", err)
+			}
+			fmt.Printf("✓ Fetched revocation list from %s\n", cfg.Server)
+		}
+
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write revocation list: %w", err)
+		}
+
+		fmt.Printf("✓ Wrote %s\n", output)
+		fmt.Printf("  Distribute it to servers and set in sshd_config: RevokedKeys %s\n", output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(krlCmd)
+	krlCmd.AddCommand(krlExportCmd)
+	krlExportCmd.Flags().StringP("output", "o", "", "Output file (default: revoked_keys.krl next to the config)")
+	krlExportCmd.Flags().Bool("local", false, "Build the list from local revoked keys instead of fetching it from the server")
+}
@@ -15,6 +15,9 @@ var (
 	configManager *config.Manager
 	debugMode     bool
 	verboseMode   bool
+
+	passphraseFile string
+	passphraseFD   int
 )
 
 // rootCmd represents the base command
@@ -66,6 +69,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/skm/config.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "enable debug mode with detailed error messages")
 	rootCmd.PersistentFlags().BoolVarP(&verboseMode, "verbose", "v", false, "enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "", "read passphrases from this file, one per line")
+	rootCmd.PersistentFlags().IntVar(&passphraseFD, "passphrase-fd", -1, "read passphrases from this file descriptor, one per line")
 }
 
 // initConfig reads in config file
@@ -85,6 +90,11 @@ func initConfig() {
 		fmt.Fprintf(os.Stderr, "Debug: Could not load project config: %v\n", err)
 	}
 
+	// Route the server token and cached passphrases through the secret backend
+	if err := configureSecretStore(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Secret store unavailable, using the config file: %v\n", err)
+	}
+
 	// Override debug mode if set via flag
 	if debugMode {
 		cfg := configManager.Get()
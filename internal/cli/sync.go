@@ -4,13 +4,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/all-dot-files/ssh-key-manager/internal/api"
 	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/knownhosts"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+	"github.com/all-dot-files/ssh-key-manager/internal/sync"
+	"github.com/all-dot-files/ssh-key-manager/pkg/crypto"
 )
 
 var syncCmd = &cobra.Command{
@@ -24,7 +30,9 @@ var syncPushCmd = &cobra.Command{
 	Short: "Push keys to the server",
 	Long: `Push public keys (and optionally encrypted private keys) to the server.
 By default, only public keys are pushed. Use --include-private to push
-encrypted private keys as well.`,
+private keys as well, encrypted with a sync passphrase (SKM_SYNC_PASSPHRASE
+in scripts) that is asked again on pull. The Argon2id cost is set with
+skm config set key_encryption.argon_time and key_encryption.argon_memory_kib.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := configManager.Get()
 
@@ -32,13 +40,21 @@ encrypted private keys as well.`,
 			return fmt.Errorf("no server configured. Run: skm server-login")
 		}
 
-		if cfg.ServerToken == "" {
+		if configManager.GetServerToken() == "" {
 			return fmt.Errorf("not logged in. Run: skm server-login")
 		}
 
 		includePrivate, _ := cmd.Flags().GetBool("include-private")
 
-		client := api.NewClient(cfg.Server, cfg.ServerToken)
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
+
+		// Push tombstones first so the server drops deleted keys
+		if len(cfg.Tombstones) > 0 {
+			if err := client.SyncTombstones(cfg.Tombstones); err != nil {
+				return fmt.Errorf("failed to push deleted keys: %w", err)
+			}
+			fmt.Printf("✓ Pushed %d deleted keys\n", len(cfg.Tombstones))
+		}
 
 		// Push public keys
 		fmt.Println("Pushing public keys...")
@@ -63,6 +79,9 @@ encrypted private keys as well.`,
 				Tags:        key.Tags,
 				Comment:     key.Comment,
 				CreatedAt:   key.CreatedAt,
+				SecurityKey: key.SecurityKey,
+				State:       key.State,
+				DeviceID:    key.DeviceID,
 			})
 		}
 
@@ -72,6 +91,28 @@ encrypted private keys as well.`,
 
 		fmt.Printf("✓ Pushed %d public keys\n", len(publicKeys))
 
+		// Push pinned host keys
+		hosts, err := configManager.ListHosts()
+		if err != nil {
+			return fmt.Errorf("failed to list hosts: %w", err)
+		}
+		var hostKeys []api.HostKeyData
+		for i := range hosts {
+			if len(hosts[i].HostKeys) == 0 {
+				continue
+			}
+			hostKeys = append(hostKeys, api.HostKeyData{
+				Address: knownhosts.Normalize(&hosts[i]),
+				Keys:    hosts[i].HostKeys,
+			})
+		}
+		if len(hostKeys) > 0 {
+			if err := client.SyncHostKeys(hostKeys); err != nil {
+				return fmt.Errorf("failed to push host keys: %w", err)
+			}
+			fmt.Printf("✓ Pushed pinned keys of %d hosts\n", len(hostKeys))
+		}
+
 		// Push private keys if requested
 		if includePrivate {
 			if !cfg.SyncPolicy.SyncPrivateKeys {
@@ -80,33 +121,54 @@ encrypted private keys as well.`,
 
 			fmt.Println("\nPushing encrypted private keys...")
 			fmt.Println("⚠️  Warning: This will upload encrypted private keys to the server.")
-			fmt.Print("Continue? (yes/no): ")
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				ok, err := confirm(cmd, "Continue? Type 'yes' to confirm", "yes")
+				if err != nil {
+					return fmt.Errorf("%w; rerun with --yes to skip confirmation", err)
+				}
+				if !ok {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
 
-			var confirm string
-			fmt.Scanln(&confirm)
-			if confirm != "yes" {
-				fmt.Println("Cancelled.")
-				return nil
+			params := kdfParams()
+			if err := params.Validate(); err != nil {
+				return fmt.Errorf("invalid key_encryption settings: %w", err)
+			}
+			syncPassphrase, err := prompter().NewSecret(syncPassphraseRequest)
+			if err != nil {
+				return fmt.Errorf("failed to read sync passphrase: %w", err)
+			}
+			if syncPassphrase == "" {
+				return fmt.Errorf("a sync passphrase is required to push private keys")
 			}
 
 			var privateKeys []api.PrivateKeyData
 			for _, key := range cfg.Keys {
-				// Read encrypted private key
 				privKeyData, err := os.ReadFile(key.Path)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Warning: failed to read private key for %s: %v\n", key.Name, err)
 					continue
 				}
 
+				// The key file is wrapped under the sync passphrase, so keys
+				// without a passphrase of their own never reach the server in clear
+				encrypted, err := crypto.EncryptToBase64(privKeyData, syncPassphrase, key.Fingerprint, params)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to encrypt private key for %s: %v\n", key.Name, err)
+					continue
+				}
+
 				pubKeyContent, _ := ks.GetPublicKeyContent(&key)
 
 				privateKeys = append(privateKeys, api.PrivateKeyData{
 					Name:             key.Name,
 					Type:             string(key.Type),
-					EncryptedPrivate: string(privKeyData), // Already encrypted locally
+					EncryptedPrivate: encrypted,
 					PublicKey:        string(pubKeyContent),
 					Fingerprint:      key.Fingerprint,
-					EncryptionMethod: "aes-256-gcm",
+					EncryptionMethod: api.EncryptionMethodEnvelope,
 					CreatedAt:        key.CreatedAt,
 				})
 			}
@@ -133,13 +195,20 @@ var syncPullCmd = &cobra.Command{
 			return fmt.Errorf("no server configured. Run: skm server-login")
 		}
 
-		if cfg.ServerToken == "" {
+		if configManager.GetServerToken() == "" {
 			return fmt.Errorf("not logged in. Run: skm server-login")
 		}
 
 		includePrivate, _ := cmd.Flags().GetBool("include-private")
+		allowWeak, _ := cmd.Flags().GetBool("allow-weak")
+		keepDeleted, _ := cmd.Flags().GetBool("keep-deleted")
+
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
 
-		client := api.NewClient(cfg.Server, cfg.ServerToken)
+		if err := pullTombstones(client, keepDeleted); err != nil {
+			return err
+		}
+		cfg = configManager.Get()
 
 		// Pull public keys
 		fmt.Println("Pulling public keys...")
@@ -172,6 +241,16 @@ var syncPullCmd = &cobra.Command{
 				continue
 			}
 
+			if t := configManager.GetTombstone(remoteKey.Fingerprint); t != nil {
+				fmt.Printf("  Skipping key deleted on device %s: %s\n", t.DeviceID, remoteKey.Name)
+				continue
+			}
+
+			if err := checkPublicKeyPolicy([]byte(remoteKey.PublicKey), allowWeak); err != nil {
+				fmt.Printf("  Refusing key %s: %v\n", remoteKey.Name, err)
+				continue
+			}
+
 			// Create new key entry
 			fmt.Printf("  Importing key: %s\n", remoteKey.Name)
 
@@ -194,6 +273,17 @@ var syncPullCmd = &cobra.Command{
 				UpdatedAt:   time.Now(),
 				Fingerprint: remoteKey.Fingerprint,
 				Installed:   false,
+				SecurityKey: remoteKey.SecurityKey,
+				State:       remoteKey.State,
+				DeviceID:    remoteKey.DeviceID,
+			}
+			if _, bits, err := keystore.PublicKeyInfo([]byte(remoteKey.PublicKey)); err == nil {
+				switch newKey.Type {
+				case models.KeyTypeRSA:
+					newKey.RSABits = bits
+				case models.KeyTypeECDSA:
+					newKey.ECDSABits = bits
+				}
 			}
 
 			if err := configManager.AddKey(newKey); err != nil {
@@ -202,6 +292,14 @@ var syncPullCmd = &cobra.Command{
 			}
 		}
 
+		if err := pullRevocations(publicKeys); err != nil {
+			return err
+		}
+
+		if err := pullHostKeys(client); err != nil {
+			return err
+		}
+
 		if includePrivate {
 			fmt.Println("\nPulling encrypted private keys...")
 			privateKeys, err := client.FetchPrivateKeys()
@@ -211,6 +309,8 @@ var syncPullCmd = &cobra.Command{
 
 			fmt.Printf("✓ Fetched %d encrypted private keys from server\n", len(privateKeys))
 
+			var syncPassphrase string
+
 			for _, remoteKey := range privateKeys {
 				fmt.Printf("Processing private key: %s\n", remoteKey.Name)
 
@@ -227,40 +327,21 @@ var syncPullCmd = &cobra.Command{
 					continue
 				}
 
-				// Prompt for passphrase to decrypt
-				fmt.Printf("  Enter passphrase for key '%s' (leave empty if none): ", remoteKey.Name)
-				var passphrase string
-				// We use a simple scan here, but in a real app we might want terminal.ReadPassword
-				// For simplicity in this CLI tool context:
-				fmt.Scanln(&passphrase)
-
-				// Decrypt logic would go here if we were decrypting *before* saving.
-				// However, the architecture says we store encrypted keys locally if they have a passphrase.
-				// But wait, the server stores them encrypted with the *user's* password (or a sync password).
-				// If the local keystore expects them to be encrypted with the *same* passphrase, we can just save them.
-				// But if the server encryption is different from local storage encryption, we need to re-encrypt.
-
-				// Assumption: Server stores keys encrypted with a transport/sync password (or user's password).
-				// Local keystore stores keys encrypted with the key's own passphrase.
-				// If they are the same, we can just write the content.
-				// Let's assume for now we just write the encrypted content directly,
-				// implying the user used the same passphrase for sync as for the key itself.
-
-				// Actually, looking at the Push logic:
-				// privKeyData, err := os.ReadFile(key.Path)
-				// EncryptedPrivate: string(privKeyData)
-				// It sends the raw file content. So it's already encrypted with the key's passphrase.
-				// So we just need to write it back to disk.
-
-				if err := os.WriteFile(key.Path, []byte(remoteKey.EncryptedPrivate), 0600); err != nil {
+				privKeyData, err := unwrapPrivateKey(&remoteKey, &syncPassphrase)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  Failed to decrypt private key: %v\n", err)
+					continue
+				}
+				if err := os.WriteFile(key.Path, privKeyData, 0600); err != nil {
 					fmt.Fprintf(os.Stderr, "  Failed to write private key: %v\n", err)
 					continue
 				}
 
-				// Update key status
-				key.HasPassphrase = true // We assume it has one if it was encrypted, or we check metadata
-				// Actually we should probably check if it's really encrypted or just PEM
-				// But for now let's assume it's fine.
+				_, parseErr := ssh.ParseRawPrivateKey(privKeyData)
+				key.HasPassphrase = parseErr != nil
+				if err := configManager.UpdateKey(key.Name, *key); err != nil {
+					fmt.Fprintf(os.Stderr, "  Failed to update key: %v\n", err)
+				}
 
 				fmt.Printf("  ✓ Private key saved\n")
 			}
@@ -270,6 +351,217 @@ var syncPullCmd = &cobra.Command{
 	},
 }
 
+// syncPassphraseRequest reads the passphrase protecting private keys on the server
+var syncPassphraseRequest = prompt.Request{
+	Prompt: "Sync passphrase for private keys",
+	Env:    prompt.EnvSyncPassphrase,
+}
+
+// unwrapPrivateKey returns the key file of a pulled private key. Keys wrapped
+// in an envelope are decrypted with the sync passphrase, read on first use
+// into passphrase; keys pushed by earlier releases hold the key file as is.
+func unwrapPrivateKey(remoteKey *api.PrivateKeyData, passphrase *string) ([]byte, error) {
+	if remoteKey.EncryptionMethod != api.EncryptionMethodEnvelope {
+		return []byte(remoteKey.EncryptedPrivate), nil
+	}
+
+	if *passphrase == "" {
+		secret, err := prompter().Secret(syncPassphraseRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sync passphrase: %w", err)
+		}
+		*passphrase = secret
+	}
+	return crypto.DecryptFromBase64(remoteKey.EncryptedPrivate, *passphrase)
+}
+
+// kdfParams returns the configured Argon2id parameters of the envelopes
+// wrapping pushed private keys
+func kdfParams() crypto.KDFParams {
+	params := crypto.DefaultKDFParams()
+	enc := configManager.Get().KeyEncryption
+	if enc.ArgonTime != 0 {
+		params.Time = enc.ArgonTime
+	}
+	if enc.ArgonMemoryKiB != 0 {
+		params.Memory = enc.ArgonMemoryKiB
+	}
+	if enc.ArgonThreads != 0 {
+		params.Threads = enc.ArgonThreads
+	}
+	return params
+}
+
+// pullRevocations revokes local keys that were revoked on another device or
+// with their device, and regenerates the local KRL
+func pullRevocations(remoteKeys []api.PublicKeyData) error {
+	revoked := make(map[string]bool)
+	for _, remoteKey := range remoteKeys {
+		if remoteKey.State == models.KeyStateRevoked {
+			revoked[remoteKey.Fingerprint] = true
+		}
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+
+	keys, err := configManager.ListKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+	ks, err := keystore.NewKeyStore(configManager.Get().KeystorePath)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for i := range keys {
+		key := &keys[i]
+		if !revoked[key.Fingerprint] || key.EffectiveState() == models.KeyStateRevoked {
+			continue
+		}
+		if err := applyKeyState(ks, key, models.KeyStateRevoked); err != nil {
+			return err
+		}
+		fmt.Printf("  Revoked key %s (revoked on another device)\n", key.Name)
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if err := updateSSHConfig(); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+	path, _, err := writeRevokedKeys()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Revoked %d keys; updated %s\n", count, path)
+	return nil
+}
+
+// pullTombstones removes local keys that were deleted on another device and
+// records their tombstones so they are not imported again. Keys still used by
+// hosts, or all keys with keepDeleted, are only flagged.
+func pullTombstones(client *api.Client, keepDeleted bool) error {
+	fmt.Println("Pulling deleted keys...")
+	tombstones, err := client.FetchTombstones()
+	if err != nil {
+		return fmt.Errorf("failed to pull deleted keys: %w", err)
+	}
+
+	keys, err := configManager.ListKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+	hosts, err := configManager.ListHosts()
+	if err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	cfg := configManager.Get()
+	syncMgr := sync.NewSyncManager(cfg.DeviceID, sync.StrategyRemoteWins)
+	changes := syncMgr.TombstoneChanges(tombstones)
+
+	remaining := make(map[string]bool)
+	for _, key := range syncMgr.ApplyChanges(keys, changes) {
+		remaining[key.Name] = true
+	}
+
+	ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for i := range keys {
+		key := &keys[i]
+		if remaining[key.Name] {
+			continue
+		}
+
+		var users []string
+		for _, host := range hosts {
+			if host.KeyName == key.Name {
+				users = append(users, host.Host)
+			}
+		}
+		if keepDeleted || len(users) > 0 {
+			fmt.Printf("  ⚠️  %s was deleted on another device; keeping it", key.Name)
+			if len(users) > 0 {
+				fmt.Printf(" (used by %s)", strings.Join(users, ", "))
+			}
+			fmt.Println()
+			continue
+		}
+
+		if err := removeKey(ks, key); err != nil {
+			return err
+		}
+		fmt.Printf("  Removed deleted key: %s\n", key.Name)
+		removed++
+	}
+
+	if err := configManager.AddTombstones(tombstones...); err != nil {
+		return fmt.Errorf("failed to record deleted keys: %w", err)
+	}
+	fmt.Printf("✓ Removed %d keys deleted on other devices\n", removed)
+	return nil
+}
+
+// pullHostKeys adopts pinned host keys from the server that are newer than the local pins
+func pullHostKeys(client *api.Client) error {
+	fmt.Println("Pulling pinned host keys...")
+	remote, err := client.FetchHostKeys()
+	if err != nil {
+		return fmt.Errorf("failed to pull host keys: %w", err)
+	}
+
+	byAddress := make(map[string]api.HostKeyData, len(remote))
+	for _, r := range remote {
+		byAddress[r.Address] = r
+	}
+
+	hosts, err := configManager.ListHosts()
+	if err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	updated := 0
+	for i := range hosts {
+		host := &hosts[i]
+		r, ok := byAddress[knownhosts.Normalize(host)]
+		if !ok || knownhosts.SameKeys(host.HostKeys, r.Keys) {
+			continue
+		}
+		if !knownhosts.PinnedAt(r.Keys).After(knownhosts.PinnedAt(host.HostKeys)) {
+			fmt.Printf("  Keeping newer local pins for %s\n", host.Host)
+			continue
+		}
+
+		if len(host.HostKeys) > 0 {
+			fmt.Printf("  Host keys of %s were re-pinned on another device:\n", host.Host)
+			diff := knownhosts.CompareKeys(host.HostKeys, r.Keys)
+			fmt.Println(indent(diff.String(), "    "))
+		}
+
+		host.HostKeys = r.Keys
+		if err := configManager.UpdateHost(host.Host, *host); err != nil {
+			return fmt.Errorf("failed to update host %s: %w", host.Host, err)
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		if err := updateSSHConfig(); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+	}
+	fmt.Printf("✓ Updated pinned keys of %d hosts\n", updated)
+	return nil
+}
+
 var serverLoginCmd = &cobra.Command{
 	Use:   "server-login",
 	Short: "Login to SKM server",
@@ -287,10 +579,10 @@ var serverLoginCmd = &cobra.Command{
 		}
 
 		if password == "" {
-			fmt.Print("Password: ")
-			_, err := fmt.Scanln(&password)
+			var err error
+			password, err = prompter().Secret(prompt.Request{Prompt: "Password", Env: prompt.EnvPassword})
 			if err != nil {
-				return fmt.Errorf("failed to read password: %w", err)
+				return err
 			}
 		}
 
@@ -303,10 +595,9 @@ var serverLoginCmd = &cobra.Command{
 		// Save to config
 		cfg := configManager.Get()
 		cfg.Server = server
-		cfg.ServerToken = token
 		cfg.User = username
 
-		if err := configManager.Save(); err != nil {
+		if err := configManager.SetServerToken(token); err != nil {
 			return fmt.Errorf("failed to save config: %w", err)
 		}
 
@@ -324,7 +615,7 @@ var deviceRegisterCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := configManager.Get()
 
-		if cfg.Server == "" || cfg.ServerToken == "" {
+		if cfg.Server == "" || configManager.GetServerToken() == "" {
 			return fmt.Errorf("not logged in. Run: skm server-login first")
 		}
 
@@ -338,7 +629,7 @@ var deviceRegisterCmd = &cobra.Command{
 			Name: cfg.DeviceName,
 		}
 
-		client := api.NewClient(cfg.Server, cfg.ServerToken)
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
 		if err := client.RegisterDevice(device); err != nil {
 			return fmt.Errorf("failed to register device: %w", err)
 		}
@@ -356,26 +647,80 @@ var deviceRegisterCmd = &cobra.Command{
 	},
 }
 
+var deviceRevokeCmd = &cobra.Command{
+	Use:   "device-revoke <device-id>",
+	Short: "Revoke a device on the server",
+	Long: `Revoke a device so it can no longer sync.
+
+With --revoke-keys every key created on the device is revoked as well. The
+keys are added to the server's key revocation list (skm krl export) and are
+revoked on other devices at their next skm sync pull.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+
+		if cfg.Server == "" || configManager.GetServerToken() == "" {
+			return fmt.Errorf("not logged in. Run: skm server-login first")
+		}
+
+		deviceID := args[0]
+		revokeKeys, _ := cmd.Flags().GetBool("revoke-keys")
+		if revokeKeys {
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				fmt.Fprintf(cmd.OutOrStdout(), "Every key created on device %s will be revoked. This cannot be undone.\n", deviceID)
+				ok, err := confirm(cmd, "Type the device ID to confirm or 'no' to cancel", deviceID)
+				if err != nil {
+					return fmt.Errorf("%w; rerun with --yes to skip confirmation", err)
+				}
+				if !ok {
+					fmt.Println("Cancelled.")
+					return nil
+				}
+			}
+		}
+
+		client := api.NewClient(cfg.Server, configManager.GetServerToken())
+		if err := client.RevokeDevice(deviceID, revokeKeys); err != nil {
+			return fmt.Errorf("failed to revoke device: %w", err)
+		}
+
+		fmt.Printf("✓ Device %s revoked\n", deviceID)
+		if revokeKeys {
+			fmt.Println("  Its keys were revoked. Run skm sync pull to apply this here and skm krl export to update the KRL.")
+		}
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(syncCmd)
 
 	// Push command
 	syncCmd.AddCommand(syncPushCmd)
 	syncPushCmd.Flags().Bool("include-private", false, "Include encrypted private keys")
+	syncPushCmd.Flags().BoolP("yes", "y", false, "Push private keys without interactive confirmation")
 
 	// Pull command
 	syncCmd.AddCommand(syncPullCmd)
 	syncPullCmd.Flags().Bool("include-private", false, "Pull encrypted private keys")
+	syncPullCmd.Flags().Bool("allow-weak", false, "Accept keys rejected by the key algorithm policy")
+	syncPullCmd.Flags().Bool("keep-deleted", false, "Keep keys deleted on other devices and only report them")
 
 	// Server login
 	rootCmd.AddCommand(serverLoginCmd)
 	serverLoginCmd.Flags().StringP("server", "s", "", "SKM server URL (required)")
 	serverLoginCmd.Flags().StringP("user", "u", "", "Username (required)")
 	serverLoginCmd.Flags().StringP("password", "p", "", "Password (will prompt if not provided)")
+	serverLoginCmd.Flags().MarkDeprecated("password", "it leaks into shell history; enter it at the prompt or set SKM_PASSWORD")
 	serverLoginCmd.MarkFlagRequired("server")
 	serverLoginCmd.MarkFlagRequired("user")
 
 	// Device register
 	rootCmd.AddCommand(deviceRegisterCmd)
 	deviceRegisterCmd.Flags().StringP("name", "n", "", "Device name")
+
+	// Device revoke
+	rootCmd.AddCommand(deviceRevokeCmd)
+	deviceRevokeCmd.Flags().Bool("revoke-keys", false, "Also revoke every key created on the device")
+	deviceRevokeCmd.Flags().BoolP("yes", "y", false, "Revoke keys without interactive confirmation")
 }
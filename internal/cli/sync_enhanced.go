@@ -25,6 +25,15 @@ var syncStatusCmd = &cobra.Command{
 		// Detect changes
 		changes := syncMgr.DetectChanges(cfg.Keys)
 
+		// Keys kept with sync pull --keep-deleted or because hosts still use them
+		for _, change := range syncMgr.TombstoneChanges(cfg.Tombstones) {
+			for _, key := range cfg.Keys {
+				if key.Fingerprint == change.Key.Fingerprint {
+					fmt.Printf("⚠️  %s was deleted on device %s at %s\n", key.Name, change.DeviceID, change.Timestamp.Format("2006-01-02 15:04"))
+				}
+			}
+		}
+
 		if len(changes) == 0 {
 			fmt.Println("✓ Everything is up to date")
 			return nil
@@ -119,13 +128,15 @@ var syncClearHistoryCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := configManager.Get()
 
-		fmt.Print("⚠️  This will delete all sync history. Continue? (yes/no): ")
-		var confirm string
-		fmt.Scanln(&confirm)
-
-		if confirm != "yes" {
-			fmt.Println("Cancelled.")
-			return nil
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			ok, err := confirm(cmd, "⚠️  This will delete all sync history. Type 'yes' to continue", "yes")
+			if err != nil {
+				return fmt.Errorf("%w; rerun with --yes to skip confirmation", err)
+			}
+			if !ok {
+				fmt.Println("Cancelled.")
+				return nil
+			}
 		}
 
 		history, err := sync.NewSyncHistory(cfg.KeystorePath, 100)
@@ -149,6 +160,7 @@ func init() {
 	syncCmd.AddCommand(syncHistoryCmd)
 	syncCmd.AddCommand(syncResolveCmd)
 	syncCmd.AddCommand(syncClearHistoryCmd)
+	syncClearHistoryCmd.Flags().BoolP("yes", "y", false, "Clear without interactive confirmation")
 
 	// Flags for history command
 	syncHistoryCmd.Flags().IntP("limit", "n", 10, "Number of entries to show")
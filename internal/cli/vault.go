@@ -0,0 +1,428 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/agent"
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/prompt"
+)
+
+// envVaultPassphrase provides the vault master passphrase non-interactively
+const envVaultPassphrase = "SKM_VAULT_PASSPHRASE"
+
+// openedVault is the vault of this process, unlocked on first use
+var openedVault *keystore.Vault
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Protect every key with one master passphrase",
+	Long: `In vault mode each private key is encrypted with its own random data key,
+and the data keys are wrapped by a master key derived from one master
+passphrase. Unlock the vault once with 'skm unlock' to use every key in it;
+'skm lock' forgets the master key again.
+
+Changing the master passphrase only re-wraps the data keys; the key files
+themselves are not rewritten.
+
+Vault keys are agent-only: their files, including the copies installed in
+~/.ssh, are encrypted with data keys you are never asked for, so 'ssh -i' and
+IdentityFile cannot decrypt them. Load them with 'skm agent unlock' once the
+vault is unlocked.`,
+}
+
+var vaultInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Enable vault mode and move unencrypted keys into the vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+
+		master, err := prompter().NewSecret(prompt.Request{Prompt: "New master passphrase", Env: envVaultPassphrase})
+		if err != nil {
+			return err
+		}
+
+		v, err := ks.CreateVault(master)
+		if err != nil {
+			return fmt.Errorf("failed to create vault: %w", err)
+		}
+		openedVault = v
+		fmt.Printf("✓ Created vault at %s\n", ks.VaultPath())
+
+		keys, err := configManager.ListKeys()
+		if err != nil {
+			return err
+		}
+
+		migrated := 0
+		for i := range keys {
+			key := &keys[i]
+			if key.HasPassphrase || key.Type.IsSecurityKey() {
+				continue
+			}
+			if err := addToVault(ks, v, key, ""); err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				continue
+			}
+			fmt.Printf("✓ Moved %s into the vault\n", key.Name)
+			migrated++
+		}
+
+		fmt.Printf("\nMoved %d unencrypted key(s) into the vault\n", migrated)
+		fmt.Println("Keys with their own passphrase can be added with: skm vault add <name>")
+		return nil
+	},
+}
+
+var vaultAddCmd = &cobra.Command{
+	Use:   "add <name...>",
+	Short: "Move keys with their own passphrase into the vault",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := configManager.Get()
+		ks, err := keystore.NewKeyStore(cfg.KeystorePath)
+		if err != nil {
+			return err
+		}
+		v, err := unlockedVault()
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, name := range args {
+			key, err := configManager.GetKey(name)
+			if err != nil {
+				return err
+			}
+			if v.Has(key.Name) {
+				fmt.Printf("- %s: already in the vault\n", key.Name)
+				continue
+			}
+			if key.Type.IsSecurityKey() {
+				fmt.Printf("✗ %s: security keys are protected by the authenticator\n", key.Name)
+				failed++
+				continue
+			}
+
+			err = withPassphrase(key, func(passphrase string) error {
+				return addToVault(ks, v, key, passphrase)
+			})
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", key.Name, err)
+				failed++
+				continue
+			}
+			forgetPassphrase(key)
+			fmt.Printf("✓ Moved %s into the vault\n", key.Name)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("failed to add %d key(s)", failed)
+		}
+		return nil
+	},
+}
+
+var vaultPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master passphrase",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := unlockedVault()
+		if err != nil {
+			return err
+		}
+
+		master, err := prompter().NewSecret(prompt.Request{Prompt: "New master passphrase", Env: "SKM_NEW_VAULT_PASSPHRASE"})
+		if err != nil {
+			return err
+		}
+		if err := v.ChangeMaster(master); err != nil {
+			return fmt.Errorf("failed to change master passphrase: %w", err)
+		}
+
+		// An unlocked session holds the old master key; replace it
+		if client, err := agent.Dial(agentSocketPath()); err == nil {
+			defer client.Close()
+			if _, err := client.VaultKey(); err == nil {
+				key, _ := v.MasterKey()
+				if err := client.UnlockVault(key, vaultTimeout(cmd)); err != nil {
+					fmt.Printf("⚠️  Failed to update the unlocked session: %v\n", err)
+				}
+			}
+		}
+
+		fmt.Printf("✓ Changed the master passphrase (%d data key(s) re-wrapped)\n", len(v.Keys()))
+		return nil
+	},
+}
+
+var vaultStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether vault mode is enabled and unlocked",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := currentVault()
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			fmt.Println("Vault mode is not enabled (run: skm vault init)")
+			return nil
+		}
+
+		fmt.Printf("Vault:    enabled\n")
+		fmt.Printf("Keys:     %d\n", len(v.Keys()))
+		for _, name := range v.Keys() {
+			fmt.Printf("  • %s\n", name)
+		}
+
+		status := "locked"
+		if _, err := sessionVaultKey(); err == nil {
+			status = "unlocked"
+		}
+		fmt.Printf("Session:  %s\n", status)
+		return nil
+	},
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Unlock the vault for this session",
+	Long: `Unlock the key vault with the master passphrase. The master key is held by
+the SKM agent, so every key in the vault can be used without further
+prompts until 'skm lock' or the timeout.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := currentVault()
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			return fmt.Errorf("vault mode is not enabled (run: skm vault init)")
+		}
+
+		client, err := agent.Dial(agentSocketPath())
+		if err != nil {
+			return fmt.Errorf("%w (start it with: skm agent start)", err)
+		}
+		defer client.Close()
+
+		master, err := prompter().Secret(prompt.Request{Prompt: "Master passphrase", Env: envVaultPassphrase})
+		if err != nil {
+			return err
+		}
+		if err := v.Unlock(master); err != nil {
+			return err
+		}
+
+		key, _ := v.MasterKey()
+		timeout := vaultTimeout(cmd)
+		if err := client.UnlockVault(key, timeout); err != nil {
+			return fmt.Errorf("failed to unlock session: %w", err)
+		}
+
+		if timeout > 0 {
+			fmt.Printf("✓ Vault unlocked for %s\n", timeout)
+		} else {
+			fmt.Println("✓ Vault unlocked until 'skm lock'")
+		}
+		return nil
+	},
+}
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock the vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := agent.Dial(agentSocketPath())
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if err := client.LockVault(); err != nil {
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		fmt.Println("✓ Vault locked")
+		return nil
+	},
+}
+
+// currentVault returns the vault of the keystore, or nil when vault mode is off
+func currentVault() (*keystore.Vault, error) {
+	if openedVault != nil {
+		return openedVault, nil
+	}
+
+	ks, err := keystore.NewKeyStore(configManager.Get().KeystorePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(ks.VaultPath()); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	v, err := ks.OpenVault()
+	if err != nil {
+		return nil, err
+	}
+	openedVault = v
+	return v, nil
+}
+
+// unlockedVault returns the vault unlocked by the session, or after asking
+// for the master passphrase
+func unlockedVault() (*keystore.Vault, error) {
+	v, err := currentVault()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("vault mode is not enabled (run: skm vault init)")
+	}
+	if _, err := v.MasterKey(); err == nil {
+		return v, nil
+	}
+
+	if key, err := sessionVaultKey(); err == nil {
+		if err := v.UnlockWithKey(key); err == nil {
+			return v, nil
+		}
+		LogVerbose("the unlocked session holds an outdated master key")
+	}
+
+	master, err := prompter().Secret(prompt.Request{Prompt: "Master passphrase", Env: envVaultPassphrase})
+	if err != nil {
+		return nil, fmt.Errorf("%w (or run: skm unlock)", err)
+	}
+	if err := v.Unlock(master); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// sessionVaultKey returns the master key held by an unlocked agent session
+func sessionVaultKey() ([]byte, error) {
+	client, err := agent.Dial(agentSocketPath())
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.VaultKey()
+}
+
+// vaultDataKey returns the data key of key when it is in the vault
+func vaultDataKey(key *models.Key) (string, bool, error) {
+	v, err := currentVault()
+	if err != nil || v == nil || !v.Has(key.Name) {
+		return "", false, err
+	}
+
+	v, err = unlockedVault()
+	if err != nil {
+		return "", true, err
+	}
+	dataKey, err := v.DataKey(key.Name)
+	return dataKey, true, err
+}
+
+// addToVault re-encrypts a key with a new data key and persists it
+func addToVault(ks *keystore.KeyStore, v *keystore.Vault, key *models.Key, passphrase string) error {
+	dataKey, err := v.AddKey(key.Name)
+	if err != nil {
+		return err
+	}
+	if err := ks.ChangePassphrase(key, passphrase, dataKey); err != nil {
+		_ = v.RemoveKey(key.Name)
+		return err
+	}
+	if err := configManager.UpdateKey(key.Name, *key); err != nil {
+		return fmt.Errorf("failed to update key: %w", err)
+	}
+
+	cfg := configManager.Get()
+	if key.Installed {
+		if err := ks.InstallToSSH(key, cfg.SSHDir); err != nil {
+			fmt.Printf("⚠️  %s: failed to refresh installed copy: %v\n", key.Name, err)
+		} else {
+			warnAgentOnly(ks, key)
+		}
+	}
+	return nil
+}
+
+// warnAgentOnly tells that the installed copy of a vault key is encrypted
+// with its data key, which no one is asked for, so ssh can only use it
+// through the agent
+func warnAgentOnly(ks *keystore.KeyStore, key *models.Key) {
+	fmt.Printf("⚠️  %s is in the vault: %s is encrypted with a data key you cannot type.\n",
+		key.Name, ks.InstalledPath(key, configManager.Get().SSHDir))
+	fmt.Printf("   Use it through the agent: skm unlock && skm agent unlock %s\n", key.Name)
+}
+
+// protectNewKey moves a freshly generated unencrypted key into the vault when
+// vault mode is enabled. The key is not yet persisted by the caller.
+func protectNewKey(ks *keystore.KeyStore, key *models.Key) error {
+	if key.HasPassphrase || key.Type.IsSecurityKey() {
+		return nil
+	}
+	v, err := currentVault()
+	if err != nil || v == nil {
+		return err
+	}
+
+	v, err = unlockedVault()
+	if err != nil {
+		return err
+	}
+	dataKey, err := v.AddKey(key.Name)
+	if err != nil {
+		return err
+	}
+	if err := ks.ChangePassphrase(key, "", dataKey); err != nil {
+		_ = v.RemoveKey(key.Name)
+		return err
+	}
+	return nil
+}
+
+// removeFromVault drops the data key of a deleted or re-encrypted key
+func removeFromVault(key *models.Key) {
+	v, err := currentVault()
+	if err != nil || v == nil {
+		return
+	}
+	if err := v.RemoveKey(key.Name); err != nil {
+		fmt.Printf("⚠️  %s: failed to remove data key from the vault: %v\n", key.Name, err)
+	}
+}
+
+// vaultTimeout returns the session lifetime from --timeout or the agent default
+func vaultTimeout(cmd *cobra.Command) time.Duration {
+	if cmd.Flags().Lookup("timeout") != nil && cmd.Flags().Changed("timeout") {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		return timeout
+	}
+	return time.Duration(configManager.Get().Agent.DefaultTimeoutMinutes) * time.Minute
+}
+
+func init() {
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultInitCmd)
+	vaultCmd.AddCommand(vaultAddCmd)
+	vaultAddCmd.ValidArgsFunction = ValidKeyNamesFunc
+	vaultCmd.AddCommand(vaultPasswdCmd)
+	vaultCmd.AddCommand(vaultStatusCmd)
+
+	rootCmd.AddCommand(unlockCmd)
+	unlockCmd.Flags().Duration("timeout", 0, "Lock again after this long (default: agent.default_timeout_minutes)")
+	rootCmd.AddCommand(lockCmd)
+}
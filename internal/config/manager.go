@@ -6,10 +6,12 @@ import (
 	"path/filepath"
 	"time"
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/all-dot-files/ssh-key-manager/pkg/fileio"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/secrets"
 	"github.com/all-dot-files/ssh-key-manager/internal/storage"
 	"github.com/all-dot-files/ssh-key-manager/internal/storage/sqlite"
 	yamlStore "github.com/all-dot-files/ssh-key-manager/internal/storage/yaml"
@@ -31,6 +33,9 @@ type Manager struct {
 	projectPath   string
 	fileCache     *fileio.FileCache
 	store         storage.Store
+
+	secrets         secrets.Store
+	secretsRequired bool
 }
 
 // NewManager creates a new configuration manager
@@ -87,6 +92,12 @@ func (m *Manager) Load() error {
 
 	m.config = &config
 
+	// Release the store of a previous load before opening a new one
+	if m.store != nil {
+		_ = m.store.Close()
+		m.store = nil
+	}
+
 	// Initialize store based on driver
 	if m.config.StorageDriver == "sqlite" {
 		dbPath := filepath.Join(filepath.Dir(m.configPath), "skm.db")
@@ -200,6 +211,10 @@ func (m *Manager) AddKey(key models.Key) error {
 	if err := m.checkStore(); err != nil {
 		return err
 	}
+	// Keys without an origin were created or imported on this device
+	if key.DeviceID == "" {
+		key.DeviceID = m.GetDeviceID()
+	}
 	if err := m.store.Key().Add(context.Background(), key); err != nil {
 		return err
 	}
@@ -247,6 +262,23 @@ func (m *Manager) GetHost(hostname string) (*models.Host, error) {
 	return m.store.Host().Get(context.Background(), hostname)
 }
 
+// GetEffectiveHost retrieves a host with the settings it inherits from its
+// host groups. An alias that is not registered but matches a group pattern
+// gets the group settings.
+func (m *Manager) GetEffectiveHost(hostname string) (*models.Host, error) {
+	groups := m.Get().HostGroups
+	host, err := m.GetHost(hostname)
+	if err != nil {
+		probe := &models.Host{Host: hostname}
+		if len(models.GroupsOf(probe, groups)) == 0 {
+			return nil, err
+		}
+		host = probe
+	}
+	effective := host.WithGroups(groups)
+	return &effective, nil
+}
+
 // AddRepo adds a Git repository configuration
 func (m *Manager) AddRepo(repo models.GitRepo) error {
 	if err := m.checkStore(); err != nil {
@@ -259,12 +291,13 @@ func (m *Manager) AddRepo(repo models.GitRepo) error {
 	return m.reload()
 }
 
-// GetRepo retrieves a repository configuration
+// GetRepo retrieves the binding of a repository remote, or its first binding
+// if remote is empty
 func (m *Manager) GetRepo(path, remote string) (*models.GitRepo, error) {
 	if err := m.checkStore(); err != nil {
 		return nil, err
 	}
-	return m.store.Repo().Get(context.Background(), path)
+	return m.store.Repo().Get(context.Background(), path, remote)
 }
 
 // ListRepos returns all bound repositories
@@ -550,14 +583,49 @@ func (m *Manager) SetServerURL(url string) error {
 	return m.Save()
 }
 
-// GetServerToken returns the server token
+// SetSecretStore routes the server token through store. Unless required is
+// set, the token falls back to the config file when the store is unavailable.
+func (m *Manager) SetSecretStore(store secrets.Store, required bool) {
+	m.secrets = store
+	m.secretsRequired = required
+}
+
+// SecretStore returns the configured secret store, or nil when secrets are
+// kept in the config file
+func (m *Manager) SecretStore() secrets.Store {
+	return m.secrets
+}
+
+// GetServerToken returns the server token from the secret store, falling back
+// to a token still kept in the config file
 func (m *Manager) GetServerToken() string {
+	if m.secrets != nil {
+		if token, err := m.secrets.Get(secrets.ServerToken); err == nil {
+			return token
+		}
+	}
 	return m.Get().ServerToken
 }
 
-// SetServerToken sets the server token
+// SetServerToken stores the server token in the secret store and removes any
+// plaintext copy from the config file
 func (m *Manager) SetServerToken(token string) error {
 	config := m.Get()
+	if m.secrets != nil {
+		var err error
+		if token == "" {
+			err = m.secrets.Delete(secrets.ServerToken)
+		} else {
+			err = m.secrets.Set(secrets.ServerToken, token)
+		}
+		if err == nil {
+			config.ServerToken = ""
+			return m.Save()
+		}
+		if m.secretsRequired || !errors.Is(err, secrets.ErrUnavailable) {
+			return fmt.Errorf("failed to store server token in %s: %w", m.secrets.Name(), err)
+		}
+	}
 	config.ServerToken = token
 	return m.Save()
 }
@@ -574,6 +642,34 @@ func (m *Manager) SetLastSync(t time.Time) error {
 	return m.Save()
 }
 
+// AddTombstones records tombstones of deleted keys. Fingerprints that already
+// have a tombstone keep it.
+func (m *Manager) AddTombstones(tombstones ...models.KeyTombstone) error {
+	config := m.Get()
+	added := 0
+	for _, t := range tombstones {
+		if t.Fingerprint == "" || m.GetTombstone(t.Fingerprint) != nil {
+			continue
+		}
+		config.Tombstones = append(config.Tombstones, t)
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	return m.Save()
+}
+
+// GetTombstone returns the tombstone of a fingerprint, or nil
+func (m *Manager) GetTombstone(fingerprint string) *models.KeyTombstone {
+	config := m.Get()
+	for i := range config.Tombstones {
+		if config.Tombstones[i].Fingerprint == fingerprint {
+			return &config.Tombstones[i]
+		}
+	}
+	return nil
+}
 
 // ListKeys returns all keys
 func (m *Manager) ListKeys() ([]models.Key, error) {
@@ -591,6 +687,19 @@ func (m *Manager) ListHosts() ([]models.Host, error) {
 	return m.store.Host().List(context.Background())
 }
 
+// ListEffectiveHosts returns all hosts with the settings they inherit from
+// their host groups
+func (m *Manager) ListEffectiveHosts() ([]models.Host, error) {
+	hosts, err := m.ListHosts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		hosts[i] = hosts[i].WithGroups(m.Get().HostGroups)
+	}
+	return hosts, nil
+}
+
 // Helper methods
 
 func (m *Manager) checkStore() error {
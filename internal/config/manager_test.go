@@ -1,11 +1,15 @@
 package config
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/all-dot-files/ssh-key-manager/internal/keystore"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/secrets"
 )
 
 func TestInitialize(t *testing.T) {
@@ -67,6 +71,9 @@ func TestKeyManagement(t *testing.T) {
 	if retrieved.Path != "/tmp/key" {
 		t.Error("retrieved key has wrong path")
 	}
+	if retrieved.DeviceID == "" || retrieved.DeviceID != manager.GetDeviceID() {
+		t.Errorf("expected key to record device %q, got %q", manager.GetDeviceID(), retrieved.DeviceID)
+	}
 
 	// Remove Key
 	err = manager.RemoveKey("testkey")
@@ -79,3 +86,274 @@ func TestKeyManagement(t *testing.T) {
 		t.Error("key should have been removed")
 	}
 }
+
+func TestServerTokenInSecretStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	manager, _ := NewManager(configPath)
+	manager.Initialize("test", "")
+
+	// A token saved before a backend was configured stays readable
+	if err := manager.SetServerToken("legacy-token"); err != nil {
+		t.Fatalf("SetServerToken failed: %v", err)
+	}
+
+	store := secrets.NewMemoryStore()
+	manager.SetSecretStore(store, true)
+	if token := manager.GetServerToken(); token != "legacy-token" {
+		t.Fatalf("expected legacy token, got %q", token)
+	}
+
+	if err := manager.SetServerToken("new-token"); err != nil {
+		t.Fatalf("SetServerToken failed: %v", err)
+	}
+	if manager.Get().ServerToken != "" {
+		t.Error("token should no longer be kept in the config file")
+	}
+	if stored, _ := store.Get(secrets.ServerToken); stored != "new-token" {
+		t.Errorf("expected token in the secret store, got %q", stored)
+	}
+	if token := manager.GetServerToken(); token != "new-token" {
+		t.Errorf("expected new token, got %q", token)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "new-token") {
+		t.Error("config file contains the server token")
+	}
+}
+
+func TestHostOptionsRoundTrip(t *testing.T) {
+	for _, driver := range []string{"yaml", "sqlite"} {
+		t.Run(driver, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			manager, _ := NewManager(configPath)
+			if err := manager.Initialize("test", ""); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			manager.Get().StorageDriver = driver
+			if err := manager.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if err := manager.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			forward := true
+			host := models.Host{Host: "db", User: "deploy", KeyName: "k"}
+			host.ProxyJump = "bastion"
+			host.ForwardAgent = &forward
+			host.LocalForward = []string{"5432 localhost:5432"}
+			host.ServerAliveInterval = 30
+			host.Options = map[string]string{"LogLevel": "ERROR"}
+			if err := manager.AddHost(host); err != nil {
+				t.Fatalf("AddHost failed: %v", err)
+			}
+
+			reloaded, _ := NewManager(configPath)
+			if err := reloaded.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			got, err := reloaded.GetHost("db")
+			if err != nil {
+				t.Fatalf("GetHost failed: %v", err)
+			}
+			if got.ProxyJump != "bastion" || got.ForwardAgent == nil || !*got.ForwardAgent ||
+				got.ServerAliveInterval != 30 || len(got.LocalForward) != 1 || got.Options["LogLevel"] != "ERROR" {
+				t.Errorf("options did not round-trip: %+v", got.HostOptions)
+			}
+
+			got.Unset("ProxyJump")
+			if err := reloaded.UpdateHost("db", *got); err != nil {
+				t.Fatalf("UpdateHost failed: %v", err)
+			}
+			if updated, _ := reloaded.GetHost("db"); updated.ProxyJump != "" || updated.ServerAliveInterval != 30 {
+				t.Errorf("update lost options: %+v", updated.HostOptions)
+			}
+		})
+	}
+}
+
+func TestGetEffectiveHost(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	manager, _ := NewManager(configPath)
+	if err := manager.Initialize("test", ""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	manager.Get().StorageDriver = "sqlite"
+	manager.Get().HostGroups = []models.HostGroup{
+		{Name: "prod", Patterns: []string{"prod-*"}, User: "deploy", KeyName: "prodkey", Port: 2222},
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := manager.AddHost(models.Host{Host: "db", User: "dba", Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+
+	// Tagged members inherit what they leave unset
+	host, err := manager.GetEffectiveHost("db")
+	if err != nil {
+		t.Fatalf("GetEffectiveHost failed: %v", err)
+	}
+	if host.User != "dba" || host.KeyName != "prodkey" || host.Port != 2222 {
+		t.Errorf("unexpected effective host: %+v", host)
+	}
+
+	// Unregistered aliases matching a pattern get the group settings
+	if host, err := manager.GetEffectiveHost("prod-web"); err != nil || host.User != "deploy" {
+		t.Errorf("pattern member: %+v, %v", host, err)
+	}
+	if _, err := manager.GetEffectiveHost("dev-web"); err == nil {
+		t.Error("expected an error for a host outside every group")
+	}
+}
+
+func TestKeyPassphraseChangeSurvivesReload(t *testing.T) {
+	for _, driver := range []string{"yaml", "sqlite"} {
+		t.Run(driver, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, "config.yaml")
+			manager, _ := NewManager(configPath)
+			if err := manager.Initialize("test", ""); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			manager.Get().StorageDriver = driver
+			if err := manager.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if err := manager.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			ks, err := keystore.NewKeyStore(filepath.Join(dir, "keys"))
+			if err != nil {
+				t.Fatalf("NewKeyStore failed: %v", err)
+			}
+			key, err := ks.GenerateKey("work", models.KeyTypeED25519, "", 0)
+			if err != nil {
+				t.Fatalf("GenerateKey failed: %v", err)
+			}
+			if err := manager.AddKey(*key); err != nil {
+				t.Fatalf("AddKey failed: %v", err)
+			}
+
+			if err := ks.ChangePassphrase(key, "", "secret"); err != nil {
+				t.Fatalf("ChangePassphrase failed: %v", err)
+			}
+			if err := manager.UpdateKey(key.Name, *key); err != nil {
+				t.Fatalf("UpdateKey failed: %v", err)
+			}
+
+			reloaded, _ := NewManager(configPath)
+			if err := reloaded.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			got, err := reloaded.GetKey("work")
+			if err != nil {
+				t.Fatalf("GetKey failed: %v", err)
+			}
+			if !got.HasPassphrase {
+				t.Fatal("expected the key to still have a passphrase after a reload")
+			}
+			if _, err := ks.LoadPrivateKey(got, "secret"); err != nil {
+				t.Fatalf("failed to load the reloaded key: %v", err)
+			}
+			if _, err := ks.LoadPrivateKey(got, ""); err == nil {
+				t.Fatal("expected the reloaded key to need its passphrase")
+			}
+		})
+	}
+}
+
+func TestECDSAKeySizeSurvivesReload(t *testing.T) {
+	for _, driver := range []string{"yaml", "sqlite"} {
+		t.Run(driver, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			manager, _ := NewManager(configPath)
+			if err := manager.Initialize("test", ""); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			manager.Get().StorageDriver = driver
+			if err := manager.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if err := manager.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			key := models.Key{Name: "p384", Type: models.KeyTypeECDSA, ECDSABits: 384, Path: "/keys/p384", PubPath: "/keys/p384.pub"}
+			if err := manager.AddKey(key); err != nil {
+				t.Fatalf("AddKey failed: %v", err)
+			}
+
+			reloaded, _ := NewManager(configPath)
+			if err := reloaded.Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			got, err := reloaded.GetKey("p384")
+			if err != nil {
+				t.Fatalf("GetKey failed: %v", err)
+			}
+			if got.Bits() != 384 {
+				t.Errorf("expected a P-384 key after a reload, got %d bits", got.ECDSABits)
+			}
+		})
+	}
+}
+
+func TestRepoBindingPerRemote(t *testing.T) {
+	dir := t.TempDir()
+
+	// A database from before bindings were kept per remote
+	db, err := sql.Open("sqlite", filepath.Join(dir, "skm.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE repos (path TEXT PRIMARY KEY, remote TEXT NOT NULL, host_alias TEXT NOT NULL,
+		user TEXT, key_name TEXT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO repos (path, remote, host_alias, key_name) VALUES ('/repo', 'origin', 'github.com', 'work')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	db.Close()
+
+	manager, _ := NewManager(filepath.Join(dir, "config.yaml"))
+	if err := manager.Initialize("test", ""); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	manager.Get().StorageDriver = "sqlite"
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for _, repo := range []models.GitRepo{
+		{Path: "/repo", Remote: "upstream", Host: "github.com", KeyName: "personal"},
+		{Path: "/repo", Remote: "origin", Host: "github.com", KeyName: "fork"},
+	} {
+		if err := manager.AddRepo(repo); err != nil {
+			t.Fatalf("AddRepo failed: %v", err)
+		}
+	}
+
+	if repo, err := manager.GetRepo("/repo", "origin"); err != nil || repo.KeyName != "fork" {
+		t.Errorf("origin: %+v, %v", repo, err)
+	}
+	if repo, err := manager.GetRepo("/repo", "upstream"); err != nil || repo.KeyName != "personal" {
+		t.Errorf("upstream: %+v, %v", repo, err)
+	}
+	if repos, err := manager.ListRepos(); err != nil || len(repos) != 2 {
+		t.Errorf("expected 2 bindings, got %+v, %v", repos, err)
+	}
+}
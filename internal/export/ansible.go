@@ -0,0 +1,163 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register(ansibleINI{})
+	Register(ansibleYAML{})
+}
+
+// ansibleINI renders an Ansible inventory in INI format
+type ansibleINI struct{}
+
+func (ansibleINI) Name() string { return "ansible" }
+
+func (ansibleINI) Description() string {
+	return "Ansible inventory (INI), with a group per host group and tag"
+}
+
+// Render writes every host with its variables first, then a section per group
+// listing its members
+func (ansibleINI) Render(w io.Writer, inv *Inventory) error {
+	var b strings.Builder
+	for _, h := range inv.Hosts {
+		b.WriteString(h.Alias)
+		for _, v := range ansibleVars(&h) {
+			fmt.Fprintf(&b, " %s=%s", v[0], quoteINI(v[1]))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, group := range ansibleGroups(inv) {
+		fmt.Fprintf(&b, "\n[%s]\n", group.name)
+		for _, member := range group.members {
+			b.WriteString(member + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ansibleYAML renders an Ansible inventory in YAML format
+type ansibleYAML struct{}
+
+func (ansibleYAML) Name() string { return "ansible-yaml" }
+
+func (ansibleYAML) Description() string {
+	return "Ansible inventory (YAML), with a group per host group and tag"
+}
+
+// Render writes the hosts with their variables under all, and the groups as
+// its children
+func (ansibleYAML) Render(w io.Writer, inv *Inventory) error {
+	hosts := make(map[string]map[string]interface{})
+	for _, h := range inv.Hosts {
+		vars := make(map[string]interface{})
+		for _, v := range ansibleVars(&h) {
+			vars[v[0]] = v[1]
+		}
+		if h.Port > 0 {
+			vars["ansible_port"] = h.Port
+		}
+		hosts[h.Alias] = vars
+	}
+
+	all := map[string]interface{}{"hosts": hosts}
+	if groups := ansibleGroups(inv); len(groups) > 0 {
+		children := make(map[string]interface{})
+		for _, group := range groups {
+			members := make(map[string]interface{})
+			for _, member := range group.members {
+				members[member] = nil
+			}
+			children[group.name] = map[string]interface{}{"hosts": members}
+		}
+		all["children"] = children
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]interface{}{"all": all}); err != nil {
+		return fmt.Errorf("failed to encode inventory: %w", err)
+	}
+	return encoder.Close()
+}
+
+// ansibleVars returns the connection variables of a host, in a fixed order
+func ansibleVars(h *Host) [][2]string {
+	vars := [][2]string{{"ansible_host", h.Hostname}}
+	if h.User != "" {
+		vars = append(vars, [2]string{"ansible_user", h.User})
+	}
+	if h.Port > 0 {
+		vars = append(vars, [2]string{"ansible_port", strconv.Itoa(h.Port)})
+	}
+
+	// Ansible connects to the host name, so the SSH config block of the alias
+	// does not apply: the settings SKM writes there go on the command line
+	var args []string
+	if h.Key != nil {
+		vars = append(vars, [2]string{"ansible_ssh_private_key_file", h.Key.Path})
+		args = append(args, "-o IdentitiesOnly=yes")
+		if h.Key.CertPath != "" {
+			args = append(args, "-o CertificateFile="+h.Key.CertPath)
+		}
+	}
+	if h.ProxyJump != "" {
+		args = append(args, "-o ProxyJump="+h.ProxyJump)
+	}
+	if len(args) > 0 {
+		vars = append(vars, [2]string{"ansible_ssh_common_args", strings.Join(args, " ")})
+	}
+	return vars
+}
+
+// ansibleGroup is an inventory group and its members
+type ansibleGroup struct {
+	name    string
+	members []string
+}
+
+// invalidGroupChars are the characters Ansible does not accept in group names
+var invalidGroupChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// ansibleGroups returns the groups of the hosts in order of appearance, with
+// names made valid for Ansible
+func ansibleGroups(inv *Inventory) []ansibleGroup {
+	var groups []ansibleGroup
+	index := make(map[string]int)
+	for _, h := range inv.Hosts {
+		for _, g := range h.Groups {
+			name := invalidGroupChars.ReplaceAllString(g, "_")
+			if name[0] >= '0' && name[0] <= '9' {
+				name = "_" + name
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(groups)
+				index[name] = i
+				groups = append(groups, ansibleGroup{name: name})
+			}
+			groups[i].members = appendUnique(groups[i].members, h.Alias)
+		}
+	}
+	return groups
+}
+
+// quoteINI quotes a value with spaces or quotes for the INI inventory, which
+// Ansible splits like a shell
+func quoteINI(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t'\"#\\") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,210 @@
+// Package export renders managed hosts and the keys bound to them in formats
+// read by other tools, such as Ansible inventories or PuTTY sessions.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// Key is the key a host connects with
+type Key struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Path        string `json:"path"`
+	PubPath     string `json:"pub_path,omitempty"`
+	CertPath    string `json:"cert_path,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Host is a managed host with the settings it inherits from its groups
+type Host struct {
+	Alias    string `json:"alias"`
+	Hostname string `json:"hostname"`
+	User     string `json:"user,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Key      *Key   `json:"key,omitempty"`
+	// JumpHosts are the managed hosts to go through, starting with the first hop
+	JumpHosts []string `json:"jump_hosts,omitempty"`
+	// ProxyJump is the ProxyJump value: the jump hosts or the one set as option
+	ProxyJump string `json:"proxy_jump,omitempty"`
+	// Groups are the host groups the host belongs to, then its other tags
+	Groups []string `json:"groups,omitempty"`
+	// Options are the other ssh_config options of the host
+	Options []Option `json:"options,omitempty"`
+}
+
+// Option is one ssh_config option of a host
+type Option struct {
+	Keyword string `json:"keyword"`
+	Value   string `json:"value"`
+}
+
+// Inventory is the set of hosts to export
+type Inventory struct {
+	Hosts []Host
+	// Warnings lists hosts exported without their key or jump hosts
+	Warnings []string
+}
+
+// NewInventory resolves hosts against their groups and keys. A host whose key
+// is missing or unusable is exported without one.
+func NewInventory(hosts []models.Host, keys map[string]*models.Key, groups []models.HostGroup) *Inventory {
+	inv := &Inventory{}
+	effective := make(map[string]*models.Host)
+	resolved := make([]models.Host, len(hosts))
+	for i := range hosts {
+		resolved[i] = hosts[i].WithGroups(groups)
+		effective[strings.ToLower(resolved[i].Host)] = &resolved[i]
+	}
+	lookup := func(alias string) (*models.Host, error) {
+		if host, ok := effective[strings.ToLower(alias)]; ok {
+			return host, nil
+		}
+		return nil, fmt.Errorf("host not found: %s", alias)
+	}
+
+	for i := range resolved {
+		host := &resolved[i]
+		h := Host{
+			Alias:    host.Host,
+			Hostname: host.Hostname,
+			User:     host.User,
+			Port:     host.Port,
+		}
+		if h.Hostname == "" {
+			h.Hostname = host.Host
+		}
+
+		if host.KeyName != "" {
+			key, ok := keys[host.KeyName]
+			switch {
+			case !ok:
+				inv.Warnings = append(inv.Warnings, fmt.Sprintf("host %s: key %s not found", host.Host, host.KeyName))
+			case !key.IsUsable():
+				inv.Warnings = append(inv.Warnings, fmt.Sprintf("host %s: key %s is %s", host.Host, key.Name, key.EffectiveState()))
+			default:
+				h.Key = &Key{
+					Name:        key.Name,
+					Type:        string(key.Type),
+					Path:        key.Path,
+					PubPath:     key.PubPath,
+					CertPath:    key.CertPath,
+					Fingerprint: key.Fingerprint,
+				}
+			}
+		}
+
+		options := host.HostOptions
+		h.ProxyJump = options.ProxyJump
+		if chain, err := models.JumpChain(host, lookup); err != nil {
+			inv.Warnings = append(inv.Warnings, fmt.Sprintf("host %s: %v", host.Host, err))
+		} else if len(chain) > 0 {
+			for _, hop := range chain {
+				h.JumpHosts = append(h.JumpHosts, hop.Host)
+			}
+			h.ProxyJump = models.JumpAliases(chain)
+		}
+		options.ProxyJump = ""
+		for _, d := range options.Directives() {
+			h.Options = append(h.Options, Option{Keyword: d.Keyword, Value: d.Value})
+		}
+
+		for _, g := range models.GroupsOf(host, groups) {
+			h.Groups = appendUnique(h.Groups, g.Name)
+		}
+		for _, tag := range host.Tags {
+			h.Groups = appendUnique(h.Groups, tag)
+		}
+		inv.Hosts = append(inv.Hosts, h)
+	}
+	return inv
+}
+
+// Select returns the inventory of the hosts in group, which is a host group
+// or a tag, and the jump hosts they go through
+func (inv *Inventory) Select(group string) *Inventory {
+	wanted := make(map[string]bool)
+	for _, h := range inv.Hosts {
+		if h.InGroup(group) {
+			wanted[strings.ToLower(h.Alias)] = true
+			for _, hop := range h.JumpHosts {
+				wanted[strings.ToLower(hop)] = true
+			}
+		}
+	}
+
+	selected := &Inventory{Warnings: inv.Warnings}
+	for _, h := range inv.Hosts {
+		if wanted[strings.ToLower(h.Alias)] {
+			selected.Hosts = append(selected.Hosts, h)
+		}
+	}
+	return selected
+}
+
+// InGroup reports whether the host belongs to the group or has the tag
+func (h *Host) InGroup(group string) bool {
+	for _, g := range h.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// Renderer writes an inventory in one format
+type Renderer interface {
+	// Name is the value of --format selecting the renderer
+	Name() string
+
+	// Description is shown in the list of formats
+	Description() string
+
+	// Render writes the inventory to w
+	Render(w io.Writer, inv *Inventory) error
+}
+
+var renderers = make(map[string]Renderer)
+
+// Register makes a renderer available by its name. Registering a name twice
+// panics.
+func Register(r Renderer) {
+	if _, exists := renderers[r.Name()]; exists {
+		panic(fmt.Sprintf("export: renderer %s registered twice", r.Name()))
+	}
+	renderers[r.Name()] = r
+}
+
+// Lookup returns the renderer registered with name
+func Lookup(name string) (Renderer, error) {
+	r, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q (use %s)", name, strings.Join(Formats(), ", "))
+	}
+	return r, nil
+}
+
+// Formats returns the names of the registered renderers, sorted
+func Formats() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// appendUnique appends value unless list already has it
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func testInventory() *Inventory {
+	hosts := []models.Host{
+		{Host: "bastion", Hostname: "bastion.example.com", User: "ops", KeyName: "edge"},
+		{Host: "prod-web", Hostname: "10.0.0.5", JumpHost: "bastion"},
+		{Host: "db", User: "dba", KeyName: "missing", Tags: []string{"data"}},
+	}
+	keys := map[string]*models.Key{
+		"edge": {Name: "edge", Type: models.KeyTypeED25519, Path: "/keys/edge"},
+		"prod": {Name: "prod", Type: models.KeyTypeED25519, Path: "/keys/my prod"},
+	}
+	groups := []models.HostGroup{{Name: "prod", Patterns: []string{"prod-*"}, User: "deploy", KeyName: "prod", Port: 2222}}
+	return NewInventory(hosts, keys, groups)
+}
+
+func TestNewInventory(t *testing.T) {
+	inv := testInventory()
+	if len(inv.Hosts) != 3 {
+		t.Fatalf("expected 3 hosts, got %d", len(inv.Hosts))
+	}
+
+	web := inv.Hosts[1]
+	if web.User != "deploy" || web.Port != 2222 || web.Key == nil || web.Key.Name != "prod" {
+		t.Errorf("expected the settings of group prod, got %+v", web)
+	}
+	if web.ProxyJump != "bastion" || len(web.JumpHosts) != 1 {
+		t.Errorf("expected jump host bastion, got %+v", web)
+	}
+
+	db := inv.Hosts[2]
+	if db.Hostname != "db" || db.Key != nil || !db.InGroup("data") {
+		t.Errorf("unexpected host: %+v", db)
+	}
+	if len(inv.Warnings) != 1 || !strings.Contains(inv.Warnings[0], "key missing not found") {
+		t.Errorf("expected a warning for the missing key, got %v", inv.Warnings)
+	}
+
+	selected := inv.Select("prod")
+	if len(selected.Hosts) != 2 || selected.Hosts[0].Alias != "bastion" || selected.Hosts[1].Alias != "prod-web" {
+		t.Errorf("expected prod-web and its jump host, got %+v", selected.Hosts)
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	inv := testInventory()
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"ansible", []string{
+			"prod-web ansible_host=10.0.0.5 ansible_user=deploy ansible_port=2222 ansible_ssh_private_key_file='/keys/my prod' ansible_ssh_common_args='-o IdentitiesOnly=yes -o ProxyJump=bastion'\n",
+			"db ansible_host=db ansible_user=dba\n",
+			"[prod]\nprod-web\n",
+			"[data]\ndb\n",
+		}},
+		{"ansible-yaml", []string{"ansible_port: 2222", "ansible_ssh_private_key_file: /keys/my prod", "    prod:\n      hosts:\n        prod-web: null"}},
+		{"ssh-config", []string{"Host prod-web\n    HostName 10.0.0.5\n    User deploy\n    Port 2222\n    IdentityFile \"/keys/my prod\"\n    IdentitiesOnly yes\n    ProxyJump bastion\n"}},
+		{"putty", []string{`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\prod-web]`, `"PortNumber"=dword:000008ae`, `"ProxyHost"="bastion"`}},
+	}
+	for _, tt := range tests {
+		r, err := Lookup(tt.format)
+		if err != nil {
+			t.Fatalf("lookup %s: %v", tt.format, err)
+		}
+		var buf bytes.Buffer
+		if err := r.Render(&buf, inv); err != nil {
+			t.Fatalf("render %s: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: expected %q in:\n%s", tt.format, want, buf.String())
+			}
+		}
+	}
+
+	r, _ := Lookup("json")
+	var buf bytes.Buffer
+	if err := r.Render(&buf, inv); err != nil {
+		t.Fatalf("render json: %v", err)
+	}
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if doc.Version != 1 || len(doc.Hosts) != 3 || doc.Hosts[1].Key.Path != "/keys/my prod" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+
+	if _, err := Lookup("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestPuttySessionName(t *testing.T) {
+	if got := puttySessionName(".my host*"); got != "%2Emy%20host%2A" {
+		t.Errorf("puttySessionName = %q", got)
+	}
+}
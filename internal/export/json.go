@@ -0,0 +1,41 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(jsonRenderer{})
+}
+
+// jsonRenderer renders the hosts as a JSON document for scripts
+type jsonRenderer struct{}
+
+func (jsonRenderer) Name() string { return "json" }
+
+func (jsonRenderer) Description() string {
+	return "JSON document with every host, its key, jump hosts and options"
+}
+
+// jsonDocument is the JSON export; Version changes when fields are removed or
+// change meaning
+type jsonDocument struct {
+	Version int    `json:"version"`
+	Hosts   []Host `json:"hosts"`
+}
+
+// Render writes the document, indented
+func (jsonRenderer) Render(w io.Writer, inv *Inventory) error {
+	doc := jsonDocument{Version: 1, Hosts: inv.Hosts}
+	if doc.Hosts == nil {
+		doc.Hosts = []Host{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode hosts: %w", err)
+	}
+	return nil
+}
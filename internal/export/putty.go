@@ -0,0 +1,101 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(puttyRenderer{})
+}
+
+// puttyRenderer renders the hosts as PuTTY saved sessions in a .reg file
+type puttyRenderer struct{}
+
+func (puttyRenderer) Name() string { return "putty" }
+
+func (puttyRenderer) Description() string {
+	return "PuTTY saved sessions as a Windows .reg file"
+}
+
+// puttySessions is the registry key holding the PuTTY saved sessions
+const puttySessions = `HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions`
+
+// Render writes a session per host. A host going through jump hosts uses the
+// session of its last hop as SSH proxy, which in turn goes through the hops
+// before it. PuTTY only reads keys in its own format, so each key is left as
+// a comment to convert with puttygen.
+func (puttyRenderer) Render(w io.Writer, inv *Inventory) error {
+	byAlias := make(map[string]*Host)
+	for i := range inv.Hosts {
+		byAlias[inv.Hosts[i].Alias] = &inv.Hosts[i]
+	}
+
+	var b strings.Builder
+	b.WriteString("Windows Registry Editor Version 5.00\n")
+	for _, h := range inv.Hosts {
+		fmt.Fprintf(&b, "\n[%s\\%s]\n", puttySessions, puttySessionName(h.Alias))
+		str := func(name, value string) {
+			fmt.Fprintf(&b, "%q=\"%s\"\n", name, escapeReg(value))
+		}
+		dword := func(name string, value int) {
+			fmt.Fprintf(&b, "%q=dword:%08x\n", name, value)
+		}
+
+		str("HostName", h.Hostname)
+		str("Protocol", "ssh")
+		dword("PortNumber", puttyPort(h.Port))
+		if h.User != "" {
+			str("UserName", h.User)
+		}
+		if h.Key != nil {
+			fmt.Fprintf(&b, "; key %s: convert %s with puttygen and set it as the private key of the session\n", h.Key.Name, h.Key.Path)
+		}
+
+		if len(h.JumpHosts) > 0 {
+			last := h.JumpHosts[len(h.JumpHosts)-1]
+			dword("ProxyMethod", 6) // SSH to proxy and use port forwarding
+			str("ProxyHost", puttySessionName(last))
+			if hop, ok := byAlias[last]; ok {
+				dword("ProxyPort", puttyPort(hop.Port))
+				if hop.User != "" {
+					str("ProxyUsername", hop.User)
+				}
+			}
+		} else if h.ProxyJump != "" {
+			fmt.Fprintf(&b, "; ProxyJump %s is not exported: set it as SSH proxy of the session\n", h.ProxyJump)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// puttyPort returns the port of a session, 22 if unset
+func puttyPort(port int) int {
+	if port > 0 {
+		return port
+	}
+	return 22
+}
+
+// puttySessionName escapes a session name the way PuTTY stores it in the
+// registry
+func puttySessionName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == ' ' || c == '\\' || c == '*' || c == '?' || c == '%' || c < ' ' || (c == '.' && i == 0) {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// escapeReg escapes a .reg string value
+func escapeReg(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
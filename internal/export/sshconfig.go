@@ -0,0 +1,68 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(sshConfigRenderer{})
+}
+
+// sshConfigRenderer renders the hosts as an ssh_config fragment
+type sshConfigRenderer struct{}
+
+func (sshConfigRenderer) Name() string { return "ssh-config" }
+
+func (sshConfigRenderer) Description() string {
+	return "ssh_config fragment with a Host block per host, as SKM writes it"
+}
+
+// Render writes a Host block per host. Jump hosts are referred to by alias, so
+// a fragment of a group carries the blocks of its jump hosts too.
+func (sshConfigRenderer) Render(w io.Writer, inv *Inventory) error {
+	var b strings.Builder
+	for i, h := range inv.Hosts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Host %s\n", h.Alias)
+		line := func(keyword, value string) {
+			fmt.Fprintf(&b, "    %s %s\n", keyword, value)
+		}
+
+		line("HostName", h.Hostname)
+		if h.User != "" {
+			line("User", h.User)
+		}
+		if h.Port > 0 {
+			line("Port", strconv.Itoa(h.Port))
+		}
+		if h.Key != nil {
+			line("IdentityFile", quotePath(h.Key.Path))
+			if h.Key.CertPath != "" {
+				line("CertificateFile", quotePath(h.Key.CertPath))
+			}
+			line("IdentitiesOnly", "yes")
+		}
+		if h.ProxyJump != "" {
+			line("ProxyJump", h.ProxyJump)
+		}
+		for _, o := range h.Options {
+			line(o.Keyword, o.Value)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quotePath quotes a path with spaces for ssh_config
+func quotePath(path string) string {
+	if strings.ContainsAny(path, " \t") {
+		return `"` + path + `"`
+	}
+	return path
+}
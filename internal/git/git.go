@@ -18,22 +18,69 @@ type Manager struct {
 	configManager interface {
 		GetRepo(path, remote string) (*models.GitRepo, error)
 		GetKey(name string) (*models.Key, error)
-		GetHost(hostname string) (*models.Host, error)
+		GetEffectiveHost(hostname string) (*models.Host, error)
 	}
+	knownHostsFiles []string
+	rules           []models.GitKeyRule
+	sshCommand      string
 }
 
 // NewManager creates a new Git manager
 func NewManager(configManager interface {
 	GetRepo(path, remote string) (*models.GitRepo, error)
 	GetKey(name string) (*models.Key, error)
-	GetHost(hostname string) (*models.Host, error)
+	GetEffectiveHost(hostname string) (*models.Host, error)
 }) *Manager {
 	return &Manager{
 		configManager: configManager,
 	}
 }
 
-// BindRepo configures a Git repository to use SKM
+// SetKnownHostsFiles makes SSH commands verify host keys against files, such
+// as the known_hosts holding the SKM managed @cert-authority entries and the
+// skm-owned file of pinned host keys. No files uses ssh's default.
+func (m *Manager) SetKnownHostsFiles(files ...string) {
+	m.knownHostsFiles = files
+}
+
+// SetKeyRules sets the rules choosing the key of remotes by URL pattern
+func (m *Manager) SetKeyRules(rules []models.GitKeyRule) {
+	m.rules = rules
+}
+
+// SetSSHCommand makes WrapCommand run git with command as its ssh, so each
+// remote gets its own key. Without it every remote uses the key of the
+// default binding.
+func (m *Manager) SetSSHCommand(command string) {
+	m.sshCommand = command
+}
+
+// hostKeyArgs returns the ssh options enforcing strict host key checking
+func (m *Manager) hostKeyArgs(quote bool) []string {
+	args := []string{"-o", "StrictHostKeyChecking=yes"}
+	if len(m.knownHostsFiles) == 0 {
+		return args
+	}
+
+	// ssh splits the value into files on spaces, so a path with spaces is
+	// quoted on its own
+	paths := make([]string, len(m.knownHostsFiles))
+	for i, file := range m.knownHostsFiles {
+		paths[i] = filepath.ToSlash(filepath.Clean(file))
+		if strings.ContainsAny(paths[i], " \t\"") {
+			paths[i] = strconv.Quote(paths[i])
+		}
+	}
+	option := "UserKnownHostsFile=" + strings.Join(paths, " ")
+	if quote {
+		option = strconv.Quote(option)
+	}
+	return append(args, "-o", option)
+}
+
+// BindRepo configures a remote of a Git repository to use SKM. Every remote
+// has its own binding; the first one bound is also the default binding of
+// the repository.
 func (m *Manager) BindRepo(repoPath, remote, host, user, keyName string) error {
 	// Verify repository exists
 	gitDir := filepath.Join(repoPath, ".git")
@@ -41,45 +88,75 @@ func (m *Manager) BindRepo(repoPath, remote, host, user, keyName string) error {
 		return fmt.Errorf("not a git repository: %s", repoPath)
 	}
 
-	// Set Git config for this repository
-	// We'll use git config to store SKM metadata
-	if err := m.setGitConfig(repoPath, "skm.remote", remote); err != nil {
-		return err
-	}
-
-	if err := m.setGitConfig(repoPath, "skm.host", host); err != nil {
-		return err
-	}
-
-	if user != "" {
-		if err := m.setGitConfig(repoPath, "skm.user", user); err != nil {
+	prefixes := []string{"skm." + remote + "."}
+	defaultRemote, err := m.getGitConfig(repoPath, "skm.remote")
+	if err != nil || defaultRemote == remote {
+		if err := m.setGitConfig(repoPath, "skm.remote", remote); err != nil {
 			return err
 		}
+		prefixes = append(prefixes, "skm.")
 	}
 
-	if keyName != "" {
-		if err := m.setGitConfig(repoPath, "skm.key", keyName); err != nil {
+	for _, prefix := range prefixes {
+		if err := m.setGitConfig(repoPath, prefix+"host", host); err != nil {
 			return err
 		}
+		for _, v := range []struct{ key, value string }{{"user", user}, {"key", keyName}} {
+			if v.value == "" {
+				// A binding replaced without the value no longer has it
+				m.unsetGitConfig(repoPath, prefix+v.key)
+				continue
+			}
+			if err := m.setGitConfig(repoPath, prefix+v.key, v.value); err != nil {
+				return err
+			}
+		}
 	}
-
 	return nil
 }
 
-// GetRepoConfig gets SKM configuration for a Git repository
+// GetRepoConfig gets the default SKM binding of a Git repository: the first
+// remote bound, or else the first remote with a binding
 func (m *Manager) GetRepoConfig(repoPath string) (*models.GitRepo, error) {
 	remote, err := m.getGitConfig(repoPath, "skm.remote")
 	if err != nil {
 		remote = "origin"
 	}
+	if repo, err := m.GetRemoteConfig(repoPath, remote); err == nil {
+		return repo, nil
+	}
 
-	host, err := m.getGitConfig(repoPath, "skm.host")
+	remotes, _ := m.Remotes(repoPath)
+	for _, r := range remotes {
+		if repo, err := m.GetRemoteConfig(repoPath, r.Name); err == nil {
+			return repo, nil
+		}
+	}
+	return nil, fmt.Errorf("repository not configured with SKM")
+}
+
+// GetRemoteConfig gets the SKM binding of a remote of a Git repository.
+// Repositories bound before remotes had their own binding only have the
+// default one.
+func (m *Manager) GetRemoteConfig(repoPath, remote string) (*models.GitRepo, error) {
+	prefix := "skm." + remote + "."
+	host, err := m.getGitConfig(repoPath, prefix+"host")
 	if err != nil {
-		return nil, fmt.Errorf("repository not configured with SKM")
+		defaultRemote, err := m.getGitConfig(repoPath, "skm.remote")
+		if err != nil {
+			defaultRemote = "origin"
+		}
+		if defaultRemote != remote {
+			return nil, fmt.Errorf("remote %s not configured with SKM", remote)
+		}
+		if host, err = m.getGitConfig(repoPath, "skm.host"); err != nil {
+			return nil, fmt.Errorf("remote %s not configured with SKM", remote)
+		}
+		prefix = "skm."
 	}
 
-	user, _ := m.getGitConfig(repoPath, "skm.user")
-	keyName, _ := m.getGitConfig(repoPath, "skm.key")
+	user, _ := m.getGitConfig(repoPath, prefix+"user")
+	keyName, _ := m.getGitConfig(repoPath, prefix+"key")
 
 	return &models.GitRepo{
 		Path:    repoPath,
@@ -90,6 +167,33 @@ func (m *Manager) GetRepoConfig(repoPath string) (*models.GitRepo, error) {
 	}, nil
 }
 
+// Remote is a remote of a Git repository
+type Remote struct {
+	Name string
+	URL  string
+}
+
+// Remotes returns the remotes of a repository in the order of its config
+func (m *Manager) Remotes(repoPath string) ([]Remote, error) {
+	cmd := exec.Command("git", "config", "--get-regexp", `^remote\..*\.url$`)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+
+	var remotes []Remote
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, url, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		remotes = append(remotes, Remote{Name: name, URL: url})
+	}
+	return remotes, nil
+}
+
 // GetSSHCommand returns the GIT_SSH_COMMAND for a repository
 func (m *Manager) GetSSHCommand(repoPath string) (string, error) {
 	repo, err := m.GetRepoConfig(repoPath)
@@ -99,14 +203,24 @@ func (m *Manager) GetSSHCommand(repoPath string) (string, error) {
 
 	// Get key
 	var keyPath string
+	extraArgs := m.hostKeyArgs(true)
 	if repo.KeyName != "" {
 		key, err := m.configManager.GetKey(repo.KeyName)
 		if err != nil {
 			return "", fmt.Errorf("failed to get key: %w", err)
 		}
 		keyPath = key.Path
+
+		// The jump hosts of a managed host still apply
+		if host, err := m.configManager.GetEffectiveHost(repo.Host); err == nil {
+			jumpArgs, err := m.jumpArgs(host, true)
+			if err != nil {
+				return "", err
+			}
+			extraArgs = append(extraArgs, jumpArgs...)
+		}
 	} else {
-		host, err := m.configManager.GetHost(repo.Host)
+		host, err := m.configManager.GetEffectiveHost(repo.Host)
 		if err != nil {
 			return "", fmt.Errorf("failed to get host: %w", err)
 		}
@@ -115,10 +229,16 @@ func (m *Manager) GetSSHCommand(repoPath string) (string, error) {
 			return "", fmt.Errorf("failed to get key: %w", err)
 		}
 		keyPath = key.Path
+
+		jumpArgs, err := m.jumpArgs(host, true)
+		if err != nil {
+			return "", err
+		}
+		extraArgs = append(extraArgs, jumpArgs...)
 	}
 
 	// Build SSH command
-	return buildSSHCommand(keyPath), nil
+	return buildSSHCommand(keyPath, extraArgs...), nil
 }
 
 // WrapCommand wraps a Git command to use the correct SSH key
@@ -127,6 +247,9 @@ func (m *Manager) WrapCommand(repoPath string, args []string) error {
 	if err != nil {
 		return err
 	}
+	if m.sshCommand != "" {
+		sshCmd = m.sshCommand
+	}
 
 	// Set GIT_SSH_COMMAND environment variable
 	cmd := exec.Command("git", args...)
@@ -149,6 +272,13 @@ func (m *Manager) setGitConfig(repoPath, key, value string) error {
 	return nil
 }
 
+// unsetGitConfig removes a git config value of a repository, if set
+func (m *Manager) unsetGitConfig(repoPath, key string) {
+	cmd := exec.Command("git", "config", "--local", "--unset", key)
+	cmd.Dir = repoPath
+	_ = cmd.Run()
+}
+
 // getGitConfig gets a git config value for a repository
 func (m *Manager) getGitConfig(repoPath, key string) (string, error) {
 	cmd := exec.Command("git", "config", "--local", "--get", key)
@@ -216,68 +346,79 @@ func UninstallGlobalHook() error {
 	return nil
 }
 
-// AutoConfigureRepo automatically configures a repository based on remote URL
+// AutoConfigureRepo binds every remote of a repository that is not bound
+// yet, using the key rule matching its URL or the managed host it points to
 func (m *Manager) AutoConfigureRepo(repoPath string) error {
-	// Check if already configured
-	if _, err := m.GetRepoConfig(repoPath); err == nil {
-		// Already configured, use existing config
-		return nil
+	remotes, err := m.Remotes(repoPath)
+	if err != nil || len(remotes) == 0 {
+		return fmt.Errorf("no remote URL found")
 	}
 
-	// Get remote URL
-	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("failed to get remote URL: %w", err)
-	}
+	var unresolved []string
+	bound := 0
+	for _, remote := range remotes {
+		if _, err := m.GetRemoteConfig(repoPath, remote.Name); err == nil {
+			// Already configured, use existing config
+			bound++
+			continue
+		}
 
-	remoteURL := strings.TrimSpace(string(output))
-	if remoteURL == "" {
-		return fmt.Errorf("no remote URL found")
-	}
+		host, path := parseRemoteURL(remote.URL)
+		if host == "" {
+			unresolved = append(unresolved, fmt.Sprintf("%s: failed to parse host from URL %s", remote.Name, remote.URL))
+			continue
+		}
 
-	// Parse remote URL to extract host
-	host := parseHostFromURL(remoteURL)
-	if host == "" {
-		return fmt.Errorf("failed to parse host from URL: %s", remoteURL)
-	}
+		var keyName string
+		if rule := models.MatchGitKeyRule(m.rules, host, path); rule != nil {
+			keyName = rule.KeyName
+		} else if hostConfig, err := m.configManager.GetEffectiveHost(host); err == nil {
+			keyName = hostConfig.KeyName
+		} else {
+			unresolved = append(unresolved, fmt.Sprintf("%s: host %s not configured. Run: skm host add %s --user <user> --key <key>", remote.Name, host, host))
+			continue
+		}
 
-	// Check if host exists in config
-	hostConfig, err := m.configManager.GetHost(host)
-	if err != nil {
-		// Host doesn't exist, we'll handle auto-creation later
-		return fmt.Errorf("host %s not configured. Run: skm host add %s --user <user> --key <key>", host, host)
+		if err := m.BindRepo(repoPath, remote.Name, host, "", keyName); err != nil {
+			return err
+		}
+		bound++
 	}
 
-	// Bind the repository
-	return m.BindRepo(repoPath, "origin", host, "", hostConfig.KeyName)
+	if bound == 0 {
+		return fmt.Errorf("no remote could be configured:\n  %s", strings.Join(unresolved, "\n  "))
+	}
+	return nil
 }
 
-// parseHostFromURL extracts hostname from Git remote URL
-func parseHostFromURL(url string) string {
-	// Handle SSH URLs: git@github.com:user/repo.git
-	if strings.HasPrefix(url, "git@") {
-		parts := strings.Split(url, "@")
-		if len(parts) > 1 {
-			hostParts := strings.Split(parts[1], ":")
-			if len(hostParts) > 0 {
-				return hostParts[0]
-			}
+// parseRemoteURL extracts the host and repository path from a Git remote
+// URL: ssh://[user@]host[:port]/path, [user@]host:path or http(s)://host/path
+func parseRemoteURL(url string) (host, path string) {
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		switch scheme {
+		case "ssh", "git+ssh", "ssh+git", "https", "http", "git":
+		default:
+			return "", ""
 		}
-	}
-
-	// Handle HTTPS URLs: https://github.com/user/repo.git
-	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
-		url = strings.TrimPrefix(url, "https://")
-		url = strings.TrimPrefix(url, "http://")
-		parts := strings.Split(url, "/")
-		if len(parts) > 0 {
-			return parts[0]
+		authority, path, _ := strings.Cut(rest, "/")
+		if i := strings.LastIndex(authority, "@"); i >= 0 {
+			authority = authority[i+1:]
+		}
+		if h, _, ok := strings.Cut(authority, ":"); ok {
+			authority = h
 		}
+		return authority, models.NormalizeRepoPath(path)
 	}
 
-	return ""
+	// scp-like syntax; a colon after a slash makes it a local path
+	authority, path, ok := strings.Cut(url, ":")
+	if !ok || strings.Contains(authority, "/") {
+		return "", ""
+	}
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		authority = authority[i+1:]
+	}
+	return authority, models.NormalizeRepoPath(path)
 }
 
 // InstallCredentialHelper installs SKM as a Git credential helper
@@ -402,7 +543,7 @@ func (m *Manager) CredentialHelperGet() error {
 	}
 
 	// Get the host configuration
-	hostConfig, err := m.configManager.GetHost(host)
+	hostConfig, err := m.configManager.GetEffectiveHost(host)
 	if err != nil {
 		// Host not configured, silently skip
 		return nil
@@ -547,7 +688,7 @@ func (m *Manager) GetSSHCommandForHost(host string) (string, error) {
 	}
 
 	// Get the host configuration
-	hostConfig, err := m.configManager.GetHost(host)
+	hostConfig, err := m.configManager.GetEffectiveHost(host)
 	if err != nil {
 		return "", fmt.Errorf("host not found: %w", err)
 	}
@@ -558,80 +699,56 @@ func (m *Manager) GetSSHCommandForHost(host string) (string, error) {
 		return "", fmt.Errorf("key not found: %w", err)
 	}
 
+	jumpArgs, err := m.jumpArgs(hostConfig, true)
+	if err != nil {
+		return "", err
+	}
+
 	// Build SSH command
-	knownHosts := filepath.ToSlash(os.DevNull)
-	sshCmd := buildSSHCommand(
-		key.Path,
-		"-o", fmt.Sprintf("UserKnownHostsFile=%s", knownHosts),
-		"-o", "StrictHostKeyChecking=no",
-	)
-	return sshCmd, nil
+	return buildSSHCommand(key.Path, append(m.hostKeyArgs(true), jumpArgs...)...), nil
 }
 
-// HandleSSHCommand handles SSH command wrapping for Git operations
+// HandleSSHCommand handles SSH command wrapping for Git operations. The key
+// is chosen from the host and the repository path git passes in the remote
+// command, so remotes of the same host can use different keys.
 func (m *Manager) HandleSSHCommand(args []string) error {
-	// Git calls SSH with arguments like: user@host -p port command
-	// We need to extract the host and inject the correct SSH key
-
-	if len(args) == 0 {
-		// No arguments, just execute SSH
-		return execSSH(args)
-	}
-
-	// Find the host argument (usually first arg, format: user@host or just host)
-	hostArg := ""
-	for i, arg := range args {
-		// Skip flags
-		if strings.HasPrefix(arg, "-") {
-			continue
-		}
-		// First non-flag argument should be the host
-		hostArg = args[i]
-		break
-	}
-
-	if hostArg == "" {
+	// Git calls SSH with arguments like: [-p port] [-o option] user@host command
+	host, repoPath := parseSSHInvocation(args)
+	if host == "" {
 		// No host found, just execute SSH normally
 		return execSSH(args)
 	}
 
-	// Extract host from user@host format
-	host := hostArg
-	if strings.Contains(hostArg, "@") {
-		parts := strings.Split(hostArg, "@")
-		if len(parts) == 2 {
-			host = parts[1]
-		}
-	}
-
-	// Remove port if present (host:port)
-	if strings.Contains(host, ":") {
-		host = strings.Split(host, ":")[0]
-	}
-
 	// Check if we should handle this host
 	if !m.shouldHandleHost(host) {
 		return execSSH(args)
 	}
 
-	// Get the host configuration
-	hostConfig, err := m.configManager.GetHost(host)
+	// Git runs ssh from the repository, whose remotes may have bindings
+	key, hostConfig, err := m.ResolveKey("", host, repoPath)
 	if err != nil {
+		return err
+	}
+	if key == nil {
 		// Host not configured, use default SSH
 		return execSSH(args)
 	}
 
-	// Get the key
-	key, err := m.configManager.GetKey(hostConfig.KeyName)
-	if err != nil {
-		return fmt.Errorf("failed to get key: %w", err)
-	}
-
 	// Build SSH command with the correct key
 	sshArgs := []string{
 		"-i", key.Path,
 		"-o", "IdentitiesOnly=yes",
 	}
+	sshArgs = append(sshArgs, m.hostKeyArgs(false)...)
+
+	// Go through the jump hosts, each with its own key
+	if hostConfig != nil {
+		jumpArgs, err := m.jumpArgs(hostConfig, false)
+		if err != nil {
+			return err
+		}
+		sshArgs = append(sshArgs, jumpArgs...)
+	}
 
 	// Append original arguments
 	sshArgs = append(sshArgs, args...)
@@ -639,6 +756,144 @@ func (m *Manager) HandleSSHCommand(args []string) error {
 	return execSSH(sshArgs)
 }
 
+// ResolveKey returns the key to connect to the repository at repoPath on host,
+// and the managed host if there is one. The binding of the remote of repoDir
+// pointing to the repository comes first, then the key rules, then the key of
+// the managed host. It returns a nil key when SKM has none for the host.
+func (m *Manager) ResolveKey(repoDir, host, repoPath string) (*models.Key, *models.Host, error) {
+	keyName := ""
+	hostConfig, err := m.configManager.GetEffectiveHost(host)
+	if err != nil {
+		hostConfig = nil
+	}
+
+	if binding := m.remoteBinding(repoDir, host, repoPath); binding != nil {
+		keyName = binding.KeyName
+		if bound, err := m.configManager.GetEffectiveHost(binding.Host); err == nil {
+			hostConfig = bound
+		}
+	}
+	if keyName == "" {
+		if rule := models.MatchGitKeyRule(m.rules, host, repoPath); rule != nil {
+			keyName = rule.KeyName
+		}
+	}
+	if keyName == "" && hostConfig != nil {
+		keyName = hostConfig.KeyName
+	}
+	if keyName == "" {
+		return nil, nil, nil
+	}
+
+	key, err := m.configManager.GetKey(keyName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get key: %w", err)
+	}
+	return key, hostConfig, nil
+}
+
+// remoteBinding returns the binding of the remote of repoDir whose URL points
+// to the repository at repoPath on host, or nil
+func (m *Manager) remoteBinding(repoDir, host, repoPath string) *models.GitRepo {
+	remotes, err := m.Remotes(repoDir)
+	if err != nil {
+		return nil
+	}
+	path := models.NormalizeRepoPath(repoPath)
+	for _, remote := range remotes {
+		remoteHost, remotePath := parseRemoteURL(remote.URL)
+		if !strings.EqualFold(remoteHost, host) || (path != "" && remotePath != path) {
+			continue
+		}
+		if binding, err := m.GetRemoteConfig(repoDir, remote.Name); err == nil {
+			return binding
+		}
+	}
+	return nil
+}
+
+// sshOptionsWithArgs are the ssh options taking a value as next argument
+const sshOptionsWithArgs = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// parseSSHInvocation returns the host and repository path of an ssh command
+// run by git, such as: -p 22 git@github.com git-upload-pack 'myorg/repo.git'
+func parseSSHInvocation(args []string) (host, repoPath string) {
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		// Skip the value of options such as -p 22 or -o SendEnv=GIT_PROTOCOL
+		if len(arg) == 2 && strings.ContainsRune(sshOptionsWithArgs, rune(arg[1])) {
+			i++
+		}
+	}
+	if i >= len(args) {
+		return "", ""
+	}
+
+	// Extract host from user@host format
+	host = args[i]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	// Remove port if present (host:port)
+	host, _, _ = strings.Cut(host, ":")
+
+	// The remote command is a single argument: git-upload-pack 'path'
+	command := strings.Join(args[i+1:], " ")
+	if name, quoted, ok := strings.Cut(command, " "); ok && strings.HasPrefix(name, "git-") {
+		quoted = strings.ReplaceAll(quoted, `'\''`, `'`)
+		repoPath = strings.Trim(strings.TrimSpace(quoted), `'`)
+	}
+	return host, repoPath
+}
+
+// jumpArgs returns the ssh options connecting to host through its jump hosts.
+// The chain is written as nested ProxyCommands rather than ProxyJump so every
+// hop gets its own key without relying on the SSH config. With quote the
+// option is quoted for a shell command line.
+func (m *Manager) jumpArgs(host *models.Host, quote bool) ([]string, error) {
+	chain, err := models.JumpChain(host, m.configManager.GetEffectiveHost)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	command := ""
+	for _, hop := range chain {
+		key, err := m.configManager.GetKey(hop.KeyName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key of jump host %s: %w", hop.Host, err)
+		}
+
+		args := []string{"ssh", "-i", strconv.Quote(filepath.ToSlash(filepath.Clean(key.Path))), "-o", "IdentitiesOnly=yes"}
+		args = append(args, m.hostKeyArgs(true)...)
+		if hop.User != "" {
+			args = append(args, "-l", strconv.Quote(hop.User))
+		}
+		if hop.Port > 0 {
+			args = append(args, "-p", strconv.Itoa(hop.Port))
+		}
+		if command != "" {
+			// ssh expands %h and %p in the ProxyCommand it runs; %% keeps
+			// them for the hop before
+			args = append(args, "-o", strconv.Quote("ProxyCommand="+strings.ReplaceAll(command, "%", "%%")))
+		}
+		args = append(args, "-W", "%h:%p", strconv.Quote(hop.RemoteName()))
+		command = strings.Join(args, " ")
+	}
+
+	option := "ProxyCommand=" + command
+	if quote {
+		option = strconv.Quote(option)
+	}
+	return []string{"-o", option}, nil
+}
+
 func buildSSHCommand(keyPath string, extraArgs ...string) string {
 	normalizedKey := filepath.ToSlash(filepath.Clean(keyPath))
 	quotedKey := strconv.Quote(normalizedKey)
@@ -1,11 +1,22 @@
 package git
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
 )
 
 func TestCreateWrapper(t *testing.T) {
@@ -44,3 +55,185 @@ func TestCreateWrapper(t *testing.T) {
 
 // We can't easily test shouldHandleHost without mocking git config commands or setting up a real git repo.
 // For now, we'll skip complex git integration tests that require external commands.
+
+type fakeConfig struct {
+	hosts map[string]*models.Host
+	keys  map[string]*models.Key
+}
+
+func (f *fakeConfig) GetRepo(path, remote string) (*models.GitRepo, error) {
+	return nil, fmt.Errorf("repo not found: %s", path)
+}
+
+func (f *fakeConfig) GetKey(name string) (*models.Key, error) {
+	if key, ok := f.keys[name]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key not found: %s", name)
+}
+
+func (f *fakeConfig) GetEffectiveHost(hostname string) (*models.Host, error) {
+	if host, ok := f.hosts[hostname]; ok {
+		return host, nil
+	}
+	return nil, fmt.Errorf("host not found: %s", hostname)
+}
+
+func TestJumpArgs(t *testing.T) {
+	cfg := &fakeConfig{
+		hosts: map[string]*models.Host{
+			"db":       {Host: "db", KeyName: "db", JumpHost: "bastion2"},
+			"bastion2": {Host: "bastion2", User: "jump", KeyName: "inner", JumpHost: "bastion1"},
+			"bastion1": {Host: "bastion1", Hostname: "bastion.example.com", Port: 2222, KeyName: "outer"},
+		},
+		keys: map[string]*models.Key{
+			"db":    {Name: "db", Path: "/keys/db"},
+			"inner": {Name: "inner", Path: "/keys/inner"},
+			"outer": {Name: "outer", Path: "/keys/outer"},
+		},
+	}
+	m := NewManager(cfg)
+
+	args, err := m.jumpArgs(cfg.hosts["db"], false)
+	if err != nil {
+		t.Fatalf("jumpArgs failed: %v", err)
+	}
+
+	// The first hop is nested inside the second, with its tokens escaped
+	inner := `ssh -i "/keys/outer" -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes -p 2222 -W %%h:%%p "bastion.example.com"`
+	want := `ProxyCommand=ssh -i "/keys/inner" -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes -l "jump" -o ` +
+		strconv.Quote("ProxyCommand="+inner) + ` -W %h:%p "bastion2"`
+	if len(args) != 2 || args[0] != "-o" || args[1] != want {
+		t.Errorf("unexpected args:\n%q\nwant:\n%q", args, want)
+	}
+
+	cfg.hosts["bastion1"].JumpHost = "db"
+	if _, err := m.jumpArgs(cfg.hosts["db"], false); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
+
+func TestSSHCommandChecksPinnedHostKeys(t *testing.T) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh not installed")
+	}
+
+	dir := t.TempDir()
+	userFile := filepath.Join(dir, "known_hosts")
+	pinnedFile := filepath.Join(dir, "skm_known_hosts")
+	if err := os.WriteFile(userFile, nil, 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	// The host key is only pinned in the skm-owned file
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	hostKey, _ := ssh.NewPublicKey(pub)
+	line := knownhosts.Line([]string{"pinned.example.com"}, hostKey)
+	if err := os.WriteFile(pinnedFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("write pinned known_hosts: %v", err)
+	}
+
+	cfg := &fakeConfig{
+		hosts: map[string]*models.Host{"pinned.example.com": {Host: "pinned.example.com", KeyName: "k"}},
+		keys:  map[string]*models.Key{"k": {Name: "k", Path: filepath.Join(dir, "id_k")}},
+	}
+	m := NewManager(cfg)
+	m.SetKnownHostsFiles(userFile, pinnedFile)
+	command, err := m.GetSSHCommandForHost("pinned.example.com")
+	if err != nil {
+		t.Fatalf("GetSSHCommandForHost failed: %v", err)
+	}
+
+	// Let ssh itself parse the command line the way git runs it
+	out, err := exec.Command("/bin/sh", "-c", command+" -F /dev/null -G pinned.example.com").Output()
+	if err != nil {
+		t.Fatalf("ssh -G failed: %v", err)
+	}
+	var files []string
+	for _, l := range strings.Split(string(out), "\n") {
+		if value, ok := strings.CutPrefix(l, "userknownhostsfile "); ok {
+			files = strings.Fields(value)
+		}
+	}
+	if len(files) != 2 || files[0] != userFile || files[1] != pinnedFile {
+		t.Fatalf("expected ssh to read %q and %q, got %q", userFile, pinnedFile, files)
+	}
+	if !strings.Contains(string(out), "stricthostkeychecking true") {
+		t.Errorf("expected strict host key checking:\n%s", out)
+	}
+
+	// Checked against the files ssh reads, the pinned key is accepted
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		t.Fatalf("read known_hosts: %v", err)
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	if err := callback("pinned.example.com:22", addr, hostKey); err != nil {
+		t.Errorf("expected the pinned host key to be accepted: %v", err)
+	}
+
+	// Paths with spaces are quoted one by one inside the option
+	m.SetKnownHostsFiles("/home/me/.ssh/known_hosts", "/home/me/my config/known_hosts")
+	want := []string{"-o", "StrictHostKeyChecking=yes", "-o", `UserKnownHostsFile=/home/me/.ssh/known_hosts "/home/me/my config/known_hosts"`}
+	if args := m.hostKeyArgs(false); strings.Join(args, "|") != strings.Join(want, "|") {
+		t.Errorf("hostKeyArgs = %q, want %q", args, want)
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url, host, path string
+	}{
+		{"git@github.com:myorg/app.git", "github.com", "myorg/app"},
+		{"github-work:myorg/app", "github-work", "myorg/app"},
+		{"ssh://git@github.com:22/myorg/app.git", "github.com", "myorg/app"},
+		{"https://gitlab.com/group/sub/app.git", "gitlab.com", "group/sub/app"},
+		{"/srv/git/app.git", "", ""},
+		{"./local:repo", "", ""},
+		{"file:///srv/git/app.git", "", ""},
+	}
+	for _, tt := range tests {
+		if host, path := parseRemoteURL(tt.url); host != tt.host || path != tt.path {
+			t.Errorf("parseRemoteURL(%q) = %q, %q; want %q, %q", tt.url, host, path, tt.host, tt.path)
+		}
+	}
+}
+
+func TestParseSSHInvocation(t *testing.T) {
+	host, path := parseSSHInvocation([]string{"-o", "SendEnv=GIT_PROTOCOL", "-p", "2222", "git@github.com", "git-upload-pack 'myorg/it'\\''s.git'"})
+	if host != "github.com" || path != "myorg/it's.git" {
+		t.Errorf("got %q, %q", host, path)
+	}
+	if host, path := parseSSHInvocation([]string{"-G", "github.com"}); host != "github.com" || path != "" {
+		t.Errorf("got %q, %q", host, path)
+	}
+}
+
+func TestMatchGitKeyRule(t *testing.T) {
+	rules := []models.GitKeyRule{
+		{Pattern: "github.com:*", KeyName: "personal"},
+		{Pattern: "github.com:myorg/*", KeyName: "work"},
+		{Pattern: "*.corp", KeyName: "corp"},
+	}
+	tests := []struct {
+		host, path, want string
+	}{
+		{"github.com", "/myorg/app.git", "work"},
+		{"GitHub.com", "MyOrg/app", "work"},
+		{"github.com", "other/app", "personal"},
+		{"git.corp", "any/repo", "corp"},
+		{"gitlab.com", "myorg/app", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if rule := models.MatchGitKeyRule(rules, tt.host, tt.path); rule != nil {
+			got = rule.KeyName
+		}
+		if got != tt.want {
+			t.Errorf("MatchGitKeyRule(%s, %s) = %q, want %q", tt.host, tt.path, got, tt.want)
+		}
+	}
+}
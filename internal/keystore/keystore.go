@@ -1,6 +1,7 @@
 package keystore
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
@@ -8,16 +9,22 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/all-dot-files/ssh-key-manager/pkg/crypto"
+	"github.com/all-dot-files/ssh-key-manager/pkg/fileio"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
 	"golang.org/x/crypto/ssh"
 )
 
+// openSSHMagic prefixes every openssh-key-v1 private key blob
+const openSSHMagic = "openssh-key-v1\x00"
+
 // KeyStore manages SSH keys
 type KeyStore struct {
 	basePath string
@@ -35,8 +42,18 @@ func NewKeyStore(basePath string) (*KeyStore, error) {
 	}, nil
 }
 
-// GenerateKey generates a new SSH key pair
+// GenerateKey generates a new SSH key pair; rsaBits only applies to RSA keys
 func (ks *KeyStore) GenerateKey(name string, keyType models.KeyType, passphrase string, rsaBits int) (*models.Key, error) {
+	bits := 0
+	if keyType == models.KeyTypeRSA {
+		bits = rsaBits
+	}
+	return ks.GenerateKeyWithSize(name, keyType, passphrase, bits)
+}
+
+// GenerateKeyWithSize generates a new SSH key pair. bits is the RSA modulus size
+// or the ECDSA curve size (256, 384 or 521); 0 selects the default for the key type.
+func (ks *KeyStore) GenerateKeyWithSize(name string, keyType models.KeyType, passphrase string, bits int) (*models.Key, error) {
 	var privateKey interface{}
 	var err error
 
@@ -49,16 +66,23 @@ func (ks *KeyStore) GenerateKey(name string, keyType models.KeyType, passphrase
 		}
 
 	case models.KeyTypeRSA:
-		if rsaBits == 0 {
-			rsaBits = 4096
+		if bits == 0 {
+			bits = DefaultBits(keyType)
 		}
-		privateKey, err = rsa.GenerateKey(rand.Reader, rsaBits)
+		privateKey, err = rsa.GenerateKey(rand.Reader, bits)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
 		}
 
 	case models.KeyTypeECDSA:
-		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if bits == 0 {
+			bits = DefaultBits(keyType)
+		}
+		curve, err := ecdsaCurve(bits)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err = ecdsa.GenerateKey(curve, rand.Reader)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate ECDSA key: %w", err)
 		}
@@ -67,27 +91,13 @@ func (ks *KeyStore) GenerateKey(name string, keyType models.KeyType, passphrase
 		return nil, fmt.Errorf("unsupported key type: %s", keyType)
 	}
 
-	// Marshal private key to PEM
-	privateKeyPEM, err := ks.marshalPrivateKey(privateKey, keyType)
+	// Marshal private key to OpenSSH format, encrypting natively with
+	// bcrypt-pbkdf when a passphrase is provided
+	privateKeyData, err := ks.marshalPrivateKey(privateKey, name, passphrase)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal private key: %w", err)
 	}
-
-	// Encrypt private key if passphrase is provided
-	var privateKeyData []byte
 	hasPassphrase := passphrase != ""
-	
-	if hasPassphrase {
-		encrypted, err := crypto.Encrypt(privateKeyPEM, passphrase)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encrypt private key: %w", err)
-		}
-		// Store as: salt|nonce|ciphertext
-		privateKeyData = append(encrypted.Salt, encrypted.Nonce...)
-		privateKeyData = append(privateKeyData, encrypted.Ciphertext...)
-	} else {
-		privateKeyData = privateKeyPEM
-	}
 
 	// Generate SSH public key
 	sshPrivateKey, err := ssh.NewSignerFromKey(privateKey)
@@ -125,82 +135,205 @@ func (ks *KeyStore) GenerateKey(name string, keyType models.KeyType, passphrase
 		HasPassphrase: hasPassphrase,
 	}
 
-	if keyType == models.KeyTypeRSA {
-		key.RSABits = rsaBits
+	switch keyType {
+	case models.KeyTypeRSA:
+		key.RSABits = bits
+	case models.KeyTypeECDSA:
+		key.ECDSABits = bits
 	}
 
 	return key, nil
 }
 
-// marshalPrivateKey marshals a private key to PEM format
-func (ks *KeyStore) marshalPrivateKey(privateKey interface{}, keyType models.KeyType) ([]byte, error) {
-	var pemType string
-	var keyBytes []byte
+// DefaultBits returns the key size GenerateKeyWithSize uses when none is given
+func DefaultBits(keyType models.KeyType) int {
+	if keyType == models.KeyTypeRSA {
+		return 4096
+	}
+	return 256
+}
+
+// ecdsaCurve returns the NIST curve for an ECDSA key size
+func ecdsaCurve(bits int) (elliptic.Curve, error) {
+	switch bits {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ECDSA key size: %d (must be 256, 384 or 521)", bits)
+	}
+}
+
+// marshalPrivateKey marshals a private key to the openssh-key-v1 format
+func (ks *KeyStore) marshalPrivateKey(privateKey interface{}, comment, passphrase string) ([]byte, error) {
+	var block *pem.Block
 	var err error
 
-	switch keyType {
-	case models.KeyTypeED25519:
-		pemType = "OPENSSH PRIVATE KEY"
-		keyBytes, err = x509.MarshalPKCS8PrivateKey(privateKey)
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(privateKey, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(privateKey, comment)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	case models.KeyTypeRSA:
-		pemType = "RSA PRIVATE KEY"
-		keyBytes = x509.MarshalPKCS1PrivateKey(privateKey.(*rsa.PrivateKey))
+	return pem.EncodeToMemory(block), nil
+}
 
-	case models.KeyTypeECDSA:
-		pemType = "EC PRIVATE KEY"
-		keyBytes, err = x509.MarshalECPrivateKey(privateKey.(*ecdsa.PrivateKey))
+// LoadPrivateKey loads and optionally decrypts a private key.
+// The returned bytes are always an unencrypted openssh-key-v1 PEM block,
+// regardless of the on-disk format.
+func (ks *KeyStore) LoadPrivateKey(key *models.Key, passphrase string) ([]byte, error) {
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
 
-	default:
-		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	if isOpenSSHFormat(data) && !key.HasPassphrase {
+		return data, nil
 	}
 
+	privateKey, err := parsePrivateKeyData(data, passphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	block := &pem.Block{
-		Type:  pemType,
-		Bytes: keyBytes,
+	plaintext, err := ks.marshalPrivateKey(privateKey, key.Name, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
 	}
 
-	return pem.EncodeToMemory(block), nil
+	return plaintext, nil
 }
 
-// LoadPrivateKey loads and optionally decrypts a private key
-func (ks *KeyStore) LoadPrivateKey(key *models.Key, passphrase string) ([]byte, error) {
+// ParsePrivateKey loads a private key and returns the decoded crypto key
+// (ed25519.PrivateKey, *rsa.PrivateKey or *ecdsa.PrivateKey)
+func (ks *KeyStore) ParsePrivateKey(key *models.Key, passphrase string) (interface{}, error) {
 	data, err := os.ReadFile(key.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read private key: %w", err)
 	}
 
+	return parsePrivateKeyData(data, passphrase)
+}
+
+// IsLegacyFormat reports whether the key file predates the openssh-key-v1
+// format and needs to be migrated before ssh can load it
+func (ks *KeyStore) IsLegacyFormat(key *models.Key) (bool, error) {
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return !isOpenSSHFormat(data), nil
+}
+
+// MigrateFormat rewrites a legacy key file (PKCS#8, PKCS#1, SEC1, the raw
+// salt|nonce|ciphertext blob or a pkg/crypto envelope) as an openssh-key-v1 file. Encrypted keys are
+// re-encrypted natively with the same passphrase.
+func (ks *KeyStore) MigrateFormat(key *models.Key, passphrase string) error {
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	if isOpenSSHFormat(data) {
+		return nil
+	}
+
+	privateKey, err := parseLegacyPrivateKey(data, passphrase)
+	if err != nil {
+		return err
+	}
+
 	if !key.HasPassphrase {
-		return data, nil
+		passphrase = ""
 	}
 
-	// Decrypt
-	if len(data) < crypto.SaltLength+crypto.NonceLength {
-		return nil, fmt.Errorf("invalid encrypted key data")
+	privateKeyData, err := ks.marshalPrivateKey(privateKey, key.Name, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
 	}
 
-	encrypted := &crypto.EncryptedData{
-		Salt:       data[:crypto.SaltLength],
-		Nonce:      data[crypto.SaltLength : crypto.SaltLength+crypto.NonceLength],
-		Ciphertext: data[crypto.SaltLength+crypto.NonceLength:],
+	return writeFileAtomic(key.Path, privateKeyData, 0600)
+}
+
+// ChangePassphrase re-encrypts a private key with a new passphrase. An empty
+// newPassphrase removes the passphrase. The key file is replaced atomically and
+// key.HasPassphrase is updated; the caller persists the key.
+func (ks *KeyStore) ChangePassphrase(key *models.Key, oldPassphrase, newPassphrase string) error {
+	if key.Type.IsSecurityKey() {
+		return fmt.Errorf("key %s is a security key handle; change its passphrase with: ssh-keygen -p -f %s", key.Name, key.Path)
 	}
 
-	plaintext, err := crypto.Decrypt(encrypted, passphrase)
+	plaintext, err := ks.LoadPrivateKey(key, oldPassphrase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		return err
 	}
 
-	return plaintext, nil
+	privateKey, err := ssh.ParseRawPrivateKey(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	privateKeyData, err := ks.marshalPrivateKey(privateKey, key.Name, newPassphrase)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	if err := writeFileAtomic(key.Path, privateKeyData, 0600); err != nil {
+		return err
+	}
+
+	key.HasPassphrase = newPassphrase != ""
+	key.UpdatedAt = time.Now()
+	return nil
+}
+
+// NeedsRehash reports whether a key file was encrypted by pkg/crypto, as a
+// versioned envelope or a headerless salt|nonce|ciphertext blob, and must be
+// re-encrypted as openssh-key-v1 before ssh can load it
+func (ks *KeyStore) NeedsRehash(key *models.Key) (bool, error) {
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	return crypto.IsEnvelope(data) ||
+		(!isOpenSSHFormat(data) && !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN"))), nil
+}
+
+// Rehash re-encrypts a key encrypted by pkg/crypto as an openssh-key-v1 file
+// protected natively by the same passphrase, the format every other key of
+// the keystore is stored in. Envelopes are only used for keys leaving the
+// keystore, such as synced keys.
+func (ks *KeyStore) Rehash(key *models.Key, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("a passphrase is required to rehash key %s", key.Name)
+	}
+
+	key.HasPassphrase = true
+	if err := ks.MigrateFormat(key, passphrase); err != nil {
+		return err
+	}
+
+	key.UpdatedAt = time.Now()
+	return nil
+}
+
+// InstalledPath returns the path a key is installed to inside sshDir
+func (ks *KeyStore) InstalledPath(key *models.Key, sshDir string) string {
+	return filepath.Join(sshDir, "id_"+key.Type.ShortName()+"_"+key.Name)
 }
 
 // InstallToSSH installs a key to the SSH directory
 func (ks *KeyStore) InstallToSSH(key *models.Key, sshDir string) error {
 	// Create target paths
-	targetPrivate := filepath.Join(sshDir, "id_"+string(key.Type)+"_"+key.Name)
+	targetPrivate := ks.InstalledPath(key, sshDir)
 	targetPublic := targetPrivate + ".pub"
 
 	// Copy private key
@@ -209,6 +342,10 @@ func (ks *KeyStore) InstallToSSH(key *models.Key, sshDir string) error {
 		return fmt.Errorf("failed to read private key: %w", err)
 	}
 
+	if !isOpenSSHFormat(privateData) {
+		return fmt.Errorf("key %s is stored in a format that ssh cannot load; run: skm key migrate-format %s", key.Name, key.Name)
+	}
+
 	if err := os.WriteFile(targetPrivate, privateData, 0600); err != nil {
 		return fmt.Errorf("failed to write private key to SSH dir: %w", err)
 	}
@@ -223,9 +360,58 @@ func (ks *KeyStore) InstallToSSH(key *models.Key, sshDir string) error {
 		return fmt.Errorf("failed to write public key to SSH dir: %w", err)
 	}
 
+	// Copy certificate so ssh picks it up next to the installed key
+	if key.CertPath != "" {
+		certData, err := os.ReadFile(key.CertPath)
+		if err != nil {
+			return fmt.Errorf("failed to read certificate: %w", err)
+		}
+		if err := os.WriteFile(targetPrivate+"-cert.pub", certData, 0644); err != nil {
+			return fmt.Errorf("failed to write certificate to SSH dir: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// CertificatePath returns where the OpenSSH certificate of a key is stored
+func (ks *KeyStore) CertificatePath(key *models.Key) string {
+	return key.Path + "-cert.pub"
+}
+
+// SaveCertificate validates a certificate for key and stores it next to the
+// private key, returning the certificate path
+func (ks *KeyStore) SaveCertificate(key *models.Key, certData []byte) (string, error) {
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(certData)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return "", fmt.Errorf("not an OpenSSH certificate")
+	}
+
+	publicData, err := os.ReadFile(key.PubPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read public key: %w", err)
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(publicData)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if !bytes.Equal(cert.Key.Marshal(), publicKey.Marshal()) {
+		return "", fmt.Errorf("certificate does not belong to key %s", key.Name)
+	}
+
+	certPath := ks.CertificatePath(key)
+	data := bytes.TrimSpace(certData)
+	if err := writeFileAtomic(certPath, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	return certPath, nil
+}
+
 // ExportPublicKey exports the public key to a file
 func (ks *KeyStore) ExportPublicKey(key *models.Key, destination string) error {
 	data, err := os.ReadFile(key.PubPath)
@@ -240,10 +426,28 @@ func (ks *KeyStore) ExportPublicKey(key *models.Key, destination string) error {
 	return nil
 }
 
-// DeleteKey deletes a key from the keystore
+// UninstallFromSSH removes the copies of a key installed to the SSH directory
+func (ks *KeyStore) UninstallFromSSH(key *models.Key, sshDir string) error {
+	targetPrivate := ks.InstalledPath(key, sshDir)
+
+	if err := secureRemove(targetPrivate); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove installed private key: %w", err)
+	}
+	for _, path := range []string{targetPrivate + ".pub", targetPrivate + "-cert.pub"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	key.Installed = false
+	return nil
+}
+
+// DeleteKey deletes a key from the keystore. The private key is overwritten
+// before it is unlinked.
 func (ks *KeyStore) DeleteKey(key *models.Key) error {
 	// Delete private key
-	if err := os.Remove(key.Path); err != nil && !os.IsNotExist(err) {
+	if err := secureRemove(key.Path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete private key: %w", err)
 	}
 
@@ -252,6 +456,11 @@ func (ks *KeyStore) DeleteKey(key *models.Key) error {
 		return fmt.Errorf("failed to delete public key: %w", err)
 	}
 
+	// Delete certificate
+	if err := os.Remove(ks.CertificatePath(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete certificate: %w", err)
+	}
+
 	return nil
 }
 
@@ -259,3 +468,128 @@ func (ks *KeyStore) DeleteKey(key *models.Key) error {
 func (ks *KeyStore) GetPublicKeyContent(key *models.Key) ([]byte, error) {
 	return os.ReadFile(key.PubPath)
 }
+
+// isOpenSSHFormat reports whether data is an openssh-key-v1 PEM block
+func isOpenSSHFormat(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil && block.Type == "OPENSSH PRIVATE KEY" && bytes.HasPrefix(block.Bytes, []byte(openSSHMagic))
+}
+
+// parsePrivateKeyData decodes a private key in any format the keystore
+// has ever written
+func parsePrivateKeyData(data []byte, passphrase string) (interface{}, error) {
+	if !isOpenSSHFormat(data) {
+		return parseLegacyPrivateKey(data, passphrase)
+	}
+
+	privateKey, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("private key is encrypted, passphrase required")
+		}
+		privateKey, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	return privateKey, nil
+}
+
+// parseLegacyPrivateKey decodes keys that are not plain openssh-key-v1 files:
+// pkg/crypto envelopes and keys written before the format was adopted
+func parseLegacyPrivateKey(data []byte, passphrase string) (interface{}, error) {
+	if crypto.IsEnvelope(data) {
+		// Versioned pkg/crypto envelope around an openssh-key-v1 file
+		if passphrase == "" {
+			return nil, fmt.Errorf("private key is encrypted, passphrase required")
+		}
+		plaintext, _, err := crypto.DecryptEnvelope(data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		if isOpenSSHFormat(plaintext) {
+			return ssh.ParseRawPrivateKey(plaintext)
+		}
+		data = plaintext
+	} else if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		// Raw salt|nonce|ciphertext blob from pkg/crypto
+		if passphrase == "" {
+			return nil, fmt.Errorf("private key is encrypted, passphrase required")
+		}
+
+		plaintext, err := crypto.DecryptLegacy(data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		data = plaintext
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key data")
+	}
+
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY", "PRIVATE KEY":
+		// Older releases wrapped PKCS#8 bytes in an OPENSSH block
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
+	}
+}
+
+// secureRemove overwrites a regular file with random data, syncs it and
+// removes it. This is best effort: journaling and copy-on-write filesystems
+// or SSDs may keep old blocks around.
+func secureRemove(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().IsRegular() && info.Size() > 0 {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open %s for overwrite: %w", path, err)
+		}
+		_, err = io.CopyN(f, rand.Reader, info.Size())
+		if err == nil {
+			err = f.Sync()
+		}
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to overwrite %s: %w", path, err)
+		}
+	}
+
+	return os.Remove(path)
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into place
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	w, err := fileio.NewAtomicWriter(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := w.Chmod(perm); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := w.Commit(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/pkg/crypto"
+)
+
+func TestGenerateKeyWritesOpenSSHFormat(t *testing.T) {
+	ks, err := NewKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+
+	for _, keyType := range []models.KeyType{models.KeyTypeED25519, models.KeyTypeECDSA, models.KeyTypeRSA} {
+		key, err := ks.GenerateKey("plain-"+string(keyType), keyType, "", 2048)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) failed: %v", keyType, err)
+		}
+
+		data, err := os.ReadFile(key.Path)
+		if err != nil {
+			t.Fatalf("failed to read key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			t.Fatalf("ssh cannot parse %s key: %v", keyType, err)
+		}
+		if got := ssh.FingerprintSHA256(signer.PublicKey()); got != key.Fingerprint {
+			t.Errorf("fingerprint mismatch for %s: %s != %s", keyType, got, key.Fingerprint)
+		}
+	}
+}
+
+func TestGenerateKeyWithPassphrase(t *testing.T) {
+	ks, _ := NewKeyStore(t.TempDir())
+
+	key, err := ks.GenerateKey("protected", models.KeyTypeED25519, "s3cret", 0)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(key.Path)
+	if _, err := ssh.ParsePrivateKey(data); err == nil {
+		t.Fatal("expected encrypted key to require a passphrase")
+	}
+	if _, err := ssh.ParsePrivateKeyWithPassphrase(data, []byte("s3cret")); err != nil {
+		t.Fatalf("ssh cannot decrypt key: %v", err)
+	}
+
+	plaintext, err := ks.LoadPrivateKey(key, "s3cret")
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	if _, err := ssh.ParsePrivateKey(plaintext); err != nil {
+		t.Fatalf("loaded key is not a usable OpenSSH key: %v", err)
+	}
+
+	if _, err := ks.LoadPrivateKey(key, "wrong"); err == nil {
+		t.Fatal("expected wrong passphrase to fail")
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	ks, _ := NewKeyStore(t.TempDir())
+
+	key, err := ks.GenerateKey("plain", models.KeyTypeED25519, "", 0)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	// Add a passphrase
+	if err := ks.ChangePassphrase(key, "", "first"); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	if !key.HasPassphrase {
+		t.Error("expected HasPassphrase after adding a passphrase")
+	}
+	data, _ := os.ReadFile(key.Path)
+	if _, err := ssh.ParsePrivateKeyWithPassphrase(data, []byte("first")); err != nil {
+		t.Fatalf("ssh cannot decrypt key: %v", err)
+	}
+
+	// Change it; the old passphrase must be correct
+	if err := ks.ChangePassphrase(key, "wrong", "second"); err == nil {
+		t.Fatal("expected wrong passphrase to fail")
+	}
+	if err := ks.ChangePassphrase(key, "first", "second"); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	if _, err := ks.LoadPrivateKey(key, "second"); err != nil {
+		t.Fatalf("LoadPrivateKey with new passphrase failed: %v", err)
+	}
+
+	// Remove it
+	if err := ks.ChangePassphrase(key, "second", ""); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	if key.HasPassphrase {
+		t.Error("expected HasPassphrase to be cleared")
+	}
+	data, _ = os.ReadFile(key.Path)
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		t.Fatalf("ssh cannot parse unencrypted key: %v", err)
+	}
+	if got := ssh.FingerprintSHA256(signer.PublicKey()); got != key.Fingerprint {
+		t.Errorf("fingerprint changed: %s != %s", got, key.Fingerprint)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(key.Path))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestMigrateFormat(t *testing.T) {
+	dir := t.TempDir()
+	ks, _ := NewKeyStore(dir)
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal pkcs8: %v", err)
+	}
+	legacyPEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: pkcs8})
+
+	// Unencrypted legacy key: PKCS#8 inside an OPENSSH block
+	plainKey := &models.Key{Name: "legacy-plain", Path: filepath.Join(dir, "legacy-plain")}
+	if err := os.WriteFile(plainKey.Path, legacyPEM, 0600); err != nil {
+		t.Fatalf("write legacy key: %v", err)
+	}
+
+	// Encrypted legacy key: raw salt|nonce|ciphertext blob
+	encrypted, err := crypto.Encrypt(legacyPEM, "pass")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	blob := append(append(encrypted.Salt, encrypted.Nonce...), encrypted.Ciphertext...)
+	encKey := &models.Key{Name: "legacy-enc", Path: filepath.Join(dir, "legacy-enc"), HasPassphrase: true}
+	if err := os.WriteFile(encKey.Path, blob, 0600); err != nil {
+		t.Fatalf("write legacy key: %v", err)
+	}
+
+	for _, tc := range []struct {
+		key        *models.Key
+		passphrase string
+	}{
+		{plainKey, ""},
+		{encKey, "pass"},
+	} {
+		legacy, err := ks.IsLegacyFormat(tc.key)
+		if err != nil || !legacy {
+			t.Fatalf("expected %s to be detected as legacy (err=%v)", tc.key.Name, err)
+		}
+		if err := ks.InstallToSSH(tc.key, t.TempDir()); err == nil {
+			t.Errorf("expected install of legacy key %s to be refused", tc.key.Name)
+		}
+
+		if err := ks.MigrateFormat(tc.key, tc.passphrase); err != nil {
+			t.Fatalf("MigrateFormat(%s) failed: %v", tc.key.Name, err)
+		}
+
+		data, _ := os.ReadFile(tc.key.Path)
+		var signer ssh.Signer
+		if tc.passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(tc.passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(data)
+		}
+		if err != nil {
+			t.Fatalf("migrated key %s not loadable by ssh: %v", tc.key.Name, err)
+		}
+
+		want, _ := ssh.NewSignerFromKey(priv)
+		if ssh.FingerprintSHA256(signer.PublicKey()) != ssh.FingerprintSHA256(want.PublicKey()) {
+			t.Errorf("migrated key %s changed identity", tc.key.Name)
+		}
+	}
+}
+
+func TestRehash(t *testing.T) {
+	dir := t.TempDir()
+	ks, _ := NewKeyStore(dir)
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal pkcs8: %v", err)
+	}
+	legacyPEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: pkcs8})
+	encrypted, err := crypto.Encrypt(legacyPEM, "pass")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	blob := append(append(encrypted.Salt, encrypted.Nonce...), encrypted.Ciphertext...)
+	key := &models.Key{Name: "legacy", Path: filepath.Join(dir, "legacy"), HasPassphrase: true, Fingerprint: "SHA256:test"}
+	if err := os.WriteFile(key.Path, blob, 0600); err != nil {
+		t.Fatalf("write legacy key: %v", err)
+	}
+
+	if needed, err := ks.NeedsRehash(key); err != nil || !needed {
+		t.Fatalf("expected headerless blob to need rehash (err=%v)", err)
+	}
+	if err := ks.Rehash(key, "wrong"); err == nil {
+		t.Fatal("expected wrong passphrase to fail")
+	}
+	if err := ks.Rehash(key, "pass"); err != nil {
+		t.Fatalf("Rehash failed: %v", err)
+	}
+
+	// The rehashed key is a natively encrypted OpenSSH key, which ssh loads
+	// and InstallToSSH accepts
+	if legacy, err := ks.IsLegacyFormat(key); err != nil || legacy {
+		t.Fatalf("expected rehashed key in openssh-key-v1 format (err=%v)", err)
+	}
+	if needed, _ := ks.NeedsRehash(key); needed {
+		t.Error("expected rehashed key to be up to date")
+	}
+
+	data, _ := os.ReadFile(key.Path)
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(data, []byte("pass"))
+	if err != nil {
+		t.Fatalf("rehashed key not loadable by ssh: %v", err)
+	}
+	want, _ := ssh.NewSignerFromKey(priv)
+	if ssh.FingerprintSHA256(signer.PublicKey()) != ssh.FingerprintSHA256(want.PublicKey()) {
+		t.Error("rehashed key changed identity")
+	}
+
+	// Envelopes written by earlier releases are rehashed the same way
+	envelope, err := crypto.EncryptEnvelope(legacyPEM, "pass", key.Fingerprint, crypto.KDFParams{Time: 1, Memory: 64, Threads: 1})
+	if err != nil {
+		t.Fatalf("encrypt envelope: %v", err)
+	}
+	if err := os.WriteFile(key.Path, envelope, 0600); err != nil {
+		t.Fatalf("write envelope: %v", err)
+	}
+
+	// Tampering with the authenticated header is detected
+	tampered := bytes.Replace(envelope, []byte(`"t":1`), []byte(`"t":2`), 1)
+	if _, _, err := crypto.DecryptEnvelope(tampered, "pass"); err == nil {
+		t.Error("expected tampered header to fail authentication")
+	}
+	if needed, _ := ks.NeedsRehash(key); !needed {
+		t.Error("expected envelope to need rehash")
+	}
+	if err := ks.Rehash(key, "pass"); err != nil {
+		t.Fatalf("Rehash of envelope failed: %v", err)
+	}
+	if legacy, _ := ks.IsLegacyFormat(key); legacy {
+		t.Error("expected rehashed envelope in openssh-key-v1 format")
+	}
+
+	// Native OpenSSH keys are left alone
+	native, _ := ks.GenerateKey("native", models.KeyTypeED25519, "pass", 0)
+	if needed, _ := ks.NeedsRehash(native); needed {
+		t.Error("expected OpenSSH key not to need rehash")
+	}
+}
+
+func TestDeleteKeyOverwritesPrivateKey(t *testing.T) {
+	dir := t.TempDir()
+	ks, _ := NewKeyStore(dir)
+
+	key, err := ks.GenerateKey("doomed", models.KeyTypeED25519, "", 0)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	original, _ := os.ReadFile(key.Path)
+
+	// A second link keeps the inode reachable after the key is unlinked
+	link := filepath.Join(dir, "link")
+	if err := os.Link(key.Path, link); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	if err := ks.DeleteKey(key); err != nil {
+		t.Fatalf("DeleteKey failed: %v", err)
+	}
+	if _, err := os.Stat(key.Path); !os.IsNotExist(err) {
+		t.Fatalf("private key still exists: %v", err)
+	}
+
+	remains, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("read link: %v", err)
+	}
+	if len(remains) != len(original) || bytes.Equal(remains, original) {
+		t.Error("private key contents were not overwritten")
+	}
+}
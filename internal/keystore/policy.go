@@ -0,0 +1,55 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyTypeOf returns the key type of an SSH public key
+func KeyTypeOf(pub ssh.PublicKey) (models.KeyType, error) {
+	switch pub.Type() {
+	case ssh.KeyAlgoED25519:
+		return models.KeyTypeED25519, nil
+	case ssh.KeyAlgoRSA:
+		return models.KeyTypeRSA, nil
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return models.KeyTypeECDSA, nil
+	case ssh.KeyAlgoSKED25519:
+		return models.KeyTypeED25519SK, nil
+	case ssh.KeyAlgoSKECDSA256:
+		return models.KeyTypeECDSASK, nil
+	default:
+		return "", fmt.Errorf("unsupported key type: %s", pub.Type())
+	}
+}
+
+// KeySize returns the size in bits of an SSH public key
+func KeySize(pub ssh.PublicKey) int {
+	if cryptoKey, ok := pub.(ssh.CryptoPublicKey); ok {
+		switch k := cryptoKey.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			return k.N.BitLen()
+		case *ecdsa.PublicKey:
+			return k.Curve.Params().BitSize
+		}
+	}
+	// ed25519, ed25519-sk and ecdsa-sk keys have a fixed size
+	return 256
+}
+
+// PublicKeyInfo returns the type and size of an authorized_keys formatted public key
+func PublicKeyInfo(publicKey []byte) (models.KeyType, int, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	keyType, err := KeyTypeOf(pub)
+	if err != nil {
+		return "", 0, err
+	}
+	return keyType, KeySize(pub), nil
+}
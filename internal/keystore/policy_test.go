@@ -0,0 +1,74 @@
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func TestGenerateKeyECDSACurves(t *testing.T) {
+	ks, err := NewKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+
+	for _, bits := range []int{256, 384, 521} {
+		key, err := ks.GenerateKeyWithSize("ecdsa", models.KeyTypeECDSA, "", bits)
+		if err != nil {
+			t.Fatalf("GenerateKeyWithSize(ecdsa, %d) failed: %v", bits, err)
+		}
+		if key.ECDSABits != bits || key.Bits() != bits {
+			t.Errorf("expected %d bits recorded, got %d", bits, key.ECDSABits)
+		}
+
+		pub, err := os.ReadFile(key.PubPath)
+		if err != nil {
+			t.Fatalf("failed to read public key: %v", err)
+		}
+		keyType, size, err := PublicKeyInfo(pub)
+		if err != nil {
+			t.Fatalf("PublicKeyInfo failed: %v", err)
+		}
+		if keyType != models.KeyTypeECDSA || size != bits {
+			t.Errorf("PublicKeyInfo = %s/%d, want ecdsa/%d", keyType, size, bits)
+		}
+	}
+
+	if _, err := ks.GenerateKeyWithSize("bad", models.KeyTypeECDSA, "", 224); err == nil {
+		t.Error("expected unsupported curve to be rejected")
+	}
+}
+
+func TestKeyAlgorithmPolicy(t *testing.T) {
+	pub, err := os.ReadFile(filepath.Join("testdata", "id_ecdsa_sk.pub"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	keyType, bits, err := PublicKeyInfo(pub)
+	if err != nil {
+		t.Fatalf("PublicKeyInfo failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  models.KeyAlgorithmPolicy
+		keyType models.KeyType
+		bits    int
+		wantErr bool
+	}{
+		{"default allows rsa 2048", models.KeyAlgorithmPolicy{}, models.KeyTypeRSA, 2048, false},
+		{"default rejects rsa 1024", models.KeyAlgorithmPolicy{}, models.KeyTypeRSA, 1024, true},
+		{"raised rsa minimum", models.KeyAlgorithmPolicy{MinRSABits: 3072}, models.KeyTypeRSA, 2048, true},
+		{"ecdsa minimum", models.KeyAlgorithmPolicy{MinECDSABits: 384}, models.KeyTypeECDSA, 256, true},
+		{"allowed list", models.KeyAlgorithmPolicy{AllowedAlgorithms: []models.KeyType{models.KeyTypeED25519}}, models.KeyTypeRSA, 4096, true},
+		{"security key allowed", models.KeyAlgorithmPolicy{AllowedAlgorithms: []models.KeyType{models.KeyTypeECDSASK}}, keyType, bits, false},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Check(tt.keyType, tt.bits)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Check(%s, %d) error = %v, wantErr %v", tt.name, tt.keyType, tt.bits, err, tt.wantErr)
+		}
+	}
+}
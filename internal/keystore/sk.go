@@ -0,0 +1,212 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// Flags stored in the key handle file of a FIDO2 key
+const (
+	skUserPresenceRequired     = 0x01
+	skUserVerificationRequired = 0x04
+	skResidentKey              = 0x20
+)
+
+// ImportSecurityKey copies a FIDO2 key handle file and its public key into the
+// keystore. The private key itself stays on the authenticator.
+func (ks *KeyStore) ImportSecurityKey(name, handlePath string) (*models.Key, error) {
+	handleData, err := os.ReadFile(handlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key handle: %w", err)
+	}
+	publicData, err := os.ReadFile(handlePath + ".pub")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(publicData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	keyType, err := KeyTypeOf(pub)
+	if err != nil {
+		return nil, err
+	}
+	if !keyType.IsSecurityKey() {
+		return nil, fmt.Errorf("%s is not a security key (type %s)", handlePath, keyType)
+	}
+
+	info, err := ParseSecurityKey(handleData, publicData)
+	if err != nil {
+		return nil, err
+	}
+
+	privatePath := filepath.Join(ks.basePath, name)
+	publicPath := privatePath + ".pub"
+	if _, err := os.Stat(privatePath); err == nil {
+		return nil, fmt.Errorf("key file already exists: %s", privatePath)
+	}
+
+	if err := writeFileAtomic(privatePath, handleData, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key handle: %w", err)
+	}
+	if err := writeFileAtomic(publicPath, publicData, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	return &models.Key{
+		Name:          name,
+		Type:          keyType,
+		Path:          privatePath,
+		PubPath:       publicPath,
+		Tags:          []string{},
+		Comment:       comment,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		Fingerprint:   ssh.FingerprintSHA256(pub),
+		HasPassphrase: !info.FlagsKnown,
+		SecurityKey:   info,
+	}, nil
+}
+
+// ParseSecurityKey reads the FIDO2 metadata of an sk key from its public key and
+// key handle file. Flags can only be read from key handles without a passphrase.
+func ParseSecurityKey(privateData, publicData []byte) (*models.SecurityKeyInfo, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(publicData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	application, err := skApplication(pub)
+	if err != nil {
+		return nil, err
+	}
+	info := &models.SecurityKeyInfo{Application: application}
+
+	block, _ := pem.Decode(privateData)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, fmt.Errorf("key handle is not an OpenSSH private key file")
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(openSSHMagic)) {
+		return nil, fmt.Errorf("key handle is not in openssh-key-v1 format")
+	}
+
+	var envelope struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}
+	if err := ssh.Unmarshal(block.Bytes[len(openSSHMagic):], &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse key handle: %w", err)
+	}
+	if envelope.NumKeys != 1 {
+		return nil, fmt.Errorf("key handle file holds %d keys, expected 1", envelope.NumKeys)
+	}
+	if !bytes.Equal(envelope.PubKey, pub.Marshal()) {
+		return nil, fmt.Errorf("key handle does not match the public key")
+	}
+
+	if envelope.CipherName != "none" {
+		// The flags are inside the encrypted section
+		return info, nil
+	}
+
+	flags, err := skFlags(pub.Type(), envelope.PrivKeyBlock)
+	if err != nil {
+		return nil, err
+	}
+	info.Resident = flags&skResidentKey != 0
+	info.VerifyRequired = flags&skUserVerificationRequired != 0
+	info.NoTouchRequired = flags&skUserPresenceRequired == 0
+	info.FlagsKnown = true
+
+	return info, nil
+}
+
+// skApplication extracts the FIDO application string from an sk public key
+func skApplication(pub ssh.PublicKey) (string, error) {
+	switch pub.Type() {
+	case ssh.KeyAlgoSKED25519:
+		var w struct {
+			Type        string
+			PubKey      []byte
+			Application string
+		}
+		if err := ssh.Unmarshal(pub.Marshal(), &w); err != nil {
+			return "", fmt.Errorf("failed to parse security key: %w", err)
+		}
+		return w.Application, nil
+	case ssh.KeyAlgoSKECDSA256:
+		var w struct {
+			Type        string
+			Curve       string
+			KeyBytes    []byte
+			Application string
+		}
+		if err := ssh.Unmarshal(pub.Marshal(), &w); err != nil {
+			return "", fmt.Errorf("failed to parse security key: %w", err)
+		}
+		return w.Application, nil
+	default:
+		return "", fmt.Errorf("not a security key: %s", pub.Type())
+	}
+}
+
+// skFlags reads the authenticator flags from an unencrypted private key section
+func skFlags(keyType string, privKeyBlock []byte) (uint8, error) {
+	var section struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Rest    []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(privKeyBlock, &section); err != nil {
+		return 0, fmt.Errorf("failed to parse key handle: %w", err)
+	}
+	if section.Check1 != section.Check2 {
+		return 0, fmt.Errorf("key handle is corrupt")
+	}
+	if section.Keytype != keyType {
+		return 0, fmt.Errorf("key handle type %s does not match public key type %s", section.Keytype, keyType)
+	}
+
+	switch keyType {
+	case ssh.KeyAlgoSKED25519:
+		var key struct {
+			PubKey      []byte
+			Application string
+			Flags       uint8
+			KeyHandle   []byte
+			Reserved    []byte
+			Rest        []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(section.Rest, &key); err != nil {
+			return 0, fmt.Errorf("failed to parse key handle: %w", err)
+		}
+		return key.Flags, nil
+	default:
+		var key struct {
+			Curve       string
+			PubKey      []byte
+			Application string
+			Flags       uint8
+			KeyHandle   []byte
+			Reserved    []byte
+			Rest        []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(section.Rest, &key); err != nil {
+			return 0, fmt.Errorf("failed to parse key handle: %w", err)
+		}
+		return key.Flags, nil
+	}
+}
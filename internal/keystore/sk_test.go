@@ -0,0 +1,79 @@
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func TestParseSecurityKey(t *testing.T) {
+	tests := []struct {
+		file string
+		want models.SecurityKeyInfo
+	}{
+		{"id_ed25519_sk", models.SecurityKeyInfo{Application: "ssh:", Resident: true, FlagsKnown: true}},
+		{"id_ecdsa_sk", models.SecurityKeyInfo{Application: "ssh:work", VerifyRequired: true, FlagsKnown: true}},
+	}
+
+	for _, tt := range tests {
+		handle, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		pub, err := os.ReadFile(filepath.Join("testdata", tt.file+".pub"))
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+
+		info, err := ParseSecurityKey(handle, pub)
+		if err != nil {
+			t.Fatalf("ParseSecurityKey(%s) failed: %v", tt.file, err)
+		}
+		if *info != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.file, *info, tt.want)
+		}
+	}
+
+	// A key handle must match its public key
+	handle, _ := os.ReadFile(filepath.Join("testdata", "id_ed25519_sk"))
+	pub, _ := os.ReadFile(filepath.Join("testdata", "id_ecdsa_sk.pub"))
+	if _, err := ParseSecurityKey(handle, pub); err == nil {
+		t.Error("expected mismatched key handle to be rejected")
+	}
+}
+
+func TestImportSecurityKey(t *testing.T) {
+	ks, err := NewKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+
+	key, err := ks.ImportSecurityKey("yubikey", filepath.Join("testdata", "id_ed25519_sk"))
+	if err != nil {
+		t.Fatalf("ImportSecurityKey failed: %v", err)
+	}
+	if key.Type != models.KeyTypeED25519SK {
+		t.Errorf("unexpected type %s", key.Type)
+	}
+	if key.Comment != "alice@yubikey" {
+		t.Errorf("unexpected comment %q", key.Comment)
+	}
+	if key.SecurityKey == nil || !key.SecurityKey.Resident {
+		t.Errorf("expected resident security key, got %+v", key.SecurityKey)
+	}
+	if key.HasPassphrase {
+		t.Error("unencrypted key handle reported as having a passphrase")
+	}
+	if _, err := os.Stat(key.Path); err != nil {
+		t.Errorf("key handle not copied: %v", err)
+	}
+	if got := filepath.Base(ks.InstalledPath(key, "/ssh")); got != "id_ed25519-sk_yubikey" {
+		t.Errorf("unexpected installed name %s", got)
+	}
+
+	if _, err := ks.ImportSecurityKey("yubikey", filepath.Join("testdata", "id_ed25519_sk")); err == nil {
+		t.Error("expected importing over an existing key to fail")
+	}
+}
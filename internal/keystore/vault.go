@@ -0,0 +1,261 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/all-dot-files/ssh-key-manager/pkg/crypto"
+)
+
+// VaultFile is the name of the vault inside the keystore directory
+const VaultFile = "vault.json"
+
+// vaultVersion is the vault format written by this release
+const vaultVersion = 1
+
+// vaultCheck is sealed under the master key to verify the master passphrase
+var vaultCheck = []byte("skm-vault")
+
+var (
+	// ErrVaultLocked is returned when the master key is needed but not known
+	ErrVaultLocked = errors.New("vault is locked")
+
+	// ErrNotInVault is returned for keys without a data key in the vault
+	ErrNotInVault = errors.New("key is not in the vault")
+)
+
+// Vault wraps a random data key per private key under one master key derived
+// from the master passphrase. Each key file is encrypted natively with its
+// data key, so changing the master passphrase only re-wraps the data keys.
+type Vault struct {
+	path string
+	data vaultData
+
+	// master is the derived master key, nil while locked
+	master []byte
+}
+
+// vaultData is the on-disk vault
+type vaultData struct {
+	Version int               `json:"version"`
+	Salt    []byte            `json:"salt"`
+	Check   []byte            `json:"check"`
+	Keys    map[string][]byte `json:"keys"` // key name -> sealed data key
+}
+
+// VaultPath returns the path of the vault in this keystore
+func (ks *KeyStore) VaultPath() string {
+	return filepath.Join(ks.basePath, VaultFile)
+}
+
+// CreateVault creates a new, unlocked vault protected by masterPassphrase
+func (ks *KeyStore) CreateVault(masterPassphrase string) (*Vault, error) {
+	path := ks.VaultPath()
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("vault already exists at %s", path)
+	}
+
+	v := &Vault{path: path, data: vaultData{Version: vaultVersion, Keys: make(map[string][]byte)}}
+	if err := v.setMaster(masterPassphrase); err != nil {
+		return nil, err
+	}
+	if err := v.save(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// OpenVault loads the vault of this keystore. The vault starts locked.
+func (ks *KeyStore) OpenVault() (*Vault, error) {
+	data, err := os.ReadFile(ks.VaultPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("vault mode is not enabled (run: skm vault init)")
+		}
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	v := &Vault{path: ks.VaultPath()}
+	if err := json.Unmarshal(data, &v.data); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if v.data.Version != vaultVersion {
+		return nil, fmt.Errorf("unsupported vault version %d", v.data.Version)
+	}
+	if v.data.Keys == nil {
+		v.data.Keys = make(map[string][]byte)
+	}
+	return v, nil
+}
+
+// Unlock derives the master key from the master passphrase
+func (v *Vault) Unlock(masterPassphrase string) error {
+	return v.UnlockWithKey(crypto.DeriveKey(masterPassphrase, v.data.Salt))
+}
+
+// UnlockWithKey unlocks the vault with a master key obtained earlier, such as
+// one held by an unlocked session
+func (v *Vault) UnlockWithKey(master []byte) error {
+	if _, err := crypto.OpenWithKey(master, v.data.Check, nil); err != nil {
+		return fmt.Errorf("incorrect master passphrase")
+	}
+	v.master = append([]byte(nil), master...)
+	return nil
+}
+
+// MasterKey returns the derived master key of an unlocked vault
+func (v *Vault) MasterKey() ([]byte, error) {
+	if v.master == nil {
+		return nil, ErrVaultLocked
+	}
+	return v.master, nil
+}
+
+// Lock forgets the master key
+func (v *Vault) Lock() {
+	for i := range v.master {
+		v.master[i] = 0
+	}
+	v.master = nil
+}
+
+// Has reports whether the vault holds a data key for the named key
+func (v *Vault) Has(name string) bool {
+	_, ok := v.data.Keys[name]
+	return ok
+}
+
+// Keys returns the names of the keys in the vault
+func (v *Vault) Keys() []string {
+	names := make([]string, 0, len(v.data.Keys))
+	for name := range v.data.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DataKey returns the passphrase the named key file is encrypted with
+func (v *Vault) DataKey(name string) (string, error) {
+	if v.master == nil {
+		return "", ErrVaultLocked
+	}
+	sealed, ok := v.data.Keys[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotInVault, name)
+	}
+
+	dataKey, err := crypto.OpenWithKey(v.master, sealed, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key of %s: %w", name, err)
+	}
+	return string(dataKey), nil
+}
+
+// AddKey creates and stores a new data key for the named key and returns it.
+// The caller encrypts the key file with it.
+func (v *Vault) AddKey(name string) (string, error) {
+	if v.master == nil {
+		return "", ErrVaultLocked
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataKey := base64.RawURLEncoding.EncodeToString(raw)
+
+	sealed, err := crypto.SealWithKey(v.master, []byte(dataKey), []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	v.data.Keys[name] = sealed
+	if err := v.save(); err != nil {
+		delete(v.data.Keys, name)
+		return "", err
+	}
+	return dataKey, nil
+}
+
+// RemoveKey drops the data key of the named key
+func (v *Vault) RemoveKey(name string) error {
+	if !v.Has(name) {
+		return nil
+	}
+	delete(v.data.Keys, name)
+	return v.save()
+}
+
+// ChangeMaster re-wraps every data key under a new master passphrase. Key
+// files are not touched.
+func (v *Vault) ChangeMaster(newPassphrase string) error {
+	if v.master == nil {
+		return ErrVaultLocked
+	}
+
+	dataKeys := make(map[string][]byte, len(v.data.Keys))
+	for name := range v.data.Keys {
+		dataKey, err := v.DataKey(name)
+		if err != nil {
+			return err
+		}
+		dataKeys[name] = []byte(dataKey)
+	}
+
+	next := &Vault{path: v.path, data: vaultData{Version: vaultVersion, Keys: make(map[string][]byte)}}
+	if err := next.setMaster(newPassphrase); err != nil {
+		return err
+	}
+	for name, dataKey := range dataKeys {
+		sealed, err := crypto.SealWithKey(next.master, dataKey, []byte(name))
+		if err != nil {
+			return fmt.Errorf("failed to wrap data key: %w", err)
+		}
+		next.data.Keys[name] = sealed
+	}
+
+	if err := next.save(); err != nil {
+		return err
+	}
+	v.Lock()
+	*v = *next
+	return nil
+}
+
+// setMaster derives a master key with a fresh salt
+func (v *Vault) setMaster(masterPassphrase string) error {
+	if masterPassphrase == "" {
+		return fmt.Errorf("empty master passphrase")
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return err
+	}
+	master := crypto.DeriveKey(masterPassphrase, salt)
+
+	check, err := crypto.SealWithKey(master, vaultCheck, nil)
+	if err != nil {
+		return fmt.Errorf("failed to seal vault check: %w", err)
+	}
+
+	v.data.Salt = salt
+	v.data.Check = check
+	v.master = master
+	return nil
+}
+
+// save writes the vault atomically
+func (v *Vault) save() error {
+	data, err := json.MarshalIndent(v.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+	return writeFileAtomic(v.path, data, 0600)
+}
@@ -0,0 +1,68 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func TestVault(t *testing.T) {
+	ks, _ := NewKeyStore(t.TempDir())
+
+	key, err := ks.GenerateKey("work", models.KeyTypeED25519, "", 0)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	v, err := ks.CreateVault("master-one")
+	if err != nil {
+		t.Fatalf("CreateVault failed: %v", err)
+	}
+	dataKey, err := v.AddKey(key.Name)
+	if err != nil {
+		t.Fatalf("AddKey failed: %v", err)
+	}
+	if err := ks.ChangePassphrase(key, "", dataKey); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	keyFile, _ := os.ReadFile(key.Path)
+
+	// A new process has to unlock the vault first
+	reopened, err := ks.OpenVault()
+	if err != nil {
+		t.Fatalf("OpenVault failed: %v", err)
+	}
+	if _, err := reopened.DataKey(key.Name); err != ErrVaultLocked {
+		t.Fatalf("expected ErrVaultLocked, got %v", err)
+	}
+	if err := reopened.Unlock("wrong"); err == nil {
+		t.Fatal("expected the wrong master passphrase to fail")
+	}
+	if err := reopened.Unlock("master-one"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	if err := reopened.ChangeMaster("master-two"); err != nil {
+		t.Fatalf("ChangeMaster failed: %v", err)
+	}
+	if after, _ := os.ReadFile(key.Path); !bytes.Equal(after, keyFile) {
+		t.Error("changing the master passphrase rewrote the key file")
+	}
+
+	final, _ := ks.OpenVault()
+	if err := final.Unlock("master-one"); err == nil {
+		t.Fatal("old master passphrase still unlocks the vault")
+	}
+	if err := final.Unlock("master-two"); err != nil {
+		t.Fatalf("Unlock with new master failed: %v", err)
+	}
+	unwrapped, err := final.DataKey(key.Name)
+	if err != nil {
+		t.Fatalf("DataKey failed: %v", err)
+	}
+	if _, err := ks.ParsePrivateKey(key, unwrapped); err != nil {
+		t.Fatalf("key does not open with its data key: %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package knownhosts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// DefaultFileName is the name of the skm-owned known_hosts file in the config directory
+const DefaultFileName = "known_hosts"
+
+// DefaultTimeout bounds each connection made while scanning a host
+const DefaultTimeout = 10 * time.Second
+
+// scanAlgorithms are requested one at a time so every host key type is collected
+var scanAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512,
+}
+
+var errKeyCaptured = errors.New("knownhosts: host key captured")
+
+// Address returns the host:port ssh connects to for a managed host
+func Address(host *models.Host) string {
+	port := host.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host.RemoteName(), strconv.Itoa(port))
+}
+
+// Normalize returns the known_hosts form of a host's address, which
+// identifies the host across devices
+func Normalize(host *models.Host) string {
+	return knownhosts.Normalize(Address(host))
+}
+
+// Scan connects to address and returns the host keys it offers
+func Scan(address string, timeout time.Duration) ([]ssh.PublicKey, error) {
+	var keys []ssh.PublicKey
+	var lastErr error
+	for _, algorithm := range scanAlgorithms {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			// The host is unreachable; other algorithms will not fare better
+			return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+
+		key, err := scanKey(conn, address, algorithm, timeout)
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("failed to fetch host keys from %s: %w", address, lastErr)
+	}
+	return keys, nil
+}
+
+// scanKey performs a key exchange restricted to one host key algorithm and
+// returns the key presented by the server. No authentication is attempted.
+func scanKey(conn net.Conn, address, algorithm string, timeout time.Duration) (ssh.PublicKey, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var captured ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "skm",
+		HostKeyAlgorithms: []string{algorithm},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			captured = key
+			return errKeyCaptured
+		},
+		Timeout: timeout,
+	}
+
+	client, _, _, err := ssh.NewClientConn(conn, address, config)
+	if client != nil {
+		client.Close()
+	}
+	if captured != nil {
+		return captured, nil
+	}
+	return nil, err
+}
+
+// Pin converts scanned keys into pinned host key entries
+func Pin(keys []ssh.PublicKey, now time.Time) []models.HostKey {
+	pinned := make([]models.HostKey, 0, len(keys))
+	for _, key := range keys {
+		pinned = append(pinned, models.HostKey{
+			PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			PinnedAt:    now,
+		})
+	}
+	return pinned
+}
+
+// PinnedAt returns when the most recent of the keys was pinned
+func PinnedAt(keys []models.HostKey) time.Time {
+	var latest time.Time
+	for _, k := range keys {
+		if k.PinnedAt.After(latest) {
+			latest = k.PinnedAt
+		}
+	}
+	return latest
+}
+
+// SameKeys reports whether two sets of pinned keys hold the same fingerprints
+func SameKeys(a, b []models.HostKey) bool {
+	return !CompareKeys(a, b).Changed()
+}
+
+// Diff describes how the keys a host presents differ from its pinned keys
+type Diff struct {
+	Added     []models.HostKey
+	Removed   []models.HostKey
+	Unchanged []models.HostKey
+}
+
+// Compare compares pinned host keys with the keys a host presented
+func Compare(pinned []models.HostKey, seen []ssh.PublicKey) Diff {
+	return CompareKeys(pinned, Pin(seen, time.Now()))
+}
+
+// CompareKeys compares two sets of pinned host keys
+func CompareKeys(pinned, seen []models.HostKey) Diff {
+	var diff Diff
+
+	pinnedSet := make(map[string]bool, len(pinned))
+	for _, k := range pinned {
+		pinnedSet[k.Fingerprint] = true
+	}
+	seenSet := make(map[string]bool, len(seen))
+	for _, k := range seen {
+		seenSet[k.Fingerprint] = true
+		if pinnedSet[k.Fingerprint] {
+			diff.Unchanged = append(diff.Unchanged, k)
+		} else {
+			diff.Added = append(diff.Added, k)
+		}
+	}
+	for _, k := range pinned {
+		if !seenSet[k.Fingerprint] {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	return diff
+}
+
+// Changed reports whether the host presented different keys than were pinned
+func (d Diff) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
+}
+
+// String renders the diff with - for pinned keys no longer offered and + for new keys
+func (d Diff) String() string {
+	var lines []string
+	for _, k := range d.Removed {
+		lines = append(lines, "- "+keyLabel(k))
+	}
+	for _, k := range d.Added {
+		lines = append(lines, "+ "+keyLabel(k))
+	}
+	for _, k := range d.Unchanged {
+		lines = append(lines, "  "+keyLabel(k))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// keyLabel describes a host key by type and fingerprint
+func keyLabel(k models.HostKey) string {
+	keyType, _, _ := strings.Cut(k.PublicKey, " ")
+	return fmt.Sprintf("%s %s", keyType, k.Fingerprint)
+}
+
+// WriteFile writes a known_hosts file holding the pinned keys of hosts
+func WriteFile(path string, hosts []models.Host) error {
+	var lines []string
+	for i := range hosts {
+		host := &hosts[i]
+		address := Normalize(host)
+		for _, k := range host.HostKeys {
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.PublicKey))
+			if err != nil {
+				return fmt.Errorf("invalid pinned key for host %s: %w", host.Host, err)
+			}
+			lines = append(lines, knownhosts.Line([]string{address}, key))
+		}
+	}
+	sort.Strings(lines)
+
+	content := "# Managed by SKM. Do not edit manually; use 'skm host trust'.\n"
+	if len(lines) > 0 {
+		content += strings.Join(lines, "\n") + "\n"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package knownhosts
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func newHostSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+// startServer runs an SSH server that completes key exchange and rejects all auth
+func startServer(t *testing.T, signer ssh.Signer) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestScanAndCompare(t *testing.T) {
+	signer := newHostSigner(t)
+	address := startServer(t, signer)
+
+	keys, err := Scan(address, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(keys) != 1 || ssh.FingerprintSHA256(keys[0]) != ssh.FingerprintSHA256(signer.PublicKey()) {
+		t.Fatalf("unexpected scanned keys: %v", keys)
+	}
+
+	pinned := Pin(keys, time.Now())
+	if diff := Compare(pinned, keys); diff.Changed() {
+		t.Errorf("expected no change, got:\n%s", diff)
+	}
+
+	other := newHostSigner(t)
+	diff := Compare(pinned, []ssh.PublicKey{other.PublicKey()})
+	if !diff.Changed() || len(diff.Added) != 1 || len(diff.Removed) != 1 {
+		t.Errorf("expected one added and one removed key, got:\n%s", diff)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	signer := newHostSigner(t)
+	pinned := Pin([]ssh.PublicKey{signer.PublicKey()}, time.Now())
+
+	hosts := []models.Host{
+		{Host: "prod", Hostname: "10.0.0.5", Port: 2222, HostKeys: pinned},
+		{Host: "unpinned.example.com"},
+	}
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := WriteFile(path, hosts); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		t.Fatalf("written file is not a valid known_hosts file: %v", err)
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 2222}
+	if err := callback(net.JoinHostPort("10.0.0.5", strconv.Itoa(2222)), remote, signer.PublicKey()); err != nil {
+		t.Errorf("pinned key not accepted: %v", err)
+	}
+	if err := callback(net.JoinHostPort("10.0.0.5", strconv.Itoa(2222)), remote, newHostSigner(t).PublicKey()); err == nil {
+		t.Error("expected a different key to be rejected")
+	}
+}
@@ -0,0 +1,206 @@
+// Package krl writes OpenSSH key revocation lists in the binary format read
+// by sshd's RevokedKeys and ssh-keygen -Q (see PROTOCOL.krl in OpenSSH).
+package krl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Format constants from PROTOCOL.krl
+const (
+	magic         = 0x5353484b524c0a00 // "SSHKRL\n\0"
+	formatVersion = 1
+
+	sectionCertificates      = 1
+	sectionExplicitKey       = 2
+	sectionFingerprintSHA256 = 5
+
+	certSectionSerialList = 0x20
+)
+
+// KRL is a key revocation list
+type KRL struct {
+	// Version increases with every generated list so hosts can tell which
+	// list is newer
+	Version uint64
+	// GeneratedAt is the generation time stored in the list
+	GeneratedAt time.Time
+	// Comment is free text stored in the list
+	Comment string
+
+	keys         map[string][]byte     // public key blob by string(blob)
+	fingerprints map[[32]byte]string   // SHA256 hash -> fingerprint
+	serials      map[string]*caSerials // certificate serials by CA key blob
+}
+
+// caSerials holds the revoked certificate serials of one CA
+type caSerials struct {
+	caKey   []byte
+	serials map[uint64]bool
+}
+
+// New creates an empty revocation list
+func New(version uint64, comment string) *KRL {
+	return &KRL{
+		Version:      version,
+		GeneratedAt:  time.Now(),
+		Comment:      comment,
+		keys:         make(map[string][]byte),
+		fingerprints: make(map[[32]byte]string),
+		serials:      make(map[string]*caSerials),
+	}
+}
+
+// RevokeKey revokes a public key. Certificates are revoked by their key.
+func (k *KRL) RevokeKey(pub ssh.PublicKey) {
+	if cert, ok := pub.(*ssh.Certificate); ok {
+		pub = cert.Key
+	}
+	blob := pub.Marshal()
+	k.keys[string(blob)] = blob
+}
+
+// RevokeFingerprint revokes a key known only by its SHA256 fingerprint
+// ("SHA256:..." as printed by ssh-keygen -l). Fingerprint revocation needs
+// OpenSSH 7.9 or newer on the host.
+func (k *KRL) RevokeFingerprint(fingerprint string) error {
+	encoded, ok := strings.CutPrefix(fingerprint, "SHA256:")
+	if !ok {
+		return fmt.Errorf("unsupported fingerprint %q: expected SHA256:...", fingerprint)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("invalid SHA256 fingerprint %q", fingerprint)
+	}
+
+	var h [32]byte
+	copy(h[:], hash)
+	k.fingerprints[h] = fingerprint
+	return nil
+}
+
+// RevokeCertSerial revokes the certificate with serial signed by ca
+func (k *KRL) RevokeCertSerial(ca ssh.PublicKey, serial uint64) {
+	blob := ca.Marshal()
+	entry, ok := k.serials[string(blob)]
+	if !ok {
+		entry = &caSerials{caKey: blob, serials: make(map[uint64]bool)}
+		k.serials[string(blob)] = entry
+	}
+	entry.serials[serial] = true
+}
+
+// Len returns the number of revoked keys, fingerprints and certificate serials
+func (k *KRL) Len() int {
+	n := len(k.keys) + len(k.fingerprints)
+	for _, entry := range k.serials {
+		n += len(entry.serials)
+	}
+	return n
+}
+
+// Marshal encodes the list in the binary KRL format
+func (k *KRL) Marshal() []byte {
+	var buf bytes.Buffer
+	writeUint64(&buf, magic)
+	writeUint32(&buf, formatVersion)
+	writeUint64(&buf, k.Version)
+	writeUint64(&buf, uint64(k.GeneratedAt.Unix()))
+	writeUint64(&buf, 0) // flags
+	writeString(&buf, nil)
+	writeString(&buf, []byte(k.Comment))
+
+	cas := make([]*caSerials, 0, len(k.serials))
+	for _, entry := range k.serials {
+		cas = append(cas, entry)
+	}
+	sort.Slice(cas, func(i, j int) bool { return bytes.Compare(cas[i].caKey, cas[j].caKey) < 0 })
+	for _, entry := range cas {
+		writeCertSection(&buf, entry)
+	}
+
+	if len(k.keys) > 0 {
+		blobs := make([][]byte, 0, len(k.keys))
+		for _, blob := range k.keys {
+			blobs = append(blobs, blob)
+		}
+		writeSection(&buf, sectionExplicitKey, blobs)
+	}
+
+	if len(k.fingerprints) > 0 {
+		hashes := make([][]byte, 0, len(k.fingerprints))
+		for h := range k.fingerprints {
+			hashes = append(hashes, append([]byte(nil), h[:]...))
+		}
+		writeSection(&buf, sectionFingerprintSHA256, hashes)
+	}
+
+	return buf.Bytes()
+}
+
+// writeSection writes a section holding a sorted list of strings
+func writeSection(buf *bytes.Buffer, sectionType byte, items [][]byte) {
+	sort.Slice(items, func(i, j int) bool {
+		if len(items[i]) != len(items[j]) {
+			return len(items[i]) < len(items[j])
+		}
+		return bytes.Compare(items[i], items[j]) < 0
+	})
+
+	var data bytes.Buffer
+	for _, item := range items {
+		writeString(&data, item)
+	}
+
+	buf.WriteByte(sectionType)
+	writeString(buf, data.Bytes())
+}
+
+// writeCertSection writes the revoked serials of one CA
+func writeCertSection(buf *bytes.Buffer, entry *caSerials) {
+	serials := make([]uint64, 0, len(entry.serials))
+	for serial := range entry.serials {
+		serials = append(serials, serial)
+	}
+	sort.Slice(serials, func(i, j int) bool { return serials[i] < serials[j] })
+
+	var list bytes.Buffer
+	for _, serial := range serials {
+		writeUint64(&list, serial)
+	}
+
+	var data bytes.Buffer
+	writeString(&data, entry.caKey)
+	writeString(&data, nil) // reserved
+	data.WriteByte(certSectionSerialList)
+	writeString(&data, list.Bytes())
+
+	buf.WriteByte(sectionCertificates)
+	writeString(buf, data.Bytes())
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeString(buf *bytes.Buffer, s []byte) {
+	writeUint32(buf, uint32(len(s)))
+	buf.Write(s)
+}
@@ -0,0 +1,102 @@
+package krl
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("convert key: %v", err)
+	}
+	return sshPub
+}
+
+func newCertificate(t *testing.T, serial uint64) (*ssh.Certificate, ssh.PublicKey) {
+	t.Helper()
+	_, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(caPriv)
+	if err != nil {
+		t.Fatalf("create CA signer: %v", err)
+	}
+	cert := &ssh.Certificate{
+		Key:             newPublicKey(t),
+		Serial:          serial,
+		CertType:        ssh.UserCert,
+		KeyId:           "test",
+		ValidPrincipals: []string{"test"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		t.Fatalf("sign certificate: %v", err)
+	}
+	return cert, signer.PublicKey()
+}
+
+func TestKRLAcceptedBySSHKeygen(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+
+	explicit := newPublicKey(t)
+	byFingerprint := newPublicKey(t)
+	valid := newPublicKey(t)
+	revokedCert, caKey := newCertificate(t, 42)
+	validCert, _ := newCertificate(t, 42)
+
+	list := New(1, "test")
+	list.RevokeKey(explicit)
+	if err := list.RevokeFingerprint(ssh.FingerprintSHA256(byFingerprint)); err != nil {
+		t.Fatalf("RevokeFingerprint failed: %v", err)
+	}
+	list.RevokeCertSerial(caKey, 42)
+	if err := list.RevokeFingerprint("MD5:00:11"); err == nil {
+		t.Error("expected MD5 fingerprint to be rejected")
+	}
+
+	dir := t.TempDir()
+	krlPath := filepath.Join(dir, "revoked.krl")
+	if err := os.WriteFile(krlPath, list.Marshal(), 0644); err != nil {
+		t.Fatalf("write krl: %v", err)
+	}
+
+	query := func(pub ssh.PublicKey) bool {
+		keyPath := filepath.Join(dir, "key.pub")
+		if err := os.WriteFile(keyPath, ssh.MarshalAuthorizedKey(pub), 0644); err != nil {
+			t.Fatalf("write key: %v", err)
+		}
+		// ssh-keygen -Q exits non-zero when the key is revoked
+		return exec.Command("ssh-keygen", "-Q", "-f", krlPath, keyPath).Run() != nil
+	}
+
+	if !query(explicit) {
+		t.Error("explicitly revoked key is not revoked")
+	}
+	if !query(byFingerprint) {
+		t.Error("key revoked by fingerprint is not revoked")
+	}
+	if query(valid) {
+		t.Error("unrelated key is reported as revoked")
+	}
+	if !query(revokedCert) {
+		t.Error("certificate revoked by serial is not revoked")
+	}
+	if query(validCert) {
+		t.Error("certificate with the same serial from another CA is reported as revoked")
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -25,6 +26,9 @@ type Config struct {
 	KeystorePath string `yaml:"keystore_path" json:"keystore_path"`
 	SSHDir       string `yaml:"ssh_dir" json:"ssh_dir"`
 
+	// SSHConfigMode is where managed hosts are written (empty = include)
+	SSHConfigMode string `yaml:"ssh_config_mode,omitempty" json:"ssh_config_mode,omitempty"`
+
 	// Policies
 	DefaultKeyPolicy KeyPolicy  `yaml:"default_key_policy" json:"default_key_policy"`
 	SyncPolicy       SyncPolicy `yaml:"sync_policy" json:"sync_policy"`
@@ -32,6 +36,28 @@ type Config struct {
 	// Key rotation policy
 	KeyRotationPolicy KeyRotationPolicy `yaml:"key_rotation_policy,omitempty" json:"key_rotation_policy,omitempty"`
 
+	// Key algorithms and sizes accepted for generated, imported and pulled keys
+	KeyAlgorithmPolicy KeyAlgorithmPolicy `yaml:"key_algorithm_policy,omitempty" json:"key_algorithm_policy,omitempty"`
+
+	// Argon2id cost of private keys encrypted for sync
+	KeyEncryption KeyEncryptionConfig `yaml:"key_encryption,omitempty" json:"key_encryption,omitempty"`
+
+	// Where the server token and cached key passphrases are kept
+	Secrets SecretsConfig `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+
+	// Built-in SSH agent
+	Agent AgentConfig `yaml:"agent,omitempty" json:"agent,omitempty"`
+
+	// Host groups with defaults for the hosts matching their patterns, in
+	// order of precedence
+	HostGroups []HostGroup `yaml:"host_groups,omitempty" json:"host_groups,omitempty"`
+
+	// Host certificate authorities trusted through known_hosts
+	HostCAs []HostCA `yaml:"host_cas,omitempty" json:"host_cas,omitempty"`
+
+	// Rules selecting the key for Git remotes by URL pattern
+	GitKeyRules []GitKeyRule `yaml:"git_key_rules,omitempty" json:"git_key_rules,omitempty"`
+
 	// Debug mode
 	Debug bool `yaml:"debug,omitempty" json:"debug,omitempty"`
 
@@ -45,11 +71,132 @@ type Config struct {
 	// Sync metadata
 	LastSync *time.Time `yaml:"last_sync,omitempty" json:"last_sync,omitempty"`
 
+	// Tombstones of deleted keys, synced so other devices drop them too
+	Tombstones []KeyTombstone `yaml:"tombstones,omitempty" json:"tombstones,omitempty"`
+
 	// Metadata
 	Version   string    `yaml:"version" json:"version"`
 	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
 }
 
+// SSH config modes
+const (
+	// SSHConfigModeInclude writes managed hosts to <ssh_dir>/skm/config,
+	// included from the top of the user config
+	SSHConfigModeInclude = "include"
+	// SSHConfigModeInline keeps managed hosts in a block at the end of the user config
+	SSHConfigModeInline = "inline"
+)
+
+// Default minimum key sizes of the key algorithm policy
+const (
+	DefaultMinRSABits   = 2048
+	DefaultMinECDSABits = 256
+)
+
+// KeyAlgorithmPolicy restricts the key types and sizes SKM accepts
+type KeyAlgorithmPolicy struct {
+	// AllowedAlgorithms lists the accepted key types (empty = all supported types)
+	AllowedAlgorithms []KeyType `yaml:"allowed_algorithms,omitempty" json:"allowed_algorithms,omitempty"`
+
+	// MinRSABits is the smallest accepted RSA modulus (0 = DefaultMinRSABits)
+	MinRSABits int `yaml:"min_rsa_bits,omitempty" json:"min_rsa_bits,omitempty"`
+
+	// MinECDSABits is the smallest accepted ECDSA curve (0 = DefaultMinECDSABits)
+	MinECDSABits int `yaml:"min_ecdsa_bits,omitempty" json:"min_ecdsa_bits,omitempty"`
+}
+
+// Check returns an error when a key of the given type and size violates the policy
+func (p KeyAlgorithmPolicy) Check(keyType KeyType, bits int) error {
+	if len(p.AllowedAlgorithms) > 0 {
+		allowed := false
+		for _, t := range p.AllowedAlgorithms {
+			if t == keyType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("key type %s is not in the allowed algorithms", keyType.ShortName())
+		}
+	}
+
+	switch keyType {
+	case KeyTypeRSA:
+		minBits := p.MinRSABits
+		if minBits == 0 {
+			minBits = DefaultMinRSABits
+		}
+		if bits < minBits {
+			return fmt.Errorf("RSA key size %d is below the minimum of %d bits", bits, minBits)
+		}
+	case KeyTypeECDSA, KeyTypeECDSASK:
+		minBits := p.MinECDSABits
+		if minBits == 0 {
+			minBits = DefaultMinECDSABits
+		}
+		if bits < minBits {
+			return fmt.Errorf("ECDSA key size %d is below the minimum of %d bits", bits, minBits)
+		}
+	}
+	return nil
+}
+
+// KeyEncryptionConfig tunes the Argon2id parameters of the envelopes wrapping
+// private keys pushed to the server.
+// Zero values select the built-in defaults.
+type KeyEncryptionConfig struct {
+	ArgonTime      uint32 `yaml:"argon_time,omitempty" json:"argon_time,omitempty"`
+	ArgonMemoryKiB uint32 `yaml:"argon_memory_kib,omitempty" json:"argon_memory_kib,omitempty"`
+	ArgonThreads   uint8  `yaml:"argon_threads,omitempty" json:"argon_threads,omitempty"`
+}
+
+// SecretsConfig selects the secret backend
+type SecretsConfig struct {
+	// Backend is auto, secret-service, file or none (empty = auto)
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+
+	// FilePath is the encrypted secrets file of the file backend
+	FilePath string `yaml:"file_path,omitempty" json:"file_path,omitempty"`
+
+	// CachePassphrases remembers key passphrases in the backend after they were entered
+	CachePassphrases bool `yaml:"cache_passphrases,omitempty" json:"cache_passphrases,omitempty"`
+}
+
+// AgentConfig configures the built-in SSH agent
+type AgentConfig struct {
+	// Enabled points managed hosts at the agent via IdentityAgent
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// SocketPath overrides the default socket location (<config dir>/agent.sock)
+	SocketPath string `yaml:"socket_path,omitempty" json:"socket_path,omitempty"`
+
+	// DefaultTimeoutMinutes is how long unlocked keys stay loaded (0 = until locked)
+	DefaultTimeoutMinutes int `yaml:"default_timeout_minutes" json:"default_timeout_minutes"`
+
+	// EnforcePolicies applies key policies and host restrictions to sign requests
+	EnforcePolicies bool `yaml:"enforce_policies,omitempty" json:"enforce_policies,omitempty"`
+
+	// Askpass is the program used to confirm "ask" keys (default: $SKM_ASKPASS, $SSH_ASKPASS)
+	Askpass string `yaml:"askpass,omitempty" json:"askpass,omitempty"`
+}
+
+// HostCA is a certificate authority trusted to vouch for host keys
+type HostCA struct {
+	Name      string    `yaml:"name" json:"name"`
+	PublicKey string    `yaml:"public_key" json:"public_key"` // authorized_keys format
+	Patterns  []string  `yaml:"patterns" json:"patterns"`     // known_hosts host patterns the CA is trusted for
+	AddedAt   time.Time `yaml:"added_at" json:"added_at"`
+}
+
+// DefaultAgentConfig returns the default agent configuration
+func DefaultAgentConfig() AgentConfig {
+	return AgentConfig{
+		Enabled:               false,
+		DefaultTimeoutMinutes: 60,
+	}
+}
+
 // ProjectConfig represents a project-level SKM configuration (.skmconfig)
 // This is a subset of Config that can be defined at the project level
 type ProjectConfig struct {
@@ -97,6 +244,7 @@ func DefaultConfig() *Config {
 			RequireEncryption: true,
 		},
 		KeyRotationPolicy: DefaultKeyRotationPolicy(),
+		Agent:             DefaultAgentConfig(),
 		Debug:             false,
 		Version:           "1.0.0",
 		Keys:              []Key{},
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/pattern"
+)
+
+// GitKeyRule selects the key for Git remotes whose URL matches Pattern, such
+// as github.com:myorg/* or github.com:*. The host part is matched against the
+// host of the URL and the path part against the repository path without a
+// trailing .git; * matches across slashes. A pattern without a path matches
+// every repository of the host.
+type GitKeyRule struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	KeyName string `yaml:"key" json:"key"`
+}
+
+// Matches reports whether the repository at path on host matches the rule,
+// case insensitively
+func (r *GitKeyRule) Matches(host, path string) bool {
+	hostPattern, pathPattern, ok := strings.Cut(strings.ToLower(r.Pattern), ":")
+	if !ok {
+		pathPattern = "*"
+	}
+	return pattern.Match(strings.ToLower(host), hostPattern) &&
+		pattern.Match(strings.ToLower(NormalizeRepoPath(path)), NormalizeRepoPath(pathPattern))
+}
+
+// Validate checks the rule before it is saved
+func (r *GitKeyRule) Validate() error {
+	host, _, _ := strings.Cut(r.Pattern, ":")
+	if host == "" || strings.ContainsAny(r.Pattern, " \t\r\n") {
+		return fmt.Errorf("invalid git rule pattern %q (use host:path, e.g. github.com:myorg/*)", r.Pattern)
+	}
+	if r.KeyName == "" {
+		return fmt.Errorf("git rule %s: key is required", r.Pattern)
+	}
+	return nil
+}
+
+// MatchGitKeyRule returns the most specific rule matching the repository at
+// path on host, or nil. Like url.<base>.insteadOf in Git, the longest
+// matching pattern wins; among patterns of the same length, the first.
+func MatchGitKeyRule(rules []GitKeyRule, host, path string) *GitKeyRule {
+	var best *GitKeyRule
+	for i := range rules {
+		if rules[i].Matches(host, path) && (best == nil || len(rules[i].Pattern) > len(best.Pattern)) {
+			best = &rules[i]
+		}
+	}
+	return best
+}
+
+// NormalizeRepoPath returns a repository path as written in remote URLs and
+// git ssh commands without its leading slash, ~/ and trailing .git, so
+// /myorg/repo.git and myorg/repo compare equal
+func NormalizeRepoPath(path string) string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), "~/")
+	path = strings.TrimSuffix(path, "/")
+	return strings.TrimSuffix(path, ".git")
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/pattern"
+)
+
+// HostGroup holds defaults shared by the hosts matching its patterns. Members
+// inherit every setting they leave unset. The group is also written as its
+// own Host block, so hosts that are not managed individually get the defaults
+// too.
+type HostGroup struct {
+	Name string `yaml:"name" json:"name"`
+	// Patterns are ssh host patterns such as prod-* or *.internal.corp; a
+	// pattern starting with ! excludes matching hosts
+	Patterns []string `yaml:"patterns" json:"patterns"`
+
+	User    string `yaml:"user,omitempty" json:"user,omitempty"`
+	KeyName string `yaml:"key,omitempty" json:"key,omitempty"`
+	Port    int    `yaml:"port,omitempty" json:"port,omitempty"`
+	// JumpHost is the managed host members connect through
+	JumpHost string `yaml:"jump_host,omitempty" json:"jump_host,omitempty"`
+
+	HostOptions `yaml:",inline"`
+}
+
+// Matches reports whether host belongs to the group: its alias matches the
+// patterns or it is tagged with the group name
+func (g *HostGroup) Matches(host *Host) bool {
+	for _, tag := range host.Tags {
+		if tag == g.Name {
+			return true
+		}
+	}
+	return g.MatchesAlias(host.Host)
+}
+
+// MatchesAlias matches alias against the patterns like ssh matches a Host
+// line: case insensitively, and a matching negated pattern excludes it
+func (g *HostGroup) MatchesAlias(alias string) bool {
+	alias = strings.ToLower(alias)
+	matched := false
+	for _, p := range g.Patterns {
+		p = strings.ToLower(p)
+		if negated, ok := strings.CutPrefix(p, "!"); ok {
+			if pattern.Match(alias, negated) {
+				return false
+			}
+			continue
+		}
+		if pattern.Match(alias, p) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// Validate checks the group before it is written to the SSH config
+func (g *HostGroup) Validate() error {
+	if g.Name == "" || strings.ContainsAny(g.Name, " \t\r\n,") {
+		return fmt.Errorf("invalid group name %q", g.Name)
+	}
+	positive := false
+	for _, pattern := range g.Patterns {
+		if pattern == "" || pattern == "!" || strings.ContainsAny(pattern, " \t\r\n,") {
+			return fmt.Errorf("group %s: invalid pattern %q", g.Name, pattern)
+		}
+		if !strings.HasPrefix(pattern, "!") {
+			positive = true
+		}
+	}
+	if !positive {
+		return fmt.Errorf("group %s: needs at least one pattern that is not negated", g.Name)
+	}
+	if strings.ContainsAny(g.User, " \t\r\n") {
+		return fmt.Errorf("group %s: user cannot contain whitespace", g.Name)
+	}
+	if g.Port < 0 || g.Port > 65535 {
+		return fmt.Errorf("group %s: invalid port %d", g.Name, g.Port)
+	}
+	if strings.ContainsAny(g.JumpHost, " \t\r\n,") {
+		return fmt.Errorf("group %s: invalid jump host %q", g.Name, g.JumpHost)
+	}
+	if g.JumpHost != "" && g.ProxyJump != "" {
+		return fmt.Errorf("group %s: set either a jump host or ProxyJump, not both", g.Name)
+	}
+	if err := g.HostOptions.Validate(); err != nil {
+		return fmt.Errorf("group %s: %w", g.Name, err)
+	}
+	return nil
+}
+
+// GroupsOf returns the groups host belongs to, in order
+func GroupsOf(host *Host, groups []HostGroup) []*HostGroup {
+	var member []*HostGroup
+	for i := range groups {
+		if groups[i].Matches(host) {
+			member = append(member, &groups[i])
+		}
+	}
+	return member
+}
+
+// WithGroups returns the host with the settings it leaves unset taken from
+// the groups it belongs to. Like ssh, the first group setting a value wins.
+func (h Host) WithGroups(groups []HostGroup) Host {
+	for _, g := range GroupsOf(&h, groups) {
+		if h.User == "" {
+			h.User = g.User
+		}
+		if h.KeyName == "" {
+			h.KeyName = g.KeyName
+		}
+		if h.Port == 0 {
+			h.Port = g.Port
+		}
+		// A jump host in the group does not go through itself, and an own
+		// ProxyJump replaces the inherited jump host
+		if h.JumpHost == "" && h.ProxyJump == "" && !strings.EqualFold(g.JumpHost, h.Host) {
+			h.JumpHost = g.JumpHost
+		}
+		h.HostOptions = h.HostOptions.WithDefaults(g.HostOptions)
+	}
+	return h
+}
+
+// WithDefaults returns the options with those left unset taken from defaults.
+// A list such as LocalForward is inherited only as a whole.
+func (o HostOptions) WithDefaults(defaults HostOptions) HostOptions {
+	if o.ProxyJump == "" {
+		o.ProxyJump = defaults.ProxyJump
+	}
+	if o.ForwardAgent == nil {
+		o.ForwardAgent = defaults.ForwardAgent
+	}
+	if len(o.LocalForward) == 0 {
+		o.LocalForward = defaults.LocalForward
+	}
+	if len(o.RemoteForward) == 0 {
+		o.RemoteForward = defaults.RemoteForward
+	}
+	if len(o.DynamicForward) == 0 {
+		o.DynamicForward = defaults.DynamicForward
+	}
+	if o.ServerAliveInterval == 0 {
+		o.ServerAliveInterval = defaults.ServerAliveInterval
+	}
+	if o.ServerAliveCountMax == 0 {
+		o.ServerAliveCountMax = defaults.ServerAliveCountMax
+	}
+	if o.ControlMaster == "" {
+		o.ControlMaster = defaults.ControlMaster
+	}
+	if o.ControlPath == "" {
+		o.ControlPath = defaults.ControlPath
+	}
+	if o.ControlPersist == "" {
+		o.ControlPersist = defaults.ControlPersist
+	}
+	if o.AddKeysToAgent == "" {
+		o.AddKeysToAgent = defaults.AddKeysToAgent
+	}
+	if o.StrictHostKeyChecking == "" {
+		o.StrictHostKeyChecking = defaults.StrictHostKeyChecking
+	}
+	if o.Compression == nil {
+		o.Compression = defaults.Compression
+	}
+	if o.RequestTTY == "" {
+		o.RequestTTY = defaults.RequestTTY
+	}
+
+	if len(defaults.Options) > 0 {
+		merged := make(map[string]string, len(o.Options)+len(defaults.Options))
+		for k, v := range defaults.Options {
+			merged[k] = v
+		}
+		for k, v := range o.Options {
+			merged[k] = v
+		}
+		o.Options = merged
+	}
+	return o
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JumpChain returns the managed hosts to go through to reach host, starting
+// with the first hop. lookup returns the managed host with an alias. A jump
+// host that is not found or a chain leading back to a host is an error.
+func JumpChain(host *Host, lookup func(alias string) (*Host, error)) ([]*Host, error) {
+	var chain []*Host
+	path := []string{host.Host}
+	seen := map[string]bool{strings.ToLower(host.Host): true}
+
+	for current := host; current.JumpHost != ""; {
+		alias := current.JumpHost
+		path = append(path, alias)
+		if seen[strings.ToLower(alias)] {
+			return nil, fmt.Errorf("jump host cycle: %s", strings.Join(path, " -> "))
+		}
+		seen[strings.ToLower(alias)] = true
+
+		hop, err := lookup(alias)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s of %s not found", alias, current.Host)
+		}
+		chain = append([]*Host{hop}, chain...)
+		current = hop
+	}
+	return chain, nil
+}
+
+// JumpAliases returns the ProxyJump value going through chain
+func JumpAliases(chain []*Host) string {
+	aliases := make([]string, len(chain))
+	for i, hop := range chain {
+		aliases[i] = hop.Host
+	}
+	return strings.Join(aliases, ",")
+}
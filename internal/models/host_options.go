@@ -0,0 +1,361 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HostOptions are the ssh_config options of a managed host besides its
+// HostName, User, Port and key. Common options are typed; any other ssh_config
+// option goes in Options.
+type HostOptions struct {
+	ProxyJump             string   `yaml:"proxy_jump,omitempty" json:"proxy_jump,omitempty"`
+	ForwardAgent          *bool    `yaml:"forward_agent,omitempty" json:"forward_agent,omitempty"`
+	LocalForward          []string `yaml:"local_forward,omitempty" json:"local_forward,omitempty"`
+	RemoteForward         []string `yaml:"remote_forward,omitempty" json:"remote_forward,omitempty"`
+	DynamicForward        []string `yaml:"dynamic_forward,omitempty" json:"dynamic_forward,omitempty"`
+	ServerAliveInterval   int      `yaml:"server_alive_interval,omitempty" json:"server_alive_interval,omitempty"`
+	ServerAliveCountMax   int      `yaml:"server_alive_count_max,omitempty" json:"server_alive_count_max,omitempty"`
+	ControlMaster         string   `yaml:"control_master,omitempty" json:"control_master,omitempty"`
+	ControlPath           string   `yaml:"control_path,omitempty" json:"control_path,omitempty"`
+	ControlPersist        string   `yaml:"control_persist,omitempty" json:"control_persist,omitempty"`
+	AddKeysToAgent        string   `yaml:"add_keys_to_agent,omitempty" json:"add_keys_to_agent,omitempty"`
+	StrictHostKeyChecking string   `yaml:"strict_host_key_checking,omitempty" json:"strict_host_key_checking,omitempty"`
+	Compression           *bool    `yaml:"compression,omitempty" json:"compression,omitempty"`
+	RequestTTY            string   `yaml:"request_tty,omitempty" json:"request_tty,omitempty"`
+
+	// Options holds other ssh_config options by keyword, e.g. "LogLevel": "ERROR"
+	Options map[string]string `yaml:"options,omitempty" json:"options,omitempty"`
+}
+
+// HostDirective is one ssh_config line of a host
+type HostDirective struct {
+	Keyword string
+	Value   string
+}
+
+// managedKeywords are written by skm from other Host fields or settings and
+// cannot be set as options
+var managedKeywords = []string{
+	"Host", "Match", "Include", "HostName", "User", "Port", "IdentityFile",
+	"IdentitiesOnly", "CertificateFile", "IdentityAgent", "UserKnownHostsFile",
+}
+
+// sshKeywords are the ssh_config(5) keywords accepted in Options
+var sshKeywords = []string{
+	"AddKeysToAgent", "AddressFamily", "BatchMode", "BindAddress", "BindInterface",
+	"CanonicalDomains", "CanonicalizeFallbackLocal", "CanonicalizeHostname",
+	"CanonicalizeMaxDots", "CanonicalizePermittedCNAMEs", "CASignatureAlgorithms",
+	"ChannelTimeout", "CheckHostIP", "Ciphers", "ClearAllForwardings", "Compression",
+	"ConnectionAttempts", "ConnectTimeout", "ControlMaster", "ControlPath",
+	"ControlPersist", "DynamicForward", "EnableEscapeCommandline", "EnableSSHKeysign",
+	"EscapeChar", "ExitOnForwardFailure", "FingerprintHash", "ForkAfterAuthentication",
+	"ForwardAgent", "ForwardX11", "ForwardX11Timeout", "ForwardX11Trusted",
+	"GatewayPorts", "GlobalKnownHostsFile", "GSSAPIAuthentication",
+	"GSSAPIDelegateCredentials", "HashKnownHosts", "HostbasedAcceptedAlgorithms",
+	"HostbasedAuthentication", "HostKeyAlgorithms", "HostKeyAlias", "IgnoreUnknown",
+	"IPQoS", "KbdInteractiveAuthentication", "KbdInteractiveDevices", "KexAlgorithms",
+	"KnownHostsCommand", "LocalCommand", "LocalForward", "LogLevel", "LogVerbose",
+	"MACs", "NoHostAuthenticationForLocalhost", "NumberOfPasswordPrompts",
+	"ObscureKeystrokeTiming", "PasswordAuthentication", "PermitLocalCommand",
+	"PermitRemoteOpen", "PKCS11Provider", "PreferredAuthentications", "ProxyCommand",
+	"ProxyJump", "ProxyUseFdpass", "PubkeyAcceptedAlgorithms", "PubkeyAuthentication",
+	"RekeyLimit", "RemoteCommand", "RemoteForward", "RequestTTY", "RequiredRSASize",
+	"RevokedHostKeys", "SecurityKeyProvider", "SendEnv", "ServerAliveCountMax",
+	"ServerAliveInterval", "SessionType", "SetEnv", "StdinNull", "StreamLocalBindMask",
+	"StreamLocalBindUnlink", "StrictHostKeyChecking", "SyslogFacility", "TCPKeepAlive",
+	"Tag", "Tunnel", "TunnelDevice", "UpdateHostKeys", "VerifyHostKeyDNS",
+	"VisualHostKey", "XAuthLocation",
+}
+
+// timeSpec matches ssh_config time formats such as 30, 10m or 1h30m
+var timeSpec = regexp.MustCompile(`^([0-9]+[sSmMhHdDwW]?)+$`)
+
+// CanonicalKeyword returns the ssh_config spelling of keyword, matched case
+// insensitively, and whether it is a known keyword
+func CanonicalKeyword(keyword string) (string, bool) {
+	for _, list := range [][]string{managedKeywords, sshKeywords} {
+		for _, k := range list {
+			if strings.EqualFold(k, keyword) {
+				return k, true
+			}
+		}
+	}
+	return keyword, false
+}
+
+// IsManagedKeyword reports whether keyword is written by skm from other host
+// fields and cannot be set as an option
+func IsManagedKeyword(keyword string) bool {
+	for _, k := range managedKeywords {
+		if strings.EqualFold(k, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set sets an option by its ssh_config keyword. Typed options are parsed into
+// their fields; repeated options such as LocalForward are appended.
+func (o *HostOptions) Set(keyword, value string) error {
+	canonical, known := CanonicalKeyword(keyword)
+	if !known {
+		return fmt.Errorf("unknown ssh_config option: %s", keyword)
+	}
+	if IsManagedKeyword(canonical) {
+		return fmt.Errorf("%s is set from the host's own fields and cannot be used as an option", canonical)
+	}
+	value = strings.TrimSpace(value)
+
+	switch canonical {
+	case "ProxyJump":
+		o.ProxyJump = value
+	case "ForwardAgent":
+		b, err := parseYesNo(canonical, value)
+		if err != nil {
+			return err
+		}
+		o.ForwardAgent = &b
+	case "LocalForward":
+		o.LocalForward = append(o.LocalForward, value)
+	case "RemoteForward":
+		o.RemoteForward = append(o.RemoteForward, value)
+	case "DynamicForward":
+		o.DynamicForward = append(o.DynamicForward, value)
+	case "ServerAliveInterval", "ServerAliveCountMax":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number: %s", canonical, value)
+		}
+		if canonical == "ServerAliveInterval" {
+			o.ServerAliveInterval = n
+		} else {
+			o.ServerAliveCountMax = n
+		}
+	case "ControlMaster":
+		o.ControlMaster = value
+	case "ControlPath":
+		o.ControlPath = value
+	case "ControlPersist":
+		o.ControlPersist = value
+	case "AddKeysToAgent":
+		o.AddKeysToAgent = value
+	case "StrictHostKeyChecking":
+		o.StrictHostKeyChecking = value
+	case "Compression":
+		b, err := parseYesNo(canonical, value)
+		if err != nil {
+			return err
+		}
+		o.Compression = &b
+	case "RequestTTY":
+		o.RequestTTY = value
+	default:
+		if o.Options == nil {
+			o.Options = make(map[string]string)
+		}
+		o.Options[canonical] = value
+	}
+	return nil
+}
+
+// Unset clears an option by its ssh_config keyword
+func (o *HostOptions) Unset(keyword string) error {
+	canonical, known := CanonicalKeyword(keyword)
+	if !known || IsManagedKeyword(canonical) {
+		return fmt.Errorf("not a host option: %s", keyword)
+	}
+
+	switch canonical {
+	case "ProxyJump":
+		o.ProxyJump = ""
+	case "ForwardAgent":
+		o.ForwardAgent = nil
+	case "LocalForward":
+		o.LocalForward = nil
+	case "RemoteForward":
+		o.RemoteForward = nil
+	case "DynamicForward":
+		o.DynamicForward = nil
+	case "ServerAliveInterval":
+		o.ServerAliveInterval = 0
+	case "ServerAliveCountMax":
+		o.ServerAliveCountMax = 0
+	case "ControlMaster":
+		o.ControlMaster = ""
+	case "ControlPath":
+		o.ControlPath = ""
+	case "ControlPersist":
+		o.ControlPersist = ""
+	case "AddKeysToAgent":
+		o.AddKeysToAgent = ""
+	case "StrictHostKeyChecking":
+		o.StrictHostKeyChecking = ""
+	case "Compression":
+		o.Compression = nil
+	case "RequestTTY":
+		o.RequestTTY = ""
+	default:
+		delete(o.Options, canonical)
+	}
+	return nil
+}
+
+// Directives returns the options as ssh_config lines: typed options first in a
+// fixed order, then Options sorted by keyword
+func (o *HostOptions) Directives() []HostDirective {
+	var d []HostDirective
+	add := func(keyword, value string) {
+		if value != "" {
+			d = append(d, HostDirective{Keyword: keyword, Value: value})
+		}
+	}
+
+	add("ProxyJump", o.ProxyJump)
+	if o.ForwardAgent != nil {
+		add("ForwardAgent", formatYesNo(*o.ForwardAgent))
+	}
+	for _, f := range o.LocalForward {
+		add("LocalForward", f)
+	}
+	for _, f := range o.RemoteForward {
+		add("RemoteForward", f)
+	}
+	for _, f := range o.DynamicForward {
+		add("DynamicForward", f)
+	}
+	if o.ServerAliveInterval > 0 {
+		add("ServerAliveInterval", strconv.Itoa(o.ServerAliveInterval))
+	}
+	if o.ServerAliveCountMax > 0 {
+		add("ServerAliveCountMax", strconv.Itoa(o.ServerAliveCountMax))
+	}
+	add("ControlMaster", o.ControlMaster)
+	add("ControlPath", o.ControlPath)
+	add("ControlPersist", o.ControlPersist)
+	add("AddKeysToAgent", o.AddKeysToAgent)
+	add("StrictHostKeyChecking", o.StrictHostKeyChecking)
+	if o.Compression != nil {
+		add("Compression", formatYesNo(*o.Compression))
+	}
+	add("RequestTTY", o.RequestTTY)
+
+	keywords := make([]string, 0, len(o.Options))
+	for k := range o.Options {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	for _, k := range keywords {
+		add(k, o.Options[k])
+	}
+	return d
+}
+
+// Validate checks the options so a bad value never reaches ~/.ssh/config,
+// where ssh would reject the whole file
+func (o *HostOptions) Validate() error {
+	for _, d := range o.Directives() {
+		if strings.ContainsAny(d.Value, "\r\n") {
+			return fmt.Errorf("%s must be a single line", d.Keyword)
+		}
+	}
+
+	if o.ProxyJump != "" && o.ProxyJump != "none" {
+		for _, hop := range strings.Split(o.ProxyJump, ",") {
+			if hop == "" || strings.ContainsAny(hop, " \t") {
+				return fmt.Errorf("invalid ProxyJump %q: expected [user@]host[:port] hops separated by commas", o.ProxyJump)
+			}
+		}
+	}
+	for _, f := range o.LocalForward {
+		if len(strings.Fields(f)) != 2 {
+			return fmt.Errorf("invalid LocalForward %q: expected \"[bind_address:]port host:hostport\"", f)
+		}
+	}
+	for _, f := range o.RemoteForward {
+		if n := len(strings.Fields(f)); n != 1 && n != 2 {
+			return fmt.Errorf("invalid RemoteForward %q: expected \"[bind_address:]port [host:hostport]\"", f)
+		}
+	}
+	for _, f := range o.DynamicForward {
+		if len(strings.Fields(f)) != 1 {
+			return fmt.Errorf("invalid DynamicForward %q: expected \"[bind_address:]port\"", f)
+		}
+	}
+	if o.ServerAliveInterval < 0 || o.ServerAliveCountMax < 0 {
+		return fmt.Errorf("ServerAliveInterval and ServerAliveCountMax cannot be negative")
+	}
+
+	choices := []struct {
+		keyword, value string
+		allowed        []string
+		allowTime      bool
+	}{
+		{"ControlMaster", o.ControlMaster, []string{"yes", "no", "ask", "auto", "autoask"}, false},
+		{"ControlPersist", o.ControlPersist, []string{"yes", "no"}, true},
+		{"AddKeysToAgent", o.AddKeysToAgent, []string{"yes", "no", "ask", "confirm"}, true},
+		{"StrictHostKeyChecking", o.StrictHostKeyChecking, []string{"yes", "no", "ask", "accept-new", "off"}, false},
+		{"RequestTTY", o.RequestTTY, []string{"yes", "no", "force", "auto"}, false},
+	}
+	for _, c := range choices {
+		if c.value == "" || (c.allowTime && timeSpec.MatchString(c.value)) {
+			continue
+		}
+		valid := false
+		for _, a := range c.allowed {
+			if strings.EqualFold(c.value, a) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			allowed := strings.Join(c.allowed, ", ")
+			if c.allowTime {
+				allowed += " or a time such as 10m"
+			}
+			return fmt.Errorf("invalid %s %q: expected %s", c.keyword, c.value, allowed)
+		}
+	}
+
+	for keyword, value := range o.Options {
+		canonical, known := CanonicalKeyword(keyword)
+		if !known {
+			return fmt.Errorf("unknown ssh_config option: %s", keyword)
+		}
+		if IsManagedKeyword(canonical) {
+			return fmt.Errorf("%s is set from the host's own fields and cannot be used as an option", canonical)
+		}
+		if canonical != keyword {
+			return fmt.Errorf("option %s should be spelled %s", keyword, canonical)
+		}
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("option %s has no value", keyword)
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether no option is set
+func (o *HostOptions) IsEmpty() bool {
+	return len(o.Directives()) == 0
+}
+
+func parseYesNo(keyword, value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "true":
+		return true, nil
+	case "no", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s must be yes or no: %s", keyword, value)
+}
+
+func formatYesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
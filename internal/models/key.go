@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,8 +13,39 @@ const (
 	KeyTypeED25519 KeyType = "ed25519"
 	KeyTypeRSA     KeyType = "rsa"
 	KeyTypeECDSA   KeyType = "ecdsa"
+
+	// FIDO2 security key types; the private key file only holds a key handle
+	KeyTypeED25519SK KeyType = "sk-ssh-ed25519@openssh.com"
+	KeyTypeECDSASK   KeyType = "sk-ecdsa-sha2-nistp256@openssh.com"
 )
 
+// IsSecurityKey reports whether keys of this type live on a FIDO2 authenticator
+func (t KeyType) IsSecurityKey() bool {
+	return t == KeyTypeED25519SK || t == KeyTypeECDSASK
+}
+
+// ShortName returns the ssh-keygen style name of the key type (e.g. ed25519-sk)
+func (t KeyType) ShortName() string {
+	switch t {
+	case KeyTypeED25519SK:
+		return "ed25519-sk"
+	case KeyTypeECDSASK:
+		return "ecdsa-sk"
+	default:
+		return string(t)
+	}
+}
+
+// ParseKeyType parses a key type from its short or full name
+func ParseKeyType(name string) (KeyType, error) {
+	for _, t := range []KeyType{KeyTypeED25519, KeyTypeRSA, KeyTypeECDSA, KeyTypeED25519SK, KeyTypeECDSASK} {
+		if name == string(t) || name == t.ShortName() {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key type: %s", name)
+}
+
 // Key represents an SSH key with its metadata
 type Key struct {
 	Name      string    `yaml:"name" json:"name"`
@@ -29,6 +62,8 @@ type Key struct {
 	Installed bool `yaml:"installed" json:"installed"`
 	// RSA key size (only for RSA keys)
 	RSABits int `yaml:"rsa_bits,omitempty" json:"rsa_bits,omitempty"`
+	// ECDSA curve size: 256, 384 or 521 (only for ECDSA keys)
+	ECDSABits int `yaml:"ecdsa_bits,omitempty" json:"ecdsa_bits,omitempty"`
 	// Whether the private key is encrypted with passphrase
 	HasPassphrase bool `yaml:"has_passphrase" json:"has_passphrase"`
 
@@ -36,6 +71,110 @@ type Key struct {
 	LastRotatedAt *time.Time `yaml:"last_rotated_at,omitempty" json:"last_rotated_at,omitempty"`
 	RotationDueAt *time.Time `yaml:"rotation_due_at,omitempty" json:"rotation_due_at,omitempty"`
 	RotatedFrom   string     `yaml:"rotated_from,omitempty" json:"rotated_from,omitempty"` // Previous key name if this is a rotation
+
+	// Usage policy enforced by the SKM agent (empty = Config.DefaultKeyPolicy)
+	Policy KeyPolicy `yaml:"policy,omitempty" json:"policy,omitempty"`
+	// RestrictToHosts limits agent signing to the hosts bound to this key
+	RestrictToHosts bool `yaml:"restrict_to_hosts,omitempty" json:"restrict_to_hosts,omitempty"`
+
+	// CertPath is the OpenSSH certificate issued for this key (<path>-cert.pub)
+	CertPath string `yaml:"cert_path,omitempty" json:"cert_path,omitempty"`
+
+	// SecurityKey describes the authenticator binding of FIDO2 (sk) keys
+	SecurityKey *SecurityKeyInfo `yaml:"security_key,omitempty" json:"security_key,omitempty"`
+
+	// State is the lifecycle state of the key (empty = active)
+	State KeyState `yaml:"state,omitempty" json:"state,omitempty"`
+	// DeviceID is the device the key was created or imported on
+	DeviceID string `yaml:"device_id,omitempty" json:"device_id,omitempty"`
+}
+
+// KeyState is the lifecycle state of a key
+type KeyState string
+
+const (
+	// KeyStateActive keys are used normally
+	KeyStateActive KeyState = "active"
+	// KeyStateDeprecated keys still work but have been superseded, e.g. by rotation
+	KeyStateDeprecated KeyState = "deprecated"
+	// KeyStateArchived keys are kept encrypted for recovery but are not used
+	KeyStateArchived KeyState = "archived"
+	// KeyStateRevoked keys are listed in the key revocation list and never used again
+	KeyStateRevoked KeyState = "revoked"
+)
+
+// KeyStates lists the lifecycle states in order
+var KeyStates = []KeyState{KeyStateActive, KeyStateDeprecated, KeyStateArchived, KeyStateRevoked}
+
+// ParseKeyState parses a key lifecycle state
+func ParseKeyState(name string) (KeyState, error) {
+	for _, s := range KeyStates {
+		if name == string(s) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key state: %s (expected active, deprecated, archived or revoked)", name)
+}
+
+// SecurityKeyInfo holds the metadata of a FIDO2 security key
+type SecurityKeyInfo struct {
+	// Application is the FIDO application string (usually "ssh:")
+	Application string `yaml:"application" json:"application"`
+	// Resident keys are stored on the authenticator and can be recovered with ssh-keygen -K
+	Resident bool `yaml:"resident,omitempty" json:"resident,omitempty"`
+	// VerifyRequired requires PIN or biometric verification for every signature
+	VerifyRequired bool `yaml:"verify_required,omitempty" json:"verify_required,omitempty"`
+	// NoTouchRequired disables the user presence (touch) check
+	NoTouchRequired bool `yaml:"no_touch_required,omitempty" json:"no_touch_required,omitempty"`
+	// FlagsKnown is false when the flags could not be read from an encrypted key handle
+	FlagsKnown bool `yaml:"flags_known" json:"flags_known"`
+}
+
+// Bits returns the key size recorded for RSA and ECDSA keys, or 0
+func (k *Key) Bits() int {
+	switch k.Type {
+	case KeyTypeRSA:
+		return k.RSABits
+	case KeyTypeECDSA:
+		return k.ECDSABits
+	default:
+		return 0
+	}
+}
+
+// EffectiveState returns the key's lifecycle state, treating empty as active
+func (k *Key) EffectiveState() KeyState {
+	if k.State == "" {
+		return KeyStateActive
+	}
+	return k.State
+}
+
+// IsUsable reports whether the key may be installed, configured for hosts and
+// loaded into the agent
+func (k *Key) IsUsable() bool {
+	state := k.EffectiveState()
+	return state == KeyStateActive || state == KeyStateDeprecated
+}
+
+// EffectivePolicy returns the key's policy, falling back to the given default
+func (k *Key) EffectivePolicy(defaultPolicy KeyPolicy) KeyPolicy {
+	if k.Policy != "" {
+		return k.Policy
+	}
+	if defaultPolicy != "" {
+		return defaultPolicy
+	}
+	return KeyPolicyAsk
+}
+
+// KeyTombstone records the deletion of a key so other devices remove it
+// instead of re-importing it on the next sync
+type KeyTombstone struct {
+	Name        string    `yaml:"name" json:"name"`
+	Fingerprint string    `yaml:"fingerprint" json:"fingerprint"`
+	DeviceID    string    `yaml:"device_id" json:"device_id"` // Device the key was deleted on
+	DeletedAt   time.Time `yaml:"deleted_at" json:"deleted_at"`
 }
 
 // KeyRotationStatus represents the rotation status of a key
@@ -88,6 +227,64 @@ type Host struct {
 	Port     int      `yaml:"port,omitempty" json:"port,omitempty"`
 	Hostname string   `yaml:"hostname,omitempty" json:"hostname,omitempty"` // Actual hostname if different
 	Tags     []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
+	// JumpHost is the alias of the managed host to connect through; each hop
+	// uses its own key
+	JumpHost string `yaml:"jump_host,omitempty" json:"jump_host,omitempty"`
+
+	// HostKeys are the host keys pinned on first connection
+	HostKeys []HostKey `yaml:"host_keys,omitempty" json:"host_keys,omitempty"`
+
+	// HostOptions are the other ssh_config options written for the host
+	HostOptions `yaml:",inline"`
+}
+
+// Validate checks the host before it is written to the SSH config
+func (h *Host) Validate() error {
+	if h.Host == "" || strings.ContainsAny(h.Host, " \t\r\n") {
+		return fmt.Errorf("invalid host alias %q", h.Host)
+	}
+	if strings.ContainsAny(h.User+h.Hostname, " \t\r\n") {
+		return fmt.Errorf("host %s: user and hostname cannot contain whitespace", h.Host)
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		return fmt.Errorf("host %s: invalid port %d", h.Host, h.Port)
+	}
+	if h.JumpHost != "" {
+		if strings.ContainsAny(h.JumpHost, " \t\r\n,") {
+			return fmt.Errorf("host %s: invalid jump host %q", h.Host, h.JumpHost)
+		}
+		if strings.EqualFold(h.JumpHost, h.Host) {
+			return fmt.Errorf("host %s: cannot be its own jump host", h.Host)
+		}
+		if h.ProxyJump != "" {
+			return fmt.Errorf("host %s: set either a jump host or ProxyJump, not both", h.Host)
+		}
+	}
+	for _, tag := range h.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\r\n,") {
+			return fmt.Errorf("host %s: invalid tag %q", h.Host, tag)
+		}
+	}
+	if err := h.HostOptions.Validate(); err != nil {
+		return fmt.Errorf("host %s: %w", h.Host, err)
+	}
+	return nil
+}
+
+// RemoteName returns the name ssh connects to and looks up in known_hosts
+func (h *Host) RemoteName() string {
+	if h.Hostname != "" {
+		return h.Hostname
+	}
+	return h.Host
+}
+
+// HostKey is a host key pinned for a managed host
+type HostKey struct {
+	PublicKey   string    `yaml:"public_key" json:"public_key"` // authorized_keys format
+	Fingerprint string    `yaml:"fingerprint" json:"fingerprint"`
+	PinnedAt    time.Time `yaml:"pinned_at" json:"pinned_at"`
 }
 
 // Device represents a registered device
@@ -118,6 +315,11 @@ const (
 	KeyPolicyNever KeyPolicy = "never" // Never auto-use keys
 )
 
+// IsValid reports whether the policy is one of the known values
+func (p KeyPolicy) IsValid() bool {
+	return p == KeyPolicyAuto || p == KeyPolicyAsk || p == KeyPolicyNever
+}
+
 // SyncPolicy defines what gets synced to the server
 type SyncPolicy struct {
 	// SyncPublicKeys enables syncing public keys to server
@@ -0,0 +1,41 @@
+// Package pattern matches names against the glob patterns of ssh_config(5),
+// as used by Host lines, host groups and Git key rules.
+package pattern
+
+import "strings"
+
+// Match matches s against an ssh pattern, where * matches any run of
+// characters and ? matches one character
+func Match(s, pattern string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(s[i:], pattern) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		s = s[1:]
+		pattern = pattern[1:]
+	}
+	return s == ""
+}
+
+// HasWildcard reports whether a host pattern contains wildcards
+func HasWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
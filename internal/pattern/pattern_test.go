@@ -0,0 +1,25 @@
+package pattern
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		s, pattern string
+		want       bool
+	}{
+		{"web.corp", "*.corp", true},
+		{"web.corp", "w?b.*", true},
+		{"web.corp", "*.org", false},
+		{"a", "*", true},
+		{"", "?", false},
+		{"myorg/app", "myorg/*", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.s, tt.pattern); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v", tt.s, tt.pattern, got)
+		}
+	}
+	if HasWildcard("web.corp") || !HasWildcard("web-?") {
+		t.Error("unexpected HasWildcard result")
+	}
+}
@@ -0,0 +1,253 @@
+// Package prompt reads passphrases, passwords and confirmations without
+// echoing secrets or leaving them in shell history.
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Environment variables understood by the prompter
+const (
+	EnvAskpass        = "SKM_ASKPASS"
+	EnvAskpassRequire = "SKM_ASKPASS_REQUIRE" // "force" uses askpass even with a terminal
+	EnvPassphraseFile = "SKM_PASSPHRASE_FILE"
+	EnvPassphraseFD   = "SKM_PASSPHRASE_FD"
+
+	// Secrets for non-interactive use such as CI
+	EnvPassphrase    = "SKM_PASSPHRASE"
+	EnvNewPassphrase = "SKM_NEW_PASSPHRASE"
+	EnvPassword      = "SKM_PASSWORD"
+
+	// EnvMasterPassphrase unlocks the encrypted secrets file
+	EnvMasterPassphrase = "SKM_MASTER_PASSPHRASE"
+
+	// EnvSyncPassphrase encrypts private keys pushed to the server
+	EnvSyncPassphrase = "SKM_SYNC_PASSPHRASE"
+)
+
+// ErrNotInteractive is returned when input is needed but stdin is not a terminal
+var ErrNotInteractive = errors.New("stdin is not a terminal")
+
+// Prompter reads secrets from, in order: the request's environment variable,
+// a passphrase file or file descriptor, and then the terminal without echo.
+// The askpass program is used when there is no terminal or it is required.
+type Prompter struct {
+	// In and Out are the terminal streams (default os.Stdin and os.Stderr)
+	In  io.Reader
+	Out io.Writer
+
+	// Askpass is an ssh-askpass compatible program
+	Askpass string
+	// AskpassRequired uses Askpass even when a terminal is available
+	AskpassRequired bool
+
+	// PassphraseFile holds secrets, one per line
+	PassphraseFile string
+	// PassphraseFD is a file descriptor secrets are read from, one per line (-1 = none)
+	PassphraseFD int
+
+	// Getenv looks up environment variables (default os.Getenv)
+	Getenv func(string) string
+
+	mu     sync.Mutex
+	lines  *bufio.Reader
+	source string
+}
+
+// Request describes one secret to read
+type Request struct {
+	// Prompt is shown on the terminal or passed to the askpass program
+	Prompt string
+	// Env names an environment variable that provides the secret directly
+	Env string
+}
+
+// New returns a prompter configured from the environment
+func New() *Prompter {
+	p := &Prompter{
+		In:             os.Stdin,
+		Out:            os.Stderr,
+		Askpass:        os.Getenv(EnvAskpass),
+		PassphraseFile: os.Getenv(EnvPassphraseFile),
+		PassphraseFD:   -1,
+		Getenv:         os.Getenv,
+	}
+	p.AskpassRequired = os.Getenv(EnvAskpassRequire) == "force"
+	if fd, err := strconv.Atoi(os.Getenv(EnvPassphraseFD)); err == nil && fd >= 0 {
+		p.PassphraseFD = fd
+	}
+	return p
+}
+
+// Secret reads a secret from the first available source
+func (p *Prompter) Secret(req Request) (string, error) {
+	secret, _, err := p.read(req)
+	return secret, err
+}
+
+// NewSecret reads a new secret. When it is typed on the terminal it is asked
+// twice and both entries must match.
+func (p *Prompter) NewSecret(req Request) (string, error) {
+	secret, typed, err := p.read(req)
+	if err != nil || !typed {
+		return secret, err
+	}
+
+	again, err := p.readTerminal("Repeat " + strings.ToLower(req.Prompt))
+	if err != nil {
+		return "", err
+	}
+	if again != secret {
+		return "", fmt.Errorf("entries do not match")
+	}
+	return secret, nil
+}
+
+// HasSource reports whether a secret for req can be read at all, either from
+// a non-interactive source or from the terminal
+func (p *Prompter) HasSource(req Request) bool {
+	return (req.Env != "" && p.getenv(req.Env) != "") || p.PassphraseFile != "" || p.PassphraseFD >= 0 ||
+		p.Askpass != "" || p.IsInteractive()
+}
+
+// read returns the secret and whether it was typed on the terminal
+func (p *Prompter) read(req Request) (string, bool, error) {
+	if req.Env != "" && p.getenv(req.Env) != "" {
+		return p.getenv(req.Env), false, nil
+	}
+
+	if p.PassphraseFile != "" || p.PassphraseFD >= 0 {
+		secret, err := p.nextLine()
+		return secret, false, err
+	}
+
+	interactive := p.IsInteractive()
+	if p.Askpass != "" && (p.AskpassRequired || !interactive) {
+		secret, err := p.askpass(req.Prompt)
+		return secret, false, err
+	}
+
+	if interactive {
+		secret, err := p.readTerminal(req.Prompt)
+		return secret, true, err
+	}
+
+	return "", false, fmt.Errorf("cannot read %s: %w; set %s, %s, %s or %s",
+		strings.ToLower(req.Prompt), ErrNotInteractive, req.envHint(), EnvPassphraseFile, EnvPassphraseFD, EnvAskpass)
+}
+
+// Confirm asks a question on the terminal and reports whether the answer
+// equals expected. It fails with ErrNotInteractive when stdin is not a terminal.
+func (p *Prompter) Confirm(question, expected string) (bool, error) {
+	if !p.IsInteractive() {
+		return false, fmt.Errorf("cannot ask %q: %w", question, ErrNotInteractive)
+	}
+
+	fmt.Fprintf(p.out(), "%s: ", question)
+	answer, err := bufio.NewReader(p.in()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(answer) == expected, nil
+}
+
+// IsInteractive reports whether stdin is a terminal
+func (p *Prompter) IsInteractive() bool {
+	f, ok := p.in().(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// readTerminal reads a line from the terminal without echo
+func (p *Prompter) readTerminal(prompt string) (string, error) {
+	f, ok := p.in().(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return "", ErrNotInteractive
+	}
+
+	fmt.Fprintf(p.out(), "%s: ", prompt)
+	secret, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(p.out())
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", strings.ToLower(prompt), err)
+	}
+	return string(secret), nil
+}
+
+// askpass runs the askpass program with the prompt and returns its output
+func (p *Prompter) askpass(prompt string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.Askpass, prompt+": ")
+	cmd.Stdout = &stdout
+	cmd.Stderr = p.out()
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("askpass program failed: %w", err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// nextLine returns the next line of the passphrase file or descriptor, so
+// commands that need several secrets read one per line
+func (p *Prompter) nextLine() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lines == nil {
+		if p.PassphraseFile != "" {
+			data, err := os.ReadFile(p.PassphraseFile)
+			if err != nil {
+				return "", fmt.Errorf("failed to read passphrase file: %w", err)
+			}
+			p.lines = bufio.NewReader(bytes.NewReader(data))
+			p.source = "passphrase file"
+		} else {
+			p.lines = bufio.NewReader(os.NewFile(uintptr(p.PassphraseFD), "passphrase-fd"))
+			p.source = fmt.Sprintf("file descriptor %d", p.PassphraseFD)
+		}
+	}
+
+	line, err := p.lines.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("no more secrets in %s", p.source)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *Prompter) getenv(name string) string {
+	if p.Getenv == nil {
+		return os.Getenv(name)
+	}
+	return p.Getenv(name)
+}
+
+func (p *Prompter) in() io.Reader {
+	if p.In == nil {
+		return os.Stdin
+	}
+	return p.In
+}
+
+func (p *Prompter) out() io.Writer {
+	if p.Out == nil {
+		return os.Stderr
+	}
+	return p.Out
+}
+
+// envHint names the environment variable a request can be satisfied with
+func (r Request) envHint() string {
+	if r.Env != "" {
+		return r.Env
+	}
+	return EnvPassphrase
+}
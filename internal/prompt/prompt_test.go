@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretFromEnv(t *testing.T) {
+	p := &Prompter{
+		In:           strings.NewReader(""),
+		PassphraseFD: -1,
+		Getenv: func(name string) string {
+			if name == EnvPassphrase {
+				return "from-env"
+			}
+			return ""
+		},
+	}
+
+	secret, err := p.Secret(Request{Prompt: "Passphrase", Env: EnvPassphrase})
+	if err != nil {
+		t.Fatalf("Secret failed: %v", err)
+	}
+	if secret != "from-env" {
+		t.Fatalf("expected secret from env, got %q", secret)
+	}
+}
+
+func TestSecretFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	if err := os.WriteFile(path, []byte("first\nsecond\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Prompter{
+		In:             strings.NewReader(""),
+		PassphraseFile: path,
+		PassphraseFD:   -1,
+		Getenv:         func(string) string { return "" },
+	}
+
+	for _, want := range []string{"first", "second"} {
+		// NewSecret must not ask twice for non-interactive input
+		secret, err := p.NewSecret(Request{Prompt: "New passphrase"})
+		if err != nil {
+			t.Fatalf("NewSecret failed: %v", err)
+		}
+		if secret != want {
+			t.Fatalf("expected %q, got %q", want, secret)
+		}
+	}
+
+	if _, err := p.Secret(Request{Prompt: "Passphrase"}); err == nil {
+		t.Fatal("expected an error once the file is exhausted")
+	}
+}
+
+func TestNotInteractive(t *testing.T) {
+	p := &Prompter{
+		In:           strings.NewReader("yes\n"),
+		PassphraseFD: -1,
+		Getenv:       func(string) string { return "" },
+	}
+
+	if p.HasSource(Request{Prompt: "Passphrase", Env: EnvPassphrase}) {
+		t.Fatal("expected no source without a terminal")
+	}
+	if _, err := p.Secret(Request{Prompt: "Passphrase"}); !errors.Is(err, ErrNotInteractive) {
+		t.Fatalf("expected ErrNotInteractive from Secret, got %v", err)
+	}
+	if _, err := p.Confirm("Continue?", "yes"); !errors.Is(err, ErrNotInteractive) {
+		t.Fatalf("expected ErrNotInteractive from Confirm, got %v", err)
+	}
+}
@@ -81,11 +81,15 @@ func (rc *RotationChecker) CheckAllKeys(keys []models.Key) []KeyRotationInfo {
 	return results
 }
 
-// GetExpiredKeys returns only keys that require rotation
+// GetExpiredKeys returns only active keys that require rotation; deprecated,
+// archived and revoked keys have already been replaced
 func (rc *RotationChecker) GetExpiredKeys(keys []models.Key) []KeyRotationInfo {
 	var expired []KeyRotationInfo
 
 	for i := range keys {
+		if keys[i].EffectiveState() != models.KeyStateActive {
+			continue
+		}
 		info := rc.CheckKey(&keys[i])
 		if info.Status == models.RotationStatusExpired {
 			expired = append(expired, info)
@@ -95,11 +99,14 @@ func (rc *RotationChecker) GetExpiredKeys(keys []models.Key) []KeyRotationInfo {
 	return expired
 }
 
-// GetWarningKeys returns keys approaching rotation
+// GetWarningKeys returns active keys approaching rotation
 func (rc *RotationChecker) GetWarningKeys(keys []models.Key) []KeyRotationInfo {
 	var warnings []KeyRotationInfo
 
 	for i := range keys {
+		if keys[i].EffectiveState() != models.KeyStateActive {
+			continue
+		}
 		info := rc.CheckKey(&keys[i])
 		if info.Status == models.RotationStatusWarning {
 			warnings = append(warnings, info)
@@ -224,7 +231,7 @@ func FormatSummary(summary RotationSummary) string {
 
 // ShouldNotify determines if user should be notified about rotation
 func (rc *RotationChecker) ShouldNotify(key *models.Key) bool {
-	if !rc.policy.NotifyOnRotation {
+	if !rc.policy.NotifyOnRotation || key.EffectiveState() != models.KeyStateActive {
 		return false
 	}
 
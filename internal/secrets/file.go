@@ -0,0 +1,177 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/all-dot-files/ssh-key-manager/pkg/crypto"
+	"github.com/all-dot-files/ssh-key-manager/pkg/fileio"
+)
+
+// fileKeyID is recorded in the envelope header of the secrets file
+const fileKeyID = "skm-secrets"
+
+// FileStore keeps secrets in a file encrypted with a master passphrase
+type FileStore struct {
+	path       string
+	passphrase func() (string, error)
+
+	mu      sync.Mutex
+	master  string
+	secrets map[string]string
+}
+
+// NewFileStore creates a store backed by the encrypted file at path
+func NewFileStore(path string, passphrase func() (string, error)) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+// Name identifies the backend
+func (s *FileStore) Name() string {
+	return BackendFile
+}
+
+// Get returns the secret stored under name
+func (s *FileStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A missing file holds no secrets; don't ask for the master passphrase
+	if s.secrets == nil {
+		if _, err := os.Stat(s.path); os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+	}
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	value, ok := s.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores a secret and rewrites the file
+func (s *FileStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.secrets[name] = value
+	return s.save()
+}
+
+// Delete removes a secret and rewrites the file
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		if _, err := os.Stat(s.path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.secrets[name]; !ok {
+		return nil
+	}
+	delete(s.secrets, name)
+	return s.save()
+}
+
+// load decrypts the file once; a missing file starts an empty store
+func (s *FileStore) load() error {
+	if s.secrets != nil {
+		return nil
+	}
+
+	master, err := s.masterPassphrase()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.secrets = make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	plaintext, _, err := crypto.DecryptEnvelope(data, master)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets file (wrong master passphrase?): %w", err)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	s.secrets = secrets
+	return nil
+}
+
+// save encrypts the secrets and replaces the file atomically
+func (s *FileStore) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+
+	data, err := crypto.EncryptEnvelope(plaintext, s.master, fileKeyID, crypto.DefaultKDFParams())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	w, err := fileio.NewAtomicWriter(s.path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := w.Chmod(0600); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := w.Commit(); err != nil {
+		w.Abort()
+		return fmt.Errorf("failed to replace secrets file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) masterPassphrase() (string, error) {
+	if s.master != "" {
+		return s.master, nil
+	}
+	if s.passphrase == nil {
+		return "", fmt.Errorf("%w: no master passphrase for %s", ErrUnavailable, s.path)
+	}
+
+	master, err := s.passphrase()
+	if err != nil {
+		return "", err
+	}
+	if master == "" {
+		return "", fmt.Errorf("empty master passphrase")
+	}
+	s.master = master
+	return master, nil
+}
@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	asked := 0
+	master := func(passphrase string) func() (string, error) {
+		return func() (string, error) {
+			asked++
+			return passphrase, nil
+		}
+	}
+
+	store := NewFileStore(path, master("master"))
+	if _, err := store.Get(ServerToken); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from an empty store, got %v", err)
+	}
+	if asked != 0 {
+		t.Fatal("master passphrase asked before the file exists")
+	}
+
+	if err := store.Set(ServerToken, "token-value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("token-value")) {
+		t.Fatal("secrets file is not encrypted")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reopened := NewFileStore(path, master("master"))
+	if value, err := reopened.Get(ServerToken); err != nil || value != "token-value" {
+		t.Fatalf("expected token-value, got %q (%v)", value, err)
+	}
+
+	if err := reopened.Delete(ServerToken); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := reopened.Get(ServerToken); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	wrong := NewFileStore(path, master("wrong"))
+	if _, err := wrong.Get(ServerToken); err == nil {
+		t.Fatal("expected an error with the wrong master passphrase")
+	}
+}
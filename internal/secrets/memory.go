@@ -0,0 +1,49 @@
+package secrets
+
+import "sync"
+
+// MemoryStore keeps secrets in memory. It is meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: make(map[string]string)}
+}
+
+// Name identifies the backend
+func (s *MemoryStore) Name() string {
+	return BackendMemory
+}
+
+// Get returns the secret stored under name
+func (s *MemoryStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores a secret
+func (s *MemoryStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.secrets[name] = value
+	return nil
+}
+
+// Delete removes a secret
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.secrets, name)
+	return nil
+}
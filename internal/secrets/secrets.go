@@ -0,0 +1,89 @@
+// Package secrets stores small secrets such as the server token and cached
+// key passphrases outside the plaintext configuration.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Backend names accepted by Open
+const (
+	BackendAuto          = "auto"
+	BackendSecretService = "secret-service"
+	BackendFile          = "file"
+	BackendMemory        = "memory"
+	BackendNone          = "none"
+)
+
+// Well-known secret names
+const (
+	ServerToken = "server-token"
+)
+
+var (
+	// ErrNotFound is returned when a secret does not exist
+	ErrNotFound = errors.New("secret not found")
+
+	// ErrUnavailable is returned when the backend cannot be reached
+	ErrUnavailable = errors.New("secret backend unavailable")
+)
+
+// Store reads and writes named secrets
+type Store interface {
+	// Name identifies the backend
+	Name() string
+
+	// Get returns the secret stored under name or ErrNotFound
+	Get(name string) (string, error)
+
+	// Set stores a secret, replacing any previous value
+	Set(name, value string) error
+
+	// Delete removes a secret. Deleting a missing secret is not an error.
+	Delete(name string) error
+}
+
+// Options configure the backends opened by Open
+type Options struct {
+	// FilePath is the encrypted file used by the file backend
+	FilePath string
+
+	// MasterPassphrase returns the passphrase protecting the file backend.
+	// It is only called when the file is first read or written.
+	MasterPassphrase func() (string, error)
+}
+
+// Open returns the store for backend. It returns a nil store for "none", and
+// for "auto" when no Secret Service is reachable, meaning secrets stay in the
+// configuration file.
+func Open(backend string, opts Options) (Store, error) {
+	switch backend {
+	case "", BackendAuto:
+		if SecretServiceAvailable() {
+			return NewSecretServiceStore(), nil
+		}
+		return nil, nil
+	case BackendSecretService:
+		if !SecretServiceAvailable() {
+			return nil, fmt.Errorf("%w: no D-Bus session bus", ErrUnavailable)
+		}
+		return NewSecretServiceStore(), nil
+	case BackendFile:
+		if opts.FilePath == "" {
+			return nil, fmt.Errorf("file secret backend needs a path")
+		}
+		return NewFileStore(opts.FilePath, opts.MasterPassphrase), nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q (use auto, secret-service, file, memory or none)", backend)
+	}
+}
+
+// KeyPassphrase returns the secret name a key's passphrase is cached under
+func KeyPassphrase(fingerprint string) string {
+	return "key-passphrase/" + fingerprint
+}
@@ -0,0 +1,208 @@
+//go:build linux
+
+package secrets
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	ssService      = "org.freedesktop.secrets"
+	ssServicePath  = dbus.ObjectPath("/org/freedesktop/secrets")
+	ssDefaultAlias = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	ssServiceIface = "org.freedesktop.Secret.Service"
+	ssItemIface    = "org.freedesktop.Secret.Item"
+	ssPromptIface  = "org.freedesktop.Secret.Prompt"
+	ssCreateItem   = "org.freedesktop.Secret.Collection.CreateItem"
+
+	// ssApplication is stored as an attribute of every item SKM creates
+	ssApplication = "skm"
+)
+
+// noPrompt is the object path returned when no prompt is needed
+const noPrompt = dbus.ObjectPath("/")
+
+// ssSecret is the Secret Service (oayays) secret struct
+type ssSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceStore keeps secrets in the desktop keyring (GNOME Keyring,
+// KWallet) through the freedesktop Secret Service D-Bus API
+type SecretServiceStore struct{}
+
+// NewSecretServiceStore creates a Secret Service store. The session bus is
+// connected on each call.
+func NewSecretServiceStore() *SecretServiceStore {
+	return &SecretServiceStore{}
+}
+
+// SecretServiceAvailable reports whether a D-Bus session bus is configured
+func SecretServiceAvailable() bool {
+	return os.Getenv("DBUS_SESSION_BUS_ADDRESS") != ""
+}
+
+// Name identifies the backend
+func (s *SecretServiceStore) Name() string {
+	return BackendSecretService
+}
+
+// Get returns the secret stored under name
+func (s *SecretServiceStore) Get(name string) (string, error) {
+	var value string
+	err := s.withSession(func(conn *dbus.Conn, session dbus.ObjectPath) error {
+		item, err := s.findItem(conn, name)
+		if err != nil {
+			return err
+		}
+
+		var secret ssSecret
+		if err := conn.Object(ssService, item).Call(ssItemIface+".GetSecret", 0, session).Store(&secret); err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		value = string(secret.Value)
+		return nil
+	})
+	return value, err
+}
+
+// Set stores a secret in the default collection, replacing any previous value
+func (s *SecretServiceStore) Set(name, value string) error {
+	return s.withSession(func(conn *dbus.Conn, session dbus.ObjectPath) error {
+		if err := s.unlock(conn, []dbus.ObjectPath{ssDefaultAlias}); err != nil {
+			return err
+		}
+
+		properties := map[string]dbus.Variant{
+			ssItemIface + ".Label":      dbus.MakeVariant("SKM " + name),
+			ssItemIface + ".Attributes": dbus.MakeVariant(attributes(name)),
+		}
+		secret := ssSecret{Session: session, Value: []byte(value), ContentType: "text/plain"}
+
+		var item, prompt dbus.ObjectPath
+		call := conn.Object(ssService, ssDefaultAlias).Call(ssCreateItem, 0, properties, secret, true)
+		if err := call.Store(&item, &prompt); err != nil {
+			return fmt.Errorf("failed to store secret: %w", err)
+		}
+		return s.prompt(conn, prompt)
+	})
+}
+
+// Delete removes a secret
+func (s *SecretServiceStore) Delete(name string) error {
+	return s.withSession(func(conn *dbus.Conn, session dbus.ObjectPath) error {
+		item, err := s.findItem(conn, name)
+		if err == ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var prompt dbus.ObjectPath
+		if err := conn.Object(ssService, item).Call(ssItemIface+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("failed to delete secret: %w", err)
+		}
+		return s.prompt(conn, prompt)
+	})
+}
+
+// withSession connects to the session bus and opens a plain session. The
+// bus is local to the user, so secrets are not transport-encrypted.
+func (s *SecretServiceStore) withSession(fn func(conn *dbus.Conn, session dbus.ObjectPath) error) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer conn.Close()
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	call := conn.Object(ssService, ssServicePath).Call(ssServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant(""))
+	if err := call.Store(&output, &session); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer conn.Object(ssService, session).Call("org.freedesktop.Secret.Session.Close", 0)
+
+	return fn(conn, session)
+}
+
+// findItem returns the unlocked item holding name
+func (s *SecretServiceStore) findItem(conn *dbus.Conn, name string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	call := conn.Object(ssService, ssServicePath).Call(ssServiceIface+".SearchItems", 0, attributes(name))
+	if err := call.Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("failed to search secrets: %w", err)
+	}
+
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", ErrNotFound
+	}
+
+	if err := s.unlock(conn, locked[:1]); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+// unlock unlocks objects, letting the keyring ask the user if needed
+func (s *SecretServiceStore) unlock(conn *dbus.Conn, objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	call := conn.Object(ssService, ssServicePath).Call(ssServiceIface+".Unlock", 0, objects)
+	if err := call.Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	return s.prompt(conn, prompt)
+}
+
+// prompt runs a Secret Service prompt and waits for it to complete
+func (s *SecretServiceStore) prompt(conn *dbus.Conn, prompt dbus.ObjectPath) error {
+	if prompt == noPrompt || prompt == "" {
+		return nil
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(ssPromptIface),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return fmt.Errorf("failed to watch keyring prompt: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(ssService, prompt).Call(ssPromptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	for signal := range signals {
+		if signal.Path != prompt || len(signal.Body) == 0 {
+			continue
+		}
+		if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+			return fmt.Errorf("keyring prompt dismissed")
+		}
+		return nil
+	}
+	return fmt.Errorf("keyring prompt interrupted")
+}
+
+// attributes identify the item holding name
+func attributes(name string) map[string]string {
+	return map[string]string{
+		"application": ssApplication,
+		"name":        name,
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package secrets
+
+// SecretServiceStore is only available on Linux
+type SecretServiceStore struct{}
+
+// NewSecretServiceStore returns a store whose operations fail with ErrUnavailable
+func NewSecretServiceStore() *SecretServiceStore {
+	return &SecretServiceStore{}
+}
+
+// SecretServiceAvailable reports whether the Secret Service can be used
+func SecretServiceAvailable() bool {
+	return false
+}
+
+// Name identifies the backend
+func (s *SecretServiceStore) Name() string {
+	return BackendSecretService
+}
+
+// Get always fails outside Linux
+func (s *SecretServiceStore) Get(name string) (string, error) {
+	return "", ErrUnavailable
+}
+
+// Set always fails outside Linux
+func (s *SecretServiceStore) Set(name, value string) error {
+	return ErrUnavailable
+}
+
+// Delete always fails outside Linux
+func (s *SecretServiceStore) Delete(name string) error {
+	return ErrUnavailable
+}
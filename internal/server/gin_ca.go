@@ -0,0 +1,275 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/api"
+	"github.com/all-dot-files/ssh-key-manager/internal/ca"
+)
+
+// loadCA returns the authority of the given kind, creating its key on first use
+func (gs *GinServer) loadCA(kind string) (*ca.Authority, error) {
+	gs.caMu.Lock()
+	defer gs.caMu.Unlock()
+
+	keyData, err := gs.store.GetCAKey(kind)
+	if os.IsNotExist(err) {
+		keyData, err = ca.GenerateKey(fmt.Sprintf("skm-%s-ca", kind))
+		if err != nil {
+			return nil, err
+		}
+		if err := gs.store.SaveCAKey(kind, keyData); err != nil {
+			return nil, fmt.Errorf("failed to save CA key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load CA key: %w", err)
+	}
+
+	return ca.Load(keyData)
+}
+
+func (gs *GinServer) handleGetUserCA(c *gin.Context) {
+	gs.respondCAPublicKey(c, ca.KindUser)
+}
+
+func (gs *GinServer) handleGetHostCA(c *gin.Context) {
+	gs.respondCAPublicKey(c, ca.KindHost)
+}
+
+// respondCAPublicKey writes the public key of the CA of the given kind
+func (gs *GinServer) respondCAPublicKey(c *gin.Context, kind string) {
+	authority, err := gs.loadCA(kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to load %s CA", kind)})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.CAPublicKey{
+		Kind:      kind,
+		PublicKey: authority.AuthorizedKey(),
+	})
+}
+
+func (gs *GinServer) handleSignUserCertificate(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req api.CertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.Key.PublicKey))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid public key"})
+		return
+	}
+
+	revoked, err := gs.revokedFingerprints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revoked keys"})
+		return
+	}
+	if revoked[ssh.FingerprintSHA256(publicKey)] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Key has been revoked"})
+		return
+	}
+
+	user, err := gs.store.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Users may only request principals they have been granted
+	principals := req.Principals
+	if len(principals) == 0 {
+		principals = []string{user.Username}
+	}
+	allowed := map[string]bool{user.Username: true}
+	for _, p := range user.Principals {
+		allowed[p] = true
+	}
+	for _, p := range principals {
+		if !allowed[p] {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Principal not allowed: %s", p)})
+			return
+		}
+	}
+
+	authority, err := gs.loadCA(ca.KindUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user CA"})
+		return
+	}
+
+	serial, err := gs.store.NextCertSerial()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate serial"})
+		return
+	}
+
+	signReq := ca.UserRequest{
+		PublicKey:       publicKey,
+		KeyID:           fmt.Sprintf("skm:%s:%s", user.Username, req.Key.Name),
+		Serial:          serial,
+		Principals:      principals,
+		CriticalOptions: req.CriticalOptions,
+	}
+	if len(req.Extensions) > 0 {
+		signReq.Extensions = req.Extensions
+	}
+	if req.ValidAfter != nil {
+		signReq.ValidAfter = *req.ValidAfter
+	}
+	if req.ValidBefore != nil {
+		signReq.ValidBefore = *req.ValidBefore
+	}
+
+	cert, err := authority.SignUser(signReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record := &CertificateRecord{
+		Serial:      cert.Serial,
+		Kind:        ca.KindUser,
+		KeyID:       cert.KeyId,
+		KeyName:     req.Key.Name,
+		Fingerprint: ssh.FingerprintSHA256(publicKey),
+		Principals:  cert.ValidPrincipals,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0).UTC(),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0).UTC(),
+		IssuedAt:    time.Now().UTC(),
+	}
+	if err := gs.store.SaveCertificate(userID, record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record certificate"})
+		return
+	}
+
+	gs.store.LogAudit(userID, "cert_issue", fmt.Sprintf("Issued user certificate %d for key %s (principals: %s)",
+		cert.Serial, req.Key.Name, strings.Join(cert.ValidPrincipals, ",")))
+
+	c.JSON(http.StatusOK, api.CertificateResponse{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		Serial:      record.Serial,
+		KeyID:       record.KeyID,
+		Principals:  record.Principals,
+		ValidAfter:  record.ValidAfter,
+		ValidBefore: record.ValidBefore,
+	})
+}
+
+func (gs *GinServer) handleSignHostCertificate(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req api.CertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.Key.PublicKey))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid public key"})
+		return
+	}
+
+	user, err := gs.store.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// A host certificate lets its holder impersonate the host, so every
+	// hostname must be covered by the user's host patterns
+	if len(req.Principals) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one hostname is required"})
+		return
+	}
+	for _, hostname := range req.Principals {
+		if !matchesHostPattern(user.HostPatterns, hostname) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Hostname not allowed: %s", hostname)})
+			return
+		}
+	}
+
+	authority, err := gs.loadCA(ca.KindHost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load host CA"})
+		return
+	}
+
+	serial, err := gs.store.NextCertSerial()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate serial"})
+		return
+	}
+
+	signReq := ca.HostRequest{
+		PublicKey: publicKey,
+		KeyID:     fmt.Sprintf("skm-host:%s:%s", user.Username, req.Principals[0]),
+		Serial:    serial,
+		Hostnames: req.Principals,
+	}
+	if req.ValidAfter != nil {
+		signReq.ValidAfter = *req.ValidAfter
+	}
+	if req.ValidBefore != nil {
+		signReq.ValidBefore = *req.ValidBefore
+	}
+
+	cert, err := authority.SignHost(signReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record := &CertificateRecord{
+		Serial:      cert.Serial,
+		Kind:        ca.KindHost,
+		KeyID:       cert.KeyId,
+		KeyName:     req.Key.Name,
+		Fingerprint: ssh.FingerprintSHA256(publicKey),
+		Principals:  cert.ValidPrincipals,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0).UTC(),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0).UTC(),
+		IssuedAt:    time.Now().UTC(),
+	}
+	if err := gs.store.SaveCertificate(userID, record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record certificate"})
+		return
+	}
+
+	gs.store.LogAudit(userID, "host_cert_issue", fmt.Sprintf("Issued host certificate %d (hostnames: %s)",
+		cert.Serial, strings.Join(cert.ValidPrincipals, ",")))
+
+	c.JSON(http.StatusOK, api.CertificateResponse{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		Serial:      record.Serial,
+		KeyID:       record.KeyID,
+		Principals:  record.Principals,
+		ValidAfter:  record.ValidAfter,
+		ValidBefore: record.ValidBefore,
+	})
+}
+
+// matchesHostPattern reports whether hostname matches one of the glob patterns
+func matchesHostPattern(patterns []string, hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), hostname); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
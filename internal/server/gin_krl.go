@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/ca"
+	"github.com/all-dot-files/ssh-key-manager/internal/krl"
+)
+
+// handleGetKRL serves the user's key revocation list in the binary OpenSSH
+// format. The list covers every revoked key and the serials of certificates
+// issued for them by an existing CA. Its version is the revocation counter
+// of the store, which grows on every change to the revoked keys, and is also
+// sent as the ETag.
+func (gs *GinServer) handleGetKRL(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	// Read the version first: a change racing with this request then serves
+	// newer keys under an older version, never older keys under a newer one
+	version, err := gs.store.GetRevocationVersion(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the revocation version"})
+		return
+	}
+	revoked, err := gs.store.GetRevokedKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revoked keys"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"krl-%d"`, version)
+	c.Header("ETag", etag)
+	c.Header("X-KRL-Version", strconv.FormatUint(version, 10))
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	list := krl.New(version, "skm-server revoked keys")
+	fingerprints := make(map[string]bool, len(revoked))
+	for _, key := range revoked {
+		fingerprints[key.Fingerprint] = true
+		if pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey)); err == nil {
+			list.RevokeKey(pub)
+			continue
+		}
+		if err := list.RevokeFingerprint(key.Fingerprint); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Cannot revoke %s: %v", key.Name, err)})
+			return
+		}
+	}
+
+	certs, err := gs.store.GetCertificates(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve certificates"})
+		return
+	}
+	authorities := make(map[string]ssh.PublicKey)
+	for _, cert := range certs {
+		if !fingerprints[cert.Fingerprint] {
+			continue
+		}
+		authority, ok := authorities[cert.Kind]
+		if !ok {
+			authority, err = gs.existingCAKey(cert.Kind)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to load %s CA", cert.Kind)})
+				return
+			}
+			authorities[cert.Kind] = authority
+		}
+		if authority != nil {
+			list.RevokeCertSerial(authority, cert.Serial)
+		}
+	}
+
+	c.Data(http.StatusOK, "application/octet-stream", list.Marshal())
+}
+
+// existingCAKey returns the public key of the CA of the given kind, or nil if
+// the CA has not been created
+func (gs *GinServer) existingCAKey(kind string) (ssh.PublicKey, error) {
+	gs.caMu.Lock()
+	defer gs.caMu.Unlock()
+
+	keyData, err := gs.store.GetCAKey(kind)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	authority, err := ca.Load(keyData)
+	if err != nil {
+		return nil, err
+	}
+	return authority.PublicKey(), nil
+}
+
+// revokedFingerprints returns the fingerprints of keys revoked by a user
+func (gs *GinServer) revokedFingerprints(userID string) (map[string]bool, error) {
+	keys, err := gs.store.GetRevokedKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		revoked[key.Fingerprint] = true
+	}
+	return revoked, nil
+}
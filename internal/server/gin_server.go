@@ -5,11 +5,13 @@ import (
 	"html/template"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/all-dot-files/ssh-key-manager/internal/api"
+	"github.com/all-dot-files/ssh-key-manager/internal/knownhosts"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
 )
 
@@ -18,6 +20,13 @@ type GinServer struct {
 	engine    *gin.Engine
 	jwtSecret []byte
 	store     Store
+
+	// caMu serializes creation of CA keys
+	caMu sync.Mutex
+	// hostKeysMu serializes merges of pushed host keys
+	hostKeysMu sync.Mutex
+	// keysMu serializes key saves with tombstone merges
+	keysMu sync.Mutex
 }
 
 // TokenAuthMiddleware 校验 header 里的 token 或 cookie 里的 token
@@ -117,6 +126,10 @@ func (gs *GinServer) setupRoutes() {
 		api.POST("/auth/login", gs.handleAPILogin)
 		api.POST("/auth/register", gs.handleAPIRegister)
 
+		// CA public keys are public so servers can be provisioned to trust them
+		api.GET("/ca/user", gs.handleGetUserCA)
+		api.GET("/ca/host", gs.handleGetHostCA)
+
 		// Protected API routes
 		protected := api.Group("", gs.TokenAuthMiddleware())
 		{
@@ -130,6 +143,17 @@ func (gs *GinServer) setupRoutes() {
 			protected.GET("/keys/public", gs.handleGetPublicKeys)
 			protected.POST("/keys/private", gs.handleSavePrivateKeys)
 			protected.GET("/keys/private", gs.handleGetPrivateKeys)
+			protected.POST("/keys/tombstones", gs.handleSaveTombstones)
+			protected.GET("/keys/tombstones", gs.handleGetTombstones)
+			protected.POST("/hostkeys", gs.handleSaveHostKeys)
+			protected.GET("/hostkeys", gs.handleGetHostKeys)
+
+			// Key revocation list for RevokedKeys in sshd_config
+			protected.GET("/krl", gs.handleGetKRL)
+
+			// Certificate authority
+			protected.POST("/ca/user/sign", gs.handleSignUserCertificate)
+			protected.POST("/ca/host/sign", gs.handleSignHostCertificate)
 
 			// Audit
 			protected.GET("/audit", gs.handleGetAuditLogs)
@@ -293,16 +317,44 @@ func (gs *GinServer) handleKeys(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Failed to load keys")
 		return
 	}
+	keys, err = filterPublicKeysByState(keys, c.Query("state"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
 	user, _ := gs.store.GetUserByID(userID)
 
 	c.HTML(http.StatusOK, "keys.html", gin.H{
-		"Keys":       keys,
-		"User":       user,
-		"ActivePage": "keys",
+		"Keys":        keys,
+		"User":        user,
+		"ActivePage":  "keys",
+		"States":      models.KeyStates,
+		"StateFilter": c.Query("state"),
 	})
 }
 
+// filterPublicKeysByState keeps the keys in the given lifecycle state; an
+// empty state keeps every key
+func filterPublicKeysByState(keys []api.PublicKeyData, state string) ([]api.PublicKeyData, error) {
+	if state == "" {
+		return keys, nil
+	}
+	want, err := models.ParseKeyState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []api.PublicKeyData
+	for _, key := range keys {
+		k := models.Key{State: key.State}
+		if k.EffectiveState() == want {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered, nil
+}
+
 func (gs *GinServer) handleDevices(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -468,13 +520,21 @@ func (gs *GinServer) handleGetDevices(c *gin.Context) {
 func (gs *GinServer) handleRevokeDevice(c *gin.Context) {
 	userID := c.GetString("user_id")
 	deviceID := c.Param("id")
+	revokeKeys := c.Query("revoke_keys") == "true"
 
-	if err := gs.store.RevokeDevice(userID, deviceID); err != nil {
+	gs.keysMu.Lock()
+	defer gs.keysMu.Unlock()
+
+	if err := gs.store.RevokeDevice(userID, deviceID, revokeKeys); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke device"})
 		return
 	}
 
-	gs.store.LogAudit(userID, "device_revoke", fmt.Sprintf("Revoked device: %s", deviceID))
+	if revokeKeys {
+		gs.store.LogAudit(userID, "device_revoke", fmt.Sprintf("Revoked device and its keys: %s", deviceID))
+	} else {
+		gs.store.LogAudit(userID, "device_revoke", fmt.Sprintf("Revoked device: %s", deviceID))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
@@ -482,16 +542,60 @@ func (gs *GinServer) handleRevokeDevice(c *gin.Context) {
 func (gs *GinServer) handleSavePublicKeys(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var keys []api.PublicKeyData
-	if err := c.ShouldBindJSON(&keys); err != nil {
+	var pushed []api.PublicKeyData
+	if err := c.ShouldBindJSON(&pushed); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
+	gs.keysMu.Lock()
+	defer gs.keysMu.Unlock()
+
+	deleted, err := gs.tombstonedFingerprints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tombstones"})
+		return
+	}
+
+	revoked, err := gs.revokedFingerprints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revoked keys"})
+		return
+	}
+
+	// Keys deleted on another device are not brought back by a stale push,
+	// and revoked keys stay revoked
+	keys := make([]api.PublicKeyData, 0, len(pushed))
+	var newlyRevoked []api.RevokedKeyData
+	for _, key := range pushed {
+		if deleted[key.Fingerprint] {
+			continue
+		}
+		if revoked[key.Fingerprint] {
+			key.State = models.KeyStateRevoked
+		} else if key.State == models.KeyStateRevoked {
+			newlyRevoked = append(newlyRevoked, api.RevokedKeyData{
+				Fingerprint: key.Fingerprint,
+				PublicKey:   key.PublicKey,
+				Name:        key.Name,
+				DeviceID:    key.DeviceID,
+				RevokedAt:   time.Now(),
+			})
+		}
+		keys = append(keys, key)
+	}
+
 	if err := gs.store.SavePublicKeys(userID, keys); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save keys"})
 		return
 	}
+	if len(newlyRevoked) > 0 {
+		if err := gs.store.AddRevokedKeys(userID, newlyRevoked); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revoked keys"})
+			return
+		}
+		gs.store.LogAudit(userID, "keys_revoke", fmt.Sprintf("Revoked %d keys", len(newlyRevoked)))
+	}
 
 	gs.store.LogAudit(userID, "keys_save", fmt.Sprintf("Saved %d public keys", len(keys)))
 
@@ -513,12 +617,28 @@ func (gs *GinServer) handleGetPublicKeys(c *gin.Context) {
 func (gs *GinServer) handleSavePrivateKeys(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var keys []api.PrivateKeyData
-	if err := c.ShouldBindJSON(&keys); err != nil {
+	var pushed []api.PrivateKeyData
+	if err := c.ShouldBindJSON(&pushed); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
+	gs.keysMu.Lock()
+	defer gs.keysMu.Unlock()
+
+	deleted, err := gs.tombstonedFingerprints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tombstones"})
+		return
+	}
+
+	keys := make([]api.PrivateKeyData, 0, len(pushed))
+	for _, key := range pushed {
+		if !deleted[key.Fingerprint] {
+			keys = append(keys, key)
+		}
+	}
+
 	if err := gs.store.SavePrivateKeys(userID, keys); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save keys"})
 		return
@@ -541,6 +661,171 @@ func (gs *GinServer) handleGetPrivateKeys(c *gin.Context) {
 	c.JSON(http.StatusOK, keys)
 }
 
+// handleSaveTombstones merges pushed tombstones and drops the deleted keys
+// from the stored public and private keys
+func (gs *GinServer) handleSaveTombstones(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var pushed []models.KeyTombstone
+	if err := c.ShouldBindJSON(&pushed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	gs.keysMu.Lock()
+	defer gs.keysMu.Unlock()
+
+	tombstones, err := gs.store.GetTombstones(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tombstones"})
+		return
+	}
+
+	deleted := make(map[string]bool, len(tombstones))
+	for _, t := range tombstones {
+		deleted[t.Fingerprint] = true
+	}
+	added := 0
+	for _, t := range pushed {
+		if t.Fingerprint == "" || deleted[t.Fingerprint] {
+			continue
+		}
+		tombstones = append(tombstones, t)
+		deleted[t.Fingerprint] = true
+		added++
+	}
+
+	if err := gs.store.SaveTombstones(userID, tombstones); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tombstones"})
+		return
+	}
+
+	publicKeys, err := gs.store.GetPublicKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve keys"})
+		return
+	}
+	keptPublic := publicKeys[:0]
+	for _, key := range publicKeys {
+		if !deleted[key.Fingerprint] {
+			keptPublic = append(keptPublic, key)
+		}
+	}
+
+	privateKeys, err := gs.store.GetPrivateKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve keys"})
+		return
+	}
+	keptPrivate := privateKeys[:0]
+	for _, key := range privateKeys {
+		if !deleted[key.Fingerprint] {
+			keptPrivate = append(keptPrivate, key)
+		}
+	}
+
+	if err := gs.store.SavePublicKeys(userID, keptPublic); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save keys"})
+		return
+	}
+	if err := gs.store.SavePrivateKeys(userID, keptPrivate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save keys"})
+		return
+	}
+
+	gs.store.LogAudit(userID, "keys_delete", fmt.Sprintf("Recorded %d key tombstones", added))
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (gs *GinServer) handleGetTombstones(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	tombstones, err := gs.store.GetTombstones(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tombstones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tombstones)
+}
+
+// tombstonedFingerprints returns the fingerprints of keys deleted by a user
+func (gs *GinServer) tombstonedFingerprints(userID string) (map[string]bool, error) {
+	tombstones, err := gs.store.GetTombstones(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]bool, len(tombstones))
+	for _, t := range tombstones {
+		deleted[t.Fingerprint] = true
+	}
+	return deleted, nil
+}
+
+// handleSaveHostKeys merges pushed host key pins, keeping the most recently
+// pinned keys of each host so devices converge on the same pins
+func (gs *GinServer) handleSaveHostKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var pushed []api.HostKeyData
+	if err := c.ShouldBindJSON(&pushed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	gs.hostKeysMu.Lock()
+	defer gs.hostKeysMu.Unlock()
+
+	hosts, err := gs.store.GetHostKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve host keys"})
+		return
+	}
+
+	updated := 0
+	for _, p := range pushed {
+		found := false
+		for i := range hosts {
+			if hosts[i].Address != p.Address {
+				continue
+			}
+			found = true
+			if knownhosts.PinnedAt(p.Keys).After(knownhosts.PinnedAt(hosts[i].Keys)) {
+				hosts[i] = p
+				updated++
+			}
+			break
+		}
+		if !found {
+			hosts = append(hosts, p)
+			updated++
+		}
+	}
+
+	if err := gs.store.SaveHostKeys(userID, hosts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save host keys"})
+		return
+	}
+
+	gs.store.LogAudit(userID, "hostkeys_save", fmt.Sprintf("Updated pinned keys of %d hosts", updated))
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (gs *GinServer) handleGetHostKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	hosts, err := gs.store.GetHostKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve host keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hosts)
+}
+
 func (gs *GinServer) handleGetAuditLogs(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -30,19 +30,50 @@ type Store interface {
 	// Device operations
 	RegisterDevice(userID string, device *models.Device) error
 	GetDevices(userID string) ([]models.Device, error)
-	RevokeDevice(userID, deviceID string) error
+	// RevokeDevice revokes a device and, with revokeKeys, every key created on it
+	RevokeDevice(userID, deviceID string, revokeKeys bool) error
 
 	// Key operations
 	SavePublicKeys(userID string, keys []api.PublicKeyData) error
 	GetPublicKeys(userID string) ([]api.PublicKeyData, error)
 	SavePrivateKeys(userID string, keys []api.PrivateKeyData) error
 	GetPrivateKeys(userID string) ([]api.PrivateKeyData, error)
+	SaveTombstones(userID string, tombstones []models.KeyTombstone) error
+	GetTombstones(userID string) ([]models.KeyTombstone, error)
+	AddRevokedKeys(userID string, keys []api.RevokedKeyData) error
+	GetRevokedKeys(userID string) ([]api.RevokedKeyData, error)
+	// GetRevocationVersion returns a counter increased on every change to the revoked keys
+	GetRevocationVersion(userID string) (uint64, error)
+
+	// Host key operations
+	SaveHostKeys(userID string, hosts []api.HostKeyData) error
+	GetHostKeys(userID string) ([]api.HostKeyData, error)
+
+	// Certificate authority operations
+	GetCAKey(kind string) ([]byte, error)
+	SaveCAKey(kind string, key []byte) error
+	NextCertSerial() (uint64, error)
+	SaveCertificate(userID string, record *CertificateRecord) error
+	GetCertificates(userID string) ([]CertificateRecord, error)
 
 	// Audit
 	LogAudit(userID, action, details string) error
 	GetAuditLogs(userID string, limit int) ([]interface{}, error)
 }
 
+// CertificateRecord records a certificate issued by the server CA
+type CertificateRecord struct {
+	Serial      uint64    `json:"serial"`
+	Kind        string    `json:"kind"` // "user" or "host"
+	KeyID       string    `json:"key_id"`
+	KeyName     string    `json:"key_name,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	Principals  []string  `json:"principals"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
 // User represents a user in the system
 type User struct {
 	ID           string    `json:"id"`
@@ -50,6 +81,10 @@ type User struct {
 	PasswordHash string    `json:"password_hash"` // Store in file, but exclude from API responses
 	Email        string    `json:"email"`
 	CreatedAt    time.Time `json:"created_at"`
+	// Principals lists extra certificate principals granted to the user besides their username
+	Principals []string `json:"principals,omitempty"`
+	// HostPatterns lists the hostnames (glob patterns allowed) the user may certify host keys for
+	HostPatterns []string `json:"host_patterns,omitempty"`
 }
 
 // UserResponse represents a user for API responses (without password)
@@ -251,7 +286,8 @@ func (s *Server) handleDeviceOperation(w http.ResponseWriter, r *http.Request, u
 	}
 
 	if operation == "revoke" && r.Method == http.MethodPost {
-		if err := s.store.RevokeDevice(userID, deviceID); err != nil {
+		revokeKeys := r.URL.Query().Get("revoke_keys") == "true"
+		if err := s.store.RevokeDevice(userID, deviceID, revokeKeys); err != nil {
 			http.Error(w, "Failed to revoke device", http.StatusInternalServerError)
 			return
 		}
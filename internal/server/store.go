@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/all-dot-files/ssh-key-manager/internal/api"
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
@@ -156,8 +159,9 @@ func (fs *FileStore) GetDevices(userID string) ([]models.Device, error) {
 	return devices, nil
 }
 
-// RevokeDevice revokes a device
-func (fs *FileStore) RevokeDevice(userID, deviceID string) error {
+// RevokeDevice revokes a device. With revokeKeys every stored public key
+// created on the device is marked revoked and added to the revoked keys.
+func (fs *FileStore) RevokeDevice(userID, deviceID string, revokeKeys bool) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
@@ -179,7 +183,52 @@ func (fs *FileStore) RevokeDevice(userID, deviceID string) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	if !revokeKeys {
+		return nil
+	}
+
+	keysPath := filepath.Join(fs.basePath, "keys", userID, "public_keys.json")
+	var keys []api.PublicKeyData
+	if data, err := os.ReadFile(keysPath); err == nil {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	now := time.Now()
+	var revoked []api.RevokedKeyData
+	for i := range keys {
+		if keys[i].DeviceID != deviceID {
+			continue
+		}
+		keys[i].State = models.KeyStateRevoked
+		revoked = append(revoked, api.RevokedKeyData{
+			Fingerprint: keys[i].Fingerprint,
+			PublicKey:   keys[i].PublicKey,
+			Name:        keys[i].Name,
+			DeviceID:    deviceID,
+			RevokedAt:   now,
+		})
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+
+	data, err = json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keysPath, data, 0600); err != nil {
+		return err
+	}
+
+	return fs.addRevokedKeys(userID, revoked)
 }
 
 // SavePublicKeys saves public keys for a user
@@ -264,6 +313,206 @@ func (fs *FileStore) GetPrivateKeys(userID string) ([]api.PrivateKeyData, error)
 	return keys, nil
 }
 
+// SaveTombstones saves the tombstones of deleted keys for a user
+func (fs *FileStore) SaveTombstones(userID string, tombstones []models.KeyTombstone) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, "keys", userID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "tombstones.json")
+	data, err := json.Marshal(tombstones)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// GetTombstones retrieves the tombstones of deleted keys for a user
+func (fs *FileStore) GetTombstones(userID string) ([]models.KeyTombstone, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	path := filepath.Join(fs.basePath, "keys", userID, "tombstones.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.KeyTombstone{}, nil
+		}
+		return nil, err
+	}
+
+	var tombstones []models.KeyTombstone
+	if err := json.Unmarshal(data, &tombstones); err != nil {
+		return nil, err
+	}
+
+	return tombstones, nil
+}
+
+// AddRevokedKeys records revoked keys for a user. The list only grows; keys
+// already revoked are skipped.
+func (fs *FileStore) AddRevokedKeys(userID string, keys []api.RevokedKeyData) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.addRevokedKeys(userID, keys)
+}
+
+// addRevokedKeys implements AddRevokedKeys. Callers must hold fs.mu.
+func (fs *FileStore) addRevokedKeys(userID string, keys []api.RevokedKeyData) error {
+	dir := filepath.Join(fs.basePath, "keys", userID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "revoked_keys.json")
+	var revoked []api.RevokedKeyData
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &revoked); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	version, err := fs.revocationVersion(userID, len(revoked))
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(revoked))
+	for _, key := range revoked {
+		known[key.Fingerprint] = true
+	}
+	added := 0
+	for _, key := range keys {
+		if key.Fingerprint == "" || known[key.Fingerprint] {
+			continue
+		}
+		revoked = append(revoked, key)
+		known[key.Fingerprint] = true
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(revoked)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	version++
+	return os.WriteFile(filepath.Join(dir, "revoked_version"), []byte(strconv.FormatUint(version, 10)), 0600)
+}
+
+// GetRevocationVersion returns the version of the revoked keys of a user
+func (fs *FileStore) GetRevocationVersion(userID string) (uint64, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	revoked, err := fs.readRevokedKeys(userID)
+	if err != nil {
+		return 0, err
+	}
+	return fs.revocationVersion(userID, len(revoked))
+}
+
+// revocationVersion reads the version of the revoked keys of a user. Lists
+// written before the version was kept start from their length, the version
+// served for them until then. Callers must hold fs.mu.
+func (fs *FileStore) revocationVersion(userID string, count int) (uint64, error) {
+	path := filepath.Join(fs.basePath, "keys", userID, "revoked_version")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return uint64(count), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid revocation version file: %w", err)
+	}
+	return version, nil
+}
+
+// GetRevokedKeys retrieves the revoked keys of a user
+func (fs *FileStore) GetRevokedKeys(userID string) ([]api.RevokedKeyData, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return fs.readRevokedKeys(userID)
+}
+
+// readRevokedKeys implements GetRevokedKeys. Callers must hold fs.mu.
+func (fs *FileStore) readRevokedKeys(userID string) ([]api.RevokedKeyData, error) {
+	path := filepath.Join(fs.basePath, "keys", userID, "revoked_keys.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []api.RevokedKeyData{}, nil
+		}
+		return nil, err
+	}
+
+	var keys []api.RevokedKeyData
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// SaveHostKeys saves pinned host keys for a user
+func (fs *FileStore) SaveHostKeys(userID string, hosts []api.HostKeyData) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, "keys", userID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "host_keys.json")
+	data, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// GetHostKeys retrieves pinned host keys for a user
+func (fs *FileStore) GetHostKeys(userID string) ([]api.HostKeyData, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	path := filepath.Join(fs.basePath, "keys", userID, "host_keys.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []api.HostKeyData{}, nil
+		}
+		return nil, err
+	}
+
+	var hosts []api.HostKeyData
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
 // LogAudit logs an audit event
 func (fs *FileStore) LogAudit(userID, action, details string) error {
 	fs.mu.Lock()
@@ -312,3 +561,104 @@ func (fs *FileStore) GetAuditLogs(userID string, limit int) ([]interface{}, erro
 	// In production, parse the log file and return structured data
 	return []interface{}{}, nil
 }
+
+// GetCAKey retrieves the private key of a certificate authority
+func (fs *FileStore) GetCAKey(kind string) ([]byte, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	return os.ReadFile(filepath.Join(fs.basePath, "ca", kind+"_ca"))
+}
+
+// SaveCAKey saves the private key of a certificate authority
+func (fs *FileStore) SaveCAKey(kind string, key []byte) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, "ca")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, kind+"_ca"), key, 0600)
+}
+
+// NextCertSerial allocates the next certificate serial number
+func (fs *FileStore) NextCertSerial() (uint64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, "ca")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, err
+	}
+
+	path := filepath.Join(dir, "serial")
+	var serial uint64
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err == nil {
+		serial, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid serial file: %w", err)
+		}
+	}
+
+	serial++
+	if err := os.WriteFile(path, []byte(strconv.FormatUint(serial, 10)), 0600); err != nil {
+		return 0, err
+	}
+
+	return serial, nil
+}
+
+// SaveCertificate records an issued certificate
+func (fs *FileStore) SaveCertificate(userID string, record *CertificateRecord) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dir := filepath.Join(fs.basePath, "certs", userID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "certificates.json")
+	var records []CertificateRecord
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return err
+		}
+	}
+
+	records = append(records, *record)
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// GetCertificates retrieves the certificates issued to a user
+func (fs *FileStore) GetCertificates(userID string) ([]CertificateRecord, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	path := filepath.Join(fs.basePath, "certs", userID, "certificates.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CertificateRecord{}, nil
+		}
+		return nil, err
+	}
+
+	var records []CertificateRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
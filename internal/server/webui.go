@@ -11,6 +11,8 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
 )
 
 //go:embed templates/*.html static/css/* static/js/*
@@ -186,9 +188,17 @@ func (ui *WebUI) handleKeys(w http.ResponseWriter, r *http.Request, userID strin
 		http.Error(w, "Failed to load keys", http.StatusInternalServerError)
 		return
 	}
+	state := r.URL.Query().Get("state")
+	keys, err = filterPublicKeysByState(keys, state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	data := map[string]interface{}{
-		"Keys": keys,
+		"Keys":        keys,
+		"States":      models.KeyStates,
+		"StateFilter": state,
 	}
 
 	ui.templates.ExecuteTemplate(w, "keys.html", data)
@@ -325,7 +335,8 @@ func (ui *WebUI) handleAPIDevicesRevoke(w http.ResponseWriter, r *http.Request,
 
 	deviceID := strings.TrimPrefix(r.URL.Path, "/api/devices/revoke/")
 
-	if err := ui.server.store.RevokeDevice(userID, deviceID); err != nil {
+	revokeKeys := r.URL.Query().Get("revoke_keys") == "true"
+	if err := ui.server.store.RevokeDevice(userID, deviceID, revokeKeys); err != nil {
 		http.Error(w, "Failed to revoke device", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,130 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/pattern"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig/parser"
+)
+
+// UserHostBlock is a Host block of the user's own SSH config naming concrete
+// hosts
+type UserHostBlock struct {
+	File string
+	// Line is the Host line and EndLine the line of the last directive
+	Line       int
+	EndLine    int
+	Aliases    []string
+	Directives []*parser.Directive
+}
+
+// Source returns where the block starts
+func (b *UserHostBlock) Source() string {
+	return fmt.Sprintf("%s:%d", b.File, b.Line)
+}
+
+// InConfig reports whether the block is in the SSH config itself rather than
+// in a file it includes
+func (m *Manager) InConfig(b *UserHostBlock) bool {
+	return b.File == m.configPath
+}
+
+// UserHostBlocks returns the Host blocks of the SSH config and the files it
+// includes whose patterns are all concrete host names, in the order ssh reads
+// them. The hosts written by SKM are left out. Blocks mixing names with
+// wildcards or negations are reported as warnings.
+func (m *Manager) UserHostBlocks() ([]UserHostBlock, []string, error) {
+	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	file, err := parser.Parse(m.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse SSH config: %w", err)
+	}
+	managedStart, managedEnd, err := m.managedLines()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocks []UserHostBlock
+	var warnings []string
+	file.Walk(func(f *parser.File, block *parser.Block) {
+		if block.Kind != parser.BlockHost || f.Path == m.includePath {
+			return
+		}
+		if f.Path == m.configPath && block.Line > managedStart && block.Line < managedEnd {
+			return
+		}
+
+		concrete := 0
+		for _, arg := range block.Args {
+			if !strings.HasPrefix(arg, "!") && !pattern.HasWildcard(arg) {
+				concrete++
+			}
+		}
+		if concrete == 0 {
+			return
+		}
+		if concrete < len(block.Args) {
+			warnings = append(warnings, fmt.Sprintf("Host %s at %s:%d mixes host names with patterns and is not imported",
+				strings.Join(block.Args, " "), f.Path, block.Line))
+			return
+		}
+
+		blocks = append(blocks, UserHostBlock{
+			File:       f.Path,
+			Line:       block.Line,
+			EndLine:    blockEnd(block),
+			Aliases:    block.Args,
+			Directives: block.Directives,
+		})
+	})
+	return blocks, warnings, nil
+}
+
+// blockEnd returns the line of the last directive of block
+func blockEnd(block *parser.Block) int {
+	end := block.Line
+	for _, d := range block.Directives {
+		end = max(end, d.Line)
+	}
+	return end
+}
+
+// managedLines returns the lines of the SSH config holding the SKM managed
+// block, or 0, 0 if it has none
+func (m *Manager) managedLines() (int, int, error) {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	start, end := 0, 0
+	for i, line := range splitLines(string(data)) {
+		if strings.Contains(line, skmManagedStart) {
+			start = i + 1
+		} else if strings.Contains(line, skmManagedEnd) {
+			end = i + 1
+		}
+	}
+	return start, end, nil
+}
+
+// DropHostBlocks removes Host blocks returned by UserHostBlocks from the SSH
+// config when the next plan is written. Blocks in included files are left
+// alone.
+func (m *Manager) DropHostBlocks(blocks []UserHostBlock) {
+	m.droppedLines = make(map[int]bool)
+	for _, b := range blocks {
+		if !m.InConfig(&b) {
+			continue
+		}
+		for line := b.Line; line <= b.EndLine; line++ {
+			m.droppedLines[line] = true
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+// KnownHostsManager manages the SKM section of a known_hosts file
+type KnownHostsManager struct {
+	path string
+}
+
+// NewKnownHostsManager creates a manager for the known_hosts file in sshDir
+func NewKnownHostsManager(sshDir string) *KnownHostsManager {
+	return &KnownHostsManager{
+		path: filepath.Join(sshDir, "known_hosts"),
+	}
+}
+
+// Path returns the path of the managed known_hosts file
+func (m *KnownHostsManager) Path() string {
+	return m.path
+}
+
+// UpdateCertAuthorities rewrites the managed section with an @cert-authority
+// line per host CA. Entries outside the managed section are preserved.
+func (m *KnownHostsManager) UpdateCertAuthorities(cas []models.HostCA) error {
+	existingContent, found, err := m.readUnmanaged()
+	if err != nil {
+		return err
+	}
+	if len(cas) == 0 && !found {
+		return nil
+	}
+
+	finalContent := existingContent
+	if len(cas) > 0 {
+		managedContent := []string{
+			skmManagedStart,
+			"# This section is managed by SKM. Do not edit manually.",
+		}
+		for _, ca := range cas {
+			patterns := ca.Patterns
+			if len(patterns) == 0 {
+				patterns = []string{"*"}
+			}
+			managedContent = append(managedContent, fmt.Sprintf("@cert-authority %s %s %s",
+				strings.Join(patterns, ","), strings.TrimSpace(ca.PublicKey), ca.Name))
+		}
+		managedContent = append(managedContent, skmManagedEnd, "")
+
+		if finalContent != "" && !strings.HasSuffix(finalContent, "\n") {
+			finalContent += "\n"
+		}
+		finalContent += strings.Join(managedContent, "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return fmt.Errorf("failed to create SSH directory: %w", err)
+	}
+
+	if err := os.WriteFile(m.path, []byte(finalContent), 0644); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+
+	return nil
+}
+
+// readUnmanaged returns the known_hosts content outside the managed section
+// and whether a managed section was present
+func (m *KnownHostsManager) readUnmanaged() (string, bool, error) {
+	file, err := os.Open(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer file.Close()
+
+	content := ""
+	found := false
+	inManagedSection := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.Contains(line, skmManagedStart) {
+			inManagedSection = true
+			found = true
+			continue
+		}
+
+		if strings.Contains(line, skmManagedEnd) {
+			inManagedSection = false
+			continue
+		}
+
+		if !inManagedSection {
+			content += line + "\n"
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	return content, found, nil
+}
@@ -0,0 +1,56 @@
+package sshconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func TestUpdateCertAuthorities(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewKnownHostsManager(tmpDir)
+
+	existing := "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl\n"
+	if err := os.WriteFile(manager.Path(), []byte(existing), 0644); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+
+	cas := []models.HostCA{{
+		Name:      "corp",
+		PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+		Patterns:  []string{"*.corp.example", "bastion"},
+	}}
+	if err := manager.UpdateCertAuthorities(cas); err != nil {
+		t.Fatalf("UpdateCertAuthorities failed: %v", err)
+	}
+	// Updating twice must not duplicate the managed section
+	if err := manager.UpdateCertAuthorities(cas); err != nil {
+		t.Fatalf("UpdateCertAuthorities failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manager.Path())
+	if err != nil {
+		t.Fatalf("failed to read known_hosts: %v", err)
+	}
+	str := string(content)
+	if !strings.HasPrefix(str, existing) {
+		t.Errorf("existing entries not preserved:\n%s", str)
+	}
+	if strings.Count(str, skmManagedStart) != 1 {
+		t.Errorf("expected one managed section:\n%s", str)
+	}
+	if !strings.Contains(str, "@cert-authority *.corp.example,bastion ssh-ed25519 ") {
+		t.Errorf("missing @cert-authority line:\n%s", str)
+	}
+
+	// Removing every CA drops the managed section
+	if err := manager.UpdateCertAuthorities(nil); err != nil {
+		t.Fatalf("UpdateCertAuthorities failed: %v", err)
+	}
+	content, _ = os.ReadFile(manager.Path())
+	if string(content) != existing {
+		t.Errorf("expected only the original entries, got:\n%s", content)
+	}
+}
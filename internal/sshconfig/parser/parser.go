@@ -0,0 +1,279 @@
+// Package parser reads OpenSSH client configuration files (ssh_config(5))
+// into a syntax tree, following Include directives, and resolves the
+// effective settings for a host the way ssh -G does.
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxIncludeDepth matches the recursion limit of OpenSSH's readconf.c
+const maxIncludeDepth = 16
+
+// BlockKind is the kind of a configuration block
+type BlockKind int
+
+const (
+	// BlockGlobal holds the directives before the first Host or Match line
+	BlockGlobal BlockKind = iota
+	// BlockHost is a Host block
+	BlockHost
+	// BlockMatch is a Match block
+	BlockMatch
+)
+
+// File is a parsed configuration file
+type File struct {
+	Path   string
+	Blocks []*Block
+}
+
+// Block is the global section of a file or a Host or Match block
+type Block struct {
+	Kind BlockKind
+	// Args are the host patterns of a Host block or the criteria of a Match block
+	Args       []string
+	Line       int
+	Directives []*Directive
+}
+
+// Directive is one "Keyword arguments" line
+type Directive struct {
+	// Keyword is the keyword as written
+	Keyword string
+	// Args are the arguments with quotes removed
+	Args []string
+	Line int
+	// Included holds the files read by an Include directive, in order
+	Included []*File
+}
+
+// Name returns the keyword in lower case
+func (d *Directive) Name() string {
+	return strings.ToLower(d.Keyword)
+}
+
+// Value returns the arguments joined by spaces
+func (d *Directive) Value() string {
+	return strings.Join(d.Args, " ")
+}
+
+// Patterns returns the non-negated patterns of a Host block
+func (b *Block) Patterns() []string {
+	if b.Kind != BlockHost {
+		return nil
+	}
+	var patterns []string
+	for _, arg := range b.Args {
+		if !strings.HasPrefix(arg, "!") {
+			patterns = append(patterns, arg)
+		}
+	}
+	return patterns
+}
+
+// Parse reads the configuration file at path and the files it includes.
+// Relative Include paths are resolved against the directory of path, as ssh
+// does for ~/.ssh/config and /etc/ssh/ssh_config.
+func Parse(path string) (*File, error) {
+	return parseFile(path, filepath.Dir(path), 0)
+}
+
+// ParseReader parses configuration text. Relative Include paths are resolved
+// against baseDir.
+func ParseReader(r io.Reader, name, baseDir string) (*File, error) {
+	return parse(r, name, baseDir, 0)
+}
+
+func parseFile(path, baseDir string, depth int) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f, path, baseDir, depth)
+}
+
+func parse(r io.Reader, name, baseDir string, depth int) (*File, error) {
+	file := &File{Path: name}
+	current := &Block{Kind: BlockGlobal}
+	file.Blocks = append(file.Blocks, current)
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		keyword, args, err := splitLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+		}
+		if keyword == "" {
+			continue
+		}
+
+		switch strings.ToLower(keyword) {
+		case "host":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s:%d: Host needs at least one pattern", name, lineNo)
+			}
+			current = &Block{Kind: BlockHost, Args: args, Line: lineNo}
+			file.Blocks = append(file.Blocks, current)
+		case "match":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s:%d: Match needs criteria", name, lineNo)
+			}
+			current = &Block{Kind: BlockMatch, Args: args, Line: lineNo}
+			file.Blocks = append(file.Blocks, current)
+		case "include":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s:%d: Include needs a path", name, lineNo)
+			}
+			directive := &Directive{Keyword: keyword, Args: args, Line: lineNo}
+			if err := directive.include(baseDir, depth); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+			}
+			current.Directives = append(current.Directives, directive)
+		default:
+			current.Directives = append(current.Directives, &Directive{Keyword: keyword, Args: args, Line: lineNo})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return file, nil
+}
+
+// include reads the files matched by the Include arguments. Files that do not
+// exist are skipped, like ssh does.
+func (d *Directive) include(baseDir string, depth int) error {
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("too many recursive includes")
+	}
+
+	for _, arg := range d.Args {
+		pattern := expandHome(arg)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid Include pattern %q: %w", arg, err)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			included, err := parseFile(match, baseDir, depth+1)
+			if err != nil {
+				return err
+			}
+			d.Included = append(d.Included, included)
+		}
+	}
+	return nil
+}
+
+// splitLine splits a line into its keyword and arguments. The keyword may be
+// followed by whitespace or "="; arguments may be quoted; a word starting
+// with "#" starts a comment.
+func splitLine(line string) (string, []string, error) {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return line, nil, nil
+	}
+	keyword := line[:end]
+	rest := strings.TrimLeft(line[end:], " \t")
+	if strings.HasPrefix(rest, "=") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+
+	args, err := splitArgs(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	return keyword, args, nil
+}
+
+// splitArgs splits arguments on whitespace, honouring single and double
+// quotes and backslash escapes of quotes, backslashes and spaces
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && i+1 < len(s) && s[i+1] == quote {
+				i++
+				arg.WriteByte(s[i])
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '#' && !inArg:
+			return args, nil
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`"'\ `, s[i+1]) >= 0:
+			i++
+			arg.WriteByte(s[i])
+			inArg = true
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// Walk calls fn for every block of f and of the files it includes. The
+// blocks of an included file are visited after the block holding the Include.
+func (f *File) Walk(fn func(file *File, block *Block)) {
+	for _, block := range f.Blocks {
+		fn(f, block)
+		for _, d := range block.Directives {
+			for _, included := range d.Included {
+				included.Walk(fn)
+			}
+		}
+	}
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		keyword string
+		args    []string
+	}{
+		{"  HostName example.com", "HostName", []string{"example.com"}},
+		{"Port=2222", "Port", []string{"2222"}},
+		{"User = git", "User", []string{"git"}},
+		{`IdentityFile "~/.ssh/my key"`, "IdentityFile", []string{"~/.ssh/my key"}},
+		{`LocalForward 8080 localhost:80 # web`, "LocalForward", []string{"8080", "localhost:80"}},
+		{`SetEnv FOO=a\ b`, "SetEnv", []string{"FOO=a b"}},
+		{"# comment", "", nil},
+	}
+	for _, tt := range tests {
+		keyword, args, err := splitLine(tt.line)
+		if err != nil {
+			t.Errorf("%q: %v", tt.line, err)
+			continue
+		}
+		if keyword != tt.keyword || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%q: got %q %q, want %q %q", tt.line, keyword, args, tt.keyword, tt.args)
+		}
+	}
+
+	if _, _, err := splitLine(`User "git`); err == nil {
+		t.Error("expected unterminated quote to fail")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "config"), `
+Include conf.d/*.conf
+
+Host !bastion.corp *.corp
+    ProxyJump bastion
+    IdentityFile ~/.ssh/corp
+
+Match host 10.0.* user deploy
+    Port 2200
+
+Host *
+    User nobody
+    IdentityFile ~/.ssh/id_%h
+    ServerAliveInterval 30
+`)
+	writeConfig(t, filepath.Join(dir, "conf.d", "10-web.conf"), `
+Host web web.corp
+    HostName 10.0.0.5
+    User deploy
+`)
+
+	file, err := Parse(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	web := file.Resolve("web.corp", Options{LocalUser: "me"})
+	if got := web.Get("hostname"); got != "10.0.0.5" {
+		t.Errorf("hostname: got %q", got)
+	}
+	if got := web.Get("user"); got != "deploy" {
+		t.Errorf("included User should win over Host *: got %q", got)
+	}
+	if got := web.Get("port"); got != "2200" {
+		t.Errorf("Match host/user should apply: port %q", got)
+	}
+	if got := web.Get("proxyjump"); got != "bastion" {
+		t.Errorf("proxyjump: got %q", got)
+	}
+	if got := web.Values("identityfile"); !reflect.DeepEqual(got, []string{"~/.ssh/corp", "~/.ssh/id_%h"}) {
+		t.Errorf("identityfile: got %q", got)
+	}
+	if got := web.ExpandTokens("~/.ssh/id_%h_%r_%n"); got != "~/.ssh/id_10.0.0.5_deploy_web.corp" {
+		t.Errorf("ExpandTokens: got %q", got)
+	}
+	origin := web.Origins("user")[0]
+	if !strings.HasSuffix(origin.File, "10-web.conf") || origin.Line != 4 {
+		t.Errorf("user origin: got %s:%d", origin.File, origin.Line)
+	}
+
+	bastion := file.Resolve("bastion.corp", Options{LocalUser: "me"})
+	if bastion.IsSet("proxyjump") {
+		t.Error("negated pattern should not match")
+	}
+
+	other := file.Resolve("example.org", Options{LocalUser: "me", User: "alice"})
+	if got := other.Get("hostname"); got != "example.org" {
+		t.Errorf("default hostname: got %q", got)
+	}
+	if got := other.Get("user"); got != "alice" {
+		t.Errorf("command line user should win: got %q", got)
+	}
+	if got := other.Get("port"); got != "22" {
+		t.Errorf("default port: got %q", got)
+	}
+	if other.IsSet("port") {
+		t.Error("default port reported as set")
+	}
+}
+
+func TestResolveMatchExecAndFinal(t *testing.T) {
+	file, err := ParseReader(strings.NewReader(`
+Match exec "test -n %h"
+    User execuser
+
+Match final host *.internal
+    IdentityFile /keys/internal
+
+Host db
+    HostName db.internal
+`), "config", t.TempDir())
+	if err != nil {
+		t.Fatalf("ParseReader failed: %v", err)
+	}
+
+	noExec := file.Resolve("db", Options{LocalUser: "me"})
+	if got := noExec.Get("user"); got != "me" {
+		t.Errorf("Match exec without Exec should not match: user %q", got)
+	}
+	if len(noExec.Warnings) != 1 {
+		t.Errorf("expected one warning, got %q", noExec.Warnings)
+	}
+	if got := noExec.Get("identityfile"); got != "/keys/internal" {
+		t.Errorf("Match final should see the resolved HostName: got %q", got)
+	}
+
+	// The final pass runs the command again with the resolved HostName
+	var ran []string
+	withExec := file.Resolve("db", Options{LocalUser: "me", Exec: func(cmd string) bool {
+		ran = append(ran, cmd)
+		return true
+	}})
+	if !reflect.DeepEqual(ran, []string{"test -n db", "test -n db.internal"}) {
+		t.Errorf("exec commands: %q", ran)
+	}
+	if got := withExec.Get("user"); got != "execuser" {
+		t.Errorf("Match exec should match: user %q", got)
+	}
+}
+
+func TestMatchList(t *testing.T) {
+	if matchList("web.corp", "*.corp,!web.*", true) {
+		t.Error("negated pattern in list should reject")
+	}
+}
@@ -0,0 +1,355 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/pattern"
+)
+
+// multiValued keywords accumulate every value instead of keeping the first
+var multiValued = map[string]bool{
+	"certificatefile": true,
+	"dynamicforward":  true,
+	"identityfile":    true,
+	"localforward":    true,
+	"remoteforward":   true,
+	"sendenv":         true,
+	"setenv":          true,
+}
+
+// Options controls how settings are resolved
+type Options struct {
+	// User is the remote user given on the command line (ssh -l). It takes
+	// precedence over User directives.
+	User string
+	// LocalUser is the local user name (default: the current user)
+	LocalUser string
+	// Exec runs the command of a Match exec criterion and reports whether it
+	// exited successfully. Without it Match exec never matches.
+	Exec func(command string) bool
+}
+
+// Value is a setting and where it was set
+type Value struct {
+	Value string
+	// File and Line locate the directive; File is empty for defaults and the
+	// command line
+	File string
+	Line int
+}
+
+// Settings are the effective settings for a host
+type Settings struct {
+	// Host is the host name the settings were resolved for
+	Host string
+	// Warnings lists Match criteria that could not be evaluated
+	Warnings []string
+
+	localUser string
+	values    map[string][]Value
+}
+
+// Get returns the first value of keyword, or "" if it is not set
+func (s *Settings) Get(keyword string) string {
+	values := s.values[strings.ToLower(keyword)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0].Value
+}
+
+// Values returns every value of keyword
+func (s *Settings) Values(keyword string) []string {
+	var values []string
+	for _, v := range s.values[strings.ToLower(keyword)] {
+		values = append(values, v.Value)
+	}
+	return values
+}
+
+// Origins returns the values of keyword with where they were set
+func (s *Settings) Origins(keyword string) []Value {
+	return s.values[strings.ToLower(keyword)]
+}
+
+// IsSet reports whether keyword was set by a directive
+func (s *Settings) IsSet(keyword string) bool {
+	for _, v := range s.values[strings.ToLower(keyword)] {
+		if v.File != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Keywords returns the lower case keywords that have a value, sorted
+func (s *Settings) Keywords() []string {
+	keywords := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// resolver evaluates a configuration for one host
+type resolver struct {
+	host      string
+	opts      Options
+	settings  *Settings
+	final     bool
+	needFinal bool
+}
+
+// Resolve returns the effective settings for host from f; see Resolve
+func (f *File) Resolve(host string, opts Options) *Settings {
+	return Resolve(host, opts, f)
+}
+
+// Resolve returns the effective settings for host from files read in order,
+// like ssh reads ~/.ssh/config and then /etc/ssh/ssh_config. Like ssh, the
+// first value of a keyword wins except for keywords such as IdentityFile that
+// accumulate. Host blocks match the host as given; Match host matches the
+// HostName set so far. A final pass is made when a Match uses the final or
+// canonical criteria. HostName, User and Port default to the host, the local
+// user and 22. As in ssh -G output, % tokens are left unexpanded; see
+// ExpandTokens.
+func Resolve(host string, opts Options, files ...*File) *Settings {
+	localUser := opts.LocalUser
+	if localUser == "" {
+		if u, err := user.Current(); err == nil {
+			localUser = u.Username
+		}
+	}
+	r := &resolver{
+		host:     host,
+		opts:     opts,
+		settings: &Settings{Host: host, localUser: localUser, values: make(map[string][]Value)},
+	}
+	if opts.User != "" {
+		r.settings.values["user"] = []Value{{Value: opts.User}}
+	}
+
+	for _, f := range files {
+		r.readFile(f)
+	}
+	if r.needFinal {
+		r.final = true
+		for _, f := range files {
+			r.readFile(f)
+		}
+	}
+
+	r.setDefault("hostname", host)
+	r.setDefault("user", localUser)
+	r.setDefault("port", "22")
+	return r.settings
+}
+
+// readFile applies the directives of the blocks of f that match
+func (r *resolver) readFile(f *File) {
+	for _, block := range f.Blocks {
+		active := true
+		switch block.Kind {
+		case BlockHost:
+			active = matchList(r.host, strings.Join(block.Args, ","), true)
+		case BlockMatch:
+			active = r.match(f, block)
+		}
+		if !active {
+			continue
+		}
+
+		for _, d := range block.Directives {
+			if d.Name() == "include" {
+				for _, included := range d.Included {
+					r.readFile(included)
+				}
+				continue
+			}
+			r.set(f, d)
+		}
+	}
+}
+
+// set records the value of a directive unless the keyword already has one
+func (r *resolver) set(f *File, d *Directive) {
+	name := d.Name()
+	value := d.Value()
+	if name == "hostname" {
+		value = strings.ReplaceAll(value, "%h", r.host)
+		value = strings.ReplaceAll(value, "%%", "%")
+	}
+
+	existing := r.settings.values[name]
+	if multiValued[name] {
+		for _, v := range existing {
+			if v.Value == value {
+				return
+			}
+		}
+	} else if len(existing) > 0 {
+		return
+	}
+	r.settings.values[name] = append(existing, Value{Value: value, File: f.Path, Line: d.Line})
+}
+
+func (r *resolver) setDefault(keyword, value string) {
+	if len(r.settings.values[keyword]) == 0 {
+		r.settings.values[keyword] = []Value{{Value: value}}
+	}
+}
+
+// match evaluates the criteria of a Match block; every criterion must match
+func (r *resolver) match(f *File, block *Block) bool {
+	for _, arg := range block.Args {
+		switch strings.ToLower(strings.TrimPrefix(arg, "!")) {
+		case "final", "canonical":
+			r.needFinal = true
+		}
+	}
+
+	args := block.Args
+	for i := 0; i < len(args); i++ {
+		criterion := strings.ToLower(args[i])
+		negate := strings.HasPrefix(criterion, "!")
+		criterion = strings.TrimPrefix(criterion, "!")
+
+		var result bool
+		switch criterion {
+		case "all":
+			result = true
+		case "final", "canonical":
+			result = r.final
+		default:
+			if i+1 >= len(args) {
+				r.warn(f, block, fmt.Sprintf("Match %s needs an argument", criterion))
+				return false
+			}
+			i++
+			arg := args[i]
+			switch criterion {
+			case "host":
+				result = matchList(r.settings.hostname(), arg, true)
+			case "originalhost":
+				result = matchList(r.host, arg, true)
+			case "user":
+				result = matchList(r.settings.remoteUser(), arg, false)
+			case "localuser":
+				result = matchList(r.settings.localUser, arg, false)
+			case "tagged":
+				result = matchList(r.settings.Get("tag"), arg, false)
+			case "exec":
+				if r.opts.Exec == nil {
+					r.warn(f, block, "Match exec is not evaluated")
+					return false
+				}
+				result = r.opts.Exec(r.settings.ExpandTokens(arg))
+			default:
+				r.warn(f, block, fmt.Sprintf("Match %s is not supported", criterion))
+				return false
+			}
+		}
+
+		if negate {
+			result = !result
+		}
+		if !result {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *resolver) warn(f *File, block *Block, msg string) {
+	warning := fmt.Sprintf("%s:%d: %s", f.Path, block.Line, msg)
+	for _, w := range r.settings.Warnings {
+		if w == warning {
+			return
+		}
+	}
+	r.settings.Warnings = append(r.settings.Warnings, warning)
+}
+
+// hostname returns the HostName set so far, or the host
+func (s *Settings) hostname() string {
+	if hostname := s.Get("hostname"); hostname != "" {
+		return hostname
+	}
+	return s.Host
+}
+
+// remoteUser returns the User set so far, or the local user
+func (s *Settings) remoteUser() string {
+	if u := s.Get("user"); u != "" {
+		return u
+	}
+	return s.localUser
+}
+
+// ExpandTokens expands the % tokens of ssh_config(5) that do not need a
+// connection, such as %h, %p and %r in IdentityFile
+func (s *Settings) ExpandTokens(value string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+
+	home, _ := os.UserHomeDir()
+	localHost, _ := os.Hostname()
+	port := s.Get("port")
+	if port == "" {
+		port = "22"
+	}
+	tokens := map[byte]string{
+		'%': "%",
+		'd': home,
+		'h': s.hostname(),
+		'L': strings.SplitN(localHost, ".", 2)[0],
+		'l': localHost,
+		'n': s.Host,
+		'p': port,
+		'r': s.remoteUser(),
+		'u': s.localUser,
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' && i+1 < len(value) {
+			if expanded, ok := tokens[value[i+1]]; ok {
+				b.WriteString(expanded)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// matchList matches s against a comma separated pattern list. A matching
+// negated pattern ("!pattern") rejects s even if another pattern matches.
+func matchList(s, list string, foldCase bool) bool {
+	if foldCase {
+		s = strings.ToLower(s)
+		list = strings.ToLower(list)
+	}
+
+	matched := false
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if negated, ok := strings.CutPrefix(p, "!"); ok {
+			if pattern.Match(s, negated) {
+				return false
+			}
+			continue
+		}
+		if pattern.Match(s, p) {
+			matched = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,472 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig/parser"
+)
+
+// FileChange is the planned content of a file
+type FileChange struct {
+	Path string
+	// Old is the current content; Exists is false if the file is missing
+	Old    string
+	Exists bool
+	New    string
+	// Remove deletes the file instead of writing New
+	Remove bool
+}
+
+// Changed reports whether applying the change modifies the file
+func (c *FileChange) Changed() bool {
+	if c.Remove {
+		return c.Exists
+	}
+	return !c.Exists || c.Old != c.New
+}
+
+// Diff returns the change as a unified diff
+func (c *FileChange) Diff() string {
+	newContent := c.New
+	if c.Remove {
+		newContent = ""
+	}
+	return UnifiedDiff(c.Path, c.Path, c.Old, newContent)
+}
+
+// SkippedHost is a managed host or host group left out of the SSH config
+type SkippedHost struct {
+	Host   string
+	Group  bool
+	Reason string
+}
+
+// Conflict is a user-defined setting that changes how ssh connects to a
+// managed host
+type Conflict struct {
+	Host string
+	// Keyword is the setting taking precedence over the SKM value; it is empty
+	// when a user Host block only repeats the alias
+	Keyword string
+	Value   string
+	Managed string
+	File    string
+	Line    int
+}
+
+// String describes the conflict
+func (c Conflict) String() string {
+	if c.Keyword == "" {
+		return fmt.Sprintf("%s: also defined by the Host block at %s:%d", c.Host, c.File, c.Line)
+	}
+	return fmt.Sprintf("%s: %s %q from %s:%d takes precedence over %q written by SKM",
+		c.Host, c.Keyword, c.Value, c.File, c.Line, c.Managed)
+}
+
+// Plan is an SSH config update computed without writing anything
+type Plan struct {
+	UserConfig FileChange
+	Include    FileChange
+	Skipped    []SkippedHost
+	Conflicts  []Conflict
+	// Warnings lists problems that kept conflicts from being checked
+	Warnings []string
+}
+
+// Changes returns the files the plan modifies, in the order they are written
+func (p *Plan) Changes() []*FileChange {
+	var changes []*FileChange
+	if !p.Include.Remove && p.Include.Changed() {
+		changes = append(changes, &p.Include)
+	}
+	if p.UserConfig.Changed() {
+		changes = append(changes, &p.UserConfig)
+	}
+	if p.Include.Remove && p.Include.Changed() {
+		changes = append(changes, &p.Include)
+	}
+	return changes
+}
+
+// Diff returns the changes of every file as a unified diff
+func (p *Plan) Diff() string {
+	var b strings.Builder
+	for _, c := range p.Changes() {
+		b.WriteString(c.Diff())
+	}
+	return b.String()
+}
+
+// hostBlock is the Host block written for a managed host
+type hostBlock struct {
+	alias      string
+	directives []models.HostDirective
+}
+
+// Plan computes the update of the SSH config for hosts. In include mode the
+// hosts go to the include file and the user config only gets the Include line;
+// otherwise they are kept in a block at the end of the user config. Hosts are
+// written with the settings they inherit from their groups, followed by a
+// block per group for the other hosts matching its patterns. Hosts and groups
+// without a usable key are skipped and reported.
+func (m *Manager) Plan(hosts []models.Host, keys map[string]*models.Key) (*Plan, error) {
+	// Validate every host first so a bad option never leaves a config ssh rejects
+	for i := range hosts {
+		if err := hosts[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid host configuration: %w", err)
+		}
+	}
+	names := make(map[string]bool)
+	for i := range m.groups {
+		if err := m.groups[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid host group: %w", err)
+		}
+		if names[m.groups[i].Name] {
+			return nil, fmt.Errorf("invalid host group: duplicate group %s", m.groups[i].Name)
+		}
+		names[m.groups[i].Name] = true
+	}
+
+	existingContent, _, err := m.readUnmanaged()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	if plan.UserConfig, err = currentFile(m.configPath); err != nil {
+		return nil, err
+	}
+	if plan.Include, err = currentFile(m.includePath); err != nil {
+		return nil, err
+	}
+
+	// Resolve every host before writing any: jump chains refer to the others
+	effective := make([]models.Host, len(hosts))
+	hostKeys := make([]*models.Key, len(hosts))
+	byAlias := make(map[string]*models.Host)
+	unusable := make(map[string]string)
+	for i := range hosts {
+		effective[i] = hosts[i].WithGroups(m.groups)
+		alias := strings.ToLower(effective[i].Host)
+		byAlias[alias] = &effective[i]
+		if effective[i].KeyName == "" {
+			unusable[alias] = "no key assigned"
+			continue
+		}
+		var reason string
+		if hostKeys[i], reason = usableKey(keys, effective[i].KeyName); hostKeys[i] == nil {
+			unusable[alias] = reason
+		}
+	}
+	jumps := &jumpResolver{hosts: byAlias, unusable: unusable}
+
+	var blocks []hostBlock
+	for i := range effective {
+		host := &effective[i]
+		reason := unusable[strings.ToLower(host.Host)]
+		options := host.HostOptions
+		if reason == "" {
+			options, reason = jumps.options(host)
+		}
+		if reason != "" {
+			plan.Skipped = append(plan.Skipped, SkippedHost{Host: host.Host, Reason: reason})
+			continue
+		}
+		blocks = append(blocks, hostBlock{
+			alias:      host.Host,
+			directives: m.blockDirectives(host.Hostname, host.User, host.Port, hostKeys[i], options),
+		})
+	}
+
+	// Group blocks come after the hosts, which already carry what they
+	// inherit; their members are excluded so lists such as IdentityFile do not
+	// accumulate
+	var groupBlocks []hostBlock
+	for _, g := range m.groups {
+		var key *models.Key
+		if g.KeyName != "" {
+			var reason string
+			if key, reason = usableKey(keys, g.KeyName); key == nil {
+				plan.Skipped = append(plan.Skipped, SkippedHost{Host: g.Name, Group: true, Reason: reason})
+				continue
+			}
+		}
+
+		options, reason := jumps.options(&models.Host{Host: "group " + g.Name, JumpHost: g.JumpHost, HostOptions: g.HostOptions})
+		if reason != "" {
+			plan.Skipped = append(plan.Skipped, SkippedHost{Host: g.Name, Group: true, Reason: reason})
+			continue
+		}
+
+		patterns := append([]string{}, g.Patterns...)
+		for _, host := range hosts {
+			if g.MatchesAlias(host.Host) {
+				patterns = append(patterns, "!"+host.Host)
+			}
+		}
+		groupBlocks = append(groupBlocks, hostBlock{
+			alias:      strings.Join(patterns, " "),
+			directives: m.blockDirectives("", g.User, g.Port, key, options),
+		})
+	}
+	hostLines := renderBlocks(append(append([]hostBlock{}, blocks...), groupBlocks...))
+
+	if m.includeMode {
+		include := []string{
+			"# This file is managed by SKM. Do not edit manually.",
+			fmt.Sprintf("# It is included from the top of %s.", m.configPath),
+			"",
+		}
+		plan.Include.New = strings.Join(append(include, hostLines...), "\n")
+
+		// ssh uses the first value it finds, so the Include goes above the
+		// user's own Host * defaults
+		plan.UserConfig.New = skmIncludeComment + "\n" + fmt.Sprintf("Include %s", quoteValue(m.includePath)) + "\n"
+		if rest := strings.TrimLeft(existingContent, "\n"); rest != "" {
+			plan.UserConfig.New += "\n" + rest
+		}
+	} else {
+		managedContent := []string{
+			skmManagedStart,
+			"# This section is managed by SKM. Do not edit manually.",
+			"",
+		}
+		managedContent = append(managedContent, hostLines...)
+		managedContent = append(managedContent, skmManagedEnd)
+		managedContent = append(managedContent, "")
+
+		finalContent := existingContent
+		if !strings.HasSuffix(finalContent, "\n\n") && finalContent != "" {
+			finalContent += "\n"
+		}
+		plan.UserConfig.New = finalContent + strings.Join(managedContent, "\n")
+
+		// Nothing includes the file any more after switching back
+		plan.Include.Remove = true
+	}
+
+	m.findConflicts(plan, blocks)
+	return plan, nil
+}
+
+// Apply writes a plan computed by Plan
+func (m *Manager) Apply(plan *Plan) error {
+	if !plan.Include.Remove && plan.Include.Changed() {
+		if err := os.MkdirAll(filepath.Dir(m.includePath), 0700); err != nil {
+			return fmt.Errorf("failed to create SSH config directory: %w", err)
+		}
+		if err := writeAtomic(m.includePath, plan.Include.New, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", m.includePath, err)
+		}
+	}
+
+	if err := m.writeUserConfig(plan.UserConfig.New); err != nil {
+		return err
+	}
+
+	if plan.Include.Remove {
+		return m.removeIncludeFile()
+	}
+	return nil
+}
+
+// jumpResolver resolves the jump hosts of managed hosts and groups
+type jumpResolver struct {
+	// hosts are the effective hosts by lower-case alias
+	hosts map[string]*models.Host
+	// unusable holds why a host is left out, by lower-case alias
+	unusable map[string]string
+}
+
+// lookup returns the managed host with alias
+func (r *jumpResolver) lookup(alias string) (*models.Host, error) {
+	if host, ok := r.hosts[strings.ToLower(alias)]; ok {
+		return host, nil
+	}
+	return nil, fmt.Errorf("host not found: %s", alias)
+}
+
+// options returns the options of host with ProxyJump going through its jump
+// hosts, or why the chain cannot be written. Every hop is written as its own
+// Host block, so ssh connects to it with its own key.
+func (r *jumpResolver) options(host *models.Host) (models.HostOptions, string) {
+	options := host.HostOptions
+	chain, err := models.JumpChain(host, r.lookup)
+	if err != nil {
+		return options, err.Error()
+	}
+	for _, hop := range chain {
+		if _, left := r.unusable[strings.ToLower(hop.Host)]; left {
+			return options, fmt.Sprintf("jump host %s is left out", hop.Host)
+		}
+	}
+	if len(chain) > 0 {
+		options.ProxyJump = models.JumpAliases(chain)
+	}
+	return options, ""
+}
+
+// usableKey returns the named key, or nil and why it cannot be used
+func usableKey(keys map[string]*models.Key, name string) (*models.Key, string) {
+	key, ok := keys[name]
+	if !ok {
+		return nil, fmt.Sprintf("key %s not found", name)
+	}
+	if !key.IsUsable() {
+		return nil, fmt.Sprintf("key %s is %s", key.Name, key.EffectiveState())
+	}
+	return key, ""
+}
+
+// blockDirectives returns the lines written for a host or group, with values
+// quoted as in the file. A group without a key gets no identity lines.
+func (m *Manager) blockDirectives(hostname, user string, port int, key *models.Key, options models.HostOptions) []models.HostDirective {
+	var d []models.HostDirective
+	add := func(keyword, value string) {
+		d = append(d, models.HostDirective{Keyword: keyword, Value: value})
+	}
+
+	if hostname != "" {
+		add("HostName", hostname)
+	}
+	if user != "" {
+		add("User", user)
+	}
+	if key != nil {
+		add("IdentityFile", key.Path)
+		if key.CertPath != "" {
+			add("CertificateFile", key.CertPath)
+		}
+		add("IdentitiesOnly", "yes")
+		if m.identityAgent != "" {
+			add("IdentityAgent", quoteValue(m.identityAgent))
+		}
+	}
+	if port > 0 {
+		add("Port", fmt.Sprintf("%d", port))
+	}
+	if len(m.knownHostsFiles) > 0 {
+		quoted := make([]string, len(m.knownHostsFiles))
+		for i, f := range m.knownHostsFiles {
+			quoted[i] = quoteValue(f)
+		}
+		add("UserKnownHostsFile", strings.Join(quoted, " "))
+	}
+	// Option values are written as given: forwards and commands take several arguments
+	return append(d, options.Directives()...)
+}
+
+// renderBlocks formats Host blocks, each followed by a blank line
+func renderBlocks(blocks []hostBlock) []string {
+	var lines []string
+	for _, block := range blocks {
+		lines = append(lines, fmt.Sprintf("Host %s", block.alias))
+		for _, d := range block.directives {
+			lines = append(lines, fmt.Sprintf("    %s %s", d.Keyword, d.Value))
+		}
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// accumulated keywords add to what users configure instead of replacing it,
+// so only IdentityFile and CertificateFile are checked for their order
+var accumulated = map[string]bool{
+	"dynamicforward": true,
+	"localforward":   true,
+	"remoteforward":  true,
+	"sendenv":        true,
+	"setenv":         true,
+}
+
+// findConflicts resolves every managed host against the planned config, as
+// ssh would, and records user settings that win over the SKM values and user
+// Host blocks repeating a managed alias
+func (m *Manager) findConflicts(plan *Plan, blocks []hostBlock) {
+	userFile, err := parser.ParseReader(strings.NewReader(plan.UserConfig.New), m.configPath, filepath.Dir(m.configPath))
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("conflicts not checked: %v", err))
+		return
+	}
+
+	// The managed lines are the planned include file or the managed block
+	managedStart, managedEnd := 0, 0
+	if m.includeMode {
+		included, err := parser.ParseReader(strings.NewReader(plan.Include.New), m.includePath, filepath.Dir(m.configPath))
+		if err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("conflicts not checked: %v", err))
+			return
+		}
+		userFile.Walk(func(_ *parser.File, block *parser.Block) {
+			for _, d := range block.Directives {
+				if d.Name() == "include" && len(d.Args) == 1 && d.Args[0] == m.includePath {
+					d.Included = []*parser.File{included}
+				}
+			}
+		})
+	} else {
+		for i, line := range splitLines(plan.UserConfig.New) {
+			if strings.Contains(line, skmManagedStart) {
+				managedStart = i + 1
+			} else if strings.Contains(line, skmManagedEnd) {
+				managedEnd = i + 1
+			}
+		}
+	}
+	isManaged := func(file string, line int) bool {
+		if m.includeMode {
+			return file == m.includePath
+		}
+		return file == m.configPath && line > managedStart && line < managedEnd
+	}
+
+	for _, block := range blocks {
+		settings := parser.Resolve(block.alias, parser.Options{}, userFile)
+		for _, d := range block.directives {
+			name := strings.ToLower(d.Keyword)
+			origins := settings.Origins(name)
+			if accumulated[name] || len(origins) == 0 {
+				continue
+			}
+			v := origins[0]
+			managed := strings.ReplaceAll(d.Value, `"`, "")
+			if v.File == "" || isManaged(v.File, v.Line) || v.Value == managed {
+				continue
+			}
+			plan.Conflicts = append(plan.Conflicts, Conflict{
+				Host: block.alias, Keyword: d.Keyword, Value: v.Value, Managed: managed, File: v.File, Line: v.Line,
+			})
+		}
+
+		userFile.Walk(func(file *parser.File, b *parser.Block) {
+			if isManaged(file.Path, b.Line) {
+				return
+			}
+			for _, pattern := range b.Patterns() {
+				if strings.EqualFold(pattern, block.alias) {
+					plan.Conflicts = append(plan.Conflicts, Conflict{Host: block.alias, File: file.Path, Line: b.Line})
+					return
+				}
+			}
+		})
+	}
+}
+
+// currentFile returns the current content of path for a FileChange
+func currentFile(path string) (FileChange, error) {
+	change := FileChange{Path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return change, nil
+	}
+	if err != nil {
+		return change, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	change.Old = string(data)
+	change.Exists = true
+	return change, nil
+}
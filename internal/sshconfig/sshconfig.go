@@ -8,132 +8,151 @@ import (
 	"strings"
 
 	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/pkg/fileio"
 )
 
 const (
 	skmManagedStart = "# === SKM MANAGED START ==="
 	skmManagedEnd   = "# === SKM MANAGED END ==="
+
+	// skmIncludeComment precedes the Include line of the managed hosts
+	skmIncludeComment = "# Added by SKM: hosts managed by SKM, kept first so their settings take precedence"
 )
 
 // Manager manages SSH config file
 type Manager struct {
-	configPath string
+	configPath  string
+	includePath string
+	includeMode bool
+	groups      []models.HostGroup
+	// droppedLines are lines of user Host blocks to remove on the next write
+	droppedLines    map[int]bool
+	identityAgent   string
+	knownHostsFiles []string
 }
 
 // NewManager creates a new SSH config manager
 func NewManager(sshDir string) *Manager {
 	return &Manager{
-		configPath: filepath.Join(sshDir, "config"),
+		configPath:  filepath.Join(sshDir, "config"),
+		includePath: filepath.Join(sshDir, "skm", "config"),
 	}
 }
 
-// UpdateConfig updates the SSH config file with managed hosts
-func (m *Manager) UpdateConfig(hosts []models.Host, keys map[string]*models.Key) error {
-	// Read existing config
-	existingContent := ""
-	managedContent := []string{}
-	inManagedSection := false
+// SetIncludeMode writes managed hosts to their own file, included from the top
+// of the user config, instead of a block at the end of it. Switching modes
+// moves the hosts on the next UpdateConfig.
+func (m *Manager) SetIncludeMode(enabled bool) {
+	m.includeMode = enabled
+}
 
-	if _, err := os.Stat(m.configPath); err == nil {
-		file, err := os.Open(m.configPath)
-		if err != nil {
-			return fmt.Errorf("failed to open SSH config: %w", err)
-		}
-		defer file.Close()
+// SetHostGroups sets the host groups whose defaults managed hosts inherit and
+// which are written as their own Host blocks
+func (m *Manager) SetHostGroups(groups []models.HostGroup) {
+	m.groups = groups
+}
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
+// IncludePath returns the file holding the managed hosts in include mode
+func (m *Manager) IncludePath() string {
+	return m.includePath
+}
 
-			if strings.Contains(line, skmManagedStart) {
-				inManagedSection = true
-				continue
-			}
+// BackupPath returns the copy of the user config taken before SKM changes it
+func (m *Manager) BackupPath() string {
+	return m.configPath + ".skm.bak"
+}
 
-			if strings.Contains(line, skmManagedEnd) {
-				inManagedSection = false
-				continue
-			}
+// SetIdentityAgent makes managed hosts authenticate through the agent listening
+// on socketPath. An empty path disables the IdentityAgent lines.
+func (m *Manager) SetIdentityAgent(socketPath string) {
+	m.identityAgent = socketPath
+}
 
-			if !inManagedSection {
-				existingContent += line + "\n"
-			}
-		}
+// SetKnownHostsFiles makes managed hosts verify host keys against the given
+// files through UserKnownHostsFile. No files keeps ssh's default.
+func (m *Manager) SetKnownHostsFiles(files ...string) {
+	m.knownHostsFiles = files
+}
 
-		if err := scanner.Err(); err != nil {
-			return fmt.Errorf("failed to read SSH config: %w", err)
-		}
+// UpdateConfig writes the managed hosts; see Plan
+func (m *Manager) UpdateConfig(hosts []models.Host, keys map[string]*models.Key) error {
+	plan, err := m.Plan(hosts, keys)
+	if err != nil {
+		return err
 	}
+	return m.Apply(plan)
+}
 
-	// Generate managed section
-	managedContent = append(managedContent, skmManagedStart)
-	managedContent = append(managedContent, "# This section is managed by SKM. Do not edit manually.")
-	managedContent = append(managedContent, "")
-
-	for _, host := range hosts {
-		key, ok := keys[host.KeyName]
-		if !ok {
-			continue
-		}
+// HasManagedSection reports whether the user config still holds a managed
+// block, as written before include mode
+func (m *Manager) HasManagedSection() (bool, error) {
+	_, found, err := m.readUnmanaged()
+	return found, err
+}
 
-		managedContent = append(managedContent, fmt.Sprintf("Host %s", host.Host))
-		if host.Hostname != "" {
-			managedContent = append(managedContent, fmt.Sprintf("    HostName %s", host.Hostname))
+// RemoveManagedSection removes the SKM managed section and Include line from
+// the SSH config and deletes the include file
+func (m *Manager) RemoveManagedSection() error {
+	if _, err := os.Stat(m.configPath); err == nil {
+		content, _, err := m.readUnmanaged()
+		if err != nil {
+			return err
 		}
-		managedContent = append(managedContent, fmt.Sprintf("    User %s", host.User))
-		managedContent = append(managedContent, fmt.Sprintf("    IdentityFile %s", key.Path))
-		managedContent = append(managedContent, "    IdentitiesOnly yes")
-		if host.Port > 0 {
-			managedContent = append(managedContent, fmt.Sprintf("    Port %d", host.Port))
+		if err := m.writeUserConfig(strings.TrimLeft(content, "\n")); err != nil {
+			return err
 		}
-		managedContent = append(managedContent, "")
-	}
-
-	managedContent = append(managedContent, skmManagedEnd)
-	managedContent = append(managedContent, "")
-
-	// Combine content
-	finalContent := existingContent
-	if !strings.HasSuffix(finalContent, "\n\n") && finalContent != "" {
-		finalContent += "\n"
 	}
-	finalContent += strings.Join(managedContent, "\n")
 
-	// Ensure directory exists
-	dir := filepath.Dir(m.configPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create SSH directory: %w", err)
-	}
+	return m.removeIncludeFile()
+}
 
-	// Write config
-	if err := os.WriteFile(m.configPath, []byte(finalContent), 0600); err != nil {
-		return fmt.Errorf("failed to write SSH config: %w", err)
+// removeIncludeFile deletes the include file and its directory once empty
+func (m *Manager) removeIncludeFile() error {
+	if err := os.Remove(m.includePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", m.includePath, err)
 	}
-
+	_ = os.Remove(filepath.Dir(m.includePath))
 	return nil
 }
 
-// RemoveManagedSection removes the SKM managed section from SSH config
-func (m *Manager) RemoveManagedSection() error {
-	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
-		return nil
-	}
-
+// readUnmanaged returns the user config without the managed block, the SKM
+// Include line and the dropped Host blocks, and whether a managed block was
+// found
+func (m *Manager) readUnmanaged() (string, bool, error) {
 	file, err := os.Open(m.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to open SSH config: %w", err)
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to open SSH config: %w", err)
 	}
 	defer file.Close()
 
-	var lines []string
+	content := ""
+	found := false
 	inManagedSection := false
+	afterDropped := false
 
 	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
+	for lineNo := 1; scanner.Scan(); lineNo++ {
 		line := scanner.Text()
 
+		if m.droppedLines[lineNo] {
+			afterDropped = true
+			continue
+		}
+		// Do not leave runs of blank lines where blocks were dropped
+		if strings.TrimSpace(line) == "" {
+			if afterDropped && (content == "" || strings.HasSuffix(content, "\n\n")) {
+				continue
+			}
+		} else {
+			afterDropped = false
+		}
+
 		if strings.Contains(line, skmManagedStart) {
 			inManagedSection = true
+			found = true
 			continue
 		}
 
@@ -142,19 +161,90 @@ func (m *Manager) RemoveManagedSection() error {
 			continue
 		}
 
+		// The Include line is dropped wherever the user moved it
+		if line == skmIncludeComment || m.isIncludeLine(line) {
+			continue
+		}
+
 		if !inManagedSection {
-			lines = append(lines, line)
+			content += line + "\n"
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	return content, found, nil
+}
+
+// isIncludeLine reports whether line includes the managed hosts file
+func (m *Manager) isIncludeLine(line string) bool {
+	keyword, arg, ok := strings.Cut(strings.TrimSpace(line), " ")
+	return ok && strings.EqualFold(keyword, "Include") &&
+		strings.Trim(strings.TrimSpace(arg), `"`) == m.includePath
+}
+
+// writeUserConfig replaces the user config if its content changed. The old
+// file is copied to a backup first and a symlinked config is written through.
+func (m *Manager) writeUserConfig(content string) error {
+	path := m.configPath
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	mode := os.FileMode(0600)
+	old, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if string(old) == content {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := writeAtomic(m.BackupPath(), string(old), 0600); err != nil {
+			return fmt.Errorf("failed to back up SSH config: %w", err)
+		}
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create SSH directory: %w", err)
+		}
+	default:
 		return fmt.Errorf("failed to read SSH config: %w", err)
 	}
 
-	content := strings.Join(lines, "\n")
-	if err := os.WriteFile(m.configPath, []byte(content), 0600); err != nil {
+	if err := writeAtomic(path, content, mode); err != nil {
 		return fmt.Errorf("failed to write SSH config: %w", err)
 	}
+	return nil
+}
 
+// writeAtomic replaces path with content through a temporary file
+func writeAtomic(path, content string, mode os.FileMode) error {
+	w, err := fileio.NewAtomicWriter(path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.Chmod(mode); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.Commit(); err != nil {
+		w.Abort()
+		return err
+	}
 	return nil
 }
+
+// quoteValue quotes an ssh_config argument that contains whitespace
+func quoteValue(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
@@ -133,3 +133,474 @@ Host example.com
 		t.Error("unmanaged content removed")
 	}
 }
+
+func TestUpdateConfigIdentityAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	keys := map[string]*models.Key{
+		"testkey": {Name: "testkey", Path: "/path/to/key"},
+	}
+	hosts := []models.Host{
+		{Host: "example.com", User: "git", KeyName: "testkey"},
+	}
+
+	manager.SetIdentityAgent("/home/me/my config/agent.sock")
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manager.configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if !strings.Contains(string(content), `IdentityAgent "/home/me/my config/agent.sock"`) {
+		t.Errorf("config missing quoted IdentityAgent:\n%s", content)
+	}
+
+	// Disabling the agent drops the line again
+	manager.SetIdentityAgent("")
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	content, _ = os.ReadFile(manager.configPath)
+	if strings.Contains(string(content), "IdentityAgent") {
+		t.Errorf("IdentityAgent should be omitted when unset:\n%s", content)
+	}
+}
+
+func TestUpdateConfigCertificateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	keys := map[string]*models.Key{
+		"testkey": {Name: "testkey", Path: "/path/to/key", CertPath: "/path/to/key-cert.pub"},
+	}
+	hosts := []models.Host{
+		{Host: "example.com", User: "git", KeyName: "testkey"},
+	}
+
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manager.configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if !strings.Contains(string(content), "CertificateFile /path/to/key-cert.pub") {
+		t.Errorf("config missing CertificateFile:\n%s", content)
+	}
+}
+
+func TestUpdateConfigSkipsRetiredKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	keys := map[string]*models.Key{
+		"old":     {Name: "old", Path: "/path/to/old", State: models.KeyStateDeprecated},
+		"archive": {Name: "archive", Path: "/path/to/archive", State: models.KeyStateArchived},
+		"revoked": {Name: "revoked", Path: "/path/to/revoked", State: models.KeyStateRevoked},
+	}
+	hosts := []models.Host{
+		{Host: "deprecated.example.com", User: "git", KeyName: "old"},
+		{Host: "archived.example.com", User: "git", KeyName: "archive"},
+		{Host: "revoked.example.com", User: "git", KeyName: "revoked"},
+	}
+
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manager.configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if !strings.Contains(string(content), "Host deprecated.example.com") {
+		t.Errorf("deprecated key should still be configured:\n%s", content)
+	}
+	for _, host := range []string{"archived.example.com", "revoked.example.com"} {
+		if strings.Contains(string(content), host) {
+			t.Errorf("host %s uses a retired key but is configured:\n%s", host, content)
+		}
+	}
+}
+
+func TestUpdateConfigHostOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	keys := map[string]*models.Key{
+		"testkey": {Name: "testkey", Path: "/path/to/key"},
+	}
+	host := models.Host{Host: "db", User: "deploy", KeyName: "testkey"}
+	for _, opt := range [][2]string{
+		{"proxyjump", "bastion"},
+		{"ForwardAgent", "yes"},
+		{"LocalForward", "5432 localhost:5432"},
+		{"ServerAliveInterval", "30"},
+		{"ControlMaster", "auto"},
+		{"ControlPersist", "10m"},
+		{"loglevel", "ERROR"},
+	} {
+		if err := host.Set(opt[0], opt[1]); err != nil {
+			t.Fatalf("Set(%s): %v", opt[0], err)
+		}
+	}
+
+	if err := manager.UpdateConfig([]models.Host{host}, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	content, err := os.ReadFile(manager.configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	for _, line := range []string{
+		"    ProxyJump bastion",
+		"    ForwardAgent yes",
+		"    LocalForward 5432 localhost:5432",
+		"    ServerAliveInterval 30",
+		"    ControlMaster auto",
+		"    ControlPersist 10m",
+		"    LogLevel ERROR",
+	} {
+		if !strings.Contains(string(content), line+"\n") {
+			t.Errorf("config missing %q:\n%s", line, content)
+		}
+	}
+
+	// An invalid option is rejected before the existing config is touched
+	bad := host
+	bad.ControlMaster = "sometimes"
+	if err := manager.UpdateConfig([]models.Host{bad}, keys); err == nil {
+		t.Fatal("expected invalid ControlMaster to be rejected")
+	}
+	after, _ := os.ReadFile(manager.configPath)
+	if string(after) != string(content) {
+		t.Error("config was rewritten despite an invalid host")
+	}
+
+	if err := host.Set("IdentityFile", "/other"); err == nil {
+		t.Error("managed keyword should not be settable as an option")
+	}
+	if err := host.Set("NoSuchOption", "1"); err == nil {
+		t.Error("unknown keyword should be rejected")
+	}
+}
+
+func TestUpdateConfigIncludeMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	keys := map[string]*models.Key{
+		"testkey": {Name: "testkey", Path: "/path/to/key"},
+	}
+	hosts := []models.Host{{Host: "example.com", User: "git", KeyName: "testkey"}}
+
+	// An existing install with user defaults above the managed block
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	legacy, _ := os.ReadFile(manager.configPath)
+	legacy = append([]byte("Host *\n    User nobody\n\n"), legacy...)
+	if err := os.WriteFile(manager.configPath, legacy, 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if found, err := manager.HasManagedSection(); err != nil || !found {
+		t.Fatalf("expected managed section, got %v %v", found, err)
+	}
+
+	manager.SetIncludeMode(true)
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	content, err := os.ReadFile(manager.configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	want := skmIncludeComment + "\nInclude " + manager.IncludePath() + "\n\nHost *\n    User nobody\n\n"
+	if string(content) != want {
+		t.Errorf("user config:\n%s\nwant:\n%s", content, want)
+	}
+	included, err := os.ReadFile(manager.IncludePath())
+	if err != nil {
+		t.Fatalf("failed to read include file: %v", err)
+	}
+	if !strings.Contains(string(included), "Host example.com") || strings.Contains(string(included), skmManagedStart) {
+		t.Errorf("include file:\n%s", included)
+	}
+	backup, err := os.ReadFile(manager.BackupPath())
+	if err != nil || string(backup) != string(legacy) {
+		t.Errorf("backup should hold the previous config: %v\n%s", err, backup)
+	}
+
+	// Later updates leave the user config alone
+	os.Remove(manager.BackupPath())
+	hosts = append(hosts, models.Host{Host: "other.com", User: "git", KeyName: "testkey"})
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if again, _ := os.ReadFile(manager.configPath); string(again) != want {
+		t.Errorf("user config changed:\n%s", again)
+	}
+	if _, err := os.Stat(manager.BackupPath()); !os.IsNotExist(err) {
+		t.Error("unchanged user config should not be backed up")
+	}
+	if included, _ := os.ReadFile(manager.IncludePath()); !strings.Contains(string(included), "Host other.com") {
+		t.Errorf("include file not updated:\n%s", included)
+	}
+
+	// Switching back moves the hosts into the user config
+	manager.SetIncludeMode(false)
+	if err := manager.UpdateConfig(hosts, keys); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	inline, _ := os.ReadFile(manager.configPath)
+	if strings.Contains(string(inline), "Include") || !strings.Contains(string(inline), "Host other.com") {
+		t.Errorf("inline config:\n%s", inline)
+	}
+	if _, err := os.Stat(manager.IncludePath()); !os.IsNotExist(err) {
+		t.Error("include file should be removed")
+	}
+}
+
+func TestUpdateConfigKeepsSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "dotfiles", "ssh_config")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("Host *\n    User me\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	sshDir := filepath.Join(tmpDir, "ssh")
+	os.MkdirAll(sshDir, 0700)
+	if err := os.Symlink(target, filepath.Join(sshDir, "config")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	manager := NewManager(sshDir)
+	manager.SetIncludeMode(true)
+	if err := manager.UpdateConfig(nil, nil); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if info, err := os.Lstat(manager.configPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("symlinked config was replaced")
+	}
+	content, _ := os.ReadFile(target)
+	if !strings.HasPrefix(string(content), skmIncludeComment) {
+		t.Errorf("symlink target not updated:\n%s", content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0644 {
+		t.Errorf("file mode changed to %v", info.Mode().Perm())
+	}
+}
+
+func TestPlanReportsSkippedHostsAndConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+
+	user := "Host d*\n    User nobody\n\nHost web\n    ForwardAgent yes\n"
+	if err := os.WriteFile(manager.configPath, []byte(user), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	keys := map[string]*models.Key{
+		"testkey": {Name: "testkey", Path: "/path/to/key"},
+		"revoked": {Name: "revoked", Path: "/path/to/revoked", State: models.KeyStateRevoked},
+	}
+	hosts := []models.Host{
+		{Host: "db", User: "deploy", KeyName: "testkey"},
+		{Host: "web", User: "deploy", KeyName: "testkey"},
+		{Host: "lost", User: "deploy", KeyName: "missing"},
+		{Host: "old", User: "deploy", KeyName: "revoked"},
+	}
+
+	plan, err := manager.Plan(hosts, keys)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got, _ := os.ReadFile(manager.configPath); string(got) != user {
+		t.Error("Plan should not write the config")
+	}
+
+	if len(plan.Skipped) != 2 || plan.Skipped[0].Reason != "key missing not found" || plan.Skipped[1].Reason != "key revoked is revoked" {
+		t.Errorf("skipped: %+v", plan.Skipped)
+	}
+
+	// In inline mode the user's Host d* comes first and wins
+	if len(plan.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", plan.Conflicts)
+	}
+	if c := plan.Conflicts[0]; c.Host != "db" || c.Keyword != "User" || c.Value != "nobody" || c.Line != 2 {
+		t.Errorf("shadow conflict: %+v", c)
+	}
+	if c := plan.Conflicts[1]; c.Host != "web" || c.Keyword != "" || c.Line != 4 {
+		t.Errorf("duplicate conflict: %+v", c)
+	}
+
+	// With the Include at the top SKM's values win; the duplicate remains
+	manager.SetIncludeMode(true)
+	plan, err = manager.Plan(hosts, keys)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].Host != "web" {
+		t.Errorf("include mode conflicts: %v", plan.Conflicts)
+	}
+	if changes := plan.Changes(); len(changes) != 2 || changes[0].Path != manager.IncludePath() {
+		t.Errorf("expected include file then user config to change, got %d changes", len(changes))
+	}
+	if diff := plan.Diff(); !strings.Contains(diff, "+Include "+manager.IncludePath()) || !strings.Contains(diff, "+Host db") {
+		t.Errorf("diff:\n%s", diff)
+	}
+
+	if err := manager.Apply(plan); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	plan, _ = manager.Plan(hosts, keys)
+	if len(plan.Changes()) != 0 || plan.Diff() != "" {
+		t.Errorf("expected no changes after Apply, got:\n%s", plan.Diff())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := UnifiedDiff("old", "new", old, new); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := UnifiedDiff("old", "new", old, old); got != "" {
+		t.Errorf("expected no diff, got:\n%s", got)
+	}
+	if got := UnifiedDiff("old", "new", "", "x\n"); got != "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+x\n" {
+		t.Errorf("diff of a new file:\n%s", got)
+	}
+}
+
+func TestPlanHostGroups(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+	manager.SetIncludeMode(true)
+	manager.SetHostGroups([]models.HostGroup{
+		{Name: "prod", Patterns: []string{"prod-*", "!prod-old"}, User: "deploy", KeyName: "prodkey", Port: 2222},
+		{Name: "corp", Patterns: []string{"*.corp"}, KeyName: "revoked"},
+	})
+
+	keys := map[string]*models.Key{
+		"prodkey": {Name: "prodkey", Path: "/path/to/prod"},
+		"own":     {Name: "own", Path: "/path/to/own"},
+		"revoked": {Name: "revoked", Path: "/path/to/revoked", State: models.KeyStateRevoked},
+	}
+	hosts := []models.Host{
+		{Host: "prod-web"},
+		{Host: "prod-db", User: "dba", KeyName: "own"},
+		{Host: "tagged", Tags: []string{"prod"}},
+	}
+
+	plan, err := manager.Plan(hosts, keys)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	want := `Host prod-web
+    User deploy
+    IdentityFile /path/to/prod
+    IdentitiesOnly yes
+    Port 2222
+
+Host prod-db
+    User dba
+    IdentityFile /path/to/own
+    IdentitiesOnly yes
+    Port 2222
+
+Host tagged
+    User deploy
+    IdentityFile /path/to/prod
+    IdentitiesOnly yes
+    Port 2222
+
+Host prod-* !prod-old !prod-web !prod-db
+    User deploy
+    IdentityFile /path/to/prod
+    IdentitiesOnly yes
+    Port 2222
+`
+	if !strings.Contains(plan.Include.New, want) {
+		t.Errorf("unexpected include file:\n%s", plan.Include.New)
+	}
+
+	if len(plan.Skipped) != 1 || !plan.Skipped[0].Group || plan.Skipped[0].Host != "corp" {
+		t.Errorf("skipped: %+v", plan.Skipped)
+	}
+}
+
+func TestPlanJumpHosts(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewManager(tmpDir)
+	manager.SetIncludeMode(true)
+
+	keys := map[string]*models.Key{
+		"outer": {Name: "outer", Path: "/path/to/outer"},
+		"inner": {Name: "inner", Path: "/path/to/inner"},
+		"db":    {Name: "db", Path: "/path/to/db"},
+	}
+	hosts := []models.Host{
+		{Host: "db", User: "deploy", KeyName: "db", JumpHost: "bastion2"},
+		{Host: "bastion2", User: "jump", KeyName: "inner", JumpHost: "bastion1"},
+		{Host: "bastion1", User: "jump", KeyName: "outer"},
+		{Host: "lost", User: "deploy", KeyName: "db", JumpHost: "missing"},
+		{Host: "a", User: "deploy", KeyName: "db", JumpHost: "b"},
+		{Host: "b", User: "deploy", KeyName: "db", JumpHost: "a"},
+	}
+
+	plan, err := manager.Plan(hosts, keys)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	want := `Host db
+    User deploy
+    IdentityFile /path/to/db
+    IdentitiesOnly yes
+    ProxyJump bastion1,bastion2
+
+Host bastion2
+    User jump
+    IdentityFile /path/to/inner
+    IdentitiesOnly yes
+    ProxyJump bastion1
+`
+	if !strings.Contains(plan.Include.New, want) {
+		t.Errorf("unexpected include file:\n%s", plan.Include.New)
+	}
+
+	reasons := make(map[string]string)
+	for _, s := range plan.Skipped {
+		reasons[s.Host] = s.Reason
+	}
+	if reasons["lost"] != "jump host missing of lost not found" {
+		t.Errorf("missing hop: %q", reasons["lost"])
+	}
+	if reasons["a"] != "jump host cycle: a -> b -> a" || reasons["b"] != "jump host cycle: b -> a -> b" {
+		t.Errorf("cycle: %v", reasons)
+	}
+}
@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -73,22 +75,113 @@ func (s *Store) migrate() error {
 			fingerprint TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
-		`CREATE TABLE IF NOT EXISTS repos (
-			path TEXT PRIMARY KEY,
+		reposTable,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to execute migration: %w", err)
+		}
+	}
+
+	// Columns added after the initial schema
+	columns := []struct{ table, column, definition string }{
+		{"keys", "policy", "TEXT NOT NULL DEFAULT ''"},
+		{"keys", "restrict_to_hosts", "INTEGER NOT NULL DEFAULT 0"},
+		{"keys", "cert_path", "TEXT NOT NULL DEFAULT ''"},
+		{"hosts", "host_keys", "TEXT NOT NULL DEFAULT ''"},
+		{"keys", "security_key", "TEXT NOT NULL DEFAULT ''"},
+		{"keys", "state", "TEXT NOT NULL DEFAULT ''"},
+		{"keys", "device_id", "TEXT NOT NULL DEFAULT ''"},
+		{"hosts", "options", "TEXT NOT NULL DEFAULT ''"},
+		{"hosts", "tags", "TEXT NOT NULL DEFAULT ''"},
+		{"hosts", "jump_host", "TEXT NOT NULL DEFAULT ''"},
+		{"keys", "has_passphrase", "INTEGER NOT NULL DEFAULT 0"},
+		{"keys", "ecdsa_bits", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := s.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	return s.migrateRepoRemotes()
+}
+
+// reposTable holds a binding per repository and remote
+const reposTable = `CREATE TABLE IF NOT EXISTS repos (
+			path TEXT NOT NULL,
 			remote TEXT NOT NULL,
 			host_alias TEXT NOT NULL,
 			user TEXT,
 			key_name TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (path, remote)
+		)`
+
+// migrateRepoRemotes rebuilds a repos table keyed by path alone, from before
+// repositories had a binding per remote
+func (s *Store) migrateRepoRemotes() error {
+	var keyColumns int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('repos') WHERE pk > 0`).Scan(&keyColumns); err != nil {
+		return fmt.Errorf("failed to inspect table repos: %w", err)
+	}
+	if keyColumns != 1 {
+		return nil
 	}
 
-	for _, query := range queries {
-		if _, err := s.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute migration: %w", err)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to migrate table repos: %w", err)
+	}
+	defer tx.Rollback()
+	for _, query := range []string{
+		`ALTER TABLE repos RENAME TO repos_by_path`,
+		reposTable,
+		`INSERT INTO repos (path, remote, host_alias, user, key_name, created_at)
+			SELECT path, remote, host_alias, user, key_name, created_at FROM repos_by_path`,
+		`DROP TABLE repos_by_path`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to migrate table repos: %w", err)
 		}
 	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already present
+func (s *Store) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	rows.Close()
 
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -99,19 +192,28 @@ type hostStore struct {
 }
 
 func (s *hostStore) Add(ctx context.Context, host models.Host) error {
-	query := `INSERT INTO hosts (alias, host, user, key_name, port, hostname, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.ExecContext(ctx, query, host.Host, host.Host, host.User, host.KeyName, host.Port, host.Hostname, time.Now())
+	hostKeys, err := encodeHostKeys(host.HostKeys)
+	if err != nil {
+		return err
+	}
+	options, err := encodeHostOptions(host.HostOptions)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO hosts (alias, host, user, key_name, port, hostname, updated_at, host_keys, options, tags, jump_host) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, query, host.Host, host.Host, host.User, host.KeyName, host.Port, host.Hostname, time.Now(), hostKeys, options, strings.Join(host.Tags, ","), host.JumpHost)
 	return err
 }
 
 func (s *hostStore) Get(ctx context.Context, alias string) (*models.Host, error) {
-	query := `SELECT alias, host, user, key_name, port, hostname FROM hosts WHERE alias = ?`
+	query := `SELECT alias, host, user, key_name, port, hostname, host_keys, options, tags, jump_host FROM hosts WHERE alias = ?`
 	row := s.db.QueryRowContext(ctx, query, alias)
 
 	var h models.Host
 	var hostAlias string // we use this to map back to models.Host.Host which is the alias
-	err := row.Scan(&hostAlias, &h.Host, &h.User, &h.KeyName, &h.Port, &h.Hostname)
+	var hostKeys, options, tags string
+	err := row.Scan(&hostAlias, &h.Host, &h.User, &h.KeyName, &h.Port, &h.Hostname, &hostKeys, &options, &tags, &h.JumpHost)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("host not found: %s", alias)
 	}
@@ -119,11 +221,18 @@ func (s *hostStore) Get(ctx context.Context, alias string) (*models.Host, error)
 		return nil, err
 	}
 	h.Host = hostAlias // ensure alias is set correctly
+	h.Tags = splitTags(tags)
+	if h.HostKeys, err = decodeHostKeys(hostKeys); err != nil {
+		return nil, err
+	}
+	if h.HostOptions, err = decodeHostOptions(options); err != nil {
+		return nil, err
+	}
 	return &h, nil
 }
 
 func (s *hostStore) List(ctx context.Context) ([]models.Host, error) {
-	query := `SELECT alias, host, user, key_name, port, hostname FROM hosts`
+	query := `SELECT alias, host, user, key_name, port, hostname, host_keys, options, tags, jump_host FROM hosts`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -134,21 +243,93 @@ func (s *hostStore) List(ctx context.Context) ([]models.Host, error) {
 	for rows.Next() {
 		var h models.Host
 		var hostAlias string
-		if err := rows.Scan(&hostAlias, &h.Host, &h.User, &h.KeyName, &h.Port, &h.Hostname); err != nil {
+		var hostKeys, options, tags string
+		if err := rows.Scan(&hostAlias, &h.Host, &h.User, &h.KeyName, &h.Port, &h.Hostname, &hostKeys, &options, &tags, &h.JumpHost); err != nil {
 			return nil, err
 		}
 		h.Host = hostAlias
+		h.Tags = splitTags(tags)
+		if h.HostKeys, err = decodeHostKeys(hostKeys); err != nil {
+			return nil, err
+		}
+		if h.HostOptions, err = decodeHostOptions(options); err != nil {
+			return nil, err
+		}
 		hosts = append(hosts, h)
 	}
 	return hosts, nil
 }
 
 func (s *hostStore) Update(ctx context.Context, host models.Host) error {
-	query := `UPDATE hosts SET user=?, key_name=?, port=?, hostname=?, updated_at=? WHERE alias=?`
-	_, err := s.db.ExecContext(ctx, query, host.User, host.KeyName, host.Port, host.Hostname, time.Now(), host.Host)
+	hostKeys, err := encodeHostKeys(host.HostKeys)
+	if err != nil {
+		return err
+	}
+	options, err := encodeHostOptions(host.HostOptions)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE hosts SET user=?, key_name=?, port=?, hostname=?, updated_at=?, host_keys=?, options=?, tags=?, jump_host=? WHERE alias=?`
+	_, err = s.db.ExecContext(ctx, query, host.User, host.KeyName, host.Port, host.Hostname, time.Now(), hostKeys, options, strings.Join(host.Tags, ","), host.JumpHost, host.Host)
 	return err
 }
 
+// encodeHostKeys serializes pinned host keys for the host_keys column
+func encodeHostKeys(keys []models.HostKey) (string, error) {
+	if len(keys) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode host keys: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeHostKeys parses the host_keys column
+func decodeHostKeys(value string) ([]models.HostKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var keys []models.HostKey
+	if err := json.Unmarshal([]byte(value), &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode host keys: %w", err)
+	}
+	return keys, nil
+}
+
+// encodeHostOptions serializes the ssh_config options of a host for the options column
+func encodeHostOptions(options models.HostOptions) (string, error) {
+	if options.IsEmpty() {
+		return "", nil
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode host options: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeHostOptions parses the options column
+func decodeHostOptions(value string) (models.HostOptions, error) {
+	var options models.HostOptions
+	if value == "" {
+		return options, nil
+	}
+	if err := json.Unmarshal([]byte(value), &options); err != nil {
+		return options, fmt.Errorf("failed to decode host options: %w", err)
+	}
+	return options, nil
+}
+
+// splitTags parses the comma separated tags column
+func splitTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 func (s *hostStore) Delete(ctx context.Context, alias string) error {
 	query := `DELETE FROM hosts WHERE alias = ?`
 	_, err := s.db.ExecContext(ctx, query, alias)
@@ -162,29 +343,37 @@ type keyStore struct {
 }
 
 func (s *keyStore) Add(ctx context.Context, key models.Key) error {
-	query := `INSERT INTO keys (name, type, path, pub_path, fingerprint, created_at) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := s.db.ExecContext(ctx, query, key.Name, key.Type, key.Path, key.PubPath, key.Fingerprint, key.CreatedAt)
+	securityKey, err := encodeSecurityKey(key.SecurityKey)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO keys (name, type, path, pub_path, fingerprint, created_at, policy, restrict_to_hosts, cert_path, security_key, state, device_id, has_passphrase, ecdsa_bits) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.db.ExecContext(ctx, query, key.Name, key.Type, key.Path, key.PubPath, key.Fingerprint, key.CreatedAt, key.Policy, key.RestrictToHosts, key.CertPath, securityKey, key.State, key.DeviceID, key.HasPassphrase, key.ECDSABits)
 	return err
 }
 
 func (s *keyStore) Get(ctx context.Context, name string) (*models.Key, error) {
-	query := `SELECT name, type, path, pub_path, fingerprint, created_at FROM keys WHERE name = ?`
+	query := `SELECT name, type, path, pub_path, fingerprint, created_at, policy, restrict_to_hosts, cert_path, security_key, state, device_id, has_passphrase, ecdsa_bits FROM keys WHERE name = ?`
 	row := s.db.QueryRowContext(ctx, query, name)
 
 	var k models.Key
+	var securityKey string
 	// models.KeyType is string alias, scan should work
-	err := row.Scan(&k.Name, &k.Type, &k.Path, &k.PubPath, &k.Fingerprint, &k.CreatedAt)
+	err := row.Scan(&k.Name, &k.Type, &k.Path, &k.PubPath, &k.Fingerprint, &k.CreatedAt, &k.Policy, &k.RestrictToHosts, &k.CertPath, &securityKey, &k.State, &k.DeviceID, &k.HasPassphrase, &k.ECDSABits)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("key not found: %s", name)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if k.SecurityKey, err = decodeSecurityKey(securityKey); err != nil {
+		return nil, err
+	}
 	return &k, nil
 }
 
 func (s *keyStore) List(ctx context.Context) ([]models.Key, error) {
-	query := `SELECT name, type, path, pub_path, fingerprint, created_at FROM keys`
+	query := `SELECT name, type, path, pub_path, fingerprint, created_at, policy, restrict_to_hosts, cert_path, security_key, state, device_id, has_passphrase, ecdsa_bits FROM keys`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -194,7 +383,12 @@ func (s *keyStore) List(ctx context.Context) ([]models.Key, error) {
 	var keys []models.Key
 	for rows.Next() {
 		var k models.Key
-		if err := rows.Scan(&k.Name, &k.Type, &k.Path, &k.PubPath, &k.Fingerprint, &k.CreatedAt); err != nil {
+		var securityKey string
+		if err := rows.Scan(&k.Name, &k.Type, &k.Path, &k.PubPath, &k.Fingerprint, &k.CreatedAt, &k.Policy, &k.RestrictToHosts, &k.CertPath, &securityKey, &k.State, &k.DeviceID, &k.HasPassphrase, &k.ECDSABits); err != nil {
+			return nil, err
+		}
+		var err error
+		if k.SecurityKey, err = decodeSecurityKey(securityKey); err != nil {
 			return nil, err
 		}
 		keys = append(keys, k)
@@ -202,6 +396,30 @@ func (s *keyStore) List(ctx context.Context) ([]models.Key, error) {
 	return keys, nil
 }
 
+// encodeSecurityKey serializes FIDO2 key metadata for the security_key column
+func encodeSecurityKey(info *models.SecurityKeyInfo) (string, error) {
+	if info == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode security key: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeSecurityKey parses the security_key column
+func decodeSecurityKey(value string) (*models.SecurityKeyInfo, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var info models.SecurityKeyInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		return nil, fmt.Errorf("failed to decode security key: %w", err)
+	}
+	return &info, nil
+}
+
 func (s *keyStore) Delete(ctx context.Context, name string) error {
 	query := `DELETE FROM keys WHERE name = ?`
 	_, err := s.db.ExecContext(ctx, query, name)
@@ -215,14 +433,15 @@ type repoStore struct {
 }
 
 func (s *repoStore) Add(ctx context.Context, repo models.GitRepo) error {
-	query := `INSERT INTO repos (path, remote, host_alias, user, key_name) VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT INTO repos (path, remote, host_alias, user, key_name) VALUES (?, ?, ?, ?, ?)
+			  ON CONFLICT (path, remote) DO UPDATE SET host_alias = excluded.host_alias, user = excluded.user, key_name = excluded.key_name`
 	_, err := s.db.ExecContext(ctx, query, repo.Path, repo.Remote, repo.Host, repo.User, repo.KeyName)
 	return err
 }
 
-func (s *repoStore) Get(ctx context.Context, path string) (*models.GitRepo, error) {
-	query := `SELECT path, remote, host_alias, user, key_name FROM repos WHERE path = ?`
-	row := s.db.QueryRowContext(ctx, query, path)
+func (s *repoStore) Get(ctx context.Context, path, remote string) (*models.GitRepo, error) {
+	query := `SELECT path, remote, host_alias, user, key_name FROM repos WHERE path = ? AND (? = '' OR remote = ?) ORDER BY created_at LIMIT 1`
+	row := s.db.QueryRowContext(ctx, query, path, remote, remote)
 
 	var r models.GitRepo
 	err := row.Scan(&r.Path, &r.Remote, &r.Host, &r.User, &r.KeyName)
@@ -256,6 +475,12 @@ func (s *repoStore) List(ctx context.Context) ([]models.GitRepo, error) {
 
 func (s *repoStore) Delete(ctx context.Context, path string) error {
 	query := `DELETE FROM repos WHERE path = ?`
-	_, err := s.db.ExecContext(ctx, query, path)
-	return err
+	result, err := s.db.ExecContext(ctx, query, path)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("repo not found: %s", path)
+	}
+	return nil
 }
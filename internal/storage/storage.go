@@ -40,7 +40,10 @@ type KeyStore interface {
 // RepoStore manages Git repository bindings
 type RepoStore interface {
 	Add(ctx context.Context, repo models.GitRepo) error
-	Get(ctx context.Context, path string) (*models.GitRepo, error)
+	// Get returns the binding of remote, or the first binding of the
+	// repository if remote is empty
+	Get(ctx context.Context, path, remote string) (*models.GitRepo, error)
 	List(ctx context.Context) ([]models.GitRepo, error)
+	// Delete removes the bindings of every remote of the repository
 	Delete(ctx context.Context, path string) error
 }
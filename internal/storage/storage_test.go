@@ -0,0 +1,60 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/storage"
+	"github.com/all-dot-files/ssh-key-manager/internal/storage/sqlite"
+	"github.com/all-dot-files/ssh-key-manager/internal/storage/yaml"
+)
+
+func TestRepoStoreDeleteRemovesEveryRemote(t *testing.T) {
+	stores := map[string]func(dir string) (storage.Store, error){
+		"yaml": func(dir string) (storage.Store, error) {
+			return yaml.NewStore(filepath.Join(dir, "config.yaml"))
+		},
+		"sqlite": func(dir string) (storage.Store, error) {
+			return sqlite.NewStore(filepath.Join(dir, "skm.db"))
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := open(t.TempDir())
+			if err != nil {
+				t.Fatalf("open store: %v", err)
+			}
+			defer store.Close()
+
+			ctx := context.Background()
+			repos := store.Repo()
+			for _, repo := range []models.GitRepo{
+				{Path: "/src/app", Remote: "origin", Host: "github.com"},
+				{Path: "/src/app", Remote: "upstream", Host: "github.com"},
+				{Path: "/src/lib", Remote: "origin", Host: "github.com"},
+			} {
+				if err := repos.Add(ctx, repo); err != nil {
+					t.Fatalf("add %s %s: %v", repo.Path, repo.Remote, err)
+				}
+			}
+
+			if err := repos.Delete(ctx, "/src/app"); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			for _, remote := range []string{"", "origin", "upstream"} {
+				if _, err := repos.Get(ctx, "/src/app", remote); err == nil {
+					t.Errorf("expected no binding of remote %q left", remote)
+				}
+			}
+			if _, err := repos.Get(ctx, "/src/lib", "origin"); err != nil {
+				t.Errorf("expected other repositories kept: %v", err)
+			}
+			if err := repos.Delete(ctx, "/src/app"); err == nil {
+				t.Error("expected deleting a repository without bindings to fail")
+			}
+		})
+	}
+}
@@ -270,7 +270,7 @@ func (r *repoStore) Add(ctx context.Context, repo models.GitRepo) error {
 	return r.s.save(config)
 }
 
-func (r *repoStore) Get(ctx context.Context, path string) (*models.GitRepo, error) {
+func (r *repoStore) Get(ctx context.Context, path, remote string) (*models.GitRepo, error) {
 	r.s.mu.RLock()
 	defer r.s.mu.RUnlock()
 
@@ -280,7 +280,7 @@ func (r *repoStore) Get(ctx context.Context, path string) (*models.GitRepo, erro
 	}
 
 	for _, repo := range config.Repos {
-		if repo.Path == path {
+		if repo.Path == path && (remote == "" || repo.Remote == remote) {
 			return &repo, nil
 		}
 	}
@@ -308,12 +308,17 @@ func (r *repoStore) Delete(ctx context.Context, path string) error {
 		return err
 	}
 
-	for i, repo := range config.Repos {
-		if repo.Path == path {
-			config.Repos = append(config.Repos[:i], config.Repos[i+1:]...)
-			return r.s.save(config)
+	// Every remote of the repository has its own binding
+	remaining := config.Repos[:0]
+	for _, repo := range config.Repos {
+		if repo.Path != path {
+			remaining = append(remaining, repo)
 		}
 	}
+	if len(remaining) == len(config.Repos) {
+		return fmt.Errorf("repo not found: %s", path)
+	}
 
-	return fmt.Errorf("repo not found: %s", path)
+	config.Repos = remaining
+	return r.s.save(config)
 }
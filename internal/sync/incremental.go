@@ -35,6 +35,8 @@ type KeyChange struct {
 	Timestamp   time.Time     `json:"timestamp"`
 	DeviceID    string        `json:"device_id"`
 	Checksum    string        `json:"checksum"`
+	// Tombstone is set on deletions synced from another device
+	Tombstone   *models.KeyTombstone `json:"tombstone,omitempty"`
 }
 
 // SyncState tracks the sync state
@@ -233,20 +235,56 @@ func (sm *SyncManager) ResolveConflict(conflict *ConflictResolution) models.Key
 	return conflict.ResolvedKey
 }
 
-// ApplyChanges applies a list of changes to local keys
+// TombstoneChanges turns tombstones into delete changes. The key name is
+// informational; tombstones match keys by fingerprint.
+func (sm *SyncManager) TombstoneChanges(tombstones []models.KeyTombstone) []KeyChange {
+	changes := make([]KeyChange, 0, len(tombstones))
+	for i := range tombstones {
+		t := tombstones[i]
+		changes = append(changes, KeyChange{
+			Type:      ChangeTypeDelete,
+			Key:       models.Key{Name: t.Name, Fingerprint: t.Fingerprint},
+			Timestamp: t.DeletedAt,
+			DeviceID:  t.DeviceID,
+			Tombstone: &t,
+		})
+	}
+	return changes
+}
+
+// ApplyChanges applies a list of changes to local keys. Tombstoned keys are
+// removed by fingerprint and are not re-created by changes in the same list.
 func (sm *SyncManager) ApplyChanges(localKeys []models.Key, changes []KeyChange) []models.Key {
 	keyMap := make(map[string]models.Key)
 	for _, key := range localKeys {
 		keyMap[key.Name] = key
 	}
 
+	tombstoned := make(map[string]bool)
+	for _, change := range changes {
+		if change.Type == ChangeTypeDelete && change.Tombstone != nil && change.Tombstone.Fingerprint != "" {
+			tombstoned[change.Tombstone.Fingerprint] = true
+		}
+	}
+
 	for _, change := range changes {
 		switch change.Type {
 		case ChangeTypeCreate, ChangeTypeUpdate:
+			if tombstoned[change.Key.Fingerprint] {
+				continue
+			}
 			keyMap[change.Key.Name] = change.Key
 
 		case ChangeTypeDelete:
-			delete(keyMap, change.Key.Name)
+			if change.Tombstone == nil {
+				delete(keyMap, change.Key.Name)
+				continue
+			}
+			for name, key := range keyMap {
+				if tombstoned[key.Fingerprint] && key.Fingerprint == change.Tombstone.Fingerprint {
+					delete(keyMap, name)
+				}
+			}
 		}
 	}
 
@@ -270,6 +308,10 @@ func (sm *SyncManager) GetChangelog(changes []KeyChange) []string {
 		case ChangeTypeUpdate:
 			changelog = append(changelog, "📝 Updated: "+change.Key.Name)
 		case ChangeTypeDelete:
+			if change.Tombstone != nil {
+				changelog = append(changelog, "🗑️  Deleted: "+change.Key.Name+" (on device "+change.Tombstone.DeviceID+")")
+				continue
+			}
 			changelog = append(changelog, "🗑️  Deleted: "+change.Key.Name)
 		}
 	}
@@ -10,11 +10,11 @@ import (
 	"io"
 
 	"github.com/all-dot-files/ssh-key-manager/pkg/errors"
-	"golang.org/x/crypto/argon2"
 )
 
 const (
-	// Argon2 parameters (OWASP recommended)
+	// Default Argon2 parameters (OWASP recommended). Headerless blobs written
+	// before the envelope format always use these.
 	ArgonTime    = 3
 	ArgonMemory  = 64 * 1024 // 64 MB
 	ArgonThreads = 4
@@ -34,16 +34,9 @@ type EncryptedData struct {
 	Ciphertext []byte `json:"ciphertext"`
 }
 
-// DeriveKey derives a key from a passphrase using Argon2id
+// DeriveKey derives a key from a passphrase using Argon2id with the default parameters
 func DeriveKey(passphrase string, salt []byte) []byte {
-	return argon2.IDKey(
-		[]byte(passphrase),
-		salt,
-		ArgonTime,
-		ArgonMemory,
-		ArgonThreads,
-		ArgonKeyLen,
-	)
+	return DeriveKeyWithParams(passphrase, salt, DefaultKDFParams())
 }
 
 // GenerateSalt generates a random salt
@@ -55,7 +48,8 @@ func GenerateSalt() ([]byte, error) {
 	return salt, nil
 }
 
-// Encrypt encrypts data using AES-256-GCM with a passphrase
+// Encrypt encrypts data using AES-256-GCM with a passphrase. The result has
+// no header; new data should use EncryptEnvelope.
 func Encrypt(plaintext []byte, passphrase string) (*EncryptedData, error) {
 	// Generate salt
 	salt, err := GenerateSalt()
@@ -120,29 +114,37 @@ func Decrypt(data *EncryptedData, passphrase string) ([]byte, error) {
 	return plaintext, nil
 }
 
-// EncryptToBase64 encrypts data and returns base64-encoded result
-func EncryptToBase64(plaintext []byte, passphrase string) (string, error) {
-	encrypted, err := Encrypt(plaintext, passphrase)
+// EncryptToBase64 encrypts data into a versioned envelope tagged with keyID
+// and returns it base64-encoded
+func EncryptToBase64(plaintext []byte, passphrase, keyID string, params KDFParams) (string, error) {
+	envelope, err := EncryptEnvelope(plaintext, passphrase, keyID, params)
 	if err != nil {
 		return "", err
 	}
 
-	// Concatenate salt + nonce + ciphertext
-	combined := append(encrypted.Salt, encrypted.Nonce...)
-	combined = append(combined, encrypted.Ciphertext...)
-
-	return base64.StdEncoding.EncodeToString(combined), nil
+	return base64.StdEncoding.EncodeToString(envelope), nil
 }
 
-// DecryptFromBase64 decrypts base64-encoded encrypted data
+// DecryptFromBase64 decrypts base64-encoded data in either the envelope format
+// or the older headerless salt|nonce|ciphertext layout
 func DecryptFromBase64(encoded string, passphrase string) ([]byte, error) {
 	combined, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrInvalidInput, "DecryptFromBase64", "failed to decode base64")
 	}
 
+	if IsEnvelope(combined) {
+		plaintext, _, err := DecryptEnvelope(combined, passphrase)
+		return plaintext, err
+	}
+
+	return DecryptLegacy(combined, passphrase)
+}
+
+// DecryptLegacy decrypts a headerless salt|nonce|ciphertext blob
+func DecryptLegacy(combined []byte, passphrase string) ([]byte, error) {
 	if len(combined) < SaltLength+NonceLength {
-		return nil, errors.New(errors.ErrInvalidInput, "DecryptFromBase64", "invalid encrypted data: too short")
+		return nil, errors.New(errors.ErrInvalidInput, "DecryptLegacy", "invalid encrypted data: too short")
 	}
 
 	data := &EncryptedData{
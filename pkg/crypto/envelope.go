@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/all-dot-files/ssh-key-manager/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// EnvelopeVersion is the envelope format written by EncryptEnvelope
+	EnvelopeVersion = 1
+
+	// CipherAES256GCM is the only envelope cipher
+	CipherAES256GCM = "aes-256-gcm"
+
+	// KDFArgon2id is the only envelope key derivation function
+	KDFArgon2id = "argon2id"
+
+	// Upper bound on the Argon2 memory an envelope may request (4 GiB)
+	maxArgonMemory = 4 * 1024 * 1024
+
+	// Upper bound on the Argon2 passes an envelope may request
+	maxArgonTime = 64
+)
+
+// envelopeMagic prefixes every encrypted envelope
+var envelopeMagic = []byte("skm-enc\x00")
+
+// KDFParams are the Argon2id cost parameters
+type KDFParams struct {
+	Time    uint32 `json:"t"`
+	Memory  uint32 `json:"m"` // KiB
+	Threads uint8  `json:"p"`
+}
+
+// DefaultKDFParams returns the Argon2id parameters used when none are configured
+func DefaultKDFParams() KDFParams {
+	return KDFParams{Time: ArgonTime, Memory: ArgonMemory, Threads: ArgonThreads}
+}
+
+// Validate checks that the parameters are usable
+func (p KDFParams) Validate() error {
+	if p.Time == 0 || p.Threads == 0 {
+		return errors.New(errors.ErrInvalidInput, "KDFParams", "argon2 time and threads must be at least 1")
+	}
+	if p.Time > maxArgonTime {
+		return errors.New(errors.ErrInvalidInput, "KDFParams", "argon2 time out of range")
+	}
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgonMemory {
+		return errors.New(errors.ErrInvalidInput, "KDFParams", "argon2 memory out of range")
+	}
+	return nil
+}
+
+// EnvelopeHeader describes how an envelope was encrypted. It is
+// authenticated together with the ciphertext.
+type EnvelopeHeader struct {
+	Version   int       `json:"v"`
+	Cipher    string    `json:"cipher"`
+	KDF       string    `json:"kdf"`
+	KDFParams KDFParams `json:"kdf_params"`
+	KeyID     string    `json:"key_id,omitempty"`
+	Salt      []byte    `json:"salt"`
+	Nonce     []byte    `json:"nonce"`
+}
+
+// IsEnvelope reports whether data is a versioned envelope rather than a
+// headerless salt|nonce|ciphertext blob
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// EncryptEnvelope encrypts plaintext with AES-256-GCM under an Argon2id key
+// derived from passphrase. keyID identifies the protected key and may be empty.
+func EncryptEnvelope(plaintext []byte, passphrase, keyID string, params KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, NonceLength)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "EncryptEnvelope", "failed to generate nonce")
+	}
+
+	header := EnvelopeHeader{
+		Version:   EnvelopeVersion,
+		Cipher:    CipherAES256GCM,
+		KDF:       KDFArgon2id,
+		KDFParams: params,
+		KeyID:     keyID,
+		Salt:      salt,
+		Nonce:     nonce,
+	}
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "EncryptEnvelope", "failed to encode header")
+	}
+
+	prefix := make([]byte, 0, len(envelopeMagic)+4+len(headerData))
+	prefix = append(prefix, envelopeMagic...)
+	prefix = binary.BigEndian.AppendUint32(prefix, uint32(len(headerData)))
+	prefix = append(prefix, headerData...)
+
+	gcm, err := newGCM(DeriveKeyWithParams(passphrase, salt, params))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(prefix, nonce, plaintext, prefix), nil
+}
+
+// ParseEnvelopeHeader returns the header of an envelope without decrypting it
+func ParseEnvelopeHeader(data []byte) (*EnvelopeHeader, error) {
+	header, _, _, err := splitEnvelope(data)
+	return header, err
+}
+
+// DecryptEnvelope decrypts an envelope written by EncryptEnvelope
+func DecryptEnvelope(data []byte, passphrase string) ([]byte, *EnvelopeHeader, error) {
+	header, prefix, ciphertext, err := splitEnvelope(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := newGCM(DeriveKeyWithParams(passphrase, header.Salt, header.KDFParams))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, header.Nonce, ciphertext, prefix)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrInvalidInput, "DecryptEnvelope", "failed to decrypt")
+	}
+
+	return plaintext, header, nil
+}
+
+// splitEnvelope separates an envelope into its header, the authenticated
+// prefix and the ciphertext
+func splitEnvelope(data []byte) (*EnvelopeHeader, []byte, []byte, error) {
+	if !IsEnvelope(data) || len(data) < len(envelopeMagic)+4 {
+		return nil, nil, nil, errors.New(errors.ErrInvalidInput, "ParseEnvelope", "not an encrypted envelope")
+	}
+
+	headerLen := binary.BigEndian.Uint32(data[len(envelopeMagic):])
+	headerEnd := len(envelopeMagic) + 4 + int(headerLen)
+	if headerLen == 0 || headerEnd > len(data) {
+		return nil, nil, nil, errors.New(errors.ErrInvalidInput, "ParseEnvelope", "invalid envelope header length")
+	}
+
+	var header EnvelopeHeader
+	if err := json.Unmarshal(data[len(envelopeMagic)+4:headerEnd], &header); err != nil {
+		return nil, nil, nil, errors.Wrap(err, errors.ErrInvalidInput, "ParseEnvelope", "invalid envelope header")
+	}
+
+	if header.Version != EnvelopeVersion {
+		return nil, nil, nil, errors.New(errors.ErrInvalidInput, "ParseEnvelope", "unsupported envelope version")
+	}
+	if header.Cipher != CipherAES256GCM || header.KDF != KDFArgon2id {
+		return nil, nil, nil, errors.New(errors.ErrInvalidInput, "ParseEnvelope", "unsupported envelope cipher or KDF")
+	}
+	if err := header.KDFParams.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(header.Salt) == 0 || len(header.Nonce) != NonceLength {
+		return nil, nil, nil, errors.New(errors.ErrInvalidInput, "ParseEnvelope", "invalid envelope salt or nonce")
+	}
+
+	return &header, data[:headerEnd], data[headerEnd:], nil
+}
+
+// DeriveKeyWithParams derives a key from a passphrase using Argon2id with the given cost
+func DeriveKeyWithParams(passphrase string, salt []byte, params KDFParams) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, ArgonKeyLen)
+}
+
+// newGCM creates an AES-256-GCM AEAD for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "newGCM", "failed to create cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "newGCM", "failed to create GCM")
+	}
+	return gcm, nil
+}
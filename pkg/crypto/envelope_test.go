@@ -0,0 +1,30 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnvelopeRejectsOversizedKDFParams(t *testing.T) {
+	params := KDFParams{Time: 1, Memory: 64, Threads: 1}
+	envelope, err := EncryptEnvelope([]byte("secret"), "pass", "", params)
+	if err != nil {
+		t.Fatalf("EncryptEnvelope failed: %v", err)
+	}
+
+	// A hostile envelope asking for nearly 2^32 passes is refused before
+	// any key is derived
+	hostile := bytes.Replace(envelope, []byte(`"t":1`), []byte(`"t":4294967295`), 1)
+	if _, _, err := DecryptEnvelope(hostile, "pass"); err == nil {
+		t.Fatal("expected an envelope with an oversized argon2 time to be rejected")
+	}
+
+	params.Time = maxArgonTime + 1
+	if _, err := EncryptEnvelope([]byte("secret"), "pass", "", params); err == nil {
+		t.Fatal("expected oversized argon2 time to be rejected")
+	}
+	params.Time = maxArgonTime
+	if err := params.Validate(); err != nil {
+		t.Fatalf("expected the maximum argon2 time to be accepted: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+
+	"github.com/all-dot-files/ssh-key-manager/pkg/errors"
+)
+
+// SealWithKey encrypts plaintext with AES-256-GCM under a raw 32-byte key.
+// The random nonce is prepended to the result; aad is authenticated but not
+// encrypted.
+func SealWithKey(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "SealWithKey", "failed to generate nonce")
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// OpenWithKey decrypts data written by SealWithKey
+func OpenWithKey(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New(errors.ErrInvalidInput, "OpenWithKey", "sealed data too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidInput, "OpenWithKey", "failed to decrypt")
+	}
+	return plaintext, nil
+}
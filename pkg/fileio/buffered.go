@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -257,9 +258,10 @@ type AtomicWriter struct {
 	tempFile   *os.File
 }
 
-// NewAtomicWriter creates a new atomic writer
+// NewAtomicWriter creates a new atomic writer. The temporary file is created
+// next to the target so the final rename never crosses filesystems.
 func NewAtomicWriter(path string) (*AtomicWriter, error) {
-	tempFile, err := os.CreateTemp("", "skm-atomic-*")
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -275,6 +277,11 @@ func (aw *AtomicWriter) Write(p []byte) (n int, err error) {
 	return aw.tempFile.Write(p)
 }
 
+// Chmod sets the permissions the target file will have
+func (aw *AtomicWriter) Chmod(mode os.FileMode) error {
+	return aw.tempFile.Chmod(mode)
+}
+
 // Commit commits the write by renaming temp file to target
 func (aw *AtomicWriter) Commit() error {
 	if err := aw.tempFile.Sync(); err != nil {
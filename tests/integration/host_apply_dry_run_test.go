@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/cli"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
+)
+
+// snapshotDir returns the contents of the files under dir by relative path
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	return files
+}
+
+// runSKM runs the root command with args and returns its output
+func runSKM(t *testing.T, args ...string) string {
+	t.Helper()
+
+	root := cli.RootCommandForTest()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	t.Cleanup(func() {
+		root.SetOut(nil)
+		root.SetErr(nil)
+		root.SetArgs(nil)
+	})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("skm %s failed: %v\n%s", strings.Join(args, " "), err, out.String())
+	}
+	return out.String()
+}
+
+func TestHostApplyDryRunKeepsLegacyConfig(t *testing.T) {
+	cfgManager, ks, _ := newIsolatedConfig(t)
+	cfg := cfgManager.Get()
+
+	key, err := ks.GenerateKey("work", models.KeyTypeED25519, "", 0)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := cfgManager.AddKey(*key); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	if err := cfgManager.AddHost(models.Host{Host: "github.com", User: "git", KeyName: key.Name}); err != nil {
+		t.Fatalf("failed to add host: %v", err)
+	}
+
+	// Write the managed block at the end of the user config, as releases
+	// before include mode did
+	if err := os.MkdirAll(cfg.SSHDir, 0700); err != nil {
+		t.Fatalf("failed to create ssh dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.SSHDir, "config"), []byte("Host personal\n    User me\n"), 0600); err != nil {
+		t.Fatalf("failed to write ssh config: %v", err)
+	}
+	legacy := sshconfig.NewManager(cfg.SSHDir)
+	if err := legacy.UpdateConfig(cfgManager.Get().Hosts, map[string]*models.Key{key.Name: key}); err != nil {
+		t.Fatalf("failed to write legacy block: %v", err)
+	}
+	if found, _ := legacy.HasManagedSection(); !found {
+		t.Fatal("expected a legacy managed block")
+	}
+
+	before := snapshotDir(t, cfg.SSHDir)
+	out := runSKM(t, "--config", cfgManager.GetConfigPath(), "host", "apply", "--dry-run")
+	if !strings.Contains(out, "Would update") {
+		t.Fatalf("expected the pending migration to be listed, got:\n%s", out)
+	}
+
+	after := snapshotDir(t, cfg.SSHDir)
+	if len(after) != len(before) {
+		t.Fatalf("expected files %v, got %v", keys(before), keys(after))
+	}
+	for path, content := range before {
+		if after[path] != content {
+			t.Errorf("%s changed by a dry run:\n%s", path, after[path])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.SSHDir, "skm")); !os.IsNotExist(err) {
+		t.Errorf("expected no %s after a dry run, got %v", filepath.Join(cfg.SSHDir, "skm"), err)
+	}
+}
+
+// keys returns the names of the files of a snapshot
+func keys(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestHostImportDryRunKeepsLegacyConfig(t *testing.T) {
+	cfgManager, _, _ := newIsolatedConfig(t)
+	cfg := cfgManager.Get()
+
+	if err := cfgManager.AddHost(models.Host{Host: "github.com", User: "git"}); err != nil {
+		t.Fatalf("failed to add host: %v", err)
+	}
+	if err := os.MkdirAll(cfg.SSHDir, 0700); err != nil {
+		t.Fatalf("failed to create ssh dir: %v", err)
+	}
+	content := "Host personal\n    HostName personal.example.com\n    User me\n\n" +
+		"# === SKM MANAGED START ===\nHost github.com\n    User git\n# === SKM MANAGED END ===\n"
+	if err := os.WriteFile(filepath.Join(cfg.SSHDir, "config"), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write ssh config: %v", err)
+	}
+
+	before := snapshotDir(t, cfg.SSHDir)
+	out := runSKM(t, "--config", cfgManager.GetConfigPath(), "host", "import", "--dry-run", "--remove-blocks")
+	if !strings.Contains(out, "No files written") {
+		t.Fatalf("expected a dry run, got:\n%s", out)
+	}
+
+	after := snapshotDir(t, cfg.SSHDir)
+	if len(after) != len(before) || after["config"] != before["config"] {
+		t.Fatalf("expected %v unchanged by a dry run, got %v", before, after)
+	}
+	if hosts, _ := cfgManager.ListHosts(); len(hosts) != 1 {
+		t.Fatalf("expected no hosts imported by a dry run, got %+v", hosts)
+	}
+}
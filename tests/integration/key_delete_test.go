@@ -0,0 +1,37 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+)
+
+func TestKeyDeleteRemovesInstalledCopy(t *testing.T) {
+	cfgManager, ks, _ := newIsolatedConfig(t)
+	cfg := cfgManager.Get()
+
+	key, err := ks.GenerateKey("work", models.KeyTypeED25519, "", 0)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := cfgManager.AddKey(*key); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+
+	if err := os.MkdirAll(cfg.SSHDir, 0700); err != nil {
+		t.Fatalf("failed to create ssh dir: %v", err)
+	}
+	runSKM(t, "--config", cfgManager.GetConfigPath(), "key", "install", "work")
+	installed := ks.InstalledPath(key, cfg.SSHDir)
+	if _, err := os.Stat(installed); err != nil {
+		t.Fatalf("expected the key installed at %s: %v", installed, err)
+	}
+
+	runSKM(t, "--config", cfgManager.GetConfigPath(), "key", "delete", "work", "--force")
+	for _, path := range []string{installed, installed + ".pub", key.Path} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed with the key, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/all-dot-files/ssh-key-manager/internal/cli"
+	"github.com/all-dot-files/ssh-key-manager/internal/config"
+	"github.com/all-dot-files/ssh-key-manager/internal/models"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig"
+	"github.com/all-dot-files/ssh-key-manager/internal/sshconfig/parser"
+)
+
+func TestHostImportPlansHostsFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	priv := filepath.Join(dir, "id_work")
+	if err := os.WriteFile(priv, []byte("PRIVATE"), 0600); err != nil {
+		t.Fatalf("write priv: %v", err)
+	}
+	if err := os.WriteFile(priv+".pub", []byte(cli.GenerateTestPubForTest()), 0644); err != nil {
+		t.Fatalf("write pub: %v", err)
+	}
+	content := "Host bastion\n  HostName bastion.example.com\n  User ops\n  IdentityFile " + priv + "\n\n" +
+		"Host web1 web2\n  HostName 10.0.0.5\n  User deploy\n  IdentityFile " + filepath.Join(dir, "missing") +
+		"\n  IdentityFile " + priv + "\n  ProxyJump bastion\n  ServerAliveInterval 30\n\n" +
+		"Host *.corp\n  User corp\n"
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	planner := cli.NewHostImportPlanner(nil, dir, false)
+	hosts, err := planner.Plan()
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(hosts) != 3 {
+		t.Fatalf("expected 3 hosts, got %+v", hosts)
+	}
+
+	for _, h := range hosts {
+		if h.Status != "new" {
+			t.Fatalf("expected %s to be new, got %s", h.Host.Host, h.Status)
+		}
+		if h.Host.KeyName != "id_work" || h.NewKey == nil || h.NewKey.Path != priv {
+			t.Fatalf("expected %s to use the new key id_work, got %+v", h.Host.Host, h)
+		}
+	}
+	web2 := hosts[2].Host
+	if web2.Host != "web2" || web2.Hostname != "10.0.0.5" || web2.User != "deploy" {
+		t.Fatalf("unexpected host: %+v", web2)
+	}
+	if web2.JumpHost != "bastion" || web2.ProxyJump != "" {
+		t.Fatalf("expected jump host bastion, got %+v", web2)
+	}
+	if web2.ServerAliveInterval != 30 {
+		t.Fatalf("expected ServerAliveInterval 30, got %d", web2.ServerAliveInterval)
+	}
+}
+
+func TestHostImportApplyAndRemoveBlocks(t *testing.T) {
+	for _, overwrite := range []bool{false, true} {
+		dir := t.TempDir()
+		priv := filepath.Join(dir, "id_work")
+		if err := os.WriteFile(priv, []byte("PRIVATE"), 0600); err != nil {
+			t.Fatalf("write priv: %v", err)
+		}
+		if err := os.WriteFile(priv+".pub", []byte(cli.GenerateTestPubForTest()), 0644); err != nil {
+			t.Fatalf("write pub: %v", err)
+		}
+		content := "Host personal\n  User me\n\n" +
+			"Host bastion\n  HostName bastion.example.com\n  User ops\n  IdentityFile " + priv + "\n\n" +
+			"Host web\n  HostName 10.0.0.5\n  User deploy\n  IdentityFile " + priv + "\n  ProxyJump bastion\n"
+		configPath := filepath.Join(dir, "config")
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+
+		mgr, _ := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+		_ = mgr.Load()
+		if err := mgr.AddHost(models.Host{Host: "web", Hostname: "10.0.0.5", User: "old", Tags: []string{"prod"}}); err != nil {
+			t.Fatalf("add host: %v", err)
+		}
+
+		planner := cli.NewHostImportPlanner(mgr, dir, overwrite)
+		hosts, err := planner.Plan()
+		if err != nil {
+			t.Fatalf("plan: %v", err)
+		}
+		status := make(map[string]string)
+		for _, h := range hosts {
+			status[h.Host.Host] = h.Status
+		}
+		if status["personal"] != "new" || status["bastion"] != "new" || status["web"] != "conflict" {
+			t.Fatalf("unexpected statuses: %v", status)
+		}
+
+		if err := planner.Apply(); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		web, err := mgr.GetHost("web")
+		if err != nil {
+			t.Fatalf("get web: %v", err)
+		}
+		if overwrite && (web.User != "deploy" || web.KeyName != "id_work" || web.JumpHost != "bastion" || len(web.Tags) != 1) {
+			t.Fatalf("expected web replaced keeping its tags, got %+v", web)
+		}
+		if !overwrite && web.User != "old" {
+			t.Fatalf("expected web kept without overwrite, got %+v", web)
+		}
+		if _, err := mgr.GetKey("id_work"); err != nil {
+			t.Fatalf("expected key id_work registered: %v", err)
+		}
+
+		// Blocks of hosts SKM does not write, such as personal without a key
+		// or web kept as it was, stay in the config
+		removed := make(map[string]bool)
+		blocks := planner.RemovableBlocks()
+		for _, b := range blocks {
+			removed[b.Aliases[0]] = true
+		}
+		if removed["personal"] || !removed["bastion"] || removed["web"] != overwrite {
+			t.Fatalf("unexpected removable blocks: %v", removed)
+		}
+
+		keys := make(map[string]*models.Key)
+		for _, k := range mgr.Get().Keys {
+			k := k
+			keys[k.Name] = &k
+		}
+		sshMgr := sshconfig.NewManager(dir)
+		sshMgr.SetIncludeMode(true)
+		sshMgr.DropHostBlocks(blocks)
+		if err := sshMgr.UpdateConfig(mgr.Get().Hosts, keys); err != nil {
+			t.Fatalf("update config: %v", err)
+		}
+
+		file, err := parser.Parse(configPath)
+		if err != nil {
+			t.Fatalf("parse config: %v", err)
+		}
+		// Without overwrite the managed web has no key and is not written, so
+		// its own block, kept in the config, still applies
+		want := map[string][2]string{
+			"personal": {"personal", "me"},
+			"bastion":  {"bastion.example.com", "ops"},
+			"web":      {"10.0.0.5", "deploy"},
+		}
+		for alias, w := range want {
+			s := file.Resolve(alias, parser.Options{LocalUser: "local"})
+			if s.Get("hostname") != w[0] || s.Get("user") != w[1] {
+				t.Errorf("overwrite=%v: %s resolves to %s@%s, want %s@%s", overwrite, alias, s.Get("user"), s.Get("hostname"), w[1], w[0])
+			}
+		}
+		if s := file.Resolve("bastion", parser.Options{LocalUser: "local"}); s.Get("identityfile") != priv {
+			t.Errorf("overwrite=%v: bastion resolves to IdentityFile %q", overwrite, s.Get("identityfile"))
+		}
+		if s := file.Resolve("web", parser.Options{LocalUser: "local"}); overwrite && s.Get("proxyjump") != "bastion" {
+			t.Errorf("expected web to go through bastion, got %q", s.Get("proxyjump"))
+		}
+	}
+}
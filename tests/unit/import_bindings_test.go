@@ -43,3 +43,42 @@ func TestDetectBindingsMatchesKeyByIdentityFile(t *testing.T) {
 		t.Fatalf("expected alias id_ed25519, got %s", bindings[0].TargetAlias)
 	}
 }
+
+func TestDetectBindingsFollowsIncludeAndInheritedSettings(t *testing.T) {
+	dir := t.TempDir()
+	priv := filepath.Join(dir, "id_work")
+	if err := os.WriteFile(priv, []byte("PRIVATE"), 0600); err != nil {
+		t.Fatalf("write priv: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "config.d"), 0700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	main := "Include config.d/*\n\nHost *.work\n  IdentityFile=\"" + priv + "\"\n  Port 2222\n"
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(main), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	included := "Host build.work\n  User ci\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.d", "build"), []byte(included), 0644); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+
+	planner := cli.NewImportPlanner(nil, dir, "", true)
+	planner.KeyMapForTest(map[string]cli.ImportedKey{
+		filepath.Clean(priv): {Alias: "id_work"},
+	})
+
+	bindings, err := planner.DetectBindings()
+	if err != nil {
+		t.Fatalf("detect bindings: %v", err)
+	}
+	if len(bindings) != 2 {
+		t.Fatalf("expected 2 bindings, got %+v", bindings)
+	}
+	build := bindings[0]
+	if build.HostPattern != "build.work" || build.User != "ci" || build.Port != 2222 || build.TargetAlias != "id_work" {
+		t.Fatalf("unexpected binding for included host: %+v", build)
+	}
+	if bindings[1].HostPattern != "*.work" {
+		t.Fatalf("expected wildcard binding, got %+v", bindings[1])
+	}
+}